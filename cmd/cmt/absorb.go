@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"strconv"
+	"os"
+	"os/signal"
+	"sort"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gussy/cmt/internal/ai"
 	"github.com/gussy/cmt/internal/config"
 	"github.com/gussy/cmt/internal/git"
+	"github.com/gussy/cmt/internal/preprocess"
+	"github.com/gussy/cmt/internal/prompt"
 	"github.com/gussy/cmt/internal/ui"
 	"github.com/urfave/cli/v3"
 )
@@ -22,7 +28,11 @@ func absorbCommand() *cli.Command {
 		Usage:   "Intelligently absorb staged changes into previous commits",
 		Description: `The absorb command uses AI to analyze staged changes and automatically
 assign them to the most relevant previous commits, similar to git-absorb but
-with semantic understanding. It creates fixup commits that can be autosquashed.`,
+with semantic understanding. It creates fixup commits that can be autosquashed.
+
+Paths after -- limit absorb to the staged changes in those files; the rest
+stay staged and are left alone.`,
+		ArgsUsage: "[-- <pathspec>...]",
 		Flags: []cli.Flag{
 			&cli.BoolFlag{
 				Name:    "yes",
@@ -38,10 +48,22 @@ with semantic understanding. It creates fixup commits that can be autosquashed.`
 				Aliases: []string{"d"},
 				Usage:   "Number of commits to analyze (from HEAD)",
 			},
+			&cli.StringFlag{
+				Name:  "author",
+				Usage: "Only absorb into commits whose author name or email contains this",
+			},
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "Only absorb into commits authored since a date (2024-01-31) or age (7d, 36h)",
+			},
 			&cli.BoolFlag{
 				Name:  "to-branch-point",
 				Usage: "Analyze all commits back to where branch diverged from main/master",
 			},
+			&cli.BoolFlag{
+				Name:  "fetch",
+				Usage: "Fetch the branch from origin first, so pushed commits aren't mistaken for unpushed ones",
+			},
 			&cli.BoolFlag{
 				Name:  "no-new-commit",
 				Usage: "Don't create a new commit for unmatched hunks",
@@ -51,10 +73,18 @@ with semantic understanding. It creates fixup commits that can be autosquashed.`
 				Aliases: []string{"m"},
 				Usage:   "AI model to use for analysis",
 			},
+			&cli.BoolFlag{
+				Name:  "show-patches",
+				Usage: "Show the hunks each target commit would receive before applying",
+			},
 			&cli.BoolFlag{
 				Name:  "rebase",
 				Usage: "Automatically perform autosquash rebase after creating fixup commits",
 			},
+			&cli.BoolFlag{
+				Name:  "force",
+				Usage: "Allow the rebase to rewrite commits that are already pushed",
+			},
 			&cli.BoolFlag{
 				Name:  "undo",
 				Usage: "Undo the last absorb operation",
@@ -63,10 +93,22 @@ with semantic understanding. It creates fixup commits that can be autosquashed.`
 				Name:  "list-backups",
 				Usage: "List all backup refs and old backup branches",
 			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print --list-backups output as JSON",
+			},
+			&cli.StringFlag{
+				Name:  "restore",
+				Usage: "Reset to the named backup (see --list-backups)",
+			},
 			&cli.BoolFlag{
 				Name:  "cleanup-backups",
 				Usage: "Clean up old backup refs and branches",
 			},
+			&cli.StringFlag{
+				Name:  "keep",
+				Usage: "Backups to keep when pruning: a count (10), an age (14d) or all",
+			},
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			// Handle special operations.
@@ -74,7 +116,10 @@ with semantic understanding. It creates fixup commits that can be autosquashed.`
 				return runAbsorbUndo(ctx)
 			}
 			if cmd.Bool("list-backups") {
-				return runListBackups(ctx)
+				return runListBackups(ctx, cmd.Bool("json"))
+			}
+			if name := cmd.String("restore"); name != "" {
+				return runRestoreBackup(ctx, name)
 			}
 			if cmd.Bool("cleanup-backups") {
 				return runCleanupBackups(ctx)
@@ -91,12 +136,31 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	if cmd.Bool("debug") {
+		cfg.Verbose = true
+	}
+	applyPrefixFlag(cmd, cfg)
 
 	// Initialize git repository.
 	repo, err := git.NewRepository("")
 	if err != nil {
 		return fmt.Errorf("failed to initialize git repository: %w", err)
 	}
+	repo.MaxDiffBytes = cfg.MaxDiffBytes
+	repo.BaseBranch = cfg.BaseBranch
+
+	// Prune backups from earlier runs that fall outside the retention policy.
+	retentionValue := cfg.AbsorbBackupRetention
+	if cmd.IsSet("keep") {
+		retentionValue = cmd.String("keep")
+	}
+	retention, err := git.ParseBackupRetention(retentionValue)
+	if err != nil {
+		return err
+	}
+	if !cmd.Bool("print-prompt") && !cmd.Bool("dry-run") {
+		pruneBackups(ctx, repo, retention)
+	}
 
 	// Step 1: Check for staged changes.
 	ui.SimpleProgress("Checking for staged changes...")
@@ -111,7 +175,17 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 		return nil
 	}
 
-	// Step 2: Determine commit range.
+	// Step 2: Determine commit range. Unpushed commits, and the check for
+	// published ones, are only as current as origin's tracking ref.
+	if cmd.Bool("fetch") || cfg.AbsorbFetch {
+		ui.SimpleProgress("Fetching from origin...")
+		if err := repo.FetchBeforeRange(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Could not fetch from origin, using the last fetched state: %v\n", err)
+		}
+	} else {
+		warnStaleTrackingRef(ctx, repo)
+	}
+
 	ui.SimpleProgress("Determining commit range...")
 	var commits []git.CommitInfo
 
@@ -135,6 +209,21 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
+	// Narrow the range to the requested author and date so a teammate's
+	// commits are never amended by accident.
+	filter := git.CommitFilter{Author: cmd.String("author")}
+	if since := cmd.String("since"); since != "" {
+		filter.Since, err = git.ParseSince(since, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	if filter.Author != "" || !filter.Since.IsZero() {
+		total := len(commits)
+		commits = git.FilterCommits(commits, filter)
+		ui.Infof("🔎 %d of %d commit(s) match the author/date filter\n", len(commits), total)
+	}
+
 	if len(commits) == 0 {
 		fmt.Println("❌ No commits found in the specified range.")
 		fmt.Println("\nThe absorb command needs existing commits to absorb changes into.")
@@ -142,11 +231,13 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 		return nil
 	}
 
-	fmt.Printf("📝 Found %d commit(s) to analyze\n", len(commits))
+	ui.Infof("📝 Found %d commit(s) to analyze\n", len(commits))
 
-	// Step 3: Get staged diff and split into hunks.
+	// Step 3: Get staged diff and split into hunks. A pathspec leaves the
+	// staged changes outside it out of the analysis.
 	ui.SimpleProgress("Analyzing staged changes...")
-	diff, err := repo.GetDiff(ctx, true)
+	pathspec := cmd.Args().Slice()
+	diff, err := repo.GetDiffForFiles(ctx, true, pathspec)
 	if err != nil {
 		return fmt.Errorf("failed to get diff: %w", err)
 	}
@@ -157,14 +248,37 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 	}
 
 	if len(hunks) == 0 {
+		if len(pathspec) > 0 {
+			fmt.Printf("❌ No staged hunks match %s.\n", strings.Join(pathspec, " "))
+			return nil
+		}
 		fmt.Println("❌ No hunks found in staged changes.")
 		return nil
 	}
 
-	fmt.Printf("🔍 Found %d hunk(s) to absorb\n", len(hunks))
+	// Skip binary, minified and generated files; they can't be meaningfully
+	// matched to a commit and are left staged for the user to handle.
+	preprocessOpts := filterOptions(ctx, cfg, repo)
+	hunks, filtered := git.FilterHunks(hunks, func(path string) string {
+		return preprocess.SkipReason(path, preprocessOpts)
+	})
+
+	if len(filtered) > 0 {
+		ui.Infof("🚫 Skipped %d hunk(s) from filtered files (left staged):\n", len(filtered))
+		for _, f := range filtered {
+			ui.Infof("   • %s (%s)\n", f.Hunk.FilePath, f.Reason)
+		}
+	}
 
-	// Step 4: Check for potential conflicts (unless dry-run).
-	if !cmd.Bool("dry-run") {
+	if len(hunks) == 0 {
+		fmt.Println("❌ No hunks left to absorb after filtering.")
+		return nil
+	}
+
+	ui.Infof("🔍 Found %d hunk(s) to absorb\n", len(hunks))
+
+	// Step 4: Check for potential conflicts (unless only previewing).
+	if !cmd.Bool("dry-run") && !cmd.Bool("print-prompt") {
 		ui.SimpleProgress("Checking for potential conflicts...")
 		shas := make([]string, len(commits))
 		for i, c := range commits {
@@ -184,7 +298,7 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 				var response string
 				fmt.Scanln(&response)
 				if response != "y" && response != "yes" {
-					fmt.Println("❌ Absorb cancelled.")
+					ui.Infoln("❌ Absorb cancelled.")
 					return nil
 				}
 			}
@@ -199,20 +313,25 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 	}
 
 	providerCfg := &ai.ProviderConfig{
-		DefaultModel: model,
-		Timeout:      60,
+		DefaultModel:      model,
+		Timeout:           60,
+		RequestsPerMinute: cfg.RequestsPerMinute,
 	}
 
 	provider, err := ai.NewClaudeCLI(providerCfg)
 	if err != nil {
 		return fmt.Errorf("failed to initialize AI provider: %w", err)
 	}
+	defer closeProvider(provider)
 
 	// Check if provider is available.
 	available, err := provider.IsAvailable(ctx)
-	if err != nil || !available {
+	if err != nil {
 		return fmt.Errorf("AI provider is not available: %w", err)
 	}
+	if !available {
+		return fmt.Errorf("AI provider is not available. Please ensure 'claude' is installed and in your PATH")
+	}
 
 	// Step 6: Analyze hunk assignments with AI.
 	ui.SimpleProgress("Analyzing hunk assignments with AI...")
@@ -229,6 +348,13 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 		confidence = 0.7
 	}
 
+	// Past absorb_max_hunks the model sees whole files, so a big
+	// refactor doesn't overflow the prompt or the review.
+	groupByFile := cfg.AbsorbMaxHunks > 0 && len(hunks) > cfg.AbsorbMaxHunks
+	if groupByFile {
+		ui.Infof("🗂  %d hunks is more than absorb_max_hunks (%d); assigning whole files instead\n", len(hunks), cfg.AbsorbMaxHunks)
+	}
+
 	absorbReq := &ai.AbsorbRequest{
 		Hunks:               hunks,
 		Commits:             commits,
@@ -237,6 +363,14 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 		Model:               model,
 		Temperature:         cfg.Temperature,
 		MaxTokens:           cfg.MaxTokens,
+		GroupByFile:         groupByFile,
+		FileHistory:         cfg.AbsorbFileHistory,
+	}
+
+	// Debugging aid: show exactly what would be sent and stop.
+	if cmd.Bool("print-prompt") {
+		fmt.Fprintln(os.Stderr, provider.AbsorbPrompt(absorbReq))
+		return nil
 	}
 
 	absorbResp, err := provider.AnalyzeHunkAssignment(ctx, absorbReq)
@@ -245,26 +379,30 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 	}
 
 	// Step 7: Show analysis results.
-	fmt.Println("\n📊 Analysis Results:")
-	fmt.Println("=" + strings.Repeat("=", 40))
+	ui.Infoln("\n📊 Analysis Results:")
+	ui.Infoln("=" + strings.Repeat("=", 40))
 
 	if len(absorbResp.Assignments) > 0 {
-		fmt.Printf("\n✅ Assigned hunks: %d\n", len(absorbResp.Assignments))
+		ui.Infof("\n✅ Assigned hunks: %d\n", len(absorbResp.Assignments))
 		for _, assignment := range absorbResp.Assignments {
-			fmt.Printf("   • %s → %s: %.1f%% confidence\n",
-				assignment.Hunk.FilePath,
+			file := assignment.Hunk.FilePath
+			if len(assignment.Hunks) > 0 {
+				file = fmt.Sprintf("%s (%d hunks)", file, len(assignment.Hunks))
+			}
+			ui.Infof("   • %s → %s: %.1f%% confidence\n",
+				file,
 				assignment.CommitSHA[:8],
 				assignment.Confidence*100)
 			if assignment.Reasoning != "" && cfg.Verbose {
-				fmt.Printf("     Reason: %s\n", assignment.Reasoning)
+				ui.Infof("     Reason: %s\n", assignment.Reasoning)
 			}
 		}
 	}
 
 	if len(absorbResp.UnmatchedHunks) > 0 {
-		fmt.Printf("\n❓ Unmatched hunks: %d\n", len(absorbResp.UnmatchedHunks))
+		ui.Infof("\n❓ Unmatched hunks: %d\n", len(absorbResp.UnmatchedHunks))
 		for _, hunk := range absorbResp.UnmatchedHunks {
-			fmt.Printf("   • %s\n", hunk.FilePath)
+			ui.Infof("   • %s\n", hunk.FilePath)
 		}
 	}
 
@@ -277,7 +415,7 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 		}
 
 		if !accepted {
-			fmt.Println("\n❌ Absorb cancelled.")
+			ui.Infoln("\n❌ Absorb cancelled.")
 			return nil
 		}
 
@@ -287,50 +425,71 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 		}
 	}
 
+	// The autosquash rebase rewrites every target commit; refuse to rewrite
+	// published history unless the user explicitly allows it.
+	rebase := cmd.Bool("rebase") || cfg.AbsorbStrategy == "direct"
+	if rebase {
+		published, err := repo.PublishedCommits(ctx, rebaseTargets(commits, absorbResp.Assignments))
+		if err != nil {
+			return err
+		}
+		if len(published) > 0 && !cmd.Bool("force") {
+			rebase = confirmRewritePublished(published, cmd.Bool("yes") || cmd.Bool("dry-run"))
+		}
+	}
+
+	// Anything left uncommitted, staged or not, is stashed around the rebase
+	// and reapplied afterwards; make sure that doesn't come as a surprise.
+	var dirty bool
+	if rebase {
+		clean, err := repo.IsClean(ctx)
+		if err != nil {
+			return err
+		}
+		dirty = !clean
+		if dirty && !confirmAutostash(cmd.Bool("yes") || cmd.Bool("dry-run")) {
+			ui.Infoln("\n❌ Absorb cancelled.")
+			return nil
+		}
+	}
+
+	// Show what each target commit will absorb, and confirm before any
+	// history is touched.
+	if cmd.Bool("show-patches") && len(absorbResp.Assignments) > 0 {
+		if err := ui.PrintDiff(renderPatchSeries(commits, groupHunksByCommit(absorbResp.Assignments))); err != nil {
+			return err
+		}
+		if !cmd.Bool("yes") && !cmd.Bool("dry-run") {
+			fmt.Print("\nApply these fixups? (y/n): ")
+			var response string
+			fmt.Scanln(&response)
+			if response != "y" && response != "yes" {
+				ui.Infoln("\n❌ Absorb cancelled.")
+				return nil
+			}
+		}
+	}
+
 	// Step 9: Dry-run mode - show plan and exit.
 	if cmd.Bool("dry-run") {
 		fmt.Println("\n🔍 DRY RUN - No changes will be made")
 		fmt.Println("\nPlan:")
-		for _, assignment := range absorbResp.Assignments {
-			fmt.Printf("• Create fixup commit for %s with hunks from %s\n",
-				assignment.CommitSHA[:8], assignment.Hunk.FilePath)
-		}
+		fmt.Print(renderAbsorbPlan(commits, absorbResp.Assignments))
 
-		if len(absorbResp.UnmatchedHunks) > 0 && !cmd.Bool("no-new-commit") {
+		if len(absorbResp.UnmatchedHunks) > 0 && !cmd.Bool("no-new-commit") && cfg.AbsorbAutoCommit {
 			fmt.Printf("• Create new commit with %d unmatched hunk(s)\n",
 				len(absorbResp.UnmatchedHunks))
 		}
 
-		if cmd.Bool("rebase") || cfg.AbsorbStrategy == "direct" {
+		if rebase {
 			fmt.Println("• Perform autosquash rebase")
 		}
 
 		return nil
 	}
 
-	// Step 10: Apply assignments (create fixup commits).
-	if len(absorbResp.Assignments) > 0 {
-		ui.SimpleProgress("Creating fixup commits...")
-
-		// Group hunks by target commit.
-		commitHunks := make(map[string][]git.Hunk)
-		for _, assignment := range absorbResp.Assignments {
-			commitHunks[assignment.CommitSHA] = append(
-				commitHunks[assignment.CommitSHA],
-				assignment.Hunk,
-			)
-		}
-
-		// Create fixup commit for each target.
-		for sha, hunks := range commitHunks {
-			if err := repo.ApplyHunksAsFixup(ctx, hunks, sha); err != nil {
-				return fmt.Errorf("failed to create fixup commit for %s: %w", sha[:8], err)
-			}
-			fmt.Printf("✅ Created fixup commit for %s\n", sha[:8])
-		}
-	}
-
-	// Step 11: Create backup ref AFTER fixup commits to capture the correct state.
+	// Step 10: Create backup ref and save undo state before the first
+	// mutation, so undo works even if absorb is interrupted.
 	// Uses custom refs namespace to avoid polluting branch list.
 	ui.SimpleProgress("Creating backup...")
 	backupName := fmt.Sprintf("absorb-%d", time.Now().Unix())
@@ -338,52 +497,8 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 	if err != nil {
 		return fmt.Errorf("failed to create backup: %w", err)
 	}
-	fmt.Printf("✅ Created backup: %s\n", backupName)
-
-	// Step 12: Handle unmatched hunks.
-	if len(absorbResp.UnmatchedHunks) > 0 && !cmd.Bool("no-new-commit") {
-		if cfg.AbsorbAutoCommit {
-			ui.SimpleProgress("Creating commit for unmatched hunks...")
-
-			// Re-stage the unmatched hunks.
-			for _, _ = range absorbResp.UnmatchedHunks {
-				// The hunks should still be staged if they weren't absorbed.
-			}
-
-			// Check if there are still staged changes.
-			hasChanges, _ := repo.HasStagedChanges(ctx)
-			if hasChanges {
-				// Generate commit message for unmatched hunks.
-				fmt.Println("📝 Generating commit message for unmatched hunks...")
-
-				// Use the regular commit message generation.
-				diff, _ := repo.GetDiff(ctx, true)
-				stagedFiles, _ := repo.GetStagedFiles(ctx)
-
-				commitReq := &ai.CommitRequest{
-					Diff:        diff,
-					StagedFiles: stagedFiles,
-					Model:       model,
-					Temperature: cfg.Temperature,
-					MaxTokens:   cfg.MaxTokens,
-				}
+	ui.Infof("✅ Created backup: %s\n", backupName)
 
-				commitResp, err := provider.GenerateCommitMessage(ctx, commitReq)
-				if err != nil {
-					return fmt.Errorf("failed to generate commit message: %w", err)
-				}
-
-				// Create the commit.
-				if err := repo.Commit(ctx, commitResp.Message); err != nil {
-					return fmt.Errorf("failed to create commit: %w", err)
-				}
-				fmt.Printf("✅ Created commit for unmatched hunks: %s\n",
-					strings.Split(commitResp.Message, "\n")[0])
-			}
-		}
-	}
-
-	// Step 13: Save absorb state for undo.
 	currentBranch, _ := repo.GetCurrentBranch(ctx)
 	// Get actual HEAD SHA instead of string "HEAD" for proper restoration.
 	headSHA, err := repo.GetCurrentCommitSHA(ctx)
@@ -393,7 +508,7 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 	}
 	state := &git.AbsorbState{
 		OriginalHEAD:  headSHA,
-		BackupRef:     backupRef, // Use new ref format
+		BackupRef:     backupRef,
 		CurrentBranch: currentBranch,
 		Timestamp:     time.Now().Unix(),
 		Operations: []string{
@@ -403,12 +518,73 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 	}
 
 	if err := git.SaveAbsorbState(repo, state); err != nil {
-		// Non-fatal error.
-		fmt.Printf("⚠️  Warning: Failed to save undo state: %v\n", err)
+		return fmt.Errorf("failed to save undo state: %w", err)
+	}
+
+	// Step 11: Create fixup commits and the commit for unmatched hunks.
+	// On interrupt or failure, offer to roll back to the backup.
+	confirmRollback := func() bool {
+		if cmd.Bool("yes") {
+			return true
+		}
+		fmt.Print("Restore the repository to its state before absorb? (y/n): ")
+		var response string
+		fmt.Scanln(&response)
+		return response == "y" || response == "yes"
 	}
 
-	// Step 14: Perform rebase if requested.
-	if cmd.Bool("rebase") || cfg.AbsorbStrategy == "direct" {
+	err = withAbsorbRollback(ctx, repo, confirmRollback, func(ctx context.Context) error {
+		if len(absorbResp.Assignments) > 0 {
+			ui.SimpleProgress("Creating fixup commits...")
+
+			// Create a fixup commit for each target, all or nothing.
+			commitHunks := groupHunksByCommit(absorbResp.Assignments)
+			targets := fixupOrder(commits, commitHunks)
+			if err := repo.ApplyFixups(ctx, targets, commitHunks); err != nil {
+				return err
+			}
+			for _, sha := range targets {
+				ui.Infof("✅ Created fixup commit for %s\n", sha[:8])
+			}
+		}
+
+		// Handle unmatched hunks.
+		if len(absorbResp.UnmatchedHunks) == 0 || cmd.Bool("no-new-commit") || !cfg.AbsorbAutoCommit {
+			return nil
+		}
+		ui.SimpleProgress("Creating commit for unmatched hunks...")
+
+		// Generate commit message for unmatched hunks. Only they are
+		// described and committed: filtered hunks and staged changes
+		// outside the pathspec stay staged.
+		ui.Infoln("📝 Generating commit message for unmatched hunks...")
+		unmatched := absorbResp.UnmatchedHunks
+		diff := git.FormatPatch(unmatched)
+		commitResp, err := provider.GenerateCommitMessage(ctx, leftoverCommitRequest(cfg, diff, hunkFileList(unmatched), model))
+		if err != nil {
+			return fmt.Errorf("failed to generate commit message: %w", err)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Create the commit, with the message cleaned up and styled like
+		// any other.
+		commitResp.Message = finalizeMessage(ctx, cfg, repo, commitResp.Message, nil)
+		commitResp.Message = prompt.PrefixSubject(commitResp.Message, cfg.MessagePrefix)
+		if err := repo.CommitHunks(ctx, unmatched, commitResp.Message); err != nil {
+			return fmt.Errorf("failed to create commit: %w", err)
+		}
+		ui.Infof("✅ Created commit for unmatched hunks: %s\n",
+			strings.Split(commitResp.Message, "\n")[0])
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Step 12: Perform rebase if requested.
+	if rebase {
 		ui.SimpleProgress("Performing autosquash rebase...")
 
 		// Find the base commit (oldest absorbed commit's parent).
@@ -429,24 +605,306 @@ func runAbsorb(ctx context.Context, cmd *cli.Command) error {
 		}
 
 		if baseCommit != "" {
+			// Stash leftover changes ourselves rather than relying on
+			// --autostash, so undo knows which stash to restore and
+			// staged changes come back staged.
+			if dirty {
+				stashSHA, err := repo.Stash(ctx, "cmt absorb: uncommitted changes")
+				if err != nil {
+					return fmt.Errorf("failed to stash uncommitted changes: %w", err)
+				}
+				state.StashSHA = stashSHA
+				if err := git.SaveAbsorbState(repo, state); err != nil {
+					return fmt.Errorf("failed to save undo state: %w", err)
+				}
+			}
+
 			if err := repo.AutosquashRebase(ctx, baseCommit); err != nil {
-				fmt.Printf("⚠️  Warning: Rebase failed: %v\n", err)
-				fmt.Println("You can manually run: git rebase --autosquash -i " + baseCommit)
+				fmt.Fprintf(os.Stderr, "⚠️  Warning: Rebase failed: %v\n", err)
+				fmt.Fprintln(os.Stderr, "You can manually run: git rebase --autosquash -i "+baseCommit)
+				if state.StashSHA != "" {
+					fmt.Fprintf(os.Stderr, "Your uncommitted changes are stashed as %s; cmt absorb --undo restores them.\n", state.StashSHA[:8])
+				}
 			} else {
-				fmt.Println("✅ Successfully performed autosquash rebase")
+				ui.Infoln("✅ Successfully performed autosquash rebase")
+				restoreAbsorbStash(ctx, repo, state)
 			}
 		}
 	} else {
-		fmt.Println("\n💡 To complete the absorb, run:")
-		fmt.Println("   git rebase --autosquash -i <base-commit>")
+		ui.Infoln("\n💡 To complete the absorb, run:")
+		ui.Infoln("   git rebase --autosquash -i <base-commit>")
 	}
 
-	fmt.Println("\n✨ Absorb completed successfully!")
-	fmt.Printf("💾 To undo, run: cmt absorb --undo\n")
+	ui.Infoln("\n✨ Absorb completed successfully!")
+	ui.Infof("💾 To undo, run: cmt absorb --undo\n")
 
 	return nil
 }
 
+// staleTrackingRefAge is how old origin's tracking ref may get before absorb
+// suggests --fetch.
+const staleTrackingRefAge = 24 * time.Hour
+
+// warnStaleTrackingRef warns when origin/<branch> hasn't been updated for
+// staleTrackingRefAge, since commits pushed since then look unpushed.
+// Branches without a tracking ref are left alone.
+func warnStaleTrackingRef(ctx context.Context, repo *git.Repository) {
+	branch, err := repo.GetCurrentBranch(ctx)
+	if err != nil || branch == "HEAD" {
+		return
+	}
+	updated, ok, err := repo.TrackingRefUpdated(ctx, branch)
+	if err != nil || !ok {
+		return
+	}
+	if age := time.Since(updated); age > staleTrackingRefAge {
+		fmt.Fprintf(os.Stderr, "⚠️  origin/%s was last updated %s ago; commits pushed since may look unpushed. Use --fetch to update it first.\n",
+			branch, formatAge(age))
+	}
+}
+
+// formatAge renders a duration in whole hours, or days past two days, the
+// way --since takes them.
+func formatAge(d time.Duration) string {
+	if d < 48*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// groupHunksByCommit groups the assigned hunks by target commit SHA.
+func groupHunksByCommit(assignments []ai.HunkAssignment) map[string][]git.Hunk {
+	commitHunks := make(map[string][]git.Hunk)
+	for _, assignment := range assignments {
+		commitHunks[assignment.CommitSHA] = append(commitHunks[assignment.CommitSHA], assignment.AllHunks()...)
+	}
+	return commitHunks
+}
+
+// fixupOrder lists the target SHAs of commitHunks in the order of commits,
+// followed by any targets not among commits.
+func fixupOrder(commits []git.CommitInfo, commitHunks map[string][]git.Hunk) []string {
+	var order []string
+	listed := make(map[string]bool)
+	for _, c := range commits {
+		if _, ok := commitHunks[c.SHA]; ok && !listed[c.SHA] {
+			listed[c.SHA] = true
+			order = append(order, c.SHA)
+		}
+	}
+
+	var rest []string
+	for sha := range commitHunks {
+		if !listed[sha] {
+			rest = append(rest, sha)
+		}
+	}
+	sort.Strings(rest)
+	return append(order, rest...)
+}
+
+// renderPatchSeries renders the hunks each commit will absorb as a series of
+// patches, one per target commit in the order of commits, each under a
+// header naming the commit.
+func renderPatchSeries(commits []git.CommitInfo, commitHunks map[string][]git.Hunk) string {
+	var series []string
+	for _, c := range commits {
+		hunks := commitHunks[c.SHA]
+		if len(hunks) == 0 {
+			continue
+		}
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		header := fmt.Sprintf("# fixup for %s %s (%d hunk(s))", c.SHA[:8], subject, len(hunks))
+		series = append(series, header+"\n"+strings.TrimRight(git.FormatPatch(hunks), "\n"))
+	}
+	return strings.Join(series, "\n\n")
+}
+
+// renderAbsorbPlan lists the fixup commits a dry run would create, one per
+// target commit in the order of commits, each followed by the hunks it
+// gets. Each hunk is colored by the confidence of its assignment.
+func renderAbsorbPlan(commits []git.CommitInfo, assignments []ai.HunkAssignment) string {
+	subjects := make(map[string]string, len(commits))
+	for _, c := range commits {
+		subjects[c.SHA], _, _ = strings.Cut(c.Message, "\n")
+	}
+	byCommit := make(map[string][]ai.HunkAssignment)
+	for _, a := range assignments {
+		byCommit[a.CommitSHA] = append(byCommit[a.CommitSHA], a)
+		if _, ok := subjects[a.CommitSHA]; !ok {
+			subjects[a.CommitSHA] = a.CommitMessage
+		}
+	}
+
+	var b strings.Builder
+	for _, sha := range fixupOrder(commits, groupHunksByCommit(assignments)) {
+		target := strings.TrimSpace(sha[:min(len(sha), 8)] + " " + subjects[sha])
+		fmt.Fprintf(&b, "• Create fixup commit for %s\n", target)
+		for _, a := range byCommit[sha] {
+			hunk := fmt.Sprintf("%s %s", a.Hunk.FilePath, a.Hunk.Header)
+			if len(a.Hunks) > 0 {
+				hunk = fmt.Sprintf("%s (%d hunks)", a.Hunk.FilePath, len(a.Hunks))
+			}
+			line := fmt.Sprintf("%s: %.1f%% confidence", hunk, a.Confidence*100)
+			fmt.Fprintf(&b, "    - %s\n", ui.ConfidenceStyle(a.Confidence).Render(line))
+		}
+	}
+	return b.String()
+}
+
+// hunkFileList lists the files hunks touch with their status, as
+// formatFileStatuses does for the staged files.
+func hunkFileList(hunks []git.Hunk) []string {
+	var files []string
+	seen := make(map[string]bool)
+	for _, hunk := range hunks {
+		if seen[hunk.FilePath] {
+			continue
+		}
+		seen[hunk.FilePath] = true
+		status := git.FileStatus{Path: hunk.FilePath, Status: "M"}
+		switch {
+		case hunk.IsNew:
+			status.Status = "A"
+		case hunk.IsDeleted:
+			status.Status = "D"
+		case hunk.IsRenamed:
+			status.Status = "R"
+			status.OldPath = hunk.OldFilePath
+		}
+		files = append(files, status.String())
+	}
+	return files
+}
+
+// leftoverCommitRequest builds the request for the commit of the hunks that
+// weren't absorbed, telling the model they are leftovers and about any
+// message_prefix.
+func leftoverCommitRequest(cfg *config.Config, diff string, stagedFiles []string, model string) *ai.CommitRequest {
+	instructions := cfg.AbsorbLeftoverPrompt
+	if strings.TrimSpace(cfg.MessagePrefix) != "" {
+		instructions = strings.TrimRight(instructions, "\n") + "\n" + prompt.PrefixInstructions(cfg.MessagePrefix)
+	}
+	return &ai.CommitRequest{
+		Diff:         diff,
+		StagedFiles:  stagedFiles,
+		Instructions: instructions,
+		Grounding:    cfg.Grounding,
+		Model:        model,
+		Temperature:  cfg.Temperature,
+		MaxTokens:    cfg.MaxTokens,
+	}
+}
+
+// rebaseTargets returns the commits that received at least one assignment,
+// in the order of commits.
+func rebaseTargets(commits []git.CommitInfo, assignments []ai.HunkAssignment) []git.CommitInfo {
+	assigned := make(map[string]bool, len(assignments))
+	for _, a := range assignments {
+		assigned[a.CommitSHA] = true
+	}
+
+	var targets []git.CommitInfo
+	for _, c := range commits {
+		if assigned[c.SHA] {
+			targets = append(targets, c)
+		}
+	}
+	return targets
+}
+
+// confirmRewritePublished warns that the rebase would rewrite the published
+// commits and asks whether to go ahead. Without a prompt (--yes or a dry run)
+// the rebase is skipped; --force is the only non-interactive way to allow it.
+func confirmRewritePublished(published []git.CommitInfo, noPrompt bool) bool {
+	fmt.Println("\n⚠️  Warning: The autosquash rebase would rewrite commits that are already pushed:")
+	for _, c := range published {
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		fmt.Printf("   • %s %s\n", c.SHA[:8], subject)
+	}
+
+	if noPrompt {
+		fmt.Println("Skipping the rebase. Re-run with --force to rewrite published history.")
+		return false
+	}
+
+	fmt.Print("\nRewrite published history? (y/n): ")
+	var response string
+	fmt.Scanln(&response)
+	if response != "y" && response != "yes" {
+		fmt.Println("Skipping the rebase; fixup commits will still be created.")
+		return false
+	}
+	return true
+}
+
+// confirmAutostash warns that uncommitted changes will be stashed during the
+// rebase and asks whether to go ahead. Without a prompt (--yes or a dry run)
+// absorb proceeds.
+func confirmAutostash(noPrompt bool) bool {
+	fmt.Println("\n⚠️  Warning: You have uncommitted changes. The autosquash rebase will stash them and reapply them afterwards.")
+	if noPrompt {
+		return true
+	}
+
+	fmt.Print("Proceed with absorb? (y/n): ")
+	var response string
+	fmt.Scanln(&response)
+	return response == "y" || response == "yes"
+}
+
+// restoreAbsorbStash reapplies the changes stashed before the rebase and
+// clears StashSHA from the undo state, so a later undo doesn't try to restore
+// them a second time.
+func restoreAbsorbStash(ctx context.Context, repo *git.Repository, state *git.AbsorbState) {
+	if state.StashSHA == "" {
+		return
+	}
+	if _, err := repo.StashPopSHA(ctx, state.StashSHA); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: Could not restore uncommitted changes: %v\n", err)
+		fmt.Fprintf(os.Stderr, "   They are saved in the stash: git stash apply %s\n", state.StashSHA)
+		return
+	}
+	state.StashSHA = ""
+	if err := git.SaveAbsorbState(repo, state); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Warning: Could not update undo state: %v\n", err)
+	}
+}
+
+// withAbsorbRollback runs the mutating part of absorb. If it fails, panics or
+// is interrupted (Ctrl+C), the user is offered a rollback to the backup saved
+// before the first change, which also drops any partial fixup commits.
+func withAbsorbRollback(ctx context.Context, repo *git.Repository, confirm func() bool, mutate func(context.Context) error) (err error) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("absorb panicked: %v", r)
+		}
+		if err == nil {
+			return
+		}
+
+		// Restore default signal handling so a second Ctrl+C exits.
+		stop()
+
+		fmt.Printf("\n⚠️  Absorb did not finish: %v\n", err)
+		if !confirm() {
+			fmt.Println("💾 To roll back later, run: cmt absorb --undo")
+			return
+		}
+
+		if undoErr := repo.UndoAbsorb(context.Background()); undoErr != nil {
+			err = fmt.Errorf("%w (rollback failed: %v)", err, undoErr)
+			return
+		}
+		fmt.Println("✅ Restored repository from backup")
+	}()
+
+	return mutate(ctx)
+}
+
 // runAbsorbUndo undoes the last absorb operation.
 func runAbsorbUndo(ctx context.Context) error {
 	ui.SimpleProgress("Undoing last absorb operation...")
@@ -464,46 +922,115 @@ func runAbsorbUndo(ctx context.Context) error {
 	return nil
 }
 
+// backupJSON is the --list-backups --json representation of a backup.
+type backupJSON struct {
+	Name    string `json:"name"`
+	Ref     string `json:"ref"`
+	SHA     string `json:"sha"`
+	Branch  string `json:"branch,omitempty"`
+	Created string `json:"created,omitempty"`
+}
+
 // runListBackups lists all backup refs.
-func runListBackups(ctx context.Context) error {
+func runListBackups(ctx context.Context, asJSON bool) error {
 	repo, err := git.NewRepository("")
 	if err != nil {
 		return fmt.Errorf("failed to initialize git repository: %w", err)
 	}
 
 	// List backup refs.
-	refs, err := repo.ListBackupRefs(ctx)
+	backups, err := repo.ListBackups(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to list backup refs: %w", err)
 	}
 
-	if len(refs) == 0 {
+	if asJSON {
+		entries := make([]backupJSON, len(backups))
+		for i, b := range backups {
+			entries[i] = backupJSON{Name: b.Name, Ref: b.Ref, SHA: b.SHA, Branch: b.Branch}
+			if !b.Created.IsZero() {
+				entries[i].Created = b.Created.Format(time.RFC3339)
+			}
+		}
+		out, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode backups: %w", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	if len(backups) == 0 {
 		fmt.Println("No backup refs found.")
 		return nil
 	}
 
 	fmt.Println("📚 Absorb backups:")
-	for _, ref := range refs {
-		// Extract timestamp from ref name
-		parts := strings.Split(ref, "/")
-		name := parts[len(parts)-1]
-
-		// Parse timestamp if possible
-		var timeStr string
-		if strings.HasPrefix(name, "absorb-") {
-			timestampStr := strings.TrimPrefix(name, "absorb-")
-			if timestamp, err := strconv.ParseInt(timestampStr, 10, 64); err == nil {
-				t := time.Unix(timestamp, 0)
-				timeStr = fmt.Sprintf(" (%s)", t.Format("2006-01-02 15:04:05"))
-			}
+	for _, b := range backups {
+		details := []string{b.SHA[:8]}
+		if b.Branch != "" {
+			details = append(details, "on "+b.Branch)
+		}
+		if !b.Created.IsZero() {
+			details = append(details, b.Created.Format("2006-01-02 15:04:05"))
 		}
 
-		fmt.Printf("  • %s%s\n", name, timeStr)
+		fmt.Printf("  • %s (%s)\n", b.Name, strings.Join(details, ", "))
 	}
+	fmt.Println("\n💡 To restore one, run: cmt absorb --restore <name>")
 
 	return nil
 }
 
+// runRestoreBackup resets to a specific backup ref.
+func runRestoreBackup(ctx context.Context, name string) error {
+	repo, err := git.NewRepository("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+
+	ui.SimpleProgress("Restoring backup...")
+	backup, err := repo.RestoreBackup(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to restore backup: %w", err)
+	}
+
+	fmt.Printf("✅ Restored %s (%s)\n", backup.Name, backup.SHA[:8])
+	return nil
+}
+
+// pruneBackups deletes backup refs outside the retention policy, never
+// touching the backup of the last absorb. Failures are reported but don't
+// stop the absorb run.
+func pruneBackups(ctx context.Context, repo *git.Repository, retention git.BackupRetention) {
+	if retention == (git.BackupRetention{}) {
+		return
+	}
+
+	refs, err := repo.ListBackupRefs(ctx)
+	if err != nil || len(refs) == 0 {
+		return
+	}
+
+	activeBackupRef := ""
+	if state, err := git.LoadAbsorbState(repo); err == nil && state != nil {
+		activeBackupRef = state.BackupRef
+	}
+
+	pruned := 0
+	for _, ref := range git.SelectBackupsToPrune(refs, retention, activeBackupRef, time.Now()) {
+		if err := repo.DeleteBackupRef(ctx, ref); err != nil {
+			fmt.Printf("⚠️  Failed to delete ref %s: %v\n", ref, err)
+			continue
+		}
+		pruned++
+	}
+
+	if pruned > 0 {
+		ui.Infof("🗑️  Pruned %d old backup(s)\n", pruned)
+	}
+}
+
 // runCleanupBackups cleans up old backup refs.
 func runCleanupBackups(ctx context.Context) error {
 	repo, err := git.NewRepository("")
@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gussy/cmt/internal/ai"
+	"github.com/gussy/cmt/internal/config"
+	"github.com/gussy/cmt/internal/git"
+	"github.com/gussy/cmt/internal/preprocess"
+	"github.com/muesli/termenv"
+)
+
+// startAbsorb stages a change and records a backup and undo state, as
+// runAbsorb does before its first mutation.
+func startAbsorb(t *testing.T, repo *git.Repository) string {
+	t.Helper()
+	ctx := context.Background()
+
+	if err := os.WriteFile(filepath.Join(repo.Path, "fix.txt"), []byte("fix\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.StageFiles(ctx, []string{"fix.txt"}); err != nil {
+		t.Fatal(err)
+	}
+
+	head, err := repo.GetCurrentCommitSHA(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupRef, err := repo.CreateBackupRef(ctx, "absorb-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	branch, err := repo.GetCurrentBranch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := &git.AbsorbState{
+		OriginalHEAD:  head,
+		BackupRef:     backupRef,
+		CurrentBranch: branch,
+		Timestamp:     time.Now().Unix(),
+	}
+	if err := git.SaveAbsorbState(repo, state); err != nil {
+		t.Fatal(err)
+	}
+	return head
+}
+
+// interruptedMutation creates a commit and then gets cancelled, like a
+// Ctrl+C arriving between two fixup commits.
+func interruptedMutation(repo *git.Repository) func(context.Context) error {
+	return func(ctx context.Context) error {
+		if err := repo.Commit(ctx, "fixup! initial commit"); err != nil {
+			return err
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		cancel()
+		return ctx.Err()
+	}
+}
+
+func TestAbsorbRollbackOnCancellation(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	head := startAbsorb(t, repo)
+
+	confirmed := false
+	err := withAbsorbRollback(ctx, repo, func() bool {
+		confirmed = true
+		return true
+	}, interruptedMutation(repo))
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation error, got %v", err)
+	}
+	if !confirmed {
+		t.Error("expected rollback to be offered")
+	}
+
+	current, err := repo.GetCurrentCommitSHA(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != head {
+		t.Errorf("expected HEAD to be restored to %s, got %s", head, current)
+	}
+
+	// The change itself must survive the rollback.
+	if _, err := os.Stat(filepath.Join(repo.Path, "fix.txt")); err != nil {
+		t.Errorf("expected working tree change to be kept: %v", err)
+	}
+}
+
+func TestAbsorbRollbackDeclined(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	head := startAbsorb(t, repo)
+
+	err := withAbsorbRollback(ctx, repo, func() bool { return false }, interruptedMutation(repo))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	current, _ := repo.GetCurrentCommitSHA(ctx)
+	if current == head {
+		t.Error("expected partial commit to be left in place when rollback is declined")
+	}
+
+	// Undo state must still be available for a later `cmt absorb --undo`.
+	if _, err := git.LoadAbsorbState(repo); err != nil {
+		t.Errorf("expected undo state to remain: %v", err)
+	}
+}
+
+func TestAbsorbRollbackOnPanic(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	head := startAbsorb(t, repo)
+
+	err := withAbsorbRollback(ctx, repo, func() bool { return true }, func(ctx context.Context) error {
+		if err := repo.Commit(ctx, "fixup! initial commit"); err != nil {
+			return err
+		}
+		panic("boom")
+	})
+	if err == nil {
+		t.Fatal("expected panic to be reported as an error")
+	}
+
+	current, _ := repo.GetCurrentCommitSHA(ctx)
+	if current != head {
+		t.Errorf("expected HEAD to be restored after panic")
+	}
+}
+
+func TestAbsorbRollbackNotTriggeredOnSuccess(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	startAbsorb(t, repo)
+
+	err := withAbsorbRollback(ctx, repo, func() bool {
+		t.Error("rollback should not be offered on success")
+		return false
+	}, func(ctx context.Context) error {
+		return repo.Commit(ctx, "fixup! initial commit")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestConfirmAutostash(t *testing.T) {
+	if !confirmAutostash(true) {
+		t.Error("expected absorb to proceed without a prompt")
+	}
+	// No answer on stdin declines.
+	if confirmAutostash(false) {
+		t.Error("expected an empty answer to cancel absorb")
+	}
+}
+
+func TestRestoreAbsorbStashClearsUndoState(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	startAbsorb(t, repo)
+
+	state, err := git.LoadAbsorbState(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo.Path, "wip.txt"), []byte("wip\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state.StashSHA, err = repo.Stash(ctx, "cmt absorb: uncommitted changes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := git.SaveAbsorbState(repo, state); err != nil {
+		t.Fatal(err)
+	}
+
+	restoreAbsorbStash(ctx, repo, state)
+
+	if _, err := os.Stat(filepath.Join(repo.Path, "wip.txt")); err != nil {
+		t.Errorf("expected stashed change to be restored: %v", err)
+	}
+	saved, err := git.LoadAbsorbState(repo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.StashSHA != "" {
+		t.Errorf("expected StashSHA to be cleared so undo can't pop it again, got %s", saved.StashSHA)
+	}
+}
+
+func TestLeftoverCommitRequestUsesAbsorbPrompt(t *testing.T) {
+	cfg := config.Default()
+	provider := &ai.ClaudeCLI{}
+
+	req := leftoverCommitRequest(cfg, "diff --git a/a.go b/a.go", []string{"M a.go"}, "haiku-4.5")
+	if got := provider.CommitPrompt(req); !strings.Contains(got, "left over by cmt absorb") {
+		t.Errorf("expected the leftover instructions in the prompt, got:\n%s", got)
+	}
+
+	cfg.AbsorbLeftoverPrompt = "Use the chore type for leftovers."
+	req = leftoverCommitRequest(cfg, "diff --git a/a.go b/a.go", []string{"M a.go"}, "haiku-4.5")
+	got := provider.CommitPrompt(req)
+	if !strings.Contains(got, "Use the chore type for leftovers.") || strings.Contains(got, "left over by cmt absorb") {
+		t.Errorf("expected only the configured leftover prompt, got:\n%s", got)
+	}
+
+	cfg.MessagePrefix = "PROJ-7: "
+	req = leftoverCommitRequest(cfg, "diff --git a/a.go b/a.go", []string{"M a.go"}, "haiku-4.5")
+	if got := provider.CommitPrompt(req); !strings.Contains(got, "Use the chore type for leftovers.") || !strings.Contains(got, `prefixed with "PROJ-7: "`) {
+		t.Errorf("expected the leftover prompt and the prefix note, got:\n%s", got)
+	}
+}
+
+func TestRenderPatchSeries(t *testing.T) {
+	hunk := func(file, content string) git.Hunk {
+		return git.Hunk{FilePath: file, Content: content}
+	}
+	commits := []git.CommitInfo{
+		{SHA: "1111111111aa", Message: "feat: add parser\n\nBody."},
+		{SHA: "2222222222bb", Message: "fix: handle empty input"},
+		{SHA: "3333333333cc", Message: "docs: explain parser"},
+	}
+	assignments := []ai.HunkAssignment{
+		{CommitSHA: "2222222222bb", Hunk: hunk("b.go", "@@ -1 +1 @@\n-x\n+y\n")},
+		{CommitSHA: "1111111111aa", Hunk: hunk("a.go", "@@ -1 +1 @@\n-a\n+b\n")},
+		{CommitSHA: "2222222222bb", Hunk: hunk("b.go", "@@ -9 +9 @@\n-p\n+q\n")},
+	}
+
+	groups := groupHunksByCommit(assignments)
+	if len(groups) != 2 || len(groups["2222222222bb"]) != 2 {
+		t.Fatalf("groupHunksByCommit() = %v, want two hunks for 22222222 and one for 11111111", groups)
+	}
+
+	expected := `# fixup for 11111111 feat: add parser (1 hunk(s))
+diff --git a/a.go b/a.go
+--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-a
++b
+
+# fixup for 22222222 fix: handle empty input (2 hunk(s))
+diff --git a/b.go b/b.go
+--- a/b.go
++++ b/b.go
+@@ -1 +1 @@
+-x
++y
+@@ -9 +9 @@
+-p
++q`
+	if got := renderPatchSeries(commits, groups); got != expected {
+		t.Errorf("renderPatchSeries() =\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestRenderAbsorbPlan(t *testing.T) {
+	// Compare the text, not the colors
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.Ascii)
+	defer lipgloss.SetColorProfile(profile)
+
+	commits := []git.CommitInfo{
+		{SHA: "1111111111aa", Message: "feat: add parser\n\nBody."},
+		{SHA: "2222222222bb", Message: "fix: handle empty input"},
+	}
+	big := git.Hunk{FilePath: "big.go", Header: "@@ -1 +1 @@"}
+	assignments := []ai.HunkAssignment{
+		{CommitSHA: "2222222222bb", Confidence: 0.9, Hunk: git.Hunk{FilePath: "b.go", Header: "@@ -1 +1 @@"}},
+		{CommitSHA: "1111111111aa", Confidence: 0.55, Hunk: git.Hunk{FilePath: "a.go", Header: "@@ -3,2 +3,4 @@"}},
+		{CommitSHA: "2222222222bb", Confidence: 0.3, Hunk: big, Hunks: []git.Hunk{big, big}},
+		{CommitSHA: "3333333333cc", Confidence: 0.8, Hunk: git.Hunk{FilePath: "c.go", Header: "@@ -9 +9 @@"}, CommitMessage: "chore: older commit"},
+	}
+
+	// Grouped by target in commit order, with targets outside the range last
+	expected := `• Create fixup commit for 11111111 feat: add parser
+    - a.go @@ -3,2 +3,4 @@: 55.0% confidence
+• Create fixup commit for 22222222 fix: handle empty input
+    - b.go @@ -1 +1 @@: 90.0% confidence
+    - big.go (2 hunks): 30.0% confidence
+• Create fixup commit for 33333333 chore: older commit
+    - c.go @@ -9 +9 @@: 80.0% confidence
+`
+	if got := renderAbsorbPlan(commits, assignments); got != expected {
+		t.Errorf("renderAbsorbPlan() =\n%s\nexpected:\n%s", got, expected)
+	}
+}
+
+func TestGroupHunksByCommitExpandsFileAssignments(t *testing.T) {
+	first := git.Hunk{FilePath: "big.go", Content: "@@ -1 +1 @@\n-a\n+b\n"}
+	second := git.Hunk{FilePath: "big.go", Content: "@@ -9 +9 @@\n-c\n+d\n"}
+	assignments := []ai.HunkAssignment{
+		{CommitSHA: "1111111111aa", Hunk: first, Hunks: []git.Hunk{first, second}},
+	}
+
+	groups := groupHunksByCommit(assignments)
+	if len(groups["1111111111aa"]) != 2 {
+		t.Errorf("groupHunksByCommit() = %v, want both hunks of the file assignment", groups)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		30 * time.Hour:      "30h",
+		47 * time.Hour:      "47h",
+		48 * time.Hour:      "2d",
+		10*24*time.Hour + 5: "10d",
+	} {
+		if got := formatAge(d); got != want {
+			t.Errorf("formatAge(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestFilterOptionsFollowConfig(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	// .gitattributes marks a file binary that its extension wouldn't
+	for name, content := range map[string]string{".gitattributes": "*.dat binary\n", "data.dat": "a\n", "package-lock.json": "{}\n"} {
+		if err := os.WriteFile(filepath.Join(repo.Path, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := exec.Command("git", "-C", repo.Path, "add", "-A").Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default()
+	cfg.FilterGenerated = false
+	opts := filterOptions(ctx, cfg, repo)
+	if preprocess.SkipReason("data.dat", opts) == "" {
+		t.Error("expected the gitattributes binary file to be filtered")
+	}
+	if reason := preprocess.SkipReason("package-lock.json", opts); reason != "" {
+		t.Errorf("expected filter_generated: false to keep the lock file, got %q", reason)
+	}
+}
+
+func TestHunkFileList(t *testing.T) {
+	hunks := []git.Hunk{
+		{FilePath: "a.go"},
+		{FilePath: "a.go"},
+		{FilePath: "new.go", IsNew: true},
+		{FilePath: "gone.go", IsDeleted: true},
+		{FilePath: "b.go", OldFilePath: "old.go", IsRenamed: true},
+	}
+	want := []string{"M a.go", "A new.go", "D gone.go", "R old.go -> b.go"}
+	if got := hunkFileList(hunks); !reflect.DeepEqual(got, want) {
+		t.Errorf("hunkFileList() = %v, want %v", got, want)
+	}
+}
+
+func TestAbsorbDryRunKeepsBackups(t *testing.T) {
+	repo := newCommitTestRepo(t, nil)
+
+	ctx := context.Background()
+	for _, name := range []string{"absorb-1", "absorb-2"} {
+		if _, err := repo.CreateBackupRef(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Nothing is staged, so absorb stops right after the pruning step
+	if err := newApp().Run(ctx, []string{"cmt", "absorb", "--dry-run", "--keep", "1", "-q"}); err != nil {
+		t.Fatalf("cmt absorb --dry-run failed: %v", err)
+	}
+
+	refs, err := repo.ListBackupRefs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 {
+		t.Errorf("expected --dry-run to keep both backups, got %v", refs)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gussy/cmt/internal/ai"
+	"github.com/gussy/cmt/internal/config"
+	"github.com/gussy/cmt/internal/git"
+	"github.com/gussy/cmt/internal/prompt"
+	"github.com/gussy/cmt/internal/ui"
+	"github.com/urfave/cli/v3"
+)
+
+// parseChangelogRange splits "<from>..<to>" into its refs. A bare "<from>"
+// runs to HEAD.
+func parseChangelogRange(arg string) (string, string, error) {
+	if strings.Contains(arg, "...") {
+		return "", "", fmt.Errorf("invalid range %q: use <from>..<to>", arg)
+	}
+	from, to, found := strings.Cut(arg, "..")
+	if !found {
+		to = "HEAD"
+	}
+	if from == "" {
+		return "", "", fmt.Errorf("invalid range %q: missing the start of the range", arg)
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	return from, to, nil
+}
+
+// runChangelog prints a Keep a Changelog section for the commits in a range,
+// grouped by their conventional commit type.
+func runChangelog(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Args().Len() != 1 {
+		return fmt.Errorf("usage: cmt changelog <from>..<to>")
+	}
+	from, to, err := parseChangelogRange(cmd.Args().First())
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	repo, err := git.NewRepository("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+
+	commits, err := repo.GetCommitRange(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		ui.Infof("No commits in %s..%s.\n", from, to)
+		return nil
+	}
+
+	entries := make([]prompt.ChangelogEntry, len(commits))
+	for i, commit := range commits {
+		entries[i] = prompt.ParseChangelogEntry(commit.SHA, commit.Message)
+	}
+	sections := prompt.GroupChangelog(entries, cmd.Bool("all"))
+
+	if cmd.Bool("polish") {
+		polishChangelog(ctx, cmd, cfg, sections)
+	}
+
+	// Commits come oldest first, so the release is dated by the last one
+	heading := prompt.ChangelogHeading(cmd.String("release"), commits[len(commits)-1].Date)
+	fmt.Print(prompt.RenderChangelog(heading, sections))
+	return nil
+}
+
+// newChangelogProvider creates the provider that polishes the changelog.
+// Tests replace it with a fake.
+var newChangelogProvider = func(ctx context.Context, cfg *config.Config) (ai.Provider, error) {
+	provider, err := newProvider(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+// polishChangelog has the model reword the entries of sections in place.
+// The changelog is still worth printing without it, so failures only warn.
+func polishChangelog(ctx context.Context, cmd *cli.Command, cfg *config.Config, sections []prompt.ChangelogSection) {
+	var entries []prompt.ChangelogEntry
+	for _, section := range sections {
+		entries = append(entries, section.Entries...)
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	provider, err := newChangelogProvider(ctx, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v\nPrinting the commit subjects as they are.\n", err)
+		return
+	}
+	defer closeProvider(provider)
+
+	model := cmd.String("model")
+	if model == "" {
+		model = cfg.Model
+	}
+	descriptions, err := provider.PolishChangelog(ctx, entries, model)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to polish the changelog: %v\nPrinting the commit subjects as they are.\n", err)
+		return
+	}
+
+	i := 0
+	for _, section := range sections {
+		for j := range section.Entries {
+			section.Entries[j].Description = descriptions[i]
+			i++
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gussy/cmt/internal/security"
+)
+
+// checkConflictMarkers blocks a commit whose staged changes add merge
+// conflict markers. allow (--allow-conflict-markers) lets it through with a
+// warning, and when ask is set the user may choose to commit anyway. It
+// reports whether the commit should go ahead.
+func checkConflictMarkers(diff string, allow, ask bool) (bool, error) {
+	markers := security.FindConflictMarkers(diff)
+	if len(markers) == 0 {
+		return true, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "⚠️  Found %d merge conflict marker(s) in the staged changes:\n%s", len(markers), formatConflictMarkers(markers))
+	if allow {
+		fmt.Fprintln(os.Stderr, "Continuing with the commit (--allow-conflict-markers).")
+		return true, nil
+	}
+	if !ask {
+		return false, fmt.Errorf("commit blocked by merge conflict markers; resolve them or pass --allow-conflict-markers")
+	}
+
+	fmt.Print("Commit anyway? [y/N]: ")
+	var response string
+	fmt.Scanln(&response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response != "y" && response != "yes" {
+		fmt.Println("❌ Commit cancelled.")
+		return false, nil
+	}
+	return true, nil
+}
+
+// formatConflictMarkers lists conflict markers one per line, as
+// "  - auth.go:12: <<<<<<< HEAD".
+func formatConflictMarkers(markers []security.ConflictMarker) string {
+	var b strings.Builder
+	for _, marker := range markers {
+		fmt.Fprintf(&b, "  - %s:%d: %s\n", marker.FilePath, marker.Line, marker.Marker)
+	}
+	return b.String()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gussy/cmt/internal/git"
+)
+
+// largeFile is a staged file above large_file_threshold.
+type largeFile struct {
+	Path string
+	Size int64
+}
+
+// largeStagedFiles returns the files in sizes larger than threshold, largest
+// first. A threshold of 0 disables the check.
+func largeStagedFiles(sizes map[string]int64, threshold int64) []largeFile {
+	if threshold <= 0 {
+		return nil
+	}
+	var files []largeFile
+	for path, size := range sizes {
+		if size > threshold {
+			files = append(files, largeFile{Path: path, Size: size})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Size != files[j].Size {
+			return files[i].Size > files[j].Size
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// formatSize formats a size in bytes for display, e.g. "12.5 MiB".
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KiB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// checkLargeFiles warns about staged files above threshold, which are
+// usually binaries or build output staged by accident. When ask is set it
+// offers to unstage them. It reports whether the commit should go ahead.
+func checkLargeFiles(ctx context.Context, repo *git.Repository, threshold int64, ask bool) (bool, error) {
+	if threshold <= 0 {
+		return true, nil
+	}
+	sizes, err := repo.StagedFileSizes(ctx)
+	if err != nil {
+		return false, err
+	}
+	files := largeStagedFiles(sizes, threshold)
+	if len(files) == 0 {
+		return true, nil
+	}
+
+	fmt.Fprintf(os.Stderr, "⚠️  %d staged file(s) larger than %s:\n", len(files), formatSize(threshold))
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = file.Path
+		fmt.Fprintf(os.Stderr, "   %s (%s)\n", file.Path, formatSize(file.Size))
+	}
+	fmt.Fprintln(os.Stderr, "Track them with git-lfs (git lfs track <pattern>) or unstage them with:")
+	fmt.Fprintf(os.Stderr, "   git restore --staged %s\n", strings.Join(paths, " "))
+	if !ask {
+		return true, nil
+	}
+
+	switch promptLargeFiles() {
+	case "u":
+		if err := repo.UnstageFiles(ctx, paths); err != nil {
+			return false, fmt.Errorf("failed to unstage large files: %w", err)
+		}
+		fmt.Printf("Unstaged %d file(s).\n", len(paths))
+		return true, nil
+	case "c":
+		return true, nil
+	default:
+		fmt.Println("❌ Commit cancelled.")
+		return false, nil
+	}
+}
+
+// promptLargeFiles asks what to do about large staged files. It returns the
+// first letter of the answer: "u" unstage, "c" continue, anything else
+// aborts.
+func promptLargeFiles() string {
+	fmt.Print("[u]nstage them, [c]ontinue, or [a]bort? ")
+	var response string
+	fmt.Scanln(&response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response == "" {
+		return ""
+	}
+	return response[:1]
+}
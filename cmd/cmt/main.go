@@ -2,16 +2,23 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/gussy/cmt/internal/ai"
 	"github.com/gussy/cmt/internal/config"
 	"github.com/gussy/cmt/internal/git"
+	"github.com/gussy/cmt/internal/hook"
 	"github.com/gussy/cmt/internal/preprocess"
+	"github.com/gussy/cmt/internal/prompt"
 	"github.com/gussy/cmt/internal/security"
+	"github.com/gussy/cmt/internal/telemetry"
 	"github.com/gussy/cmt/internal/ui"
 	"github.com/urfave/cli/v3"
 )
@@ -23,8 +30,38 @@ var (
 	BuildTime = "unknown"
 )
 
+// ErrNoStagedChanges is returned when there is nothing staged to commit.
+var ErrNoStagedChanges = errors.New("no staged changes to commit")
+
+// Exit codes returned by cmt.
+const (
+	exitError           = 1
+	exitNoStagedChanges = 2
+)
+
 func main() {
-	app := &cli.Command{
+	// -v belongs to --verbose, so --version is long-only.
+	cli.VersionFlag = &cli.BoolFlag{
+		Name:        "version",
+		Usage:       "print the version",
+		HideDefault: true,
+		Local:       true,
+	}
+
+	if err := newApp().Run(context.Background(), os.Args); err != nil {
+		code := exitCode(err)
+		if code != exitNoStagedChanges || ui.Quiet() {
+			// Guidance for missing changes has already been printed
+			// unless quiet mode suppressed it
+			log.Print(err)
+		}
+		os.Exit(code)
+	}
+}
+
+// newApp returns the cmt command with its flags and subcommands.
+func newApp() *cli.Command {
+	return &cli.Command{
 		Name:                  "cmt",
 		Usage:                 "Commit Message Tool - Generate contextual commit messages using Claude AI",
 		Version:               fmt.Sprintf("%s (built %s)", Version, BuildTime),
@@ -38,52 +75,146 @@ func main() {
 			&cli.BoolFlag{
 				Name:    "stage-updated",
 				Aliases: []string{"u"},
-				Usage:   "Stage updated files before generating commit message",
+				Usage:   "Stage changes to tracked files, but not untracked ones (git add -u), before generating commit message",
 			},
 			&cli.BoolFlag{
 				Name:    "yes",
 				Aliases: []string{"y"},
 				Usage:   "Skip confirmation and auto-commit",
 			},
+			&cli.BoolFlag{
+				Name:    "edit",
+				Aliases: []string{"E"},
+				Usage:   "Always open the message in $EDITOR before committing, even with --yes",
+			},
 			&cli.BoolFlag{
 				Name:    "oneline",
 				Aliases: []string{"o"},
 				Usage:   "Generate single-line commit message (50 chars max)",
 			},
+			&cli.StringFlag{
+				Name:    "prefix",
+				Aliases: []string{"prepend"},
+				Usage:   "Put a fixed text, such as \"PROJ-123: \", before the subject (overrides message_prefix)",
+			},
+			&cli.BoolFlag{
+				Name:  "no-body",
+				Usage: "Commit only the generated subject, dropping any body (footers cmt adds, like Closes #N, are kept)",
+			},
 			&cli.BoolFlag{
 				Name:    "verbose",
 				Aliases: []string{"v"},
-				Usage:   "Generate verbose commit message with detailed explanation",
+				Usage:   "Generate verbose commit message with detailed explanation (see --debug for verbose logging)",
+			},
+			&cli.BoolFlag{
+				Name:  "structured",
+				Usage: "Generate a message with What changed / Why / Impact sections",
 			},
 			&cli.StringFlag{
 				Name:    "hint",
 				Aliases: []string{"h"},
-				Usage:   "Additional context or requirements for the commit message",
+				Usage:   "Additional context or requirements for the commit message (@name uses a preset)",
 			},
 			&cli.StringFlag{
 				Name:    "scope",
 				Aliases: []string{"s"},
 				Usage:   "Scope for conventional commits (e.g., auth, api, ui)",
 			},
+			&cli.StringFlag{
+				Name:  "fill",
+				Usage: "Commit message template whose {{TODO: ...}} placeholders the AI fills in",
+			},
 			&cli.BoolFlag{
 				Name:    "push",
 				Aliases: []string{"p"},
 				Usage:   "Push to remote after committing",
 			},
+			&cli.StringFlag{
+				Name:  "create-branch",
+				Usage: "Create and switch to this branch before committing (e.g. from a detached HEAD)",
+			},
 			&cli.StringFlag{
 				Name:  "model",
 				Usage: "Claude model to use (default: haiku-4.5)",
 				Value: "haiku-4.5",
 			},
+			&cli.BoolFlag{
+				Name:  "allow-empty",
+				Usage: "Allow creating a commit with no staged changes",
+			},
+			&cli.BoolFlag{
+				Name:    "gpg-sign",
+				Aliases: []string{"S"},
+				Usage:   "Sign the commit (uses signing_key or git's user.signingkey)",
+			},
+			&cli.StringFlag{
+				Name:  "signing-key",
+				Usage: "Sign the commit with this GPG key ID or SSH public key file",
+			},
+			&cli.BoolFlag{
+				Name:  "no-edit-help",
+				Usage: "Leave out the help comments when editing the message in an external editor",
+			},
+			&cli.BoolFlag{
+				Name:  "amend-no-edit",
+				Usage: "Fold staged changes into the last commit, keeping its message (no AI)",
+			},
 			&cli.BoolFlag{
 				Name:  "no-secret-scan",
 				Usage: "Skip scanning for secrets in staged files",
 			},
+			&cli.BoolFlag{
+				Name:  "allow-conflict-markers",
+				Usage: "Commit even if the staged changes add merge conflict markers",
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "Only print errors and the new commit SHA",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the new commit's SHA, message and any message corrections as JSON",
+			},
+			&cli.BoolFlag{
+				Name:  "no-color",
+				Usage: "Disable colored output (also honors NO_COLOR)",
+			},
+			&cli.BoolFlag{
+				Name:  "no-ai",
+				Usage: "Write a template message from the diff without calling the AI",
+			},
+			&cli.BoolFlag{
+				Name:  "print-prompt",
+				Usage: "Print the prompt to stderr instead of calling the AI (the diff may contain secrets)",
+			},
+			&cli.BoolFlag{
+				Name:   "timing",
+				Usage:  "Print how long each step took (git diff, preprocess, secret scan, generation, commit) to stderr",
+				Hidden: true,
+			},
 			&cli.BoolFlag{
 				Name:  "debug",
-				Usage: "Enable debug output",
+				Usage: "Log diff filtering and analysis details (same as verbose: true in config)",
 			},
 		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			// Decide on color once so every command and UI agrees.
+			colorOutput := config.Default().ColorOutput
+			if cfg, err := config.LoadConfig(); err == nil {
+				colorOutput = cfg.ColorOutput
+			}
+			ui.ConfigureColor(cmd.Bool("no-color"), colorOutput)
+
+			if cmd.Bool("quiet") && cmd.Bool("debug") {
+				return ctx, fmt.Errorf("--quiet and --debug cannot be used together")
+			}
+			if cmd.Bool("stage-all") && cmd.Bool("stage-updated") {
+				return ctx, fmt.Errorf("--stage-all and --stage-updated cannot be used together")
+			}
+			ui.SetQuiet(cmd.Bool("quiet") || cmd.Bool("json"))
+			return ctx, nil
+		},
 		Commands: []*cli.Command{
 			{
 				Name:  "init",
@@ -116,40 +247,207 @@ func main() {
 							return setConfig(ctx, cmd.Args().Get(0), cmd.Args().Get(1))
 						},
 					},
+					{
+						Name:  "edit",
+						Usage: "Edit the config file in $EDITOR, validating it on save",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "global",
+								Usage: "Edit the global config instead of the local .cmt.yml",
+							},
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							return editConfig(ctx, cmd.Bool("global"))
+						},
+					},
 				},
 			},
 			{
 				Name:  "diff",
 				Usage: "Show the diff that will be committed",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "processed",
+						Usage: "Show the diff after preprocessing, as the model sees it",
+					},
+					&cli.BoolFlag{
+						Name:  "pick",
+						Usage: "Pick a staged file from a list and browse its diff",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Bool("processed") && cmd.Bool("pick") {
+						return fmt.Errorf("--processed and --pick cannot be used together")
+					}
+					return showDiff(ctx, cmd.Bool("processed"), cmd.Bool("pick"))
+				},
+			},
+			{
+				Name:  "scopes",
+				Usage: "List the conventional commit scopes used in recent history",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Value: 200,
+						Usage: "Number of recent commits to scan",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return showScopes(ctx, cmd.Int("limit"))
+				},
+			},
+			{
+				Name:      "search",
+				Usage:     "Search past commit messages to see how similar changes were described",
+				ArgsUsage: "[query]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "staged",
+						Usage: "Also list past commits that touched the staged files",
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Value: 20,
+						Usage: "Maximum number of commits to list per search",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return searchHistory(ctx, strings.Join(cmd.Args().Slice(), " "), cmd.Bool("staged"), cmd.Int("limit"))
+				},
+			},
+			{
+				Name:      "changelog",
+				Usage:     "Print a Keep a Changelog section for a range of conventional commits",
+				ArgsUsage: "<from>..<to>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "release",
+						Usage: "Version to head the section with, dated by the last commit (default: Unreleased)",
+					},
+					&cli.BoolFlag{
+						Name:  "all",
+						Usage: "Also list docs, test, build, ci and chore commits",
+					},
+					&cli.BoolFlag{
+						Name:  "polish",
+						Usage: "Have the AI reword the entries for release notes",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runChangelog(ctx, cmd)
+				},
+			},
+			{
+				Name:  "version",
+				Usage: "Print the version",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "check",
+						Usage: "Also check GitHub for a newer release (the only time cmt contacts GitHub)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return runVersion(ctx, cmd.Bool("check"))
+				},
+			},
+			{
+				Name:  "stats",
+				Usage: "Summarize the usage metrics recorded with telemetry_local",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return showStats()
+				},
+			},
+			{
+				Name:  "amend",
+				Usage: "Add to the last commit's message without the AI (staged changes stay staged)",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "append",
+						Usage:    "Text to add: trailers such as \"Closes #12\" join the footers, anything else ends the body",
+						Required: true,
+					},
+				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
-					return showDiff(ctx)
+					return runAmendAppend(ctx, cmd, cmd.String("append"))
 				},
 			},
 			absorbCommand(),
+			splitCommand(),
 		},
 		Action: func(ctx context.Context, cmd *cli.Command) error {
 			return runCommit(ctx, cmd)
 		},
 	}
+}
+
+// exitCode maps an error returned by a command to a process exit code.
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrNoStagedChanges):
+		return exitNoStagedChanges
+	default:
+		return exitError
+	}
+}
+
+// checkStagedChanges returns ErrNoStagedChanges, after printing guidance,
+// when nothing is staged and an empty commit was not requested.
+func checkStagedChanges(ctx context.Context, repo *git.Repository, allowEmpty bool) error {
+	hasChanges, err := repo.HasStagedChanges(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check staged changes: %w", err)
+	}
 
-	if err := app.Run(context.Background(), os.Args); err != nil {
-		log.Fatal(err)
+	if !hasChanges && !allowEmpty {
+		ui.Infoln("❌ No staged changes to commit.")
+		ui.Infoln("\nUse 'git add' to stage files or use the -a flag to stage all changes.")
+		ui.Infoln("Use --allow-empty to create an empty commit intentionally.")
+		return ErrNoStagedChanges
 	}
+
+	return nil
 }
 
 // runCommit is the main workflow for generating and creating a commit.
-func runCommit(ctx context.Context, cmd *cli.Command) error {
+func runCommit(ctx context.Context, cmd *cli.Command) (err error) {
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	// --debug turns on verbose logging; --verbose only picks the format
+	if cmd.Bool("debug") {
+		cfg.Verbose = true
+	}
+	applyPrefixFlag(cmd, cfg)
+	timer := newPhaseTimer(cmd.Bool("timing"))
+	defer timer.report(os.Stderr)
 
 	// Step 1: Initialize git repository
 	repo, err := git.NewRepository("")
 	if err != nil {
 		return fmt.Errorf("failed to initialize git repository: %w", err)
 	}
+	repo.MaxDiffBytes = cfg.MaxDiffBytes
+	repo.IgnoreWhitespace = cfg.DiffIgnoreWhitespace
+
+	// Report a missing external editor before spending a generation on it
+	if cmd.Bool("edit") {
+		if _, err := ui.ResolveEditor(); err != nil {
+			return fmt.Errorf("--edit needs an editor: %w", err)
+		}
+	} else if cfg.EditorMode == "external" && cfg.Interactive && !cmd.Bool("yes") {
+		if _, err := ui.ResolveEditor(); err != nil {
+			fmt.Fprintf(os.Stderr, "⚠️  Warning: editor_mode is external but %v\n", err)
+		}
+	}
+
+	push, err := prepareBranch(ctx, cmd, repo)
+	if err != nil {
+		return err
+	}
 
 	// Step 2: Stage files if requested
 	if cmd.Bool("stage-all") {
@@ -165,49 +463,101 @@ func runCommit(ctx context.Context, cmd *cli.Command) error {
 	}
 
 	// Step 3: Check if there are staged changes
-	hasChanges, err := repo.HasStagedChanges(ctx)
+	allowEmpty := cmd.Bool("allow-empty")
+	if err := checkStagedChanges(ctx, repo, allowEmpty); err != nil {
+		return err
+	}
+
+	// Check the signing key now rather than failing after generation
+	commitOpts, err := commitOptions(ctx, cmd, cfg, repo)
 	if err != nil {
-		return fmt.Errorf("failed to check staged changes: %w", err)
+		return err
+	}
+
+	// Fast path: amend the last commit without generating a message
+	if cmd.Bool("amend-no-edit") {
+		return runAmendNoEdit(ctx, repo, commitOpts, cmd.Bool("json"), cfg.MessagePrefix)
 	}
 
-	if !hasChanges {
-		fmt.Println("❌ No staged changes to commit.")
-		fmt.Println("\nUse 'git add' to stage files or use the -a flag to stage all changes.")
+	// Catch accidentally staged binaries before their diff is read
+	ask := cfg.Interactive && !cmd.Bool("yes") && ui.IsInteractive()
+	proceed, err := checkLargeFiles(ctx, repo, cfg.LargeFileThreshold, ask)
+	if err != nil {
+		return err
+	}
+	if !proceed {
 		return nil
 	}
+	// Unstaging them may have left nothing to commit
+	if err := checkStagedChanges(ctx, repo, allowEmpty); err != nil {
+		return err
+	}
 
-	// Step 4: Get diff and staged files
+	// Step 4: Get diff and staged files. They are read once, and every
+	// later step works from this snapshot; the hash remembers what the
+	// message is generated for, to catch a changed index
 	ui.SimpleProgress(ui.ProgressMessages.AnalyzingChanges)
-	diff, err := repo.GetDiff(ctx, true)
+	stop := timer.start(phaseGitDiff)
+	staged, err := repo.ReadStagedChanges(ctx)
+	stop()
 	if err != nil {
 		return fmt.Errorf("failed to get diff: %w", err)
 	}
+	diff := staged.Diff
+	stagedFiles := formatFileStatuses(staged.Files)
+	diffHash := staged.Hash
+
+	// Small changes on a branch with unpushed work may belong in an earlier commit
+	if cfg.SuggestAbsorb {
+		suggestAbsorb(ctx, repo, diff)
+	}
 
-	stagedFiles, err := repo.GetStagedFiles(ctx)
+	// Leftover conflict markers are almost never meant to be committed
+	proceed, err = checkConflictMarkers(diff, cmd.Bool("allow-conflict-markers"), ask)
 	if err != nil {
-		return fmt.Errorf("failed to get staged files: %w", err)
+		return err
+	}
+	if !proceed {
+		return nil
 	}
 
 	// Step 5: Security scan (unless skipped via flag or config)
-	skipScan := cmd.Bool("no-secret-scan") || cfg.SkipSecretScan
-	if !skipScan {
+	if !skipSecretScan(cmd.Bool("no-secret-scan"), cfg) {
 		ui.SimpleProgress(ui.ProgressMessages.ScanningSecrets)
-		scanner := security.NewScanner()
+		scanner, err := security.NewScannerWithOptions(security.ScannerOptions{
+			Enabled:  cfg.EnabledSecretPatterns,
+			Disabled: cfg.DisabledSecretPatterns,
+		})
+		if err != nil {
+			return err
+		}
+		stop := timer.start(phaseSecretScan)
 		secrets, err := scanner.Scan(diff)
+		stop()
 		if err != nil {
 			return fmt.Errorf("security scan failed: %w", err)
 		}
 
 		if len(secrets) > 0 {
-			// Show interactive secret warning.
-			action, err := ui.ShowSecretWarning(secrets)
-			if err != nil {
-				return fmt.Errorf("failed to show secret warning: %w", err)
+			// Show interactive secret warning, or apply secret_on_detect
+			// when there is no terminal to show it on or it blocks.
+			var action ui.SecretAction
+			asked := askAboutSecrets(cfg, ui.IsInteractive())
+			if asked {
+				action, err = ui.ShowSecretWarning(secrets)
+				if err != nil {
+					return fmt.Errorf("failed to show secret warning: %w", err)
+				}
+			} else {
+				action, err = secretPolicyAction(cfg.SecretOnDetect, secrets, os.Stderr)
+				if err != nil {
+					return err
+				}
 			}
 
 			switch action {
 			case ui.ActionAbort:
-				fmt.Println("\n❌ Commit aborted due to detected secrets.")
+				fmt.Fprintln(os.Stderr, "\n❌ Commit aborted due to detected secrets.")
 				return nil
 
 			case ui.ActionUnstage:
@@ -219,65 +569,98 @@ func runCommit(ctx context.Context, cmd *cli.Command) error {
 
 				for file := range uniqueFiles {
 					if err := repo.UnstageFiles(ctx, []string{file}); err != nil {
-						fmt.Printf("Warning: Failed to unstage %s: %v\n", file, err)
+						fmt.Fprintf(os.Stderr, "⚠️  Failed to unstage %s: %v\n", file, err)
 					}
 				}
-				fmt.Printf("\n⚠️  Unstaged %d file(s) containing secrets.\n", len(uniqueFiles))
-				fmt.Println("Please review and fix the issues before committing.")
+				fmt.Fprintf(os.Stderr, "\n⚠️  Unstaged %d file(s) containing secrets.\n", len(uniqueFiles))
+				fmt.Fprintln(os.Stderr, "Please review and fix the issues before committing.")
 				return nil
 
 			case ui.ActionContinue:
-				// User explicitly chose to continue despite warnings.
-				fmt.Println("\n⚠️  Continuing with commit despite secret warnings.")
+				// User explicitly chose to continue despite warnings;
+				// secretPolicyAction has already said so otherwise.
+				if asked {
+					fmt.Fprintln(os.Stderr, "\n⚠️  Continuing with commit despite secret warnings.")
+				}
 			}
 		}
 	}
 
-	// Step 6: Initialize AI provider with config
-	providerConfig := &ai.ProviderConfig{
-		DefaultModel: cfg.Model,
-		Timeout:      60, // Default timeout
+	// Step 6: Initialize AI provider with config. A nil provider means the
+	// message comes from the offline template instead (--no-ai or fallback).
+	var provider *ai.ClaudeCLI
+	if !cmd.Bool("no-ai") {
+		provider, err = newProvider(ctx, cfg)
+		if err != nil {
+			if !cfg.AllowOfflineFallback {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "⚠️  %v\nUsing the offline template message instead.\n", err)
+			provider = nil
+		} else {
+			defer closeProvider(provider)
+		}
 	}
-	provider, err := ai.NewClaudeCLI(providerConfig)
+
+	// Expand a hint preset (--hint @name) before it's used in the prompt
+	hint, err := cfg.ResolveHint(cmd.String("hint"))
 	if err != nil {
-		return fmt.Errorf("failed to initialize Claude CLI: %w", err)
+		return err
 	}
 
-	// Check if Claude is available
-	available, err := provider.IsAvailable(ctx)
-	if !available || err != nil {
-		return fmt.Errorf("Claude CLI is not available. Please ensure 'claude' is installed and in your PATH")
+	// Load the fill template up front so a bad path fails before generation
+	var template string
+	if path := cmd.String("fill"); path != "" {
+		template, err = loadFillTemplate(path)
+		if err != nil {
+			return err
+		}
 	}
 
-	// Step 7: Preprocess diff for AI
-	preprocessOpts := preprocess.Options{
-		MaxTokens:       cfg.MaxDiffTokens,
-		FilterBinary:    cfg.FilterBinary,
-		FilterMinified:  cfg.FilterMinified,
-		FilterGenerated: cfg.FilterGenerated,
+	// Issue references in the hint or branch name become footer trailers,
+	// as do the pair partners named by co_author_from_env
+	branch, _ := repo.GetCurrentBranch(ctx)
+	footers := prompt.IssueFooters(hint, branch, cfg.ClosingKeywords)
+	footers = append(footers, coAuthorFooters(ctx, cfg, repo)...)
+
+	// Usage metrics for the run, recorded when it ends if telemetry_local
+	// is set
+	var run *telemetry.Event
+	if cfg.TelemetryLocal {
+		defer func() {
+			if run != nil {
+				recordRun(repo, run, err)
+			}
+		}()
 	}
 
+	// A regeneration for changed staged changes starts over here
+generate:
+	// Step 7: Preprocess diff for AI. File-type guidance and style examples
+	// count against the instruction budget like the hint does; the examples
+	// are cut to one when they would crowd out the diff.
+	guidance := fileTypeGuidance(cfg, staged.Files)
+	hintAndGuidance := strings.Join(append([]string{hint}, guidance...), "\n")
+	examples := promptBudget(cfg).FitExamples(styleExamples(ctx, cfg, repo),
+		prompt.EstimateTokens(hintAndGuidance)+prompt.EstimateTokens(strings.Join(stagedFiles, "\n")))
+	preprocessOpts, stagedFiles := preprocessOptions(cfg, strings.Join(append([]string{hintAndGuidance}, examples...), "\n"), stagedFiles)
+
 	// Use ProcessWithStats to get information about filtering
+	stop = timer.start(phasePreprocess)
+	preprocessOpts.BinaryFiles = stagedBinaryFiles(ctx, cfg, repo)
 	processedDiff, stats := preprocess.ProcessWithStats(diff, preprocessOpts)
+	stop()
 
 	// Log preprocessing stats if verbose
 	if cfg.Verbose {
-		if stats.FilteredFiles > 0 {
-			fmt.Printf("📝 Preprocessed diff: %d/%d files included\n",
-				stats.TotalFiles-stats.FilteredFiles, stats.TotalFiles)
-			if stats.BinaryFiles > 0 {
-				fmt.Printf("   - Filtered %d binary file(s)\n", stats.BinaryFiles)
-			}
-			if stats.MinifiedFiles > 0 {
-				fmt.Printf("   - Filtered %d minified file(s)\n", stats.MinifiedFiles)
-			}
-			if stats.GeneratedFiles > 0 {
-				fmt.Printf("   - Filtered %d generated/lock file(s)\n", stats.GeneratedFiles)
-			}
-		}
-		if stats.Truncated {
-			fmt.Printf("   - Truncated at %d tokens (limit: %d)\n",
-				stats.TokensUsed, cfg.MaxDiffTokens)
+		printFilterStats(stats, preprocessOpts.MaxTokens)
+	}
+
+	// Binary-only changes leave just file headers; describe them from the
+	// --stat summary instead
+	if !preprocess.HasTextChanges(processedDiff) {
+		if stat, err := repo.GetDiffStat(ctx); err == nil && stat != "" {
+			processedDiff = "Only binary or filtered files changed. Summary (git diff --stat):\n" + stat
 		}
 	}
 
@@ -288,6 +671,8 @@ func runCommit(ctx context.Context, cmd *cli.Command) error {
 	var msgFormat ai.MessageFormat
 	if cmd.Bool("oneline") {
 		msgFormat = ai.FormatOneLine
+	} else if cmd.Bool("structured") {
+		msgFormat = ai.FormatStructured
 	} else if cmd.Bool("verbose") {
 		msgFormat = ai.FormatVerbose
 	} else {
@@ -308,88 +693,273 @@ func runCommit(ctx context.Context, cmd *cli.Command) error {
 		scope = ""
 	}
 
+	customPrompt, err := renderCustomPrompt(cfg, prompt.PromptData{
+		Files:      stagedFiles,
+		Diff:       diff,
+		PromptDiff: processedDiff,
+		Hint:       hint,
+		Scope:      scope,
+	})
+	if err != nil {
+		return err
+	}
+
 	req := &ai.CommitRequest{
-		Diff:        processedDiff, // Use preprocessed diff instead of raw diff
-		StagedFiles: stagedFiles,
-		Format:      msgFormat,
-		Hint:        cmd.String("hint"),
-		Scope:       scope,
-		Model:       model,
-		Temperature: cfg.Temperature,
-		MaxTokens:   cfg.MaxTokens,
+		Diff:         processedDiff, // Use preprocessed diff instead of raw diff
+		StagedFiles:  stagedFiles,
+		Format:       msgFormat,
+		Hint:         hint,
+		Scope:        scope,
+		CustomPrompt: customPrompt,
+		Template:     template,
+		Examples:     examples,
+		Guidance:     guidance,
+		Dependencies: dependencyChanges(diff),
+		Grounding:    cfg.Grounding,
+		Model:        model,
+		Temperature:  cfg.TemperatureFor(msgFormat.String()),
+		MaxTokens:    cfg.MaxTokens,
+	}
+	// A deletion's diff is only removed lines; ask for a message that lists
+	// every file removed
+	var instructions []string
+	if git.DeletionOnly(staged.Files) {
+		instructions = append(instructions, prompt.DeletionOnlyInstructions)
+	}
+	// The prefix is added after generation, so the model leaves room for it
+	if strings.TrimSpace(cfg.MessagePrefix) != "" {
+		instructions = append(instructions, prompt.PrefixInstructions(cfg.MessagePrefix))
+	}
+	req.Instructions = strings.Join(instructions, "\n")
+
+	// Debugging aid: show exactly what would be sent and stop
+	if cmd.Bool("print-prompt") {
+		if provider == nil {
+			return fmt.Errorf("--print-prompt needs the AI provider; no prompt is built for the offline template")
+		}
+		fmt.Fprintln(os.Stderr, provider.CommitPrompt(req))
+		return nil
+	}
+
+	run = &telemetry.Event{Time: time.Now(), Format: req.Format.String()}
+	if provider != nil {
+		run.Model = req.Model
+		if cfg.TelemetryLocal {
+			run.PromptTokens = prompt.EstimateTokens(provider.CommitPrompt(req))
+		}
+	}
+
+	// finalize applies the clean-ups to every generated message
+	finalize := func(message string) string {
+		if cmd.Bool("no-body") {
+			message = subjectOnly(message)
+		}
+		return finalizeMessage(ctx, cfg, repo, message, footers)
 	}
 
 	// Generate commit message with retry logic
 	var response *ai.CommitResponse
-	maxRetries := 3
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		response, err = provider.GenerateCommitMessage(ctx, req)
-		if err == nil && response != nil && response.Message != "" {
-			break // Success
+	var corrections []correction
+	started := time.Now()
+	stop = timer.start(phaseGeneration)
+	if staged.WhitespaceOnly {
+		// diff_ignore_whitespace left the model nothing to describe
+		ui.Infoln("🧹 Only whitespace changed; suggesting a formatting commit.")
+		message := prompt.FormattingMessage(stagedPaths(staged.Files))
+		if msgFormat == ai.FormatOneLine {
+			message, _, _ = strings.Cut(message, "\n")
+		}
+		response = &ai.CommitResponse{Message: message, Type: "style"}
+	} else if provider == nil {
+		message := prompt.FallbackMessage(diff)
+		if msgFormat == ai.FormatOneLine {
+			message, _, _ = strings.Cut(message, "\n")
 		}
+		response = &ai.CommitResponse{
+			Message: message,
+			Type:    prompt.ExtractConventionalType(message),
+			Scope:   prompt.ExtractScope(message),
+		}
+	} else {
+		maxRetries := 3
+		for attempt := 1; attempt <= maxRetries; attempt++ {
+			response, err = provider.GenerateCommitMessage(ctx, req)
+			if err == nil && response != nil && response.Message != "" {
+				break // Success
+			}
 
-		if attempt < maxRetries {
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Attempt %d failed: %v. Retrying...\n", attempt, err)
-			} else if response == nil || response.Message == "" {
-				fmt.Fprintf(os.Stderr, "Attempt %d: Empty response received. Retrying...\n", attempt)
+			if attempt < maxRetries {
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Attempt %d failed: %v. Retrying...\n", attempt, err)
+				} else if response == nil || response.Message == "" {
+					fmt.Fprintf(os.Stderr, "Attempt %d: Empty response received. Retrying...\n", attempt)
+				}
+				// Wait a bit before retrying
+				time.Sleep(time.Second * 2)
 			}
-			// Wait a bit before retrying
-			time.Sleep(time.Second * 2)
 		}
-	}
 
-	if err != nil {
-		return fmt.Errorf("failed to generate commit message after %d attempts: %w", maxRetries, err)
-	}
-	if response == nil || response.Message == "" {
-		return fmt.Errorf("received empty commit message after %d attempts", maxRetries)
+		if err != nil {
+			return fmt.Errorf("failed to generate commit message after %d attempts: %w", maxRetries, err)
+		}
+		if response == nil || response.Message == "" {
+			return fmt.Errorf("received empty commit message after %d attempts", maxRetries)
+		}
+
+		// Every check-driven regeneration goes through here, so the total
+		// stays within max_refinement_attempts.
+		checks := messageChecks(req.Format)
+		response.Message, corrections, err = refineMessage(response.Message, checks, cfg.MaxRefinementAttempts,
+			func(previous, feedback string) (string, error) {
+				regenerated, err := provider.RegenerateWithFeedback(ctx, req, previous, feedback)
+				if err != nil {
+					return "", err
+				}
+				response = regenerated
+				return regenerated.Message, nil
+			})
+		if err != nil {
+			return err
+		}
+		if cfg.Verbose {
+			for _, c := range corrections {
+				fmt.Fprintf(os.Stderr, "🔁 Regenerated (%d/%d) after the %s check: %s\n", c.Attempt, cfg.MaxRefinementAttempts, c.Check, c.Reason)
+			}
+		}
+		if name, reason := failingCheck(response.Message, checks); reason != "" {
+			fmt.Fprintf(os.Stderr, "⚠️  The message still fails the %s check (%s)\n", name, reason)
+		}
+		run.GenerationMS = time.Since(started).Milliseconds()
+		run.Corrections = len(corrections)
 	}
+	stop()
+	response.Message = finalize(response.Message)
 
 	// Step 8: Interactive review (unless auto-commit or non-interactive mode in config)
 	if !cmd.Bool("yes") && cfg.Interactive {
+		var models []string
+		if provider != nil {
+			models = provider.GetAvailableModels()
+		}
+
 		// Use the interactive Bubble Tea UI for review
+		var status ui.MessageStatus
+		var review ui.ReviewSession
 		for {
-			action, feedback, err := ui.ShowCommitReview(response.Message, diff, cfg.EditorMode)
+			action, feedback, err := review.Show(response.Message, diff, ui.ReviewOptions{
+				EditorMode: cfg.EditorMode,
+				Autoscroll: cfg.ReviewAutoscroll,
+				Models:     models,
+				Model:      req.Model,
+				Format:     req.Format,
+				Status:     status,
+			})
 			if err != nil {
 				return fmt.Errorf("failed to show review UI: %w", err)
 			}
 
 			switch action {
 			case ui.ReviewAccept:
-				// Continue to commit
+				// Accept may carry quick type/scope edits
+				response.Message = feedback
 				goto commit
 
 			case ui.ReviewReject:
-				fmt.Println("\n❌ Commit cancelled.")
+				ui.Infoln("\n❌ Commit cancelled.")
 				return nil
 
 			case ui.ReviewRegenerate:
+				if provider == nil {
+					ui.Infoln("AI is unavailable, so the message can't be regenerated. Edit it instead.")
+					continue
+				}
 				// Regenerate with feedback
+				run.Regenerations++
 				ui.SimpleProgress(ui.ProgressMessages.Regenerating)
-				response, err = provider.RegenerateWithFeedback(ctx, req, response.Message, feedback)
+				regenerated, err := provider.RegenerateWithFeedback(ctx, req, response.Message, feedback)
 				if err != nil {
 					return fmt.Errorf("failed to regenerate: %w", err)
 				}
+				regenerated.Message = finalize(regenerated.Message)
+				if blankMessage(regenerated.Message) {
+					fmt.Fprintln(os.Stderr, "⚠️  The regenerated message is empty; keeping the previous one.")
+					continue
+				}
+				response = regenerated
+				status.Regenerated()
 				// Loop back to show the new message
 				continue
 
+			case ui.ReviewRegenerateWithModel:
+				// The picker is only offered when a provider is available
+				req.Model = feedback
+				run.Model = req.Model
+				run.Regenerations++
+				ui.SimpleProgress(fmt.Sprintf("Regenerating with %s...", req.Model))
+				regenerated, err := provider.GenerateCommitMessage(ctx, req)
+				if err != nil {
+					return fmt.Errorf("failed to regenerate with %s: %w", req.Model, err)
+				}
+				regenerated.Message = finalize(regenerated.Message)
+				if blankMessage(regenerated.Message) {
+					fmt.Fprintln(os.Stderr, "⚠️  The regenerated message is empty; keeping the previous one.")
+					continue
+				}
+				response = regenerated
+				status.Regenerated()
+				continue
+
+			case ui.ReviewRegenerateWithFormat:
+				if provider == nil {
+					ui.Infoln("AI is unavailable, so the message can't be regenerated. Edit it instead.")
+					continue
+				}
+				format, err := ai.ParseMessageFormat(feedback)
+				if err != nil {
+					return err
+				}
+				req.Format = format
+				req.Temperature = cfg.TemperatureFor(format.String())
+				run.Format = format.String()
+				run.Regenerations++
+				ui.SimpleProgress(fmt.Sprintf("Regenerating as %s...", format))
+				regenerated, err := provider.GenerateCommitMessage(ctx, req)
+				if err != nil {
+					return fmt.Errorf("failed to regenerate as %s: %w", format, err)
+				}
+				regenerated.Message = finalize(regenerated.Message)
+				if blankMessage(regenerated.Message) {
+					fmt.Fprintln(os.Stderr, "⚠️  The regenerated message is empty; keeping the previous one.")
+					continue
+				}
+				response = regenerated
+				status.Regenerated()
+				continue
+
 			case ui.ReviewEdit:
 				// Open external editor for manual editing
-				fmt.Println("\n💭 Opening your editor...")
-				editedMessage, err := ui.EditInEditor(response.Message)
+				ui.Infoln("\n💭 Opening your editor...")
+				editedMessage, err := ui.EditInEditorWithOptions(response.Message, editorOptions(cmd, cfg, diff))
 				if err != nil {
-					fmt.Printf("Failed to edit message: %v\n", err)
+					fmt.Fprintf(os.Stderr, "Failed to edit message: %v\n", err)
 					continue
 				}
 				response.Message = editedMessage
-				fmt.Println("✓ Message updated")
+				run.Edited = true
+				status.Edited = true
+				ui.Infoln("✓ Message updated")
 				// Loop back to show the edited message for review
 				continue
 
 			case ui.ReviewEditInline:
 				// Inline editing was done in the UI, update the message
+				if blankMessage(feedback) {
+					fmt.Fprintln(os.Stderr, "⚠️  The edited message is empty; keeping the previous one.")
+					continue
+				}
 				response.Message = feedback // feedback contains the edited message
+				run.Edited = true
+				status.Edited = true
 				// Loop back to show the edited message for review
 				continue
 			}
@@ -398,39 +968,699 @@ func runCommit(ctx context.Context, cmd *cli.Command) error {
 
 commit:
 
+	// --edit: a final manual pass, also after --yes or the review. Inline
+	// editing lives in the review screen, so this is always the editor.
+	if cmd.Bool("edit") {
+		ui.Infoln("\n💭 Opening your editor...")
+		edited, err := ui.EditInEditorWithOptions(response.Message, editorOptions(cmd, cfg, diff))
+		if err != nil {
+			return fmt.Errorf("failed to edit message: %w", err)
+		}
+		if edited != response.Message {
+			response.Message = edited
+			run.Edited = true
+		}
+	}
+
+	// The edit paths keep the previous message rather than a blank one, so
+	// this only catches what slips past them
+	if blankMessage(response.Message) {
+		return fmt.Errorf("commit message cannot be empty")
+	}
+	// Last of all, so the review and edits never see or break the prefix
+	response.Message = prompt.PrefixSubject(response.Message, cfg.MessagePrefix)
+
+	// The index may have changed since generation (hooks, more staging)
+	currentHash, err := repo.StagedDiffHash(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to hash staged changes: %w", err)
+	}
+	if currentHash != diffHash {
+		fmt.Fprintln(os.Stderr, "\n⚠️  Staged changes were modified after the message was generated.")
+		if cmd.Bool("yes") || !cfg.Interactive {
+			return fmt.Errorf("staged changes no longer match the generated message; run cmt again")
+		}
+		switch promptStaleMessage() {
+		case "r":
+			// Generate again for what is staged now. Staging, the absorb
+			// hint and the scans already ran for this commit.
+			run.Outcome = telemetry.OutcomeRestarted
+			if cfg.TelemetryLocal {
+				recordRun(repo, run, nil)
+			}
+			run = nil
+			if err := checkStagedChanges(ctx, repo, allowEmpty); err != nil {
+				return err
+			}
+			staged, err = repo.ReadStagedChanges(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get diff: %w", err)
+			}
+			diff, stagedFiles, diffHash = staged.Diff, formatFileStatuses(staged.Files), staged.Hash
+			goto generate
+		case "c":
+			// Keep the message as is
+		default:
+			ui.Infoln("❌ Commit cancelled.")
+			return nil
+		}
+	}
+
 	// Step 9: Create the commit
 	ui.SimpleProgress(ui.ProgressMessages.CreatingCommit)
-	if err := repo.Commit(ctx, response.Message); err != nil {
+	stop = timer.start(phaseCommit)
+	err = repo.CommitWithOptions(ctx, response.Message, commitOpts)
+	stop()
+	if err != nil {
 		return fmt.Errorf("failed to create commit: %w", err)
 	}
-	fmt.Println("\n✅ Commit created successfully!")
+	run.Outcome = telemetry.OutcomeCommitted
+	if sha, err := repo.GetCurrentCommitSHA(ctx); err == nil {
+		ui.Infof("\n✅ Commit %s created successfully!\n", sha[:8])
+	} else {
+		ui.Infoln("\n✅ Commit created successfully!")
+	}
 
 	// Step 10: Push if requested
-	if cmd.Bool("push") {
+	if push {
 		ui.SimpleProgress(ui.ProgressMessages.PushingChanges)
 		if err := repo.Push(ctx); err != nil {
 			return fmt.Errorf("failed to push: %w", err)
 		}
-		fmt.Println("✅ Pushed successfully!")
+		ui.Infoln("✅ Pushed successfully!")
+	}
+
+	if err := printCommitResult(ctx, repo, cmd.Bool("json"), cfg.MessagePrefix, corrections); err != nil {
+		return err
 	}
 
 	// Show final status
-	fmt.Println("\n✨ Done! Your changes have been committed.")
+	ui.Infoln("\n✨ Done! Your changes have been committed.")
 
 	// Show the commit message one more time
 	lastMsg, _ := repo.GetLastCommitMessage(ctx)
 	if lastMsg != "" {
-		fmt.Println("\nCommit message:")
-		fmt.Println(lastMsg)
+		ui.Infoln("\nCommit message:")
+		ui.Infoln(lastMsg)
 	}
 
 	return nil
 }
 
-// initConfig initializes a .cmt.yml configuration file in the current repository.
-func initConfig(ctx context.Context) error {
-	// Create default config
-	cfg := config.Default()
+// promptStaleMessage asks what to do with a message generated for staged
+// changes that have since changed. It returns the first letter of the
+// answer: "r" regenerate, "c" commit anyway, anything else aborts.
+func promptStaleMessage() string {
+	fmt.Print("[r]egenerate for the current changes, [c]ommit anyway, or [a]bort? ")
+	var response string
+	fmt.Scanln(&response)
+	response = strings.ToLower(strings.TrimSpace(response))
+	if response == "" {
+		return ""
+	}
+	return response[:1]
+}
+
+// formatFileStatuses formats files with their status for the prompt.
+func formatFileStatuses(statuses []git.FileStatus) []string {
+	files := make([]string, len(statuses))
+	for i, status := range statuses {
+		files[i] = status.String()
+	}
+	return files
+}
+
+// styleExamples returns recent commit subjects for the prompt when
+// style_from_history is enabled. Failing to read history just means no
+// examples.
+func styleExamples(ctx context.Context, cfg *config.Config, repo *git.Repository) []string {
+	if !cfg.StyleFromHistory {
+		return nil
+	}
+	subjects, err := repo.GetRecentSubjects(ctx, cfg.StyleHistoryCount)
+	if err != nil {
+		return nil
+	}
+	return subjects
+}
+
+// fileTypeGuidance returns the file_type_guidance instructions that apply to
+// the staged files.
+func fileTypeGuidance(cfg *config.Config, staged []git.FileStatus) []string {
+	if len(cfg.FileTypeGuidance) == 0 {
+		return nil
+	}
+	return prompt.GuidanceForFiles(stagedPaths(staged), cfg.FileTypeGuidance)
+}
+
+// stagedPaths returns the paths of the staged files.
+func stagedPaths(staged []git.FileStatus) []string {
+	paths := make([]string, len(staged))
+	for i, f := range staged {
+		paths[i] = f.Path
+	}
+	return paths
+}
+
+// dependencyChanges summarizes the dependency version changes in the raw
+// diff, which still contains the manifests' lines.
+func dependencyChanges(diff string) []string {
+	var summary []string
+	for _, change := range preprocess.ExtractDependencyChanges(diff) {
+		summary = append(summary, change.String())
+	}
+	return summary
+}
+
+// absorbSuggestMaxHunks is the largest staged change, in hunks, that gets
+// the cmt absorb suggestion.
+const absorbSuggestMaxHunks = 3
+
+// suggestAbsorb prints a one-line hint when the staged change is small and
+// there are unpushed commits it could be absorbed into. It never fails the
+// commit; any error just skips the hint.
+func suggestAbsorb(ctx context.Context, repo *git.Repository, diff string) {
+	hunks, err := git.SplitDiffIntoHunks(diff)
+	if err != nil || len(hunks) == 0 || len(hunks) > absorbSuggestMaxHunks {
+		return
+	}
+	commits, err := repo.GetUnpushedCommits(ctx)
+	if err != nil || len(commits) == 0 {
+		return
+	}
+	ui.Infof("💡 Small change with %d unpushed commit(s): `cmt absorb` can fold it into the commit it fixes.\n", len(commits))
+}
+
+// closeProvider releases the provider's resources, warning on failure since
+// the command's own result matters more.
+func closeProvider(provider ai.Provider) {
+	if err := provider.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to close AI provider: %v\n", err)
+	}
+}
+
+// newProvider initializes the Claude CLI provider and checks it's usable.
+func newProvider(ctx context.Context, cfg *config.Config) (*ai.ClaudeCLI, error) {
+	providerConfig := &ai.ProviderConfig{
+		DefaultModel:      cfg.Model,
+		Timeout:           60, // Default timeout
+		RequestsPerMinute: cfg.RequestsPerMinute,
+	}
+	provider, err := ai.NewClaudeCLI(providerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize Claude CLI: %w", err)
+	}
+
+	// Check if Claude is available
+	available, err := provider.IsAvailable(ctx)
+	if !available || err != nil {
+		return nil, fmt.Errorf("Claude CLI is not available. Please ensure 'claude' is installed and in your PATH")
+	}
+	return provider, nil
+}
+
+// loadFillTemplate reads a --fill template and checks it has at least one
+// placeholder for the model to fill.
+func loadFillTemplate(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read fill template: %w", err)
+	}
+	template := string(data)
+	if len(ai.Placeholders(template)) == 0 {
+		return "", fmt.Errorf("fill template %s has no {{TODO: ...}} placeholders", path)
+	}
+	return template, nil
+}
+
+// skipSecretScan reports whether the secret scan is switched off, by
+// --no-secret-scan, skip_secret_scan or secret_on_detect: ignore.
+func skipSecretScan(noScanFlag bool, cfg *config.Config) bool {
+	return noScanFlag || cfg.SkipSecretScan || cfg.SecretOnDetect == "ignore"
+}
+
+// askAboutSecrets reports whether secret findings open the interactive
+// warning screen rather than following secret_on_detect. That needs a
+// terminal, and secret_on_detect: block always blocks, so only
+// --no-secret-scan gets past it.
+func askAboutSecrets(cfg *config.Config, terminal bool) bool {
+	return cfg.Interactive && terminal && cfg.SecretOnDetect != "block"
+}
+
+// secretPolicyAction applies secret_on_detect to findings without asking:
+// "block" prints them and fails the commit, and "warn" prints them and
+// continues.
+func secretPolicyAction(mode string, secrets []ui.Secret, w io.Writer) (ui.SecretAction, error) {
+	fmt.Fprintf(w, "⚠️  Found %d potential secret(s) in the staged changes:\n%s", len(secrets), ui.FormatSecretFindings(secrets))
+	if mode == "block" {
+		return ui.ActionAbort, fmt.Errorf("commit blocked by secret_on_detect: block; fix the findings or pass --no-secret-scan")
+	}
+	fmt.Fprintln(w, "Continuing with the commit (secret_on_detect: warn).")
+	return ui.ActionContinue, nil
+}
+
+// renderCustomPrompt renders the custom_prompt_path template for the staged
+// changes, or returns "" when none is configured.
+func renderCustomPrompt(cfg *config.Config, data prompt.PromptData) (string, error) {
+	if cfg.CustomPromptPath == "" {
+		return "", nil
+	}
+	content, err := os.ReadFile(cfg.CustomPromptPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read custom prompt: %w", err)
+	}
+	rendered, err := prompt.RenderCustomPrompt(string(content), data)
+	if err != nil {
+		return "", fmt.Errorf("custom prompt %s: %w", cfg.CustomPromptPath, err)
+	}
+	return rendered, nil
+}
+
+// promptBudget returns the prompt token budget, split between instructions
+// and the diff by prompt_instruction_budget.
+func promptBudget(cfg *config.Config) prompt.Budget {
+	return prompt.Budget{
+		Total:               cfg.MaxDiffTokens,
+		InstructionFraction: cfg.PromptInstructionBudget,
+	}
+}
+
+// preprocessOptions returns the options used to prepare a diff for the model.
+// The diff's share of the prompt budget is kept free from hint and file list
+// overhead, summarizing the file list if it runs over its share.
+func preprocessOptions(cfg *config.Config, hint string, stagedFiles []string) (preprocess.Options, []string) {
+	budget := promptBudget(cfg)
+	hintTokens := prompt.EstimateTokens(hint)
+	instructionTokens := hintTokens + prompt.EstimateTokens(strings.Join(stagedFiles, "\n"))
+	if budget.Exceeded(instructionTokens) {
+		stagedFiles = prompt.SummarizeFiles(stagedFiles, budget.InstructionLimit()-hintTokens)
+		instructionTokens = hintTokens + prompt.EstimateTokens(strings.Join(stagedFiles, "\n"))
+	}
+
+	opts := preprocess.Options{
+		MaxTokens:       budget.DiffLimit(instructionTokens),
+		FilterBinary:    cfg.FilterBinary,
+		FilterMinified:  cfg.FilterMinified,
+		FilterGenerated: cfg.FilterGenerated,
+	}
+	return opts, stagedFiles
+}
+
+// filterOptions returns the preprocessing options that decide which staged
+// files are filtered, from the filter settings and git's binary
+// classification, for commands that filter hunks rather than the diff.
+func filterOptions(ctx context.Context, cfg *config.Config, repo *git.Repository) preprocess.Options {
+	return preprocess.Options{
+		FilterBinary:    cfg.FilterBinary,
+		FilterMinified:  cfg.FilterMinified,
+		FilterGenerated: cfg.FilterGenerated,
+		BinaryFiles:     stagedBinaryFiles(ctx, cfg, repo),
+	}
+}
+
+// applyPrefixFlag lets --prefix override message_prefix. The flag belongs
+// to the root command, so split and absorb accept it as well.
+func applyPrefixFlag(cmd *cli.Command, cfg *config.Config) {
+	if cmd.IsSet("prefix") {
+		cfg.MessagePrefix = cmd.String("prefix")
+	}
+}
+
+// stagedBinaryFiles returns git's binary classification of the staged files
+// for preprocessing, which honors .gitattributes. It returns nil, leaving
+// the decision to file extensions, when binary files aren't filtered or the
+// classification can't be read.
+func stagedBinaryFiles(ctx context.Context, cfg *config.Config, repo *git.Repository) map[string]bool {
+	if !cfg.FilterBinary {
+		return nil
+	}
+	binaryFiles, err := repo.StagedBinaryFiles(ctx)
+	if err != nil {
+		return nil
+	}
+	return binaryFiles
+}
+
+// printFilterStats summarizes what preprocessing removed from the diff.
+func printFilterStats(stats *preprocess.FilterStats, limit int) {
+	if stats.FilteredFiles > 0 {
+		ui.Infof("📝 Preprocessed diff: %d/%d files included\n",
+			stats.TotalFiles-stats.FilteredFiles, stats.TotalFiles)
+		if stats.BinaryFiles > 0 {
+			ui.Infof("   - Filtered %d binary file(s)\n", stats.BinaryFiles)
+		}
+		if stats.MinifiedFiles > 0 {
+			ui.Infof("   - Filtered %d minified file(s)\n", stats.MinifiedFiles)
+		}
+		if stats.GeneratedFiles > 0 {
+			ui.Infof("   - Filtered %d generated/lock file(s)\n", stats.GeneratedFiles)
+		}
+	}
+	if stats.Truncated {
+		ui.Infof("   - Truncated at %d tokens (limit: %d)\n",
+			stats.TokensUsed, limit)
+	}
+}
+
+// editorOptions picks the help and diff comments shown in the external
+// editor from the config and the --no-edit-help flag.
+func editorOptions(cmd *cli.Command, cfg *config.Config, diff string) ui.EditorOptions {
+	var opts ui.EditorOptions
+	if cfg.EditorHelp && !cmd.Bool("no-edit-help") {
+		opts.Help = ui.DefaultEditorHelp
+		if cfg.EditorHelpText != "" {
+			opts.Help = cfg.EditorHelpText
+		}
+	}
+	if cfg.EditorShowDiff {
+		opts.Diff = diff
+	}
+	return opts
+}
+
+// prepareBranch creates the --create-branch branch, or warns when the commit
+// would be made on a detached HEAD. It reports whether to push afterwards:
+// push is turned off on a detached HEAD, which has no branch to push.
+func prepareBranch(ctx context.Context, cmd *cli.Command, repo *git.Repository) (bool, error) {
+	if name := cmd.String("create-branch"); name != "" {
+		// Already there, e.g. when the commit is restarted after a change
+		if current, err := repo.GetCurrentBranch(ctx); err == nil && current == name {
+			return cmd.Bool("push"), nil
+		}
+		if err := repo.CreateBranch(ctx, name); err != nil {
+			return false, err
+		}
+		ui.Infof("🌿 Switched to new branch %s\n", name)
+		return cmd.Bool("push"), nil
+	}
+
+	detached, err := repo.IsDetachedHead(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !detached {
+		return cmd.Bool("push"), nil
+	}
+
+	fmt.Fprintln(os.Stderr, "⚠️  HEAD is detached: the commit won't be on any branch.")
+	fmt.Fprintln(os.Stderr, "   Use --create-branch <name> to commit on a new branch instead.")
+	if cmd.Bool("push") {
+		fmt.Fprintln(os.Stderr, "   Push is disabled until you create a branch.")
+	}
+	return false, nil
+}
+
+// finalizeMessage applies the configured clean-ups to a generated message:
+// whitespace and footers first, then the subject rules, and last the
+// message formatter and the post-generate filter.
+func finalizeMessage(ctx context.Context, cfg *config.Config, repo *git.Repository, message string, footers []string) string {
+	if cfg.NormalizeMessage {
+		message = prompt.Normalize(message)
+	}
+	message = prompt.NormalizeFooters(prompt.AppendFooters(message, footers))
+	subject, rest, found := strings.Cut(message, "\n")
+	if cfg.StripEmoji {
+		subject = prompt.StripEmoji(subject)
+	}
+	if cfg.EnforceImperative {
+		subject = prompt.Imperative(subject)
+	}
+	if cfg.SubjectCase == "lower" {
+		subject = prompt.LowercaseSubject(subject)
+	}
+	if cfg.StripTrailingPeriod {
+		subject = prompt.StripTrailingPeriod(subject)
+	}
+	message = subject
+	if found {
+		message += "\n" + rest
+	}
+	message = applyFormatter(ctx, cfg, repo, message)
+	return applyPostGenerate(ctx, cfg, repo, message)
+}
+
+// applyFormatter shapes message with the configured message formatter: the
+// built-in formatter of that name, or else a command given the structured
+// message as JSON. A failing command leaves the message unformatted.
+func applyFormatter(ctx context.Context, cfg *config.Config, repo *git.Repository, message string) string {
+	if cfg.MessageFormatter == "" {
+		return message
+	}
+
+	branch, err := repo.GetCurrentBranch(ctx)
+	if err != nil || branch == "HEAD" {
+		branch = ""
+	}
+	structured := prompt.Structure(message, branch)
+	if format, ok := prompt.Formatters[cfg.MessageFormatter]; ok {
+		return format(structured)
+	}
+
+	input, err := json.Marshal(structured)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to encode the message for the formatter: %v\n", err)
+		return message
+	}
+	timeout := time.Duration(cfg.PostGenerateTimeout) * time.Second
+	result, err := hook.Format(ctx, cfg.MessageFormatter, repo.Path, string(input), message, timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v; using the unformatted message\n", err)
+	}
+	return result
+}
+
+// blankMessage reports whether message is empty or only whitespace, which
+// is never worth committing.
+func blankMessage(message string) bool {
+	return strings.TrimSpace(message) == ""
+}
+
+// subjectOnly returns the subject line of message, for --no-body.
+func subjectOnly(message string) string {
+	subject, _, _ := prompt.ParseMessage(message)
+	return subject
+}
+
+// coAuthorFooters returns the Co-authored-by trailers for the identities in
+// the co_author_from_env variables, leaving out the commit's own author.
+func coAuthorFooters(ctx context.Context, cfg *config.Config, repo *git.Repository) []string {
+	if len(cfg.CoAuthorFromEnv) == 0 {
+		return nil
+	}
+	// Without the author's email nothing can be left out, but the
+	// trailers are still worth adding
+	author, _ := repo.AuthorEmail(ctx)
+	return prompt.CoAuthorFooters(cfg.CoAuthorFromEnv, os.Getenv, author)
+}
+
+// applyPostGenerate filters message through the configured post-generate
+// command, keeping the original message if the command fails.
+func applyPostGenerate(ctx context.Context, cfg *config.Config, repo *git.Repository, message string) string {
+	if cfg.PostGenerateCommand == "" {
+		return message
+	}
+
+	timeout := time.Duration(cfg.PostGenerateTimeout) * time.Second
+	result, err := hook.PostGenerate(ctx, cfg.PostGenerateCommand, repo.Path, message, timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  %v; using the original message\n", err)
+	}
+	return result
+}
+
+// commitOptions resolves the git commit options from the flags and config.
+// When signing is requested the key is checked up front, so a missing or
+// misconfigured key is reported before any work is done.
+func commitOptions(ctx context.Context, cmd *cli.Command, cfg *config.Config, repo *git.Repository) (git.CommitOptions, error) {
+	key := cmd.String("signing-key")
+	if key == "" {
+		key = cfg.SigningKey
+	}
+
+	opts := git.CommitOptions{
+		AllowEmpty: cmd.Bool("allow-empty"),
+		Sign:       cmd.Bool("gpg-sign") || key != "",
+		SigningKey: key,
+	}
+	if opts.Sign {
+		if err := repo.CheckSigningKey(ctx, key); err != nil {
+			return opts, fmt.Errorf("cannot sign the commit: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// checkAmendable returns an error unless HEAD exists and has not been
+// pushed, so published history is never rewritten.
+func checkAmendable(ctx context.Context, repo *git.Repository) error {
+	headSHA, err := repo.GetCurrentCommitSHA(ctx)
+	if err != nil {
+		return fmt.Errorf("no commit to amend: %w", err)
+	}
+
+	pushed, err := repo.IsCommitPushed(ctx, headSHA)
+	if err != nil {
+		return err
+	}
+	if pushed {
+		return fmt.Errorf("refusing to amend %s: it has already been pushed", headSHA[:8])
+	}
+	return nil
+}
+
+// runAmendNoEdit folds the staged changes into HEAD, keeping its message.
+func runAmendNoEdit(ctx context.Context, repo *git.Repository, opts git.CommitOptions, jsonOutput bool, prefix string) error {
+	if err := checkAmendable(ctx, repo); err != nil {
+		return err
+	}
+
+	ui.SimpleProgress(ui.ProgressMessages.AmendingCommit)
+	opts.Amend, opts.NoEdit = true, true
+	if err := repo.CommitWithOptions(ctx, "", opts); err != nil {
+		return fmt.Errorf("failed to amend commit: %w", err)
+	}
+
+	ui.Infoln("\n✅ Amended last commit (message unchanged)")
+	return printCommitResult(ctx, repo, jsonOutput, prefix, nil)
+}
+
+// runAmendAppend adds text to HEAD's message and amends the commit. Trailers
+// join the footer block and other text ends the body; no model is called.
+func runAmendAppend(ctx context.Context, cmd *cli.Command, text string) error {
+	if strings.TrimSpace(text) == "" {
+		return fmt.Errorf("--append needs some text")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	applyPrefixFlag(cmd, cfg)
+
+	repo, err := git.NewRepository("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+	if err := checkAmendable(ctx, repo); err != nil {
+		return err
+	}
+
+	message, err := repo.GetLastCommitMessage(ctx)
+	if err != nil {
+		return err
+	}
+	updated := prompt.AppendText(message, text)
+	if updated == message {
+		ui.Infoln("The last commit's message already contains that; nothing to amend.")
+		return nil
+	}
+
+	opts, err := commitOptions(ctx, cmd, cfg, repo)
+	if err != nil {
+		return err
+	}
+	// Only the message changes: staged changes stay out of the commit, and
+	// an already empty commit may stay empty
+	opts.Amend, opts.MessageOnly, opts.AllowEmpty = true, true, true
+
+	ui.SimpleProgress(ui.ProgressMessages.AmendingCommit)
+	if err := repo.CommitWithOptions(ctx, updated, opts); err != nil {
+		return fmt.Errorf("failed to amend commit: %w", err)
+	}
+
+	ui.Infoln("\n✅ Amended last commit")
+	return printCommitResult(ctx, repo, cmd.Bool("json"), cfg.MessagePrefix, nil)
+}
+
+// recordRun appends run to the repository's metrics file. Runs that ended
+// without an outcome were cancelled, or failed when err is set.
+func recordRun(repo *git.Repository, run *telemetry.Event, err error) {
+	switch {
+	case run.Outcome != "":
+	case err != nil:
+		run.Outcome = telemetry.OutcomeError
+		run.Error = err.Error()
+	default:
+		run.Outcome = telemetry.OutcomeCancelled
+	}
+
+	gitDir, recordErr := repo.GetCommonDir()
+	if recordErr == nil {
+		recordErr = telemetry.Append(telemetry.Path(gitDir), *run)
+	}
+	if recordErr != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Failed to record usage metrics: %v\n", recordErr)
+	}
+}
+
+// commitResult is the --json description of a new commit. Type and Scope
+// are empty when the message isn't a conventional commit.
+type commitResult struct {
+	SHA         string       `json:"sha"`
+	Message     string       `json:"message"`
+	Type        string       `json:"type"`
+	Scope       string       `json:"scope"`
+	Corrections []correction `json:"corrections"`
+}
+
+// newCommitResult describes the commit sha with message, parsing the
+// conventional commit type and scope from the message as committed, after
+// any edits in the review. The type and scope are read after prefix.
+func newCommitResult(sha, message, prefix string, corrections []correction) commitResult {
+	if corrections == nil {
+		corrections = []correction{}
+	}
+	unprefixed := prompt.UnprefixSubject(message, prefix)
+	return commitResult{
+		SHA:         sha,
+		Message:     message,
+		Type:        prompt.ExtractConventionalType(unprefixed),
+		Scope:       prompt.ExtractScope(unprefixed),
+		Corrections: corrections,
+	}
+}
+
+// printCommitResult reports the new HEAD commit for scripts: as JSON with
+// --json, including the message's conventional commit type and scope and
+// the corrections made to it, or as the bare SHA in quiet mode.
+func printCommitResult(ctx context.Context, repo *git.Repository, jsonOutput bool, prefix string, corrections []correction) error {
+	if !jsonOutput {
+		printQuietSHA(ctx, repo)
+		return nil
+	}
+
+	sha, err := repo.GetCurrentCommitSHA(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get commit SHA: %w", err)
+	}
+	message, err := repo.GetLastCommitMessage(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get commit message: %w", err)
+	}
+
+	out, err := json.MarshalIndent(newCommitResult(sha, message, prefix, corrections), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// printQuietSHA prints the HEAD commit SHA in quiet mode, so scripts still
+// learn which commit was created.
+func printQuietSHA(ctx context.Context, repo *git.Repository) {
+	if !ui.Quiet() {
+		return
+	}
+	if sha, err := repo.GetCurrentCommitSHA(ctx); err == nil {
+		fmt.Println(sha)
+	}
+}
+
+// initConfig initializes a .cmt.yml configuration file in the current repository.
+func initConfig(ctx context.Context) error {
+	// Create default config
+	cfg := config.Default()
 
 	// Save to local .cmt.yml
 	if err := cfg.Save(false); err != nil {
@@ -481,20 +1711,218 @@ func setConfig(ctx context.Context, key, value string) error {
 	return nil
 }
 
+// editConfig opens the local or global config file in the user's editor.
+// Edits are made on a copy and only written back once they parse and pass
+// validation; invalid edits can be reopened or discarded.
+func editConfig(ctx context.Context, global bool) error {
+	path := config.LocalConfigPath()
+	if global {
+		var err error
+		if path, err = config.GlobalConfigPath(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if err := config.Default().Save(global); err != nil {
+			return fmt.Errorf("failed to save config: %w", err)
+		}
+		fmt.Printf("✓ Created %s with default configuration\n", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp("", "cmt-config-*.yml")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	_, err = tmpFile.Write(data)
+	tmpFile.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	for {
+		if err := ui.EditFile(tmpFile.Name()); err != nil {
+			return err
+		}
+
+		edited, err := os.ReadFile(tmpFile.Name())
+		if err != nil {
+			return fmt.Errorf("failed to read edited config: %w", err)
+		}
+
+		cfg, err := config.Parse(edited)
+		if err == nil {
+			err = cfg.Validate()
+		}
+		if err == nil {
+			if err := os.WriteFile(path, edited, 0644); err != nil {
+				return fmt.Errorf("failed to save config: %w", err)
+			}
+			fmt.Printf("✓ Saved %s\n", path)
+			return nil
+		}
+
+		fmt.Println("❌ The edited config is invalid:")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Printf("   • %s\n", line)
+		}
+		fmt.Print("Reopen the editor? (y/n): ")
+		var response string
+		fmt.Scanln(&response)
+		if response != "y" && response != "yes" {
+			return fmt.Errorf("discarded invalid edits; %s is unchanged", path)
+		}
+	}
+}
+
+// showScopes prints the scopes used in the last limit commit subjects, most
+// common first, so new commits can reuse an existing scope.
+func showScopes(ctx context.Context, limit int) error {
+	repo, err := git.NewRepository("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+
+	subjects, err := repo.GetRecentSubjects(ctx, limit)
+	if err != nil {
+		return err
+	}
+
+	scopes := prompt.CountScopes(subjects)
+	if len(scopes) == 0 {
+		fmt.Printf("No conventional commit scopes found in the last %d commit(s).\n", len(subjects))
+		return nil
+	}
+
+	for _, s := range scopes {
+		fmt.Printf("%5d  %s\n", s.Count, s.Scope)
+	}
+	return nil
+}
+
+// showStats prints a summary of the runs recorded in the metrics file.
+func showStats() error {
+	repo, err := git.NewRepository("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+	gitDir, err := repo.GetCommonDir()
+	if err != nil {
+		return err
+	}
+
+	events, err := telemetry.Load(telemetry.Path(gitDir))
+	if err != nil {
+		return err
+	}
+	s := telemetry.Summarize(events)
+	if s.Runs == 0 {
+		fmt.Println("No usage metrics recorded. Set telemetry_local: true to record them.")
+		return nil
+	}
+
+	percent := func(n int) float64 { return 100 * float64(n) / float64(s.Runs) }
+	fmt.Printf("Runs:            %d (%s to %s)\n", s.Runs, s.First.Format("2006-01-02"), s.Last.Format("2006-01-02"))
+	fmt.Printf("Committed:       %d (%.0f%%)\n", s.Committed, percent(s.Committed))
+	fmt.Printf("Cancelled:       %d (%.0f%%)\n", s.Cancelled, percent(s.Cancelled))
+	fmt.Printf("Errors:          %d (%.0f%%)\n", s.Errors, percent(s.Errors))
+	fmt.Printf("Avg generation:  %s\n", s.AvgGeneration.Round(100*time.Millisecond))
+	fmt.Printf("Avg prompt:      ~%d tokens\n", s.AvgTokens)
+	fmt.Printf("Regenerations:   %d (%.2f per run)\n", s.Regenerations, s.RegenerationRate())
+	fmt.Printf("Corrections:     %d\n", s.Corrections)
+	fmt.Printf("Edited:          %d (%.0f%%)\n", s.Edited, percent(s.Edited))
+	fmt.Println("Models:")
+	for _, m := range s.Models {
+		fmt.Printf("%5d  %s\n", m.Runs, m.Model)
+	}
+	return nil
+}
+
+// searchHistory prints the commits whose message contains query and, with
+// staged set, the commits that touched the staged files.
+func searchHistory(ctx context.Context, query string, staged bool, limit int) error {
+	if query == "" && !staged {
+		return fmt.Errorf("usage: cmt search <query> (or --staged)")
+	}
+
+	repo, err := git.NewRepository("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+
+	if query != "" {
+		commits, err := repo.SearchCommits(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Commits mentioning %q:\n", query)
+		printCommitSubjects(commits)
+	}
+
+	if staged {
+		files, err := repo.GetStagedFiles(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get staged files: %w", err)
+		}
+		if len(files) == 0 {
+			fmt.Println("No staged files to look up.")
+			return nil
+		}
+		commits, err := repo.LogForFiles(ctx, files, limit)
+		if err != nil {
+			return err
+		}
+		if query != "" {
+			fmt.Println()
+		}
+		fmt.Printf("Commits touching the %d staged file(s):\n", len(files))
+		printCommitSubjects(commits)
+	}
+	return nil
+}
+
+// printCommitSubjects lists commits as "sha  date  subject" lines.
+func printCommitSubjects(commits []git.CommitInfo) {
+	if len(commits) == 0 {
+		fmt.Println("  (none)")
+		return
+	}
+	for _, c := range commits {
+		fmt.Printf("  %s  %s  %s\n", c.SHA[:8], c.Date.Format("2006-01-02"), c.Message)
+	}
+}
+
 // showDiff displays the diff that will be committed.
-func showDiff(ctx context.Context) error {
+// With processed set, the staged diff is shown after preprocessing; with
+// pick set, a file picker shows one staged file's diff at a time.
+func showDiff(ctx context.Context, processed, pick bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
 	repo, err := git.NewRepository("")
 	if err != nil {
 		return fmt.Errorf("failed to initialize git repository: %w", err)
 	}
+	repo.MaxDiffBytes = cfg.MaxDiffBytes
+	if processed {
+		repo.IgnoreWhitespace = cfg.DiffIgnoreWhitespace
+	}
 
-	// Check for staged changes
-	hasChanges, err := repo.HasStagedChanges(ctx)
+	// Read the staged changes once; no files means nothing is staged
+	staged, err := repo.ReadStagedChanges(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to check staged changes: %w", err)
+		return fmt.Errorf("failed to get diff: %w", err)
 	}
 
-	if !hasChanges {
+	if len(staged.Files) == 0 {
 		fmt.Println("No staged changes. Showing unstaged diff:")
 		diff, err := repo.GetDiff(ctx, false)
 		if err != nil {
@@ -502,17 +1930,40 @@ func showDiff(ctx context.Context) error {
 		}
 		if diff == "" {
 			fmt.Println("No changes to display.")
-		} else {
-			fmt.Println(diff)
+			return nil
 		}
-	} else {
-		fmt.Println("Staged changes that will be committed:")
-		diff, err := repo.GetDiff(ctx, true)
-		if err != nil {
-			return fmt.Errorf("failed to get diff: %w", err)
+		return ui.PrintDiff(diff)
+	}
+
+	if processed {
+		return showProcessedDiff(cfg, staged, stagedBinaryFiles(ctx, cfg, repo))
+	}
+	if pick {
+		if !ui.IsInteractive() {
+			return fmt.Errorf("--pick needs a terminal")
 		}
-		fmt.Println(diff)
+		return ui.ShowDiffPicker(staged.Files, staged.Diff)
+	}
+
+	fmt.Println("Staged changes that will be committed:")
+	return ui.PrintDiff(staged.Diff)
+}
+
+// showProcessedDiff prints the staged diff as the model will see it after
+// filtering and truncation, followed by a summary of what was removed.
+func showProcessedDiff(cfg *config.Config, staged *git.StagedChanges, binaryFiles map[string]bool) error {
+	preprocessOpts, _ := preprocessOptions(cfg, "", formatFileStatuses(staged.Files))
+	preprocessOpts.BinaryFiles = binaryFiles
+	processedDiff, stats := preprocess.ProcessWithStats(staged.Diff, preprocessOpts)
+
+	fmt.Println("Staged changes as sent to the model:")
+	if err := ui.PrintDiff(processedDiff); err != nil {
+		return err
 	}
+	fmt.Println()
 
+	fmt.Printf("📊 %d file(s), ~%d tokens used (limit: %d)\n",
+		stats.TotalFiles, stats.TokensUsed, preprocessOpts.MaxTokens)
+	printFilterStats(stats, preprocessOpts.MaxTokens)
 	return nil
 }
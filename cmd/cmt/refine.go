@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gussy/cmt/internal/ai"
+	"github.com/gussy/cmt/internal/prompt"
+)
+
+// messageCheck is a check on a generated message. check returns why the
+// message should be regenerated, or "" if it passes.
+type messageCheck struct {
+	name  string
+	check func(message string) string
+}
+
+// correction records one regeneration asked for by a message check.
+type correction struct {
+	Attempt int    `json:"attempt"`
+	Check   string `json:"check"`
+	Reason  string `json:"reason"`
+}
+
+// messageChecks returns the checks generated messages must pass for format.
+func messageChecks(format ai.MessageFormat) []messageCheck {
+	checks := []messageCheck{{
+		name: "subject",
+		check: func(message string) string {
+			subject, _, _ := strings.Cut(message, "\n")
+			if strings.TrimSpace(subject) == "" {
+				return "the message must start with a non-empty subject line"
+			}
+			return ""
+		},
+	}}
+	if format == ai.FormatOneLine {
+		checks = append(checks, messageCheck{
+			name: "oneline",
+			check: func(message string) string {
+				if strings.Contains(strings.TrimSpace(message), "\n") {
+					return "the message must be a single subject line with no body"
+				}
+				return ""
+			},
+		})
+	}
+	if format == ai.FormatStructured {
+		checks = append(checks, messageCheck{
+			name: "structured",
+			check: func(message string) string {
+				missing := prompt.MissingSections(message)
+				if len(missing) == 0 {
+					return ""
+				}
+				return fmt.Sprintf("the body is missing the %s section(s); put each header, such as \"%s:\", on its own line",
+					strings.Join(missing, ", "), missing[0])
+			},
+		})
+	}
+	return checks
+}
+
+// refineMessage runs message through checks, regenerating it with the first
+// failing check's reason as feedback until every check passes or
+// maxAttempts regenerations have been made. It returns the last message and
+// the corrections made; a message that still fails after the last attempt
+// is returned as is, for the review to deal with.
+func refineMessage(message string, checks []messageCheck, maxAttempts int, regenerate func(previous, feedback string) (string, error)) (string, []correction, error) {
+	var corrections []correction
+	for attempt := 1; ; attempt++ {
+		name, reason := failingCheck(message, checks)
+		if reason == "" || attempt > maxAttempts {
+			return message, corrections, nil
+		}
+		corrections = append(corrections, correction{Attempt: attempt, Check: name, Reason: reason})
+
+		regenerated, err := regenerate(message, reason)
+		if err != nil {
+			return message, corrections, fmt.Errorf("failed to regenerate after the %s check: %w", name, err)
+		}
+		message = regenerated
+	}
+}
+
+// failingCheck returns the name and reason of the first check message
+// fails, or empty strings if it passes them all.
+func failingCheck(message string, checks []messageCheck) (string, string) {
+	for _, c := range checks {
+		if reason := c.check(message); reason != "" {
+			return c.name, reason
+		}
+	}
+	return "", ""
+}
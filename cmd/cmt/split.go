@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gussy/cmt/internal/ai"
+	"github.com/gussy/cmt/internal/config"
+	"github.com/gussy/cmt/internal/git"
+	"github.com/gussy/cmt/internal/preprocess"
+	"github.com/gussy/cmt/internal/prompt"
+	"github.com/gussy/cmt/internal/ui"
+	"github.com/urfave/cli/v3"
+)
+
+// splitCommand creates the split subcommand.
+func splitCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "split",
+		Usage: "Split the staged changes into several commits with AI-suggested messages",
+		Description: `The split command sends the staged hunks to the AI, which groups them
+into a series of coherent commits and suggests a message for each. Every
+commit is confirmed before it is made, and holds exactly its hunks; hunks
+that are skipped or left out stay staged.`,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:    "commits",
+				Aliases: []string{"n"},
+				Usage:   "Number of commits to split into (default: as many as the AI finds)",
+			},
+			&cli.BoolFlag{
+				Name:    "yes",
+				Aliases: []string{"y"},
+				Usage:   "Create every proposed commit without asking",
+			},
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Show the proposed commits without creating them",
+			},
+			&cli.StringFlag{
+				Name:    "model",
+				Aliases: []string{"m"},
+				Usage:   "AI model to use",
+			},
+		},
+		Action: runSplit,
+	}
+}
+
+// runSplit executes the split workflow.
+func runSplit(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Int("commits") < 0 {
+		return fmt.Errorf("--commits must be positive")
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cmd.Bool("debug") {
+		cfg.Verbose = true
+	}
+	applyPrefixFlag(cmd, cfg)
+
+	repo, err := git.NewRepository("")
+	if err != nil {
+		return fmt.Errorf("failed to initialize git repository: %w", err)
+	}
+	repo.MaxDiffBytes = cfg.MaxDiffBytes
+
+	// Step 1: Split the staged diff into hunks.
+	ui.SimpleProgress("Analyzing staged changes...")
+	diff, err := repo.GetDiff(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to get diff: %w", err)
+	}
+	if strings.TrimSpace(diff) == "" {
+		ui.Infoln("❌ No staged changes to split.")
+		ui.Infoln("\nUse 'git add' to stage the changes you want to split.")
+		return nil
+	}
+
+	hunks, err := git.SplitDiffIntoHunks(diff)
+	if err != nil {
+		return fmt.Errorf("failed to split diff into hunks: %w", err)
+	}
+
+	// Binary, minified and generated files can't be judged from their
+	// content; leave them staged like absorb does, following the filter
+	// settings.
+	preprocessOpts := filterOptions(ctx, cfg, repo)
+	hunks, filtered := git.FilterHunks(hunks, func(path string) string {
+		return preprocess.SkipReason(path, preprocessOpts)
+	})
+	if len(filtered) > 0 {
+		ui.Infof("🚫 Skipped %d hunk(s) from filtered files (left staged):\n", len(filtered))
+		for _, f := range filtered {
+			ui.Infof("   • %s (%s)\n", f.Hunk.FilePath, f.Reason)
+		}
+	}
+
+	if len(hunks) < 2 {
+		ui.Infoln("❌ Not enough staged hunks to split; run cmt to commit them.")
+		return nil
+	}
+	ui.Infof("🔍 Found %d hunk(s) to split\n", len(hunks))
+
+	// Step 2: Initialize AI provider.
+	ui.SimpleProgress("Initializing AI provider...")
+	model := cmd.String("model")
+	if model == "" {
+		model = cfg.Model
+	}
+
+	provider, err := ai.NewClaudeCLI(&ai.ProviderConfig{
+		DefaultModel:      model,
+		Timeout:           60,
+		RequestsPerMinute: cfg.RequestsPerMinute,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to initialize AI provider: %w", err)
+	}
+	defer closeProvider(provider)
+
+	splitReq := &ai.SplitRequest{
+		Hunks:       hunks,
+		Commits:     cmd.Int("commits"),
+		Model:       model,
+		Temperature: cfg.Temperature,
+		MaxTokens:   cfg.MaxTokens,
+	}
+
+	// Debugging aid: show exactly what would be sent and stop.
+	if cmd.Bool("print-prompt") {
+		fmt.Fprintln(os.Stderr, provider.SplitPrompt(splitReq))
+		return nil
+	}
+
+	available, err := provider.IsAvailable(ctx)
+	if err != nil {
+		return fmt.Errorf("AI provider is not available: %w", err)
+	}
+	if !available {
+		return fmt.Errorf("AI provider is not available. Please ensure 'claude' is installed and in your PATH")
+	}
+
+	// Step 3: Have the AI propose the commits.
+	ui.SimpleProgress("Grouping hunks into commits with AI...")
+	splitResp, err := provider.ProposeSplit(ctx, splitReq)
+	if err != nil {
+		return fmt.Errorf("failed to propose a split: %w", err)
+	}
+	if len(splitResp.Groups) == 0 {
+		ui.Infoln("❌ The AI proposed no commits; nothing was changed.")
+		return nil
+	}
+
+	for i := range splitResp.Groups {
+		splitResp.Groups[i].Message = finalizeMessage(ctx, cfg, repo, splitResp.Groups[i].Message, nil)
+	}
+	fmt.Print(renderSplitPlan(splitResp.Groups, splitResp.UnassignedHunks))
+
+	if cmd.Bool("dry-run") {
+		fmt.Println("\n🔍 DRY RUN - No changes will be made")
+		return nil
+	}
+
+	// Step 4: Create each commit, asking first unless --yes.
+	created, left := 0, len(splitResp.UnassignedHunks)
+	for i, group := range splitResp.Groups {
+		message := group.Message
+		if !cmd.Bool("yes") {
+			answer, edited := confirmSplitCommit(cmd, cfg, i+1, len(splitResp.Groups), message)
+			switch answer {
+			case "q":
+				for _, rest := range splitResp.Groups[i:] {
+					left += len(rest.Hunks)
+				}
+				ui.Infof("\n📌 Stopped after %d commit(s); %d hunk(s) left staged\n", created, left)
+				return nil
+			case "s":
+				left += len(group.Hunks)
+				continue
+			}
+			message = edited
+		}
+
+		message = prompt.PrefixSubject(message, cfg.MessagePrefix)
+		if err := repo.CommitHunks(ctx, group.Hunks, message); err != nil {
+			return fmt.Errorf("failed to create commit %d: %w", i+1, err)
+		}
+		created++
+		ui.Infof("✅ Created commit %d/%d: %s\n", i+1, len(splitResp.Groups), strings.Split(message, "\n")[0])
+	}
+
+	ui.Infof("\n✨ Split into %d commit(s)\n", created)
+	if left > 0 {
+		ui.Infof("📌 Left %d hunk(s) staged\n", left)
+	}
+	if created > 0 {
+		ui.Infof("💾 To undo, run: git reset --soft HEAD~%d\n", created)
+	}
+	return nil
+}
+
+// renderSplitPlan lists the proposed commits, each with its message and the
+// hunks it takes, followed by the hunks no commit took.
+func renderSplitPlan(groups []ai.SplitGroup, unassigned []git.Hunk) string {
+	var b strings.Builder
+	b.WriteString("\n📊 Proposed commits:\n")
+	b.WriteString("=" + strings.Repeat("=", 40) + "\n")
+	for i, group := range groups {
+		subject, body, _ := strings.Cut(group.Message, "\n")
+		fmt.Fprintf(&b, "\n%d. %s\n", i+1, subject)
+		if body = strings.TrimSpace(body); body != "" {
+			for _, line := range strings.Split(body, "\n") {
+				fmt.Fprintf(&b, "   %s\n", line)
+			}
+		}
+		for _, hunk := range group.Hunks {
+			fmt.Fprintf(&b, "   • %s %s\n", hunk.FilePath, hunk.Header)
+		}
+	}
+	if len(unassigned) > 0 {
+		fmt.Fprintf(&b, "\n❓ Left out (stay staged): %d hunk(s)\n", len(unassigned))
+		for _, hunk := range unassigned {
+			fmt.Fprintf(&b, "   • %s %s\n", hunk.FilePath, hunk.Header)
+		}
+	}
+	return b.String()
+}
+
+// confirmSplitCommit asks whether to create commit n of total: "y" creates
+// it with the returned message, "e" edits the message first, "s" skips it
+// and "q", or no answer, stops. The answer returned is "y", "s" or "q".
+func confirmSplitCommit(cmd *cli.Command, cfg *config.Config, n, total int, message string) (string, string) {
+	for {
+		fmt.Printf("\nCreate commit %d/%d? [y]es/[e]dit/[s]kip/[q]uit: ", n, total)
+		var response string
+		fmt.Scanln(&response)
+		switch strings.ToLower(response) {
+		case "y", "yes":
+			return "y", message
+		case "e", "edit":
+			edited, err := ui.EditInEditorWithOptions(message, editorOptions(cmd, cfg, ""))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to edit message: %v\n", err)
+				continue
+			}
+			return "y", edited
+		case "s", "skip":
+			return "s", ""
+		case "q", "quit", "":
+			return "q", ""
+		}
+	}
+}
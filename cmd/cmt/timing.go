@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Phases of runCommit timed by --timing, in the order they run.
+const (
+	phaseGitDiff    = "git diff"
+	phasePreprocess = "preprocess"
+	phaseSecretScan = "secret scan"
+	phaseGeneration = "generation"
+	phaseCommit     = "commit"
+)
+
+var commitPhases = []string{phaseGitDiff, phasePreprocess, phaseSecretScan, phaseGeneration, phaseCommit}
+
+// phaseTimer adds up the wall-clock time spent in each phase of a run, to
+// tell whether a slow run is waiting on git, preprocessing or the model.
+type phaseTimer struct {
+	enabled   bool
+	started   time.Time
+	durations map[string]time.Duration
+	ran       map[string]bool
+}
+
+// newPhaseTimer returns a timer that records and reports only if enabled.
+func newPhaseTimer(enabled bool) *phaseTimer {
+	return &phaseTimer{
+		enabled:   enabled,
+		started:   time.Now(),
+		durations: make(map[string]time.Duration),
+		ran:       make(map[string]bool),
+	}
+}
+
+// start begins timing phase and returns the function that ends it. The
+// times of a phase timed more than once add up.
+func (t *phaseTimer) start(phase string) func() {
+	began := time.Now()
+	return func() {
+		t.durations[phase] += time.Since(began)
+		t.ran[phase] = true
+	}
+}
+
+// report writes the time of every phase and the total to w. Phases that
+// didn't run, like a skipped secret scan, are listed as such. A disabled
+// timer writes nothing.
+func (t *phaseTimer) report(w io.Writer) {
+	if !t.enabled {
+		return
+	}
+	fmt.Fprintln(w, "⏱  Timing:")
+	for _, phase := range commitPhases {
+		if !t.ran[phase] {
+			fmt.Fprintf(w, "   %-12s  not run\n", phase)
+			continue
+		}
+		fmt.Fprintf(w, "   %-12s  %s\n", phase, t.durations[phase].Round(time.Millisecond))
+	}
+	fmt.Fprintf(w, "   %-12s  %s\n", "total", time.Since(t.started).Round(time.Millisecond))
+}
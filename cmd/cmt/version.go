@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// latestReleaseURL is the GitHub API endpoint for the newest cmt release.
+var latestReleaseURL = "https://api.github.com/repos/gussy/cmt/releases/latest"
+
+// updateCheckTimeout bounds the update check, so an unreachable GitHub
+// doesn't hold up cmt version.
+const updateCheckTimeout = 3 * time.Second
+
+// runVersion prints the version and, with check, whether a newer release
+// is out. The check is best effort: when GitHub can't be reached it prints
+// nothing more.
+func runVersion(ctx context.Context, check bool) error {
+	fmt.Printf("cmt version %s (built %s)\n", Version, BuildTime)
+	if !check {
+		return nil
+	}
+
+	latest, err := latestRelease(ctx, latestReleaseURL)
+	if err != nil {
+		return nil
+	}
+	if message := updateMessage(Version, latest); message != "" {
+		fmt.Println(message)
+	}
+	return nil
+}
+
+// latestRelease returns the tag of the latest release listed at url.
+func latestRelease(ctx context.Context, url string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, updateCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("release check returned %s", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", fmt.Errorf("failed to read release: %w", err)
+	}
+	if release.TagName == "" {
+		return "", fmt.Errorf("release has no tag")
+	}
+	return release.TagName, nil
+}
+
+// updateMessage describes how the running version compares to the latest
+// release. It returns "" when either isn't a version, as with dev builds.
+func updateMessage(current, latest string) string {
+	newer, ok := compareVersions(latest, current)
+	if !ok {
+		return ""
+	}
+	if newer > 0 {
+		return fmt.Sprintf("A newer version is available: %s (you have %s)\nhttps://github.com/gussy/cmt/releases/latest",
+			strings.TrimPrefix(latest, "v"), strings.TrimPrefix(current, "v"))
+	}
+	return "cmt is up to date."
+}
+
+// compareVersions compares two "v1.2.3" style versions, returning -1, 0 or
+// 1 as a is older than, the same as or newer than b. A pre-release
+// ("1.2.3-rc1") is older than its release. ok is false if either isn't a
+// version.
+func compareVersions(a, b string) (int, bool) {
+	pa, ok := parseVersion(a)
+	if !ok {
+		return 0, false
+	}
+	pb, ok := parseVersion(b)
+	if !ok {
+		return 0, false
+	}
+	for i := range 3 {
+		if pa.parts[i] != pb.parts[i] {
+			if pa.parts[i] < pb.parts[i] {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	switch {
+	case pa.pre == pb.pre:
+		return 0, true
+	case pa.pre == "":
+		return 1, true
+	case pb.pre == "":
+		return -1, true
+	case pa.pre < pb.pre:
+		return -1, true
+	}
+	return 1, true
+}
+
+// version is a parsed "major.minor.patch[-pre]" version.
+type version struct {
+	parts [3]int
+	pre   string
+}
+
+// parseVersion parses a version with an optional "v" prefix and build
+// metadata, which is ignored. Missing minor and patch numbers are zero.
+func parseVersion(s string) (version, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, pre, _ := strings.Cut(s, "-")
+
+	var v version
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return version{}, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return version{}, false
+		}
+		v.parts[i] = n
+	}
+	v.pre = pre
+	return v, true
+}
@@ -3,19 +3,19 @@ package ai
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"os/exec"
 	"strings"
 	"time"
 
-	"github.com/gussy/cmt/internal/git"
+	"github.com/gussy/cmt/internal/prompt"
 )
 
 // ClaudeCLI implements the Provider interface using the Claude Code CLI.
 type ClaudeCLI struct {
 	config     *ProviderConfig
 	claudePath string
+	limiter    *Limiter
 }
 
 // NewClaudeCLI creates a new Claude CLI provider.
@@ -36,6 +36,7 @@ func NewClaudeCLI(config *ProviderConfig) (*ClaudeCLI, error) {
 	return &ClaudeCLI{
 		config:     config,
 		claudePath: claudePath,
+		limiter:    NewLimiter(config.RequestsPerMinute),
 	}, nil
 }
 
@@ -68,7 +69,7 @@ func (c *ClaudeCLI) IsAvailable(ctx context.Context) (bool, error) {
 
 // GenerateCommitMessage generates a commit message using Claude CLI.
 func (c *ClaudeCLI) GenerateCommitMessage(ctx context.Context, req *CommitRequest) (*CommitResponse, error) {
-	if req.Diff == "" {
+	if req.Diff == "" && len(req.StagedFiles) == 0 {
 		return nil, NewProviderError(c.Name(), "no diff provided", nil)
 	}
 
@@ -84,15 +85,15 @@ func (c *ClaudeCLI) GenerateCommitMessage(ctx context.Context, req *CommitReques
 	// Parse and clean the response
 	message := c.cleanResponse(response)
 
-	// Split into title and body for multi-line messages
-	title, body := c.splitMessage(message)
+	// Fill templates only accept responses that kept the template intact
+	if req.Template != "" {
+		message, err = ApplyFill(req.Template, message)
+		if err != nil {
+			return nil, NewProviderError(c.Name(), "invalid template fill", err)
+		}
+	}
 
-	return &CommitResponse{
-		Message: message,
-		Title:   title,
-		Body:    body,
-		Model:   c.getModelName(req.Model),
-	}, nil
+	return c.newResponse(message, req.Model), nil
 }
 
 // RegenerateWithFeedback regenerates a commit message with user feedback.
@@ -109,15 +110,15 @@ func (c *ClaudeCLI) RegenerateWithFeedback(ctx context.Context, req *CommitReque
 	// Parse and clean the response
 	message := c.cleanResponse(response)
 
-	// Split into title and body for multi-line messages
-	title, body := c.splitMessage(message)
+	// Fill templates only accept responses that kept the template intact
+	if req.Template != "" {
+		message, err = ApplyFill(req.Template, message)
+		if err != nil {
+			return nil, NewProviderError(c.Name(), "invalid template fill", err)
+		}
+	}
 
-	return &CommitResponse{
-		Message: message,
-		Title:   title,
-		Body:    body,
-		Model:   c.getModelName(req.Model),
-	}, nil
+	return c.newResponse(message, req.Model), nil
 }
 
 // AnalyzeHunkAssignment analyzes which hunks should be absorbed into which commits.
@@ -135,22 +136,66 @@ func (c *ClaudeCLI) AnalyzeHunkAssignment(ctx context.Context, req *AbsorbReques
 	}
 
 	// Build the absorb prompt.
-	prompt := c.buildAbsorbPrompt(req)
+	absorbPrompt := c.AbsorbPrompt(req)
 
 	// Execute claude command.
-	response, err := c.executeClaudeCommand(ctx, prompt, req.Model)
+	response, err := c.executeClaudeCommand(ctx, absorbPrompt, req.Model)
 	if err != nil {
 		return nil, err
 	}
 
 	// Parse the JSON response.
-	absorbResp, err := c.parseAbsorbResponse(response, req)
+	assignments, unmatched, err := prompt.ParseAbsorbResponse(response, req.promptRequest())
 	if err != nil {
 		return nil, NewProviderError(c.Name(), fmt.Sprintf("failed to parse absorb response: %v", err), err)
 	}
 
-	absorbResp.Model = c.getModelName(req.Model)
-	return absorbResp, nil
+	return &AbsorbResponse{
+		Assignments:    assignments,
+		UnmatchedHunks: unmatched,
+		Model:          c.getModelName(req.Model),
+	}, nil
+}
+
+// ProposeSplit clusters staged hunks into a series of commits.
+func (c *ClaudeCLI) ProposeSplit(ctx context.Context, req *SplitRequest) (*SplitResponse, error) {
+	if len(req.Hunks) == 0 {
+		return nil, NewProviderError(c.Name(), "no hunks provided", nil)
+	}
+
+	response, err := c.executeClaudeCommand(ctx, c.SplitPrompt(req), req.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, unassigned, err := prompt.ParseSplitResponse(response, req.Hunks)
+	if err != nil {
+		return nil, NewProviderError(c.Name(), fmt.Sprintf("failed to parse split response: %v", err), err)
+	}
+
+	return &SplitResponse{
+		Groups:          groups,
+		UnassignedHunks: unassigned,
+		Model:           c.getModelName(req.Model),
+	}, nil
+}
+
+// PolishChangelog rewords changelog entries for release notes.
+func (c *ClaudeCLI) PolishChangelog(ctx context.Context, entries []prompt.ChangelogEntry, model string) ([]string, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	response, err := c.executeClaudeCommand(ctx, prompt.BuildChangelogPrompt(entries), model)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions, err := prompt.ParseChangelogResponse(response, len(entries))
+	if err != nil {
+		return nil, NewProviderError(c.Name(), fmt.Sprintf("failed to parse changelog response: %v", err), err)
+	}
+	return descriptions, nil
 }
 
 // GetDefaultModel returns the default model for Claude CLI.
@@ -170,8 +215,22 @@ func (c *ClaudeCLI) GetAvailableModels() []string {
 	}
 }
 
-// executeClaudeCommand executes the claude CLI command with the given prompt.
+// Close implements Provider. Each call runs its own claude process, so there
+// is nothing to release.
+func (c *ClaudeCLI) Close() error {
+	return nil
+}
+
+// executeClaudeCommand executes the claude CLI command with the given prompt,
+// pacing calls through the provider's rate limiter.
 func (c *ClaudeCLI) executeClaudeCommand(ctx context.Context, prompt string, model string) (string, error) {
+	return callWithRateLimit(ctx, c.limiter, func() (string, error) {
+		return c.runClaudeCommand(ctx, prompt, model)
+	})
+}
+
+// runClaudeCommand runs the claude CLI once with the given prompt.
+func (c *ClaudeCLI) runClaudeCommand(ctx context.Context, prompt string, model string) (string, error) {
 	if model == "" {
 		model = c.GetDefaultModel()
 	}
@@ -215,7 +274,8 @@ func (c *ClaudeCLI) executeClaudeCommand(ctx context.Context, prompt string, mod
 		debugMsg := fmt.Sprintf("Command: %s %s\nPrompt length: %d chars",
 			c.claudePath, strings.Join(args, " "), len(prompt))
 
-		return "", NewProviderError(c.Name(), fmt.Sprintf("%s\nDebug: %s", errMsg, debugMsg), err)
+		return "", detectRateLimit(stderrStr+stdoutStr,
+			NewProviderError(c.Name(), fmt.Sprintf("%s\nDebug: %s", errMsg, debugMsg), err))
 	}
 
 	output := stdout.String()
@@ -226,10 +286,39 @@ func (c *ClaudeCLI) executeClaudeCommand(ctx context.Context, prompt string, mod
 	return output, nil
 }
 
+// CommitPrompt returns the exact prompt GenerateCommitMessage sends for req.
+func (c *ClaudeCLI) CommitPrompt(req *CommitRequest) string {
+	return c.buildPrompt(req)
+}
+
+// AbsorbPrompt returns the exact prompt AnalyzeHunkAssignment sends for req.
+func (c *ClaudeCLI) AbsorbPrompt(req *AbsorbRequest) string {
+	return prompt.BuildAbsorbPrompt(req.promptRequest())
+}
+
+// SplitPrompt returns the exact prompt ProposeSplit sends for req.
+func (c *ClaudeCLI) SplitPrompt(req *SplitRequest) string {
+	return prompt.BuildSplitPrompt(req.promptRequest())
+}
+
 // buildPrompt builds the prompt for commit message generation.
 func (c *ClaudeCLI) buildPrompt(req *CommitRequest) string {
+	// Looked up before the builder below shadows the package name
+	grounding := prompt.GroundingInstruction(req.Grounding)
+
 	var prompt strings.Builder
 
+	// A custom prompt brings its own instructions; only a fill template
+	// still needs its rules so the response can be checked against it
+	if req.CustomPrompt != "" {
+		prompt.WriteString(strings.TrimRight(req.CustomPrompt, "\n") + "\n\n")
+		if req.Template != "" {
+			prompt.WriteString(fillInstructions(req.Template))
+			prompt.WriteString("\nReturn only the filled template, without any additional explanation or formatting.")
+		}
+		return prompt.String()
+	}
+
 	// Base instruction
 	switch req.Format {
 	case FormatOneLine:
@@ -239,11 +328,22 @@ func (c *ClaudeCLI) buildPrompt(req *CommitRequest) string {
 		prompt.WriteString("Generate a detailed git commit message for the following changes.\n")
 		prompt.WriteString("Include a short title line (max 50 chars), followed by a blank line, ")
 		prompt.WriteString("then a detailed explanation of what changed and why.\n")
+	case FormatStructured:
+		prompt.WriteString("Generate a structured git commit message for the following changes.\n")
+		prompt.WriteString("Include a short title line (max 50 chars), followed by a blank line, ")
+		prompt.WriteString("then these sections, each header on its own line followed by its text:\n")
+		prompt.WriteString("What changed:\nWhy:\nImpact:\n")
+		prompt.WriteString("Separate sections with a blank line.\n")
 	default:
 		prompt.WriteString("Generate a clear and concise git commit message for the following changes.\n")
 		prompt.WriteString("Follow conventional commit format if applicable.\n")
 	}
 
+	// Add situation-specific instructions
+	if req.Instructions != "" {
+		prompt.WriteString(strings.TrimSpace(req.Instructions) + "\n")
+	}
+
 	// Add scope if provided
 	if req.Scope != "" {
 		prompt.WriteString(fmt.Sprintf("Use scope '%s' in the commit message (e.g., 'feat(%s): description').\n", req.Scope, req.Scope))
@@ -254,21 +354,59 @@ func (c *ClaudeCLI) buildPrompt(req *CommitRequest) string {
 		prompt.WriteString(fmt.Sprintf("\nAdditional context: %s\n", req.Hint))
 	}
 
+	// Add recent subjects so the message matches the project's style
+	if len(req.Examples) > 0 {
+		prompt.WriteString("\nRecent commit subjects in this project (match their style, tone and conventions):\n")
+		for _, example := range req.Examples {
+			prompt.WriteString(fmt.Sprintf("- %s\n", example))
+		}
+	}
+
+	// Add instructions specific to the staged file types
+	if len(req.Guidance) > 0 {
+		prompt.WriteString("\nGuidance for the kinds of files changed:\n")
+		for _, guidance := range req.Guidance {
+			prompt.WriteString(fmt.Sprintf("- %s\n", guidance))
+		}
+	}
+
+	// Add dependency changes parsed from the manifests
+	if len(req.Dependencies) > 0 {
+		prompt.WriteString("\nDependency changes:\n")
+		for _, change := range req.Dependencies {
+			prompt.WriteString(fmt.Sprintf("- %s\n", change))
+		}
+		prompt.WriteString("If the commit only updates dependencies, use a subject like \"chore(deps): bump <name> from <old> to <new>\".\n")
+	}
+
 	// Add file list
 	if len(req.StagedFiles) > 0 {
-		prompt.WriteString("\nFiles being committed:\n")
+		prompt.WriteString("\nFiles being committed (A=added, M=modified, D=deleted, R=renamed):\n")
 		for _, file := range req.StagedFiles {
 			prompt.WriteString(fmt.Sprintf("- %s\n", file))
 		}
 	}
 
-	// Add the diff
-	prompt.WriteString("\nGit diff:\n```diff\n")
-	prompt.WriteString(req.Diff)
-	prompt.WriteString("\n```\n\n")
+	// Add the diff, or explain its absence so the file list is used instead
+	if req.Diff != "" {
+		prompt.WriteString("\nGit diff:\n```diff\n")
+		prompt.WriteString(req.Diff)
+		prompt.WriteString("\n```\n\n")
+	} else {
+		prompt.WriteString("\nNo text diff is available (only binary or filtered files changed). ")
+		prompt.WriteString("Describe the change from the file list.\n\n")
+	}
+
+	// Keep the message to what the diff shows
+	prompt.WriteString(grounding + "\n")
 
 	// Final instruction
-	prompt.WriteString("Generate only the commit message, without any additional explanation or formatting.")
+	if req.Template != "" {
+		prompt.WriteString(fillInstructions(req.Template))
+		prompt.WriteString("\nReturn only the filled template, without any additional explanation or formatting.")
+	} else {
+		prompt.WriteString("Generate only the commit message, without any additional explanation or formatting.")
+	}
 
 	return prompt.String()
 }
@@ -369,6 +507,22 @@ func isAttributionLine(lower string) bool {
 	return false
 }
 
+// newResponse builds the response for a generated message, splitting out
+// its title, body and conventional commit parts.
+func (c *ClaudeCLI) newResponse(message, model string) *CommitResponse {
+	// Split into title and body for multi-line messages
+	title, body := c.splitMessage(message)
+
+	return &CommitResponse{
+		Message: message,
+		Title:   title,
+		Body:    body,
+		Type:    prompt.ExtractConventionalType(message),
+		Scope:   prompt.ExtractScope(message),
+		Model:   c.getModelName(model),
+	}
+}
+
 // splitMessage splits a commit message into title and body.
 func (c *ClaudeCLI) splitMessage(message string) (string, string) {
 	lines := strings.Split(message, "\n")
@@ -422,245 +576,3 @@ func (c *ClaudeCLI) getModelName(model string) string {
 	}
 	return model
 }
-
-// buildAbsorbPrompt builds the prompt for hunk assignment analysis.
-func (c *ClaudeCLI) buildAbsorbPrompt(req *AbsorbRequest) string {
-	var prompt strings.Builder
-
-	prompt.WriteString("You are analyzing git diff hunks to determine which previous commits they should be absorbed into.\n")
-	prompt.WriteString("Each hunk should be matched with the most semantically related commit based on:\n")
-	prompt.WriteString("1. File paths and names\n")
-	prompt.WriteString("2. Code context and functionality\n")
-	prompt.WriteString("3. Commit message relevance\n")
-	prompt.WriteString("4. Related changes in the same area\n\n")
-
-	if req.Strategy == "best-match" {
-		prompt.WriteString(fmt.Sprintf("Confidence threshold: %.2f (assign only if confidence is above this)\n", req.ConfidenceThreshold))
-		prompt.WriteString("Strategy: Choose the single best matching commit for each hunk.\n\n")
-	} else {
-		prompt.WriteString("Strategy: Provide alternatives when multiple commits could match.\n\n")
-	}
-
-	// Add commits information.
-	prompt.WriteString("Available commits (from oldest to newest):\n")
-	prompt.WriteString("=====================================\n")
-	for i, commit := range req.Commits {
-		// Get first line of commit message.
-		lines := strings.Split(commit.Message, "\n")
-		firstLine := lines[0]
-		if len(firstLine) > 72 {
-			firstLine = firstLine[:69] + "..."
-		}
-
-		prompt.WriteString(fmt.Sprintf("\nCommit %d: %s\n", i+1, commit.SHA[:8]))
-		prompt.WriteString(fmt.Sprintf("Message: %s\n", firstLine))
-
-		// Add a summary of the commit diff.
-		if len(commit.Diff) > 0 {
-			prompt.WriteString("Changed files:\n")
-			for _, line := range strings.Split(commit.Diff, "\n") {
-				if strings.HasPrefix(line, "diff --git") {
-					parts := strings.Split(line, " ")
-					if len(parts) >= 4 {
-						file := strings.TrimPrefix(parts[3], "b/")
-						prompt.WriteString(fmt.Sprintf("  - %s\n", file))
-					}
-				}
-			}
-		}
-	}
-
-	// Add hunks to analyze.
-	prompt.WriteString("\n\nHunks to analyze:\n")
-	prompt.WriteString("================\n")
-	for i, hunk := range req.Hunks {
-		prompt.WriteString(fmt.Sprintf("\nHunk %d:\n", i+1))
-		prompt.WriteString(fmt.Sprintf("File: %s\n", hunk.FilePath))
-		if hunk.IsNew {
-			prompt.WriteString("Status: NEW FILE\n")
-		} else if hunk.IsDeleted {
-			prompt.WriteString("Status: DELETED FILE\n")
-		} else if hunk.IsRenamed {
-			prompt.WriteString(fmt.Sprintf("Status: RENAMED from %s\n", hunk.OldFilePath))
-		}
-		prompt.WriteString(fmt.Sprintf("Lines: %s\n", hunk.Header))
-		prompt.WriteString("Content:\n```diff\n")
-		prompt.WriteString(hunk.Content)
-		prompt.WriteString("```\n")
-	}
-
-	// Request structured output.
-	prompt.WriteString("\n\nProvide your analysis as a JSON object with this structure:\n")
-	prompt.WriteString("```json\n")
-	prompt.WriteString("{\n")
-	prompt.WriteString("  \"assignments\": [\n")
-	prompt.WriteString("    {\n")
-	prompt.WriteString("      \"hunk_index\": 0,  // 0-based index of the hunk\n")
-	prompt.WriteString("      \"commit_sha\": \"abc123...\",  // Full SHA of the target commit\n")
-	prompt.WriteString("      \"confidence\": 0.95,  // Confidence score 0.0 to 1.0\n")
-	prompt.WriteString("      \"reasoning\": \"This hunk modifies the same function...\",\n")
-	prompt.WriteString("      \"alternatives\": [  // Optional, only if strategy is 'interactive'\n")
-	prompt.WriteString("        {\n")
-	prompt.WriteString("          \"commit_sha\": \"def456...\",\n")
-	prompt.WriteString("          \"confidence\": 0.7,\n")
-	prompt.WriteString("          \"reasoning\": \"Could also relate to...\"\n")
-	prompt.WriteString("        }\n")
-	prompt.WriteString("      ]\n")
-	prompt.WriteString("    }\n")
-	prompt.WriteString("  ],\n")
-	prompt.WriteString("  \"unmatched_hunks\": [0, 2]  // Indices of hunks that don't match any commit\n")
-	prompt.WriteString("}\n")
-	prompt.WriteString("```\n\n")
-	prompt.WriteString("Return ONLY the JSON object, no additional explanation.")
-
-	return prompt.String()
-}
-
-// absorbJSONResponse is the structure for parsing the AI's JSON response.
-type absorbJSONResponse struct {
-	Assignments []struct {
-		HunkIndex    int     `json:"hunk_index"`
-		CommitSHA    string  `json:"commit_sha"`
-		Confidence   float64 `json:"confidence"`
-		Reasoning    string  `json:"reasoning"`
-		Alternatives []struct {
-			CommitSHA  string  `json:"commit_sha"`
-			Confidence float64 `json:"confidence"`
-			Reasoning  string  `json:"reasoning"`
-		} `json:"alternatives,omitempty"`
-	} `json:"assignments"`
-	UnmatchedHunks []int `json:"unmatched_hunks"`
-}
-
-// parseAbsorbResponse parses the JSON response from the AI.
-func (c *ClaudeCLI) parseAbsorbResponse(response string, req *AbsorbRequest) (*AbsorbResponse, error) {
-	// Clean the response to extract JSON.
-	response = strings.TrimSpace(response)
-
-	// Remove code block markers if present.
-	if strings.Contains(response, "```json") {
-		start := strings.Index(response, "{")
-		end := strings.LastIndex(response, "}")
-		if start >= 0 && end > start {
-			response = response[start : end+1]
-		}
-	}
-
-	// Parse JSON.
-	var jsonResp absorbJSONResponse
-	if err := json.Unmarshal([]byte(response), &jsonResp); err != nil {
-		// Try to extract JSON from the response.
-		lines := strings.Split(response, "\n")
-		var jsonStr strings.Builder
-		inJSON := false
-		for _, line := range lines {
-			if strings.Contains(line, "{") {
-				inJSON = true
-			}
-			if inJSON {
-				jsonStr.WriteString(line + "\n")
-			}
-			if strings.Contains(line, "}") && inJSON {
-				break
-			}
-		}
-		if jsonStr.Len() > 0 {
-			if err := json.Unmarshal([]byte(jsonStr.String()), &jsonResp); err != nil {
-				return nil, fmt.Errorf("failed to parse JSON: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("no valid JSON found in response")
-		}
-	}
-
-	// Convert to AbsorbResponse.
-	resp := &AbsorbResponse{
-		Assignments:    []HunkAssignment{},
-		UnmatchedHunks: []git.Hunk{},
-	}
-
-	// Track which hunks were assigned.
-	assignedHunks := make(map[int]bool)
-
-	// Process assignments.
-	for _, assignment := range jsonResp.Assignments {
-		if assignment.HunkIndex < 0 || assignment.HunkIndex >= len(req.Hunks) {
-			continue
-		}
-
-		hunk := req.Hunks[assignment.HunkIndex]
-		assignedHunks[assignment.HunkIndex] = true
-
-		// Find commit message for this SHA.
-		var commitMessage string
-		for _, commit := range req.Commits {
-			if strings.HasPrefix(commit.SHA, assignment.CommitSHA[:8]) {
-				lines := strings.Split(commit.Message, "\n")
-				commitMessage = lines[0]
-				assignment.CommitSHA = commit.SHA // Use full SHA.
-				break
-			}
-		}
-
-		hunkAssignment := HunkAssignment{
-			Hunk:          hunk,
-			CommitSHA:     assignment.CommitSHA,
-			CommitMessage: commitMessage,
-			Confidence:    assignment.Confidence,
-			Reasoning:     assignment.Reasoning,
-		}
-
-		// Process alternatives.
-		for _, alt := range assignment.Alternatives {
-			var altMessage string
-			for _, commit := range req.Commits {
-				if strings.HasPrefix(commit.SHA, alt.CommitSHA[:8]) {
-					lines := strings.Split(commit.Message, "\n")
-					altMessage = lines[0]
-					alt.CommitSHA = commit.SHA
-					break
-				}
-			}
-
-			hunkAssignment.Alternatives = append(hunkAssignment.Alternatives, AlternativeAssignment{
-				CommitSHA:     alt.CommitSHA,
-				CommitMessage: altMessage,
-				Confidence:    alt.Confidence,
-				Reasoning:     alt.Reasoning,
-			})
-		}
-
-		// Apply confidence threshold if using best-match strategy.
-		if req.Strategy == "best-match" && assignment.Confidence < req.ConfidenceThreshold {
-			resp.UnmatchedHunks = append(resp.UnmatchedHunks, hunk)
-		} else {
-			resp.Assignments = append(resp.Assignments, hunkAssignment)
-		}
-	}
-
-	// Process unmatched hunks.
-	for _, idx := range jsonResp.UnmatchedHunks {
-		if idx >= 0 && idx < len(req.Hunks) && !assignedHunks[idx] {
-			resp.UnmatchedHunks = append(resp.UnmatchedHunks, req.Hunks[idx])
-		}
-	}
-
-	// Check for any hunks that weren't mentioned.
-	for i, hunk := range req.Hunks {
-		if !assignedHunks[i] {
-			found := false
-			for _, idx := range jsonResp.UnmatchedHunks {
-				if idx == i {
-					found = true
-					break
-				}
-			}
-			if !found {
-				// Hunk wasn't assigned or marked as unmatched.
-				resp.UnmatchedHunks = append(resp.UnmatchedHunks, hunk)
-			}
-		}
-	}
-
-	return resp, nil
-}
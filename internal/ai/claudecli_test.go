@@ -1,6 +1,9 @@
 package ai
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestStripAttributionTrailers(t *testing.T) {
 	tests := []struct {
@@ -94,3 +97,59 @@ func TestIsAttributionLine(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildPromptStructured(t *testing.T) {
+	c := &ClaudeCLI{}
+	prompt := c.buildPrompt(&CommitRequest{Diff: "+x", Format: FormatStructured})
+
+	for _, section := range []string{"What changed:", "Why:", "Impact:"} {
+		if !strings.Contains(prompt, section) {
+			t.Errorf("structured prompt missing section %q", section)
+		}
+	}
+}
+
+func TestBuildPromptGrounding(t *testing.T) {
+	c := &ClaudeCLI{}
+	prompt := c.buildPrompt(&CommitRequest{Diff: "+x", Grounding: "strict"})
+
+	if !strings.Contains(prompt, "do not speculate about performance or intent") {
+		t.Errorf("strict prompt missing the grounding instruction:\n%s", prompt)
+	}
+	if prompt := c.buildPrompt(&CommitRequest{Diff: "+x"}); strings.Contains(prompt, "do not speculate") {
+		t.Error("default prompt should use the normal grounding")
+	}
+}
+
+func TestBuildPromptWithoutDiff(t *testing.T) {
+	c := &ClaudeCLI{}
+	prompt := c.buildPrompt(&CommitRequest{StagedFiles: []string{"A assets/logo.png"}})
+
+	if strings.Contains(prompt, "```diff") {
+		t.Error("prompt should not include an empty diff block")
+	}
+	if !strings.Contains(prompt, "A assets/logo.png") || !strings.Contains(prompt, "from the file list") {
+		t.Error("prompt should describe the change from the file list")
+	}
+}
+
+func TestBuildPromptWithGuidance(t *testing.T) {
+	c := &ClaudeCLI{}
+	prompt := c.buildPrompt(&CommitRequest{
+		Diff:     "diff --git a/main.tf b/main.tf",
+		Guidance: []string{"Mention Terraform resources added or destroyed."},
+	})
+
+	if !strings.Contains(prompt, "- Mention Terraform resources added or destroyed.\n") {
+		t.Errorf("prompt should include file-type guidance, got:\n%s", prompt)
+	}
+}
+
+func TestBuildPromptWithCustomPrompt(t *testing.T) {
+	c := &ClaudeCLI{}
+	prompt := c.buildPrompt(&CommitRequest{Diff: "+x", CustomPrompt: "Describe +x in one line."})
+
+	if prompt != "Describe +x in one line.\n\n" {
+		t.Errorf("custom prompt should replace the built-in instructions, got:\n%s", prompt)
+	}
+}
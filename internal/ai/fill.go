@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// placeholderPattern matches a fill placeholder such as
+// "{{TODO: describe the change}}".
+var placeholderPattern = regexp.MustCompile(`\{\{\s*TODO:[^}]*\}\}`)
+
+// Placeholders returns the placeholders in a fill template, in order.
+func Placeholders(template string) []string {
+	return placeholderPattern.FindAllString(template, -1)
+}
+
+// ApplyFill validates a model response against a fill template and returns
+// the template with each placeholder replaced by the text the model wrote
+// in its place. Everything outside the placeholders must be reproduced
+// verbatim; otherwise an error is returned and the response is discarded.
+func ApplyFill(template, response string) (string, error) {
+	template = strings.TrimSpace(template)
+	response = strings.TrimSpace(response)
+
+	literals := placeholderPattern.Split(template, -1)
+	if len(literals) < 2 {
+		return "", fmt.Errorf("template has no {{TODO: ...}} placeholders")
+	}
+
+	var pattern strings.Builder
+	pattern.WriteString(`(?s)^`)
+	for i, literal := range literals {
+		if i > 0 {
+			pattern.WriteString(`(.+?)`)
+		}
+		pattern.WriteString(regexp.QuoteMeta(literal))
+	}
+	pattern.WriteString(`$`)
+
+	match := regexp.MustCompile(pattern.String()).FindStringSubmatch(response)
+	if match == nil {
+		return "", fmt.Errorf("response changed text outside the template placeholders")
+	}
+
+	var filled strings.Builder
+	for i, literal := range literals {
+		if i > 0 {
+			value := strings.TrimSpace(match[i])
+			if value == "" || placeholderPattern.MatchString(value) {
+				return "", fmt.Errorf("placeholder %d was not filled", i)
+			}
+			filled.WriteString(value)
+		}
+		filled.WriteString(literal)
+	}
+	return filled.String(), nil
+}
+
+// fillInstructions returns the prompt section asking the model to fill the
+// placeholders of a template without touching the surrounding text.
+func fillInstructions(template string) string {
+	var b strings.Builder
+	b.WriteString("Fill in the following commit message template.\n")
+	b.WriteString("Replace each {{TODO: ...}} placeholder with text that follows its instruction.\n")
+	b.WriteString("Keep every other character of the template exactly as written, including line breaks.\n")
+	b.WriteString("\nTemplate:\n```\n")
+	b.WriteString(template)
+	b.WriteString("\n```\n")
+	return b.String()
+}
@@ -0,0 +1,80 @@
+package ai
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyFill(t *testing.T) {
+	template := "feat(api): {{TODO: one-line summary}}\n\nTicket: ABC-123\n\n{{TODO: explain why}}\n"
+
+	tests := []struct {
+		name     string
+		response string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "placeholders filled",
+			response: "feat(api): add pagination\n\nTicket: ABC-123\n\nLarge listings timed out.",
+			want:     "feat(api): add pagination\n\nTicket: ABC-123\n\nLarge listings timed out.",
+		},
+		{
+			name:     "surrounding whitespace is ignored",
+			response: "\nfeat(api):  add pagination \n\nTicket: ABC-123\n\nLarge listings timed out.\n\n",
+			want:     "feat(api): add pagination\n\nTicket: ABC-123\n\nLarge listings timed out.",
+		},
+		{
+			name:     "fixed text changed",
+			response: "feat(api): add pagination\n\nTicket: ABC-999\n\nLarge listings timed out.",
+			wantErr:  true,
+		},
+		{
+			name:     "fixed text dropped",
+			response: "feat(api): add pagination\n\nLarge listings timed out.",
+			wantErr:  true,
+		},
+		{
+			name:     "placeholder left in place",
+			response: "feat(api): add pagination\n\nTicket: ABC-123\n\n{{TODO: explain why}}",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyFill(template, tt.response)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ApplyFill() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyFill() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ApplyFill() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyFillRequiresPlaceholders(t *testing.T) {
+	if _, err := ApplyFill("fix: typo", "fix: typo"); err == nil {
+		t.Error("ApplyFill() on a template without placeholders should fail")
+	}
+}
+
+func TestBuildPromptWithTemplate(t *testing.T) {
+	c := &ClaudeCLI{}
+	template := "docs: {{TODO: summary}}"
+	prompt := c.buildPrompt(&CommitRequest{Diff: "+x", Template: template})
+
+	if !strings.Contains(prompt, template) {
+		t.Error("prompt should include the fill template")
+	}
+	if !strings.Contains(prompt, "Return only the filled template") {
+		t.Error("prompt should ask for the filled template only")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/gussy/cmt/internal/git"
+	"github.com/gussy/cmt/internal/prompt"
 )
 
 // MessageFormat represents the format of the commit message.
@@ -17,20 +18,73 @@ const (
 	FormatOneLine
 	// FormatVerbose generates a detailed commit message with explanation.
 	FormatVerbose
+	// FormatStructured generates a subject plus "What changed", "Why" and
+	// "Impact" body sections.
+	FormatStructured
 )
 
+// MessageFormats lists every message format, in the order they are offered.
+var MessageFormats = []MessageFormat{FormatStandard, FormatOneLine, FormatVerbose, FormatStructured}
+
+// String returns the format's name as used on the command line.
+func (f MessageFormat) String() string {
+	switch f {
+	case FormatOneLine:
+		return "oneline"
+	case FormatVerbose:
+		return "verbose"
+	case FormatStructured:
+		return "structured"
+	default:
+		return "standard"
+	}
+}
+
+// ParseMessageFormat returns the format with the given name.
+func ParseMessageFormat(name string) (MessageFormat, error) {
+	for _, f := range MessageFormats {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return FormatStandard, fmt.Errorf("unknown message format %q", name)
+}
+
 // CommitRequest contains the information needed to generate a commit message.
 type CommitRequest struct {
 	// Diff is the git diff to describe.
 	Diff string
-	// StagedFiles is the list of files being committed.
+	// StagedFiles is the list of files being committed, each prefixed
+	// with its status (e.g. "A internal/foo.go").
 	StagedFiles []string
 	// Format specifies the desired message format.
 	Format MessageFormat
+	// Instructions are extra instructions from cmt itself rather than the
+	// user, such as absorb's note that the changes are leftover hunks.
+	Instructions string
 	// Hint is optional additional context from the user.
 	Hint string
 	// Scope is the optional scope for conventional commits.
 	Scope string
+	// Examples are recent commit subjects from the repository whose style
+	// the message should match.
+	Examples []string
+	// Guidance holds extra instructions for the kinds of files staged,
+	// such as what to mention for Terraform or SQL changes.
+	Guidance []string
+	// Dependencies summarizes dependency version changes found in the
+	// manifests, e.g. "bump react from ^18.2.0 to ^18.3.1". Lockfiles are
+	// filtered from the diff, so this is the model's view of them.
+	Dependencies []string
+	// Grounding is how firmly the model is kept to the changes in the
+	// diff: "strict" or "normal" (the default when empty).
+	Grounding string
+	// CustomPrompt, when set, replaces the built-in instructions. It is
+	// rendered from the custom_prompt_path template and includes the diff.
+	CustomPrompt string
+	// Template is an optional fill template whose {{TODO: ...}}
+	// placeholders are the only parts the model may write.
+	Template string
 	// Model is the AI model to use (provider-specific).
 	Model string
 	// Temperature controls randomness (0.0 to 1.0).
@@ -47,6 +101,11 @@ type CommitResponse struct {
 	Title string
 	// Body is the commit body for multi-line messages.
 	Body string
+	// Type is the conventional commit type of the title, e.g. "feat", or
+	// empty if the message isn't a conventional commit.
+	Type string
+	// Scope is the conventional commit scope of the title, if any.
+	Scope string
 	// TokensUsed is the number of tokens consumed.
 	TokensUsed int
 	// Model is the actual model used.
@@ -70,11 +129,23 @@ type Provider interface {
 	// AnalyzeHunkAssignment analyzes which hunks should be absorbed into which commits.
 	AnalyzeHunkAssignment(ctx context.Context, req *AbsorbRequest) (*AbsorbResponse, error)
 
+	// ProposeSplit clusters staged hunks into a series of commits, each
+	// with a suggested message.
+	ProposeSplit(ctx context.Context, req *SplitRequest) (*SplitResponse, error)
+
+	// PolishChangelog rewords changelog entries for release notes, returning
+	// one description per entry in the same order.
+	PolishChangelog(ctx context.Context, entries []prompt.ChangelogEntry, model string) ([]string, error)
+
 	// GetDefaultModel returns the default model for this provider.
 	GetDefaultModel() string
 
 	// GetAvailableModels returns a list of available models.
 	GetAvailableModels() []string
+
+	// Close releases any resources held by the provider, such as HTTP
+	// clients. The provider must not be used after Close.
+	Close() error
 }
 
 // ProviderConfig contains configuration for a provider.
@@ -87,6 +158,8 @@ type ProviderConfig struct {
 	DefaultModel string
 	// Timeout is the request timeout in seconds.
 	Timeout int
+	// RequestsPerMinute caps model calls per minute (0 means unlimited).
+	RequestsPerMinute int
 }
 
 // ProviderError represents an error from a provider.
@@ -134,6 +207,23 @@ type AbsorbRequest struct {
 	Temperature float64
 	// MaxTokens limits the response length.
 	MaxTokens int
+	// GroupByFile has whole files assigned instead of single hunks.
+	GroupByFile bool
+	// FileHistory lists the commits that touched each staged file.
+	FileHistory bool
+}
+
+// promptRequest returns the provider-independent part of the request used
+// to build the shared absorb prompt.
+func (r *AbsorbRequest) promptRequest() prompt.AbsorbRequest {
+	return prompt.AbsorbRequest{
+		Hunks:               r.Hunks,
+		Commits:             r.Commits,
+		Strategy:            r.Strategy,
+		ConfidenceThreshold: r.ConfidenceThreshold,
+		GroupByFile:         r.GroupByFile,
+		FileHistory:         r.FileHistory,
+	}
 }
 
 // AbsorbResponse contains the hunk assignments from AI analysis.
@@ -149,25 +239,44 @@ type AbsorbResponse struct {
 }
 
 // HunkAssignment represents the AI's assignment of a hunk to a commit.
-type HunkAssignment struct {
-	// Hunk is the hunk being assigned.
-	Hunk git.Hunk
-	// CommitSHA is the target commit SHA.
-	CommitSHA string
-	// CommitMessage is the first line of the commit message.
-	CommitMessage string
-	// Confidence is the AI's confidence in this assignment (0.0 to 1.0).
-	Confidence float64
-	// Reasoning is the AI's explanation for this assignment.
-	Reasoning string
-	// Alternatives are other possible assignments with lower confidence.
-	Alternatives []AlternativeAssignment
-}
+type HunkAssignment = prompt.HunkAssignment
 
 // AlternativeAssignment represents an alternative commit for a hunk.
-type AlternativeAssignment struct {
-	CommitSHA     string
-	CommitMessage string
-	Confidence    float64
-	Reasoning     string
+type AlternativeAssignment = prompt.AlternativeAssignment
+
+// SplitRequest contains the information needed to split staged changes
+// into several commits.
+type SplitRequest struct {
+	// Hunks are the staged hunks to cluster.
+	Hunks []git.Hunk
+	// Commits is the number of commits wanted; 0 lets the model decide.
+	Commits int
+	// Model is the AI model to use.
+	Model string
+	// Temperature controls randomness.
+	Temperature float64
+	// MaxTokens limits the response length.
+	MaxTokens int
 }
+
+// promptRequest returns the provider-independent part of the request used
+// to build the shared split prompt.
+func (r *SplitRequest) promptRequest() prompt.SplitRequest {
+	return prompt.SplitRequest{
+		Hunks:   r.Hunks,
+		Commits: r.Commits,
+	}
+}
+
+// SplitResponse contains the commits proposed for a split.
+type SplitResponse struct {
+	// Groups are the proposed commits, in the order to create them.
+	Groups []SplitGroup
+	// UnassignedHunks are hunks the model left out of every commit.
+	UnassignedHunks []git.Hunk
+	// Model is the actual model used.
+	Model string
+}
+
+// SplitGroup is one proposed commit of a split.
+type SplitGroup = prompt.SplitGroup
@@ -0,0 +1,19 @@
+package ai
+
+import "testing"
+
+func TestParseMessageFormat(t *testing.T) {
+	for _, format := range MessageFormats {
+		got, err := ParseMessageFormat(format.String())
+		if err != nil {
+			t.Fatalf("ParseMessageFormat(%q) error: %v", format, err)
+		}
+		if got != format {
+			t.Errorf("ParseMessageFormat(%q) = %v", format, got)
+		}
+	}
+
+	if _, err := ParseMessageFormat("haiku"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,161 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxRateLimitRetries is how many times a rate-limited call is retried
+// before the error is returned.
+const maxRateLimitRetries = 3
+
+// defaultRateLimitBackoff is the first wait after a rate-limited call that
+// did not say how long to wait. It doubles on each further retry.
+const defaultRateLimitBackoff = 5 * time.Second
+
+// Limiter is a token-bucket rate limiter shared by all model calls made
+// through one provider. A nil Limiter never waits.
+type Limiter struct {
+	mu       sync.Mutex
+	interval time.Duration // time to earn one token
+	burst    float64       // bucket capacity
+	tokens   float64
+	last     time.Time
+
+	// now and sleep are swapped out in tests.
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewLimiter returns a limiter allowing requestsPerMinute calls per minute,
+// with bursts of up to the same number. It returns nil (no limit) when
+// requestsPerMinute is zero or negative.
+func NewLimiter(requestsPerMinute int) *Limiter {
+	if requestsPerMinute <= 0 {
+		return nil
+	}
+	return &Limiter{
+		interval: time.Minute / time.Duration(requestsPerMinute),
+		burst:    float64(requestsPerMinute),
+		tokens:   float64(requestsPerMinute),
+		now:      time.Now,
+		sleep:    sleepContext,
+	}
+}
+
+// Wait blocks until a call is allowed or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += float64(now.Sub(l.last)) / float64(l.interval)
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	// Reserve a token now, even if it has yet to be earned, so concurrent
+	// callers queue up behind each other instead of all waking at once.
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens * float64(l.interval))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	return l.sleep(ctx, wait)
+}
+
+// RateLimitError reports that the provider rejected a call for exceeding
+// its rate limit.
+type RateLimitError struct {
+	// RetryAfter is how long the provider asked us to wait, or zero if it
+	// didn't say.
+	RetryAfter time.Duration
+	Err        error
+}
+
+// Error implements the error interface.
+func (e *RateLimitError) Error() string {
+	return "rate limited: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	rateLimitPattern  = regexp.MustCompile(`(?i)\b429\b|rate[ _-]?limit|too many requests`)
+	retryAfterPattern = regexp.MustCompile(`(?i)retry[ _-]after:?\s*(\d+)`)
+)
+
+// detectRateLimit wraps err in a RateLimitError if output looks like a
+// rate-limit response, picking up any Retry-After value in seconds.
+func detectRateLimit(output string, err error) error {
+	if !rateLimitPattern.MatchString(output) {
+		return err
+	}
+	rlErr := &RateLimitError{Err: err}
+	if m := retryAfterPattern.FindStringSubmatch(output); m != nil {
+		if seconds, convErr := strconv.Atoi(m[1]); convErr == nil {
+			rlErr.RetryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+	return rlErr
+}
+
+// callWithRateLimit runs call once the limiter allows it, retrying with
+// backoff while the provider reports a rate limit.
+func callWithRateLimit(ctx context.Context, limiter *Limiter, call func() (string, error)) (string, error) {
+	sleep := sleepContext
+	if limiter != nil {
+		sleep = limiter.sleep
+	}
+
+	backoff := defaultRateLimitBackoff
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(ctx); err != nil {
+			return "", err
+		}
+
+		output, err := call()
+		var rlErr *RateLimitError
+		if err == nil || !errors.As(err, &rlErr) || attempt >= maxRateLimitRetries {
+			return output, err
+		}
+
+		wait := rlErr.RetryAfter
+		if wait <= 0 {
+			wait = backoff
+			backoff *= 2
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return "", err
+		}
+	}
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
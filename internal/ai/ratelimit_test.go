@@ -0,0 +1,179 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock drives a Limiter without real sleeping: sleeps advance the clock
+// and are recorded.
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) install(l *Limiter) *Limiter {
+	l.now = func() time.Time { return c.now }
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		c.sleeps = append(c.sleeps, d)
+		c.now = c.now.Add(d)
+		return nil
+	}
+	return l
+}
+
+func TestLimiterAllowsBurstThenPaces(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := clock.install(NewLimiter(60)) // one call per second, burst of 60
+
+	for i := 0; i < 60; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	if len(clock.sleeps) != 0 {
+		t.Fatalf("burst should not wait, slept %v", clock.sleeps)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	want := []time.Duration{time.Second, time.Second, time.Second}
+	if len(clock.sleeps) != len(want) {
+		t.Fatalf("sleeps = %v, want %v", clock.sleeps, want)
+	}
+	for i, d := range want {
+		if clock.sleeps[i] != d {
+			t.Errorf("sleep %d = %v, want %v", i, clock.sleeps[i], d)
+		}
+	}
+}
+
+func TestLimiterRefillsOverTime(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := clock.install(NewLimiter(2)) // one call per 30s, burst of 2
+
+	l.Wait(context.Background())
+	l.Wait(context.Background())
+	clock.now = clock.now.Add(30 * time.Second)
+
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if len(clock.sleeps) != 0 {
+		t.Errorf("refilled token should not wait, slept %v", clock.sleeps)
+	}
+}
+
+func TestLimiterWaitRealTime(t *testing.T) {
+	l := NewLimiter(1200) // one call per 50ms
+	l.tokens = 0
+
+	start := time.Now()
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("Wait() returned after %v, want about 50ms", elapsed)
+	}
+}
+
+func TestLimiterWaitCancelled(t *testing.T) {
+	l := NewLimiter(1)
+	l.tokens = 0
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestNilLimiterNeverWaits(t *testing.T) {
+	if l := NewLimiter(0); l != nil {
+		t.Fatalf("NewLimiter(0) = %v, want nil", l)
+	}
+	var l *Limiter
+	if err := l.Wait(context.Background()); err != nil {
+		t.Errorf("nil Limiter Wait() error = %v", err)
+	}
+}
+
+func TestDetectRateLimit(t *testing.T) {
+	base := errors.New("claude command failed")
+
+	tests := []struct {
+		name        string
+		output      string
+		wantLimited bool
+		wantAfter   time.Duration
+	}{
+		{"other failure", "authentication required", false, 0},
+		{"429 with retry-after", "HTTP 429 Too Many Requests\nRetry-After: 12", true, 12 * time.Second},
+		{"rate limit without retry-after", "rate_limit_error: slow down", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := detectRateLimit(tt.output, base)
+			var rlErr *RateLimitError
+			if got := errors.As(err, &rlErr); got != tt.wantLimited {
+				t.Fatalf("rate limited = %v, want %v", got, tt.wantLimited)
+			}
+			if tt.wantLimited && rlErr.RetryAfter != tt.wantAfter {
+				t.Errorf("RetryAfter = %v, want %v", rlErr.RetryAfter, tt.wantAfter)
+			}
+			if !errors.Is(err, base) {
+				t.Error("detectRateLimit should keep the original error")
+			}
+		})
+	}
+}
+
+func TestCallWithRateLimitRetries(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := clock.install(NewLimiter(600))
+
+	calls := 0
+	output, err := callWithRateLimit(context.Background(), l, func() (string, error) {
+		calls++
+		switch calls {
+		case 1:
+			return "", &RateLimitError{RetryAfter: 7 * time.Second, Err: errors.New("429")}
+		case 2:
+			return "", &RateLimitError{Err: errors.New("429")}
+		}
+		return "ok", nil
+	})
+	if err != nil || output != "ok" {
+		t.Fatalf("callWithRateLimit() = %q, %v; want ok", output, err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+	want := []time.Duration{7 * time.Second, defaultRateLimitBackoff}
+	if len(clock.sleeps) != len(want) || clock.sleeps[0] != want[0] || clock.sleeps[1] != want[1] {
+		t.Errorf("sleeps = %v, want %v", clock.sleeps, want)
+	}
+}
+
+func TestCallWithRateLimitGivesUp(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	l := clock.install(NewLimiter(600))
+
+	calls := 0
+	_, err := callWithRateLimit(context.Background(), l, func() (string, error) {
+		calls++
+		return "", &RateLimitError{Err: errors.New("429")}
+	})
+	if err == nil {
+		t.Fatal("callWithRateLimit() should fail once retries are exhausted")
+	}
+	if calls != maxRateLimitRetries+1 {
+		t.Errorf("calls = %d, want %d", calls, maxRateLimitRetries+1)
+	}
+}
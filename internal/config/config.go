@@ -1,73 +1,147 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
+	"github.com/gussy/cmt/internal/git"
+	"github.com/gussy/cmt/internal/prompt"
 	"gopkg.in/yaml.v3"
 )
 
+// localConfigName is the filename of the per-repository config file.
+const localConfigName = ".cmt.yml"
+
 // Config represents the configuration structure for cmt.
 type Config struct {
 	// AI settings
-	Model       string  `yaml:"model"`
-	Temperature float64 `yaml:"temperature"`
-	MaxTokens   int     `yaml:"max_tokens"`
+	Model                 string   `yaml:"model"`
+	Temperature           float64  `yaml:"temperature"`
+	TemperatureOneline    *float64 `yaml:"temperature_oneline,omitempty"` // replaces temperature for --oneline when set
+	TemperatureVerbose    *float64 `yaml:"temperature_verbose,omitempty"` // replaces temperature for --verbose when set
+	MaxTokens             int      `yaml:"max_tokens"`
+	RequestsPerMinute     int      `yaml:"requests_per_minute"`     // 0 (default) means unlimited
+	AllowOfflineFallback  bool     `yaml:"allow_offline_fallback"`  // template message when the AI is unavailable
+	MaxRefinementAttempts int      `yaml:"max_refinement_attempts"` // regenerations the message checks may ask for in total
+	Grounding             string   `yaml:"grounding"`               // "normal" (default) or "strict": how firmly the message is kept to the diff
 
 	// Behavior settings
-	AlwaysScope      bool   `yaml:"always_scope"`
-	Verbose          bool   `yaml:"verbose"`
-	SkipSecretScan   bool   `yaml:"skip_secret_scan"`
-	CustomPromptPath string `yaml:"custom_prompt_path"`
+	AlwaysScope            bool              `yaml:"always_scope"`
+	Verbose                bool              `yaml:"verbose"`
+	SkipSecretScan         bool              `yaml:"skip_secret_scan"`
+	SecretOnDetect         string            `yaml:"secret_on_detect"`         // "warn" (default, asks on a terminal), "block" or "ignore"
+	SigningKey             string            `yaml:"signing_key"`              // GPG key ID or SSH public key file; signs every commit
+	EnabledSecretPatterns  []string          `yaml:"enabled_secret_patterns"`  // if set, only these secret patterns are used
+	DisabledSecretPatterns []string          `yaml:"disabled_secret_patterns"` // secret patterns to switch off
+	CustomPromptPath       string            `yaml:"custom_prompt_path"`
+	PostGenerateCommand    string            `yaml:"post_generate_command"` // filter run on generated messages
+	PostGenerateTimeout    int               `yaml:"post_generate_timeout"` // seconds before the filter is abandoned
+	MessageFormatter       string            `yaml:"message_formatter"`     // built-in formatter name, or a command given the message as JSON
+	Hints                  map[string]string `yaml:"hints"`                 // named presets for --hint @name
+	FileTypeGuidance       map[string]string `yaml:"file_type_guidance"`    // extension or file name -> extra prompt instruction
+	ClosingKeywords        []string          `yaml:"closing_keywords"`      // words that turn an issue reference into "Closes #N"
+	CoAuthorFromEnv        []string          `yaml:"co_author_from_env"`    // environment variables naming pair partners for Co-authored-by trailers
+	SuggestAbsorb          bool              `yaml:"suggest_absorb"`        // hint at cmt absorb for small changes with unpushed commits
+	StyleFromHistory       bool              `yaml:"style_from_history"`    // show recent subjects to the model as style examples
+	StyleHistoryCount      int               `yaml:"style_history_count"`   // number of recent subjects to show
+	StripEmoji             bool              `yaml:"strip_emoji"`           // remove emoji from generated subjects
+	EnforceImperative      bool              `yaml:"enforce_imperative"`    // rewrite "Added"/"Adds"/"Adding" subjects to "Add"
+	SubjectCase            string            `yaml:"subject_case"`          // "preserve" (default) or "lower": case of the description's first letter
+	StripTrailingPeriod    bool              `yaml:"strip_trailing_period"` // remove a period that ends the subject
+	MessagePrefix          string            `yaml:"message_prefix"`        // fixed text put before every subject, e.g. "PROJ-123: "
+	NormalizeMessage       bool              `yaml:"normalize_message"`     // tidy blank lines and trailing whitespace in generated messages
+	BaseBranch             string            `yaml:"base_branch"`           // branch the branch point is measured from; "" detects origin's default
+	TelemetryLocal         bool              `yaml:"telemetry_local"`       // record usage metrics in .git/cmt/metrics.jsonl for cmt stats
 
 	// UI settings
-	ColorOutput bool   `yaml:"color_output"`
-	Interactive bool   `yaml:"interactive"`
-	EditorMode  string `yaml:"editor_mode"` // "inline" or "external"
+	ColorOutput      bool   `yaml:"color_output"`
+	Interactive      bool   `yaml:"interactive"`
+	EditorMode       string `yaml:"editor_mode"`       // "inline" or "external"
+	EditorShowDiff   bool   `yaml:"editor_show_diff"`  // show the staged diff as comments in the external editor
+	EditorHelp       bool   `yaml:"editor_help"`       // show help comments below the message in the external editor
+	EditorHelpText   string `yaml:"editor_help_text"`  // replaces the built-in help comments when set
+	ReviewAutoscroll bool   `yaml:"review_autoscroll"` // follow new content instead of resetting to top
 
 	// Preprocessing settings
-	MaxDiffTokens   int  `yaml:"max_diff_tokens"`
-	FilterBinary    bool `yaml:"filter_binary"`
-	FilterMinified  bool `yaml:"filter_minified"`
-	FilterGenerated bool `yaml:"filter_generated"`
+	MaxDiffTokens           int     `yaml:"max_diff_tokens"`
+	MaxDiffBytes            int64   `yaml:"max_diff_bytes"`            // hard cap on the raw diff read from git
+	LargeFileThreshold      int64   `yaml:"large_file_threshold"`      // warn about staged files above this many bytes; 0 disables
+	PromptInstructionBudget float64 `yaml:"prompt_instruction_budget"` // max share of max_diff_tokens for instructions
+	FilterBinary            bool    `yaml:"filter_binary"`
+	FilterMinified          bool    `yaml:"filter_minified"`
+	FilterGenerated         bool    `yaml:"filter_generated"`
+	DiffIgnoreWhitespace    bool    `yaml:"diff_ignore_whitespace"` // hide whitespace-only changes from the model (git diff -w)
 
 	// Absorb settings
-	AbsorbStrategy   string  `yaml:"absorb_strategy"`    // "fixup" (default) or "direct"
-	AbsorbRange      string  `yaml:"absorb_range"`       // "unpushed" (default) or "branch-point"
-	AbsorbAmbiguity  string  `yaml:"absorb_ambiguity"`   // "interactive" (default) or "best-match"
-	AbsorbAutoCommit bool    `yaml:"absorb_auto_commit"` // true (default) - create commit for unmatched
-	AbsorbConfidence float64 `yaml:"absorb_confidence"`  // 0.7 (default) - min confidence threshold
+	AbsorbStrategy        string  `yaml:"absorb_strategy"`         // "fixup" (default) or "direct"
+	AbsorbRange           string  `yaml:"absorb_range"`            // "unpushed" (default) or "branch-point"
+	AbsorbFetch           bool    `yaml:"absorb_fetch"`            // fetch the branch from origin before finding unpushed commits
+	AbsorbAmbiguity       string  `yaml:"absorb_ambiguity"`        // "interactive" (default) or "best-match"
+	AbsorbAutoCommit      bool    `yaml:"absorb_auto_commit"`      // true (default) - create commit for unmatched
+	AbsorbConfidence      float64 `yaml:"absorb_confidence"`       // 0.7 (default) - min confidence threshold
+	AbsorbMaxHunks        int     `yaml:"absorb_max_hunks"`        // 40 (default) - above this, whole files are assigned; 0 never groups
+	AbsorbFileHistory     bool    `yaml:"absorb_file_history"`     // true (default) - tell the model which commits touched each staged file
+	AbsorbBackupRetention string  `yaml:"absorb_backup_retention"` // "10" (default) - last N, or an age like "14d"
+	AbsorbLeftoverPrompt  string  `yaml:"absorb_leftover_prompt"`  // instructions for the commit of unmatched hunks
 }
 
 // Default returns the default configuration.
 func Default() *Config {
 	return &Config{
-		Model:            "claude-3-5-sonnet-latest",
-		Temperature:      0.2,
-		MaxTokens:        500,
-		AlwaysScope:      false,
-		Verbose:          false,
-		SkipSecretScan:   false,
-		ColorOutput:      true,
-		Interactive:      true,
-		EditorMode:       "inline",
-		MaxDiffTokens:    16384,
-		FilterBinary:     true,
-		FilterMinified:   true,
-		FilterGenerated:  true,
-		AbsorbStrategy:   "fixup",
-		AbsorbRange:      "unpushed",
-		AbsorbAmbiguity:  "interactive",
-		AbsorbAutoCommit: true,
-		AbsorbConfidence: 0.7,
+		Model:                   "claude-3-5-sonnet-latest",
+		Temperature:             0.2,
+		MaxTokens:               500,
+		MaxRefinementAttempts:   2,
+		Grounding:               "normal",
+		AlwaysScope:             false,
+		Verbose:                 false,
+		SkipSecretScan:          false,
+		SecretOnDetect:          "warn",
+		ClosingKeywords:         append([]string(nil), prompt.DefaultClosingKeywords...),
+		SuggestAbsorb:           true,
+		StyleFromHistory:        false,
+		StyleHistoryCount:       5,
+		StripEmoji:              false,
+		EnforceImperative:       false,
+		SubjectCase:             "preserve",
+		StripTrailingPeriod:     false,
+		NormalizeMessage:        true,
+		TelemetryLocal:          false,
+		ColorOutput:             true,
+		Interactive:             true,
+		EditorMode:              "inline",
+		EditorHelp:              true,
+		ReviewAutoscroll:        false,
+		MaxDiffTokens:           16384,
+		MaxDiffBytes:            git.DefaultMaxDiffBytes,
+		LargeFileThreshold:      5 << 20,
+		PromptInstructionBudget: 0.25,
+		FilterBinary:            true,
+		FilterMinified:          true,
+		FilterGenerated:         true,
+		DiffIgnoreWhitespace:    false,
+		AbsorbStrategy:          "fixup",
+		AbsorbRange:             "unpushed",
+		AbsorbFetch:             false,
+		AbsorbAmbiguity:         "interactive",
+		AbsorbAutoCommit:        true,
+		AbsorbConfidence:        0.7,
+		AbsorbMaxHunks:          40,
+		AbsorbFileHistory:       true,
+		AbsorbBackupRetention:   "10",
+		AbsorbLeftoverPrompt:    prompt.DefaultAbsorbLeftoverPrompt,
 	}
 }
 
 // LoadConfig loads configuration from multiple sources with the following precedence:
 // 1. Environment variables (highest priority)
-// 2. Local config file (.cmt.yml in current directory)
+// 2. Local config file (.cmt.yml in the current directory or any parent up to the repo root)
 // 3. Global config file (~/.config/cmt/config.yml - XDG Base Directory)
 // 4. Default values (lowest priority)
 func LoadConfig() (*Config, error) {
@@ -75,17 +149,15 @@ func LoadConfig() (*Config, error) {
 	config := Default()
 
 	// Try to load global config (XDG Base Directory)
-	homeDir, err := os.UserHomeDir()
-	if err == nil {
-		globalConfigPath := filepath.Join(homeDir, ".config", "cmt", "config.yml")
+	if globalConfigPath, err := GlobalConfigPath(); err == nil {
 		if err := loadFromFile(globalConfigPath, config); err != nil && !os.IsNotExist(err) {
 			return nil, fmt.Errorf("error loading global config: %w", err)
 		}
 	}
 
 	// Try to load local config
-	localConfigPath := ".cmt.yml"
-	if err := loadFromFile(localConfigPath, config); err != nil && !os.IsNotExist(err) {
+	localConfigPath := LocalConfigPath()
+	if err := loadLocalConfig(localConfigPath, config, os.Stderr); err != nil && !os.IsNotExist(err) {
 		return nil, fmt.Errorf("error loading local config: %w", err)
 	}
 
@@ -95,6 +167,78 @@ func LoadConfig() (*Config, error) {
 	return config, nil
 }
 
+// GlobalConfigPath returns the path of the global config file,
+// ~/.config/cmt/config.yml.
+func GlobalConfigPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("error getting home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "cmt", "config.yml"), nil
+}
+
+// LocalConfigPath returns the path of the local config file.
+// It walks up from the current directory to the repository root looking for
+// an existing .cmt.yml. If none is found, the repository root is used, or the
+// current directory when not inside a git repository.
+func LocalConfigPath() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return localConfigName
+	}
+
+	// Resolve symlinks so the walk can match the root reported by git.
+	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = resolved
+	}
+
+	repo := &git.Repository{Path: cwd}
+	rootPath, err := repo.GetRootPath()
+	if err != nil {
+		// Not inside a git repository, only consider the current directory.
+		return filepath.Join(cwd, localConfigName)
+	}
+
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		candidate := filepath.Join(dir, localConfigName)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+
+		// Stop at the repository root (or the filesystem root as a safeguard).
+		if dir == rootPath || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	return filepath.Join(rootPath, localConfigName)
+}
+
+// loadLocalConfig loads the repository's .cmt.yml on top of config. A
+// repository is as untrusted as its author, so keys that make cmt run a
+// command keep their global value, with a warning on w when the file sets
+// them: post_generate_command, and message_formatter unless it names a
+// built-in formatter.
+func loadLocalConfig(path string, config *Config, w io.Writer) error {
+	postGenerate, formatter := config.PostGenerateCommand, config.MessageFormatter
+	if err := loadFromFile(path, config); err != nil {
+		return err
+	}
+	if config.PostGenerateCommand != postGenerate {
+		if config.PostGenerateCommand != "" {
+			fmt.Fprintf(w, "⚠️  Ignoring post_generate_command in %s: set it in the global config or CMT_POST_GENERATE_COMMAND\n", path)
+		}
+		config.PostGenerateCommand = postGenerate
+	}
+	if _, builtIn := prompt.Formatters[config.MessageFormatter]; !builtIn && config.MessageFormatter != formatter {
+		if config.MessageFormatter != "" {
+			fmt.Fprintf(w, "⚠️  Ignoring message_formatter %q in %s: only built-in formatters can be set there; set commands in the global config or CMT_MESSAGE_FORMATTER\n", config.MessageFormatter, path)
+		}
+		config.MessageFormatter = formatter
+	}
+	return nil
+}
+
 // loadFromFile loads configuration from a YAML file.
 func loadFromFile(path string, config *Config) error {
 	data, err := os.ReadFile(path)
@@ -109,6 +253,89 @@ func loadFromFile(path string, config *Config) error {
 	return nil
 }
 
+// Parse decodes a config file's contents on top of the defaults. Unlike
+// loading, it rejects unknown keys so typos are reported instead of ignored.
+func Parse(data []byte) (*Config, error) {
+	config := Default()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Validate checks the values that Set would reject, returning every problem
+// found joined into one error.
+func (c *Config) Validate() error {
+	var errs []error
+	if c.RequestsPerMinute < 0 {
+		errs = append(errs, fmt.Errorf("requests_per_minute must not be negative"))
+	}
+	if c.MaxRefinementAttempts < 0 {
+		errs = append(errs, fmt.Errorf("max_refinement_attempts must not be negative"))
+	}
+	if c.StyleHistoryCount <= 0 {
+		errs = append(errs, fmt.Errorf("style_history_count must be positive"))
+	}
+	if c.PostGenerateTimeout < 0 {
+		errs = append(errs, fmt.Errorf("post_generate_timeout must not be negative"))
+	}
+	if c.Grounding != "normal" && c.Grounding != "strict" {
+		errs = append(errs, fmt.Errorf("invalid grounding value: %s (must be normal or strict)", c.Grounding))
+	}
+	if c.SubjectCase != "preserve" && c.SubjectCase != "lower" {
+		errs = append(errs, fmt.Errorf("invalid subject_case value: %s (must be preserve or lower)", c.SubjectCase))
+	}
+	if c.EditorMode != "inline" && c.EditorMode != "external" {
+		errs = append(errs, fmt.Errorf("invalid editor_mode value: %s (must be inline or external)", c.EditorMode))
+	}
+	if c.SecretOnDetect != "warn" && c.SecretOnDetect != "block" && c.SecretOnDetect != "ignore" {
+		errs = append(errs, fmt.Errorf("invalid secret_on_detect value: %s (must be warn, block or ignore)", c.SecretOnDetect))
+	}
+	if c.MaxDiffBytes <= 0 {
+		errs = append(errs, fmt.Errorf("max_diff_bytes must be positive"))
+	}
+	if c.LargeFileThreshold < 0 {
+		errs = append(errs, fmt.Errorf("large_file_threshold must not be negative"))
+	}
+	if c.PromptInstructionBudget < 0.0 || c.PromptInstructionBudget >= 1.0 {
+		errs = append(errs, fmt.Errorf("prompt_instruction_budget must be at least 0.0 and below 1.0"))
+	}
+	if c.AbsorbStrategy != "fixup" && c.AbsorbStrategy != "direct" {
+		errs = append(errs, fmt.Errorf("invalid absorb_strategy value: %s (must be fixup or direct)", c.AbsorbStrategy))
+	}
+	if c.AbsorbRange != "unpushed" && c.AbsorbRange != "branch-point" {
+		errs = append(errs, fmt.Errorf("invalid absorb_range value: %s (must be unpushed or branch-point)", c.AbsorbRange))
+	}
+	if c.AbsorbAmbiguity != "interactive" && c.AbsorbAmbiguity != "best-match" {
+		errs = append(errs, fmt.Errorf("invalid absorb_ambiguity value: %s (must be interactive or best-match)", c.AbsorbAmbiguity))
+	}
+	if c.AbsorbConfidence < 0.0 || c.AbsorbConfidence > 1.0 {
+		errs = append(errs, fmt.Errorf("absorb_confidence must be between 0.0 and 1.0"))
+	}
+	if c.AbsorbMaxHunks < 0 {
+		errs = append(errs, fmt.Errorf("absorb_max_hunks must not be negative"))
+	}
+	if _, err := git.ParseBackupRetention(c.AbsorbBackupRetention); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// TemperatureFor returns the temperature for a message format ("oneline",
+// "verbose", ...): the per-format override when one is set, otherwise the
+// global temperature.
+func (c *Config) TemperatureFor(format string) float64 {
+	switch {
+	case format == "oneline" && c.TemperatureOneline != nil:
+		return *c.TemperatureOneline
+	case format == "verbose" && c.TemperatureVerbose != nil:
+		return *c.TemperatureVerbose
+	}
+	return c.Temperature
+}
+
 // applyEnvOverrides applies environment variable overrides to the config.
 func applyEnvOverrides(config *Config) {
 	// AI settings
@@ -120,11 +347,37 @@ func applyEnvOverrides(config *Config) {
 			config.Temperature = val
 		}
 	}
+	if temp := os.Getenv("CMT_TEMPERATURE_ONELINE"); temp != "" {
+		if val, err := strconv.ParseFloat(temp, 64); err == nil {
+			config.TemperatureOneline = &val
+		}
+	}
+	if temp := os.Getenv("CMT_TEMPERATURE_VERBOSE"); temp != "" {
+		if val, err := strconv.ParseFloat(temp, 64); err == nil {
+			config.TemperatureVerbose = &val
+		}
+	}
 	if maxTokens := os.Getenv("CMT_MAX_TOKENS"); maxTokens != "" {
 		if val, err := strconv.Atoi(maxTokens); err == nil {
 			config.MaxTokens = val
 		}
 	}
+	if offlineFallback := os.Getenv("CMT_ALLOW_OFFLINE_FALLBACK"); offlineFallback != "" {
+		config.AllowOfflineFallback = parseBool(offlineFallback)
+	}
+	if requestsPerMinute := os.Getenv("CMT_REQUESTS_PER_MINUTE"); requestsPerMinute != "" {
+		if val, err := strconv.Atoi(requestsPerMinute); err == nil {
+			config.RequestsPerMinute = val
+		}
+	}
+	if refinements := os.Getenv("CMT_MAX_REFINEMENT_ATTEMPTS"); refinements != "" {
+		if val, err := strconv.Atoi(refinements); err == nil {
+			config.MaxRefinementAttempts = val
+		}
+	}
+	if grounding := os.Getenv("CMT_GROUNDING"); grounding != "" {
+		config.Grounding = grounding
+	}
 
 	// Behavior settings
 	if alwaysScope := os.Getenv("CMT_ALWAYS_SCOPE"); alwaysScope != "" {
@@ -136,9 +389,73 @@ func applyEnvOverrides(config *Config) {
 	if skipScan := os.Getenv("CMT_SKIP_SECRET_SCAN"); skipScan != "" {
 		config.SkipSecretScan = parseBool(skipScan)
 	}
+	if onDetect := os.Getenv("CMT_SECRET_ON_DETECT"); onDetect != "" {
+		config.SecretOnDetect = onDetect
+	}
+	if signingKey := os.Getenv("CMT_SIGNING_KEY"); signingKey != "" {
+		config.SigningKey = signingKey
+	}
+	if enabledPatterns := os.Getenv("CMT_ENABLED_SECRET_PATTERNS"); enabledPatterns != "" {
+		config.EnabledSecretPatterns = splitList(enabledPatterns)
+	}
+	if disabledPatterns := os.Getenv("CMT_DISABLED_SECRET_PATTERNS"); disabledPatterns != "" {
+		config.DisabledSecretPatterns = splitList(disabledPatterns)
+	}
 	if customPrompt := os.Getenv("CMT_CUSTOM_PROMPT_PATH"); customPrompt != "" {
 		config.CustomPromptPath = customPrompt
 	}
+	if closingKeywords := os.Getenv("CMT_CLOSING_KEYWORDS"); closingKeywords != "" {
+		config.ClosingKeywords = splitList(closingKeywords)
+	}
+	if coAuthorFromEnv := os.Getenv("CMT_CO_AUTHOR_FROM_ENV"); coAuthorFromEnv != "" {
+		config.CoAuthorFromEnv = splitList(coAuthorFromEnv)
+	}
+	if suggestAbsorb := os.Getenv("CMT_SUGGEST_ABSORB"); suggestAbsorb != "" {
+		config.SuggestAbsorb = parseBool(suggestAbsorb)
+	}
+	if styleFromHistory := os.Getenv("CMT_STYLE_FROM_HISTORY"); styleFromHistory != "" {
+		config.StyleFromHistory = parseBool(styleFromHistory)
+	}
+	if styleHistoryCount := os.Getenv("CMT_STYLE_HISTORY_COUNT"); styleHistoryCount != "" {
+		if val, err := strconv.Atoi(styleHistoryCount); err == nil {
+			config.StyleHistoryCount = val
+		}
+	}
+	if stripEmoji := os.Getenv("CMT_STRIP_EMOJI"); stripEmoji != "" {
+		config.StripEmoji = parseBool(stripEmoji)
+	}
+	if enforceImperative := os.Getenv("CMT_ENFORCE_IMPERATIVE"); enforceImperative != "" {
+		config.EnforceImperative = parseBool(enforceImperative)
+	}
+	if subjectCase := os.Getenv("CMT_SUBJECT_CASE"); subjectCase != "" {
+		config.SubjectCase = subjectCase
+	}
+	if stripPeriod := os.Getenv("CMT_STRIP_TRAILING_PERIOD"); stripPeriod != "" {
+		config.StripTrailingPeriod = parseBool(stripPeriod)
+	}
+	if messagePrefix := os.Getenv("CMT_MESSAGE_PREFIX"); messagePrefix != "" {
+		config.MessagePrefix = messagePrefix
+	}
+	if normalizeMessage := os.Getenv("CMT_NORMALIZE_MESSAGE"); normalizeMessage != "" {
+		config.NormalizeMessage = parseBool(normalizeMessage)
+	}
+	if baseBranch := os.Getenv("CMT_BASE_BRANCH"); baseBranch != "" {
+		config.BaseBranch = baseBranch
+	}
+	if telemetryLocal := os.Getenv("CMT_TELEMETRY_LOCAL"); telemetryLocal != "" {
+		config.TelemetryLocal = parseBool(telemetryLocal)
+	}
+	if postGenerate := os.Getenv("CMT_POST_GENERATE_COMMAND"); postGenerate != "" {
+		config.PostGenerateCommand = postGenerate
+	}
+	if postGenerateTimeout := os.Getenv("CMT_POST_GENERATE_TIMEOUT"); postGenerateTimeout != "" {
+		if val, err := strconv.Atoi(postGenerateTimeout); err == nil {
+			config.PostGenerateTimeout = val
+		}
+	}
+	if formatter := os.Getenv("CMT_MESSAGE_FORMATTER"); formatter != "" {
+		config.MessageFormatter = formatter
+	}
 
 	// UI settings
 	if colorOutput := os.Getenv("CMT_COLOR_OUTPUT"); colorOutput != "" {
@@ -150,6 +467,18 @@ func applyEnvOverrides(config *Config) {
 	if editorMode := os.Getenv("CMT_EDITOR_MODE"); editorMode != "" {
 		config.EditorMode = editorMode
 	}
+	if editorShowDiff := os.Getenv("CMT_EDITOR_SHOW_DIFF"); editorShowDiff != "" {
+		config.EditorShowDiff = parseBool(editorShowDiff)
+	}
+	if editorHelp := os.Getenv("CMT_EDITOR_HELP"); editorHelp != "" {
+		config.EditorHelp = parseBool(editorHelp)
+	}
+	if editorHelpText := os.Getenv("CMT_EDITOR_HELP_TEXT"); editorHelpText != "" {
+		config.EditorHelpText = editorHelpText
+	}
+	if reviewAutoscroll := os.Getenv("CMT_REVIEW_AUTOSCROLL"); reviewAutoscroll != "" {
+		config.ReviewAutoscroll = parseBool(reviewAutoscroll)
+	}
 
 	// Preprocessing settings
 	if maxDiffTokens := os.Getenv("CMT_MAX_DIFF_TOKENS"); maxDiffTokens != "" {
@@ -157,6 +486,21 @@ func applyEnvOverrides(config *Config) {
 			config.MaxDiffTokens = val
 		}
 	}
+	if maxDiffBytes := os.Getenv("CMT_MAX_DIFF_BYTES"); maxDiffBytes != "" {
+		if val, err := strconv.ParseInt(maxDiffBytes, 10, 64); err == nil {
+			config.MaxDiffBytes = val
+		}
+	}
+	if largeFileThreshold := os.Getenv("CMT_LARGE_FILE_THRESHOLD"); largeFileThreshold != "" {
+		if val, err := strconv.ParseInt(largeFileThreshold, 10, 64); err == nil {
+			config.LargeFileThreshold = val
+		}
+	}
+	if instructionBudget := os.Getenv("CMT_PROMPT_INSTRUCTION_BUDGET"); instructionBudget != "" {
+		if val, err := strconv.ParseFloat(instructionBudget, 64); err == nil {
+			config.PromptInstructionBudget = val
+		}
+	}
 	if filterBinary := os.Getenv("CMT_FILTER_BINARY"); filterBinary != "" {
 		config.FilterBinary = parseBool(filterBinary)
 	}
@@ -166,6 +510,9 @@ func applyEnvOverrides(config *Config) {
 	if filterGenerated := os.Getenv("CMT_FILTER_GENERATED"); filterGenerated != "" {
 		config.FilterGenerated = parseBool(filterGenerated)
 	}
+	if ignoreWhitespace := os.Getenv("CMT_DIFF_IGNORE_WHITESPACE"); ignoreWhitespace != "" {
+		config.DiffIgnoreWhitespace = parseBool(ignoreWhitespace)
+	}
 
 	// Absorb settings
 	if absorbStrategy := os.Getenv("CMT_ABSORB_STRATEGY"); absorbStrategy != "" {
@@ -174,6 +521,9 @@ func applyEnvOverrides(config *Config) {
 	if absorbRange := os.Getenv("CMT_ABSORB_RANGE"); absorbRange != "" {
 		config.AbsorbRange = absorbRange
 	}
+	if absorbFetch := os.Getenv("CMT_ABSORB_FETCH"); absorbFetch != "" {
+		config.AbsorbFetch = parseBool(absorbFetch)
+	}
 	if absorbAmbiguity := os.Getenv("CMT_ABSORB_AMBIGUITY"); absorbAmbiguity != "" {
 		config.AbsorbAmbiguity = absorbAmbiguity
 	}
@@ -185,6 +535,36 @@ func applyEnvOverrides(config *Config) {
 			config.AbsorbConfidence = val
 		}
 	}
+	if absorbMaxHunks := os.Getenv("CMT_ABSORB_MAX_HUNKS"); absorbMaxHunks != "" {
+		if val, err := strconv.Atoi(absorbMaxHunks); err == nil {
+			config.AbsorbMaxHunks = val
+		}
+	}
+	if fileHistory := os.Getenv("CMT_ABSORB_FILE_HISTORY"); fileHistory != "" {
+		config.AbsorbFileHistory = parseBool(fileHistory)
+	}
+	if backupRetention := os.Getenv("CMT_ABSORB_BACKUP_RETENTION"); backupRetention != "" {
+		config.AbsorbBackupRetention = backupRetention
+	}
+	if leftoverPrompt := os.Getenv("CMT_ABSORB_LEFTOVER_PROMPT"); leftoverPrompt != "" {
+		config.AbsorbLeftoverPrompt = leftoverPrompt
+	}
+}
+
+// ResolveHint expands a hint preset. A hint of the form "@name" is replaced
+// by the preset of that name from the hints config; any other hint is
+// returned unchanged.
+func (c *Config) ResolveHint(hint string) (string, error) {
+	name, ok := strings.CutPrefix(hint, "@")
+	if !ok {
+		return hint, nil
+	}
+
+	preset, ok := c.Hints[name]
+	if !ok {
+		return "", fmt.Errorf("unknown hint preset: %s", name)
+	}
+	return preset, nil
 }
 
 // parseBool parses a string as a boolean value.
@@ -197,24 +577,35 @@ func parseBool(s string) bool {
 	}
 }
 
+// splitList parses a comma-separated list, dropping empty entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // Save saves the configuration to a file.
-// If global is true, saves to ~/.config/gac/config.yml (XDG Base Directory), otherwise saves to .gac.yml
+// If global is true, saves to ~/.config/cmt/config.yml (XDG Base Directory), otherwise saves to
+// the local .cmt.yml found by LocalConfigPath.
 func (c *Config) Save(global bool) error {
 	var configPath string
 
 	if global {
-		homeDir, err := os.UserHomeDir()
+		path, err := GlobalConfigPath()
 		if err != nil {
-			return fmt.Errorf("error getting home directory: %w", err)
+			return err
 		}
-		configDir := filepath.Join(homeDir, ".config", "cmt")
 		// Create config directory if it doesn't exist
-		if err := os.MkdirAll(configDir, 0755); err != nil {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
 			return fmt.Errorf("error creating config directory: %w", err)
 		}
-		configPath = filepath.Join(configDir, "config.yml")
+		configPath = path
 	} else {
-		configPath = ".cmt.yml"
+		configPath = LocalConfigPath()
 	}
 
 	data, err := yaml.Marshal(c)
@@ -237,8 +628,26 @@ func (c *Config) Get(key string) (interface{}, error) {
 		return c.Model, nil
 	case "temperature":
 		return c.Temperature, nil
+	case "temperature_oneline":
+		if c.TemperatureOneline == nil {
+			return "", nil // unset: the global temperature applies
+		}
+		return *c.TemperatureOneline, nil
+	case "temperature_verbose":
+		if c.TemperatureVerbose == nil {
+			return "", nil
+		}
+		return *c.TemperatureVerbose, nil
 	case "max_tokens":
 		return c.MaxTokens, nil
+	case "requests_per_minute":
+		return c.RequestsPerMinute, nil
+	case "max_refinement_attempts":
+		return c.MaxRefinementAttempts, nil
+	case "grounding":
+		return c.Grounding, nil
+	case "allow_offline_fallback":
+		return c.AllowOfflineFallback, nil
 	// Behavior settings
 	case "always_scope":
 		return c.AlwaysScope, nil
@@ -246,8 +655,52 @@ func (c *Config) Get(key string) (interface{}, error) {
 		return c.Verbose, nil
 	case "skip_secret_scan":
 		return c.SkipSecretScan, nil
+	case "secret_on_detect":
+		return c.SecretOnDetect, nil
+	case "signing_key":
+		return c.SigningKey, nil
+	case "enabled_secret_patterns":
+		return c.EnabledSecretPatterns, nil
+	case "disabled_secret_patterns":
+		return c.DisabledSecretPatterns, nil
 	case "custom_prompt_path":
 		return c.CustomPromptPath, nil
+	case "post_generate_command":
+		return c.PostGenerateCommand, nil
+	case "post_generate_timeout":
+		return c.PostGenerateTimeout, nil
+	case "message_formatter":
+		return c.MessageFormatter, nil
+	case "hints":
+		return c.Hints, nil
+	case "file_type_guidance":
+		return c.FileTypeGuidance, nil
+	case "closing_keywords":
+		return c.ClosingKeywords, nil
+	case "co_author_from_env":
+		return c.CoAuthorFromEnv, nil
+	case "suggest_absorb":
+		return c.SuggestAbsorb, nil
+	case "style_from_history":
+		return c.StyleFromHistory, nil
+	case "style_history_count":
+		return c.StyleHistoryCount, nil
+	case "strip_emoji":
+		return c.StripEmoji, nil
+	case "enforce_imperative":
+		return c.EnforceImperative, nil
+	case "subject_case":
+		return c.SubjectCase, nil
+	case "strip_trailing_period":
+		return c.StripTrailingPeriod, nil
+	case "message_prefix":
+		return c.MessagePrefix, nil
+	case "normalize_message":
+		return c.NormalizeMessage, nil
+	case "base_branch":
+		return c.BaseBranch, nil
+	case "telemetry_local":
+		return c.TelemetryLocal, nil
 	// UI settings
 	case "color_output":
 		return c.ColorOutput, nil
@@ -255,26 +708,52 @@ func (c *Config) Get(key string) (interface{}, error) {
 		return c.Interactive, nil
 	case "editor_mode":
 		return c.EditorMode, nil
+	case "editor_show_diff":
+		return c.EditorShowDiff, nil
+	case "editor_help":
+		return c.EditorHelp, nil
+	case "editor_help_text":
+		return c.EditorHelpText, nil
+	case "review_autoscroll":
+		return c.ReviewAutoscroll, nil
 	// Preprocessing settings
 	case "max_diff_tokens":
 		return c.MaxDiffTokens, nil
+	case "max_diff_bytes":
+		return c.MaxDiffBytes, nil
+	case "large_file_threshold":
+		return c.LargeFileThreshold, nil
+	case "prompt_instruction_budget":
+		return c.PromptInstructionBudget, nil
 	case "filter_binary":
 		return c.FilterBinary, nil
 	case "filter_minified":
 		return c.FilterMinified, nil
 	case "filter_generated":
 		return c.FilterGenerated, nil
+	case "diff_ignore_whitespace":
+		return c.DiffIgnoreWhitespace, nil
 	// Absorb settings
 	case "absorb_strategy":
 		return c.AbsorbStrategy, nil
 	case "absorb_range":
 		return c.AbsorbRange, nil
+	case "absorb_fetch":
+		return c.AbsorbFetch, nil
 	case "absorb_ambiguity":
 		return c.AbsorbAmbiguity, nil
 	case "absorb_auto_commit":
 		return c.AbsorbAutoCommit, nil
 	case "absorb_confidence":
 		return c.AbsorbConfidence, nil
+	case "absorb_backup_retention":
+		return c.AbsorbBackupRetention, nil
+	case "absorb_max_hunks":
+		return c.AbsorbMaxHunks, nil
+	case "absorb_file_history":
+		return c.AbsorbFileHistory, nil
+	case "absorb_leftover_prompt":
+		return c.AbsorbLeftoverPrompt, nil
 	default:
 		return nil, fmt.Errorf("unknown configuration key: %s", key)
 	}
@@ -292,12 +771,41 @@ func (c *Config) Set(key string, value string) error {
 			return fmt.Errorf("invalid temperature value: %s", value)
 		}
 		c.Temperature = val
+	case "temperature_oneline", "temperature_verbose":
+		val, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s value: %s", key, value)
+		}
+		if key == "temperature_oneline" {
+			c.TemperatureOneline = &val
+		} else {
+			c.TemperatureVerbose = &val
+		}
 	case "max_tokens":
 		val, err := strconv.Atoi(value)
 		if err != nil {
 			return fmt.Errorf("invalid max_tokens value: %s", value)
 		}
 		c.MaxTokens = val
+	case "requests_per_minute":
+		val, err := strconv.Atoi(value)
+		if err != nil || val < 0 {
+			return fmt.Errorf("invalid requests_per_minute value: %s", value)
+		}
+		c.RequestsPerMinute = val
+	case "max_refinement_attempts":
+		val, err := strconv.Atoi(value)
+		if err != nil || val < 0 {
+			return fmt.Errorf("invalid max_refinement_attempts value: %s", value)
+		}
+		c.MaxRefinementAttempts = val
+	case "grounding":
+		if value != "normal" && value != "strict" {
+			return fmt.Errorf("invalid grounding value: %s (must be normal or strict)", value)
+		}
+		c.Grounding = value
+	case "allow_offline_fallback":
+		c.AllowOfflineFallback = parseBool(value)
 	// Behavior settings
 	case "always_scope":
 		c.AlwaysScope = parseBool(value)
@@ -305,8 +813,59 @@ func (c *Config) Set(key string, value string) error {
 		c.Verbose = parseBool(value)
 	case "skip_secret_scan":
 		c.SkipSecretScan = parseBool(value)
+	case "secret_on_detect":
+		c.SecretOnDetect = value
+	case "signing_key":
+		c.SigningKey = value
+	case "enabled_secret_patterns":
+		c.EnabledSecretPatterns = splitList(value)
+	case "disabled_secret_patterns":
+		c.DisabledSecretPatterns = splitList(value)
 	case "custom_prompt_path":
 		c.CustomPromptPath = value
+	case "post_generate_command":
+		c.PostGenerateCommand = value
+	case "message_formatter":
+		c.MessageFormatter = value
+	case "closing_keywords":
+		c.ClosingKeywords = splitList(value)
+	case "co_author_from_env":
+		c.CoAuthorFromEnv = splitList(value)
+	case "suggest_absorb":
+		c.SuggestAbsorb = parseBool(value)
+	case "style_from_history":
+		c.StyleFromHistory = parseBool(value)
+	case "style_history_count":
+		val, err := strconv.Atoi(value)
+		if err != nil || val <= 0 {
+			return fmt.Errorf("invalid style_history_count value: %s", value)
+		}
+		c.StyleHistoryCount = val
+	case "strip_emoji":
+		c.StripEmoji = parseBool(value)
+	case "enforce_imperative":
+		c.EnforceImperative = parseBool(value)
+	case "subject_case":
+		if value != "preserve" && value != "lower" {
+			return fmt.Errorf("invalid subject_case value: %s (must be preserve or lower)", value)
+		}
+		c.SubjectCase = value
+	case "strip_trailing_period":
+		c.StripTrailingPeriod = parseBool(value)
+	case "message_prefix":
+		c.MessagePrefix = value
+	case "normalize_message":
+		c.NormalizeMessage = parseBool(value)
+	case "base_branch":
+		c.BaseBranch = value
+	case "telemetry_local":
+		c.TelemetryLocal = parseBool(value)
+	case "post_generate_timeout":
+		val, err := strconv.Atoi(value)
+		if err != nil || val <= 0 {
+			return fmt.Errorf("invalid post_generate_timeout value: %s", value)
+		}
+		c.PostGenerateTimeout = val
 	// UI settings
 	case "color_output":
 		c.ColorOutput = parseBool(value)
@@ -318,6 +877,14 @@ func (c *Config) Set(key string, value string) error {
 			return fmt.Errorf("invalid editor_mode value: %s (must be inline or external)", value)
 		}
 		c.EditorMode = value
+	case "editor_show_diff":
+		c.EditorShowDiff = parseBool(value)
+	case "editor_help":
+		c.EditorHelp = parseBool(value)
+	case "editor_help_text":
+		c.EditorHelpText = value
+	case "review_autoscroll":
+		c.ReviewAutoscroll = parseBool(value)
 	// Preprocessing settings
 	case "max_diff_tokens":
 		val, err := strconv.Atoi(value)
@@ -325,12 +892,35 @@ func (c *Config) Set(key string, value string) error {
 			return fmt.Errorf("invalid max_diff_tokens value: %s", value)
 		}
 		c.MaxDiffTokens = val
+	case "max_diff_bytes":
+		val, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || val <= 0 {
+			return fmt.Errorf("invalid max_diff_bytes value: %s", value)
+		}
+		c.MaxDiffBytes = val
+	case "large_file_threshold":
+		val, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || val < 0 {
+			return fmt.Errorf("invalid large_file_threshold value: %s", value)
+		}
+		c.LargeFileThreshold = val
+	case "prompt_instruction_budget":
+		val, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid prompt_instruction_budget value: %s", value)
+		}
+		if val < 0.0 || val >= 1.0 {
+			return fmt.Errorf("prompt_instruction_budget must be at least 0.0 and below 1.0")
+		}
+		c.PromptInstructionBudget = val
 	case "filter_binary":
 		c.FilterBinary = parseBool(value)
 	case "filter_minified":
 		c.FilterMinified = parseBool(value)
 	case "filter_generated":
 		c.FilterGenerated = parseBool(value)
+	case "diff_ignore_whitespace":
+		c.DiffIgnoreWhitespace = parseBool(value)
 	// Absorb settings
 	case "absorb_strategy":
 		if value != "fixup" && value != "direct" {
@@ -342,6 +932,8 @@ func (c *Config) Set(key string, value string) error {
 			return fmt.Errorf("invalid absorb_range value: %s (must be unpushed or branch-point)", value)
 		}
 		c.AbsorbRange = value
+	case "absorb_fetch":
+		c.AbsorbFetch = parseBool(value)
 	case "absorb_ambiguity":
 		if value != "interactive" && value != "best-match" {
 			return fmt.Errorf("invalid absorb_ambiguity value: %s (must be interactive or best-match)", value)
@@ -358,6 +950,21 @@ func (c *Config) Set(key string, value string) error {
 			return fmt.Errorf("absorb_confidence must be between 0.0 and 1.0")
 		}
 		c.AbsorbConfidence = val
+	case "absorb_max_hunks":
+		val, err := strconv.Atoi(value)
+		if err != nil || val < 0 {
+			return fmt.Errorf("invalid absorb_max_hunks value: %s", value)
+		}
+		c.AbsorbMaxHunks = val
+	case "absorb_file_history":
+		c.AbsorbFileHistory = parseBool(value)
+	case "absorb_backup_retention":
+		if _, err := git.ParseBackupRetention(value); err != nil {
+			return err
+		}
+		c.AbsorbBackupRetention = value
+	case "absorb_leftover_prompt":
+		c.AbsorbLeftoverPrompt = value
 	default:
 		return fmt.Errorf("unknown configuration key: %s", key)
 	}
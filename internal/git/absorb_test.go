@@ -0,0 +1,573 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterHunksMixedDiff(t *testing.T) {
+	diff := `diff --git a/main.go b/main.go
+index 1111111..2222222 100644
+--- a/main.go
++++ b/main.go
+@@ -1,3 +1,4 @@
+ package main
+ 
++// entry point
+ func main() {}
+diff --git a/go.sum b/go.sum
+index 3333333..4444444 100644
+--- a/go.sum
++++ b/go.sum
+@@ -1,2 +1,3 @@
+ example.com/a v1.0.0 h1:abc=
++example.com/b v1.0.0 h1:def=
+ example.com/c v1.0.0 h1:ghi=
+diff --git a/logo.png b/logo.png
+index 5555555..6666666 100644
+Binary files a/logo.png and b/logo.png differ
+diff --git a/web/app.min.js b/web/app.min.js
+index 7777777..8888888 100644
+--- a/web/app.min.js
++++ b/web/app.min.js
+@@ -1 +1 @@
+-var a=1;
++var a=2;
+`
+
+	hunks, err := SplitDiffIntoHunks(diff)
+	if err != nil {
+		t.Fatalf("SplitDiffIntoHunks failed: %v", err)
+	}
+	if len(hunks) != 3 {
+		t.Fatalf("expected 3 hunks, got %d", len(hunks))
+	}
+
+	skip := func(path string) string {
+		switch {
+		case strings.HasSuffix(path, "go.sum"):
+			return "generated/lock file content filtered"
+		case strings.Contains(path, ".min.js"):
+			return "minified file content filtered"
+		}
+		return ""
+	}
+
+	kept, filtered := FilterHunks(hunks, skip)
+
+	if len(kept) != 1 || kept[0].FilePath != "main.go" {
+		t.Fatalf("expected only main.go to be kept, got %+v", kept)
+	}
+	if len(filtered) != 2 {
+		t.Fatalf("expected 2 filtered hunks, got %d", len(filtered))
+	}
+	if filtered[0].Hunk.FilePath != "go.sum" || filtered[0].Reason == "" {
+		t.Errorf("unexpected first filtered hunk: %+v", filtered[0])
+	}
+	if filtered[1].Hunk.FilePath != "web/app.min.js" {
+		t.Errorf("unexpected second filtered hunk: %+v", filtered[1])
+	}
+}
+
+func TestParseBackupRetention(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    BackupRetention
+		wantErr bool
+	}{
+		{"", BackupRetention{}, false},
+		{"all", BackupRetention{}, false},
+		{"10", BackupRetention{KeepLast: 10}, false},
+		{"14d", BackupRetention{MaxAge: 14 * 24 * time.Hour}, false},
+		{"72h", BackupRetention{MaxAge: 72 * time.Hour}, false},
+		{"0", BackupRetention{}, true},
+		{"-3", BackupRetention{}, true},
+		{"xd", BackupRetention{}, true},
+		{"0d", BackupRetention{}, true},
+		{"forever", BackupRetention{}, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBackupRetention(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseBackupRetention(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseBackupRetention(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSelectBackupsToPrune(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	ref := func(age time.Duration) string {
+		return fmt.Sprintf("refs/cmt-backup/absorb-%d", now.Add(-age).Unix())
+	}
+
+	day := 24 * time.Hour
+	newest, recent, older, oldest := ref(time.Hour), ref(2*day), ref(10*day), ref(30*day)
+	refs := []string{older, newest, oldest, recent, "refs/cmt-backup/manual"}
+
+	tests := []struct {
+		name      string
+		retention BackupRetention
+		active    string
+		want      []string
+	}{
+		{"keep all", BackupRetention{}, "", nil},
+		{"keep last two", BackupRetention{KeepLast: 2}, "", []string{older, oldest}},
+		{"keep a week", BackupRetention{MaxAge: 7 * day}, "", []string{older, oldest}},
+		{"active backup is never pruned", BackupRetention{KeepLast: 1}, oldest, []string{recent, older}},
+		{"both limits apply", BackupRetention{KeepLast: 3, MaxAge: 5 * day}, "", []string{older, oldest}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectBackupsToPrune(refs, tt.retention, tt.active, now)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCommits(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	commits := []CommitInfo{
+		{SHA: "a1", Author: "Alice Smith", AuthorEmail: "alice@example.com", Date: day(1)},
+		{SHA: "b2", Author: "Bob Jones", AuthorEmail: "bob@example.com", Date: day(5)},
+		{SHA: "a3", Author: "Alice Smith", AuthorEmail: "alice@example.com", Date: day(10)},
+	}
+
+	tests := []struct {
+		name   string
+		filter CommitFilter
+		want   []string
+	}{
+		{"no filter keeps all", CommitFilter{}, []string{"a1", "b2", "a3"}},
+		{"author by name ignores case", CommitFilter{Author: "alice"}, []string{"a1", "a3"}},
+		{"author by email", CommitFilter{Author: "bob@example.com"}, []string{"b2"}},
+		{"since is inclusive", CommitFilter{Since: day(5)}, []string{"b2", "a3"}},
+		{"author and since", CommitFilter{Author: "Alice", Since: day(2)}, []string{"a3"}},
+		{"no match", CommitFilter{Author: "carol"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range FilterCommits(commits, tt.filter) {
+				got = append(got, c.SHA)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("FilterCommits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "2024-03-01", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-03-01T08:30:00Z", want: time.Date(2024, 3, 1, 8, 30, 0, 0, time.UTC)},
+		{value: "7d", want: now.Add(-7 * 24 * time.Hour)},
+		{value: "36h", want: now.Add(-36 * time.Hour)},
+		{value: "last tuesday", wantErr: true},
+		{value: "-3d", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSince(tt.value, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseSince(%q) = %v, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSince(%q) error = %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseSince(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestPublishedCommits(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	remote := t.TempDir()
+	runGit(t, remote, "init", "-q", "--bare")
+	runGit(t, repo.Path, "remote", "add", "origin", remote)
+	runGit(t, repo.Path, "push", "-q", "origin", "main")
+	pushed := runGit(t, repo.Path, "rev-parse", "HEAD")
+
+	writeFile(t, repo.Path, "local.txt", "local\n")
+	runGit(t, repo.Path, "add", "local.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "local commit")
+	local := runGit(t, repo.Path, "rev-parse", "HEAD")
+
+	published, err := repo.PublishedCommits(ctx, []CommitInfo{{SHA: pushed}, {SHA: local}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(published) != 1 || published[0].SHA != pushed {
+		t.Errorf("PublishedCommits() = %v, want only %s", published, pushed)
+	}
+}
+
+func TestStashPopSHA(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "README.md", "# first\n")
+	first, err := repo.Stash(ctx, "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo.Path, "other.txt", "second\n")
+	if _, err := repo.Stash(ctx, "second"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The older stash is popped even though another is on top.
+	popped, err := repo.StashPopSHA(ctx, first)
+	if err != nil || !popped {
+		t.Fatalf("StashPopSHA() = %v, %v; want true, nil", popped, err)
+	}
+	if got := runGit(t, repo.Path, "show", ":README.md"); got != "# test" {
+		t.Errorf("index README.md = %q, want it unchanged", got)
+	}
+	if got := runGit(t, repo.Path, "stash", "list", "--format=%s"); !strings.Contains(got, "second") || strings.Contains(got, "first") {
+		t.Errorf("stash list = %q, want only the second stash", got)
+	}
+
+	// A second pop of the same stash is a no-op.
+	popped, err = repo.StashPopSHA(ctx, first)
+	if err != nil || popped {
+		t.Errorf("second StashPopSHA() = %v, %v; want false, nil", popped, err)
+	}
+}
+
+func TestStashPopSHAKeepsStagedChangesStaged(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "README.md", "# staged\n")
+	runGit(t, repo.Path, "add", "README.md")
+	writeFile(t, repo.Path, "notes.txt", "untracked\n")
+	sha, err := repo.Stash(ctx, "leftovers")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if popped, err := repo.StashPopSHA(ctx, sha); err != nil || !popped {
+		t.Fatalf("StashPopSHA() = %v, %v; want true, nil", popped, err)
+	}
+	if got := runGit(t, repo.Path, "show", ":README.md"); got != "# staged" {
+		t.Errorf("index README.md = %q, want the staged change restored", got)
+	}
+	if got := runGit(t, repo.Path, "status", "--porcelain"); got != "M  README.md\n?? notes.txt" {
+		t.Errorf("status = %q, want README.md staged and notes.txt untracked", got)
+	}
+}
+
+func TestAutosquashRebaseDoesNotStash(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	base := runGit(t, repo.Path, "rev-parse", "HEAD")
+	writeFile(t, repo.Path, "a.txt", "a\n")
+	runGit(t, repo.Path, "add", "a.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "add a")
+	writeFile(t, repo.Path, "a.txt", "a\nfix\n")
+	runGit(t, repo.Path, "commit", "-q", "-am", "fixup! add a")
+
+	// Leftovers must be stashed by the caller, where undo can find them; the
+	// rebase itself refuses rather than autostashing them.
+	writeFile(t, repo.Path, "README.md", "# staged\n")
+	runGit(t, repo.Path, "add", "README.md")
+	if err := repo.AutosquashRebase(ctx, base); err == nil {
+		t.Fatal("expected the rebase to refuse a dirty index")
+	}
+	if got := runGit(t, repo.Path, "stash", "list"); got != "" {
+		t.Errorf("expected no stash, got %q", got)
+	}
+
+	runGit(t, repo.Path, "reset", "-q", "--hard")
+	if err := repo.AutosquashRebase(ctx, base); err != nil {
+		t.Fatal(err)
+	}
+	if got := runGit(t, repo.Path, "log", "--format=%s", base+"..HEAD"); got != "add a" {
+		t.Errorf("commits after rebase = %q, want the fixup squashed into %q", got, "add a")
+	}
+}
+
+func TestUndoAbsorbRestoresStash(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	head := runGit(t, repo.Path, "rev-parse", "HEAD")
+	backupRef, err := repo.CreateBackupRef(ctx, "absorb-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Absorb stashes unstaged work before the rebase...
+	writeFile(t, repo.Path, "README.md", "# work in progress\n")
+	stashSHA, err := repo.Stash(ctx, "cmt absorb: uncommitted changes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := &AbsorbState{
+		OriginalHEAD:  head,
+		BackupRef:     backupRef,
+		CurrentBranch: "main",
+		Timestamp:     time.Now().Unix(),
+		StashSHA:      stashSHA,
+	}
+	if err := SaveAbsorbState(repo, state); err != nil {
+		t.Fatal(err)
+	}
+
+	// ...and rewrites history.
+	writeFile(t, repo.Path, "fix.txt", "fix\n")
+	runGit(t, repo.Path, "add", "fix.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "fixup! initial commit")
+
+	if err := repo.UndoAbsorb(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runGit(t, repo.Path, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD = %s, want %s", got, head)
+	}
+	if got := runGit(t, repo.Path, "diff", "--", "README.md"); !strings.Contains(got, "+# work in progress") {
+		t.Errorf("expected the unstaged change to be restored, got diff:\n%s", got)
+	}
+	if got := runGit(t, repo.Path, "stash", "list"); got != "" {
+		t.Errorf("expected the stash to be dropped, got %q", got)
+	}
+}
+
+// fixupTestRepo creates commits for a.txt and b.txt, stages a change to each
+// and returns the two commit SHAs with the staged hunks grouped by them.
+func fixupTestRepo(t *testing.T) (*Repository, []string, map[string][]Hunk) {
+	t.Helper()
+	repo := newTestRepo(t)
+
+	writeFile(t, repo.Path, "a.txt", "a1\na2\na3\n")
+	runGit(t, repo.Path, "add", "a.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "add a")
+	shaA := runGit(t, repo.Path, "rev-parse", "HEAD")
+
+	writeFile(t, repo.Path, "b.txt", "b1\nb2\nb3\n")
+	runGit(t, repo.Path, "add", "b.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "add b")
+	shaB := runGit(t, repo.Path, "rev-parse", "HEAD")
+
+	writeFile(t, repo.Path, "a.txt", "a1\nA2\na3\n")
+	writeFile(t, repo.Path, "b.txt", "b1\nB2\nb3\n")
+	runGit(t, repo.Path, "add", "a.txt", "b.txt")
+
+	diff, err := repo.GetDiff(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunks, err := SplitDiffIntoHunks(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	groups := make(map[string][]Hunk)
+	for _, h := range hunks {
+		if h.FilePath == "a.txt" {
+			groups[shaA] = append(groups[shaA], h)
+		} else {
+			groups[shaB] = append(groups[shaB], h)
+		}
+	}
+	return repo, []string{shaA, shaB}, groups
+}
+
+func TestApplyFixups(t *testing.T) {
+	repo, targets, groups := fixupTestRepo(t)
+
+	if err := repo.ApplyFixups(context.Background(), targets, groups); err != nil {
+		t.Fatal(err)
+	}
+	subjects := runGit(t, repo.Path, "log", "-2", "--format=%s")
+	if subjects != "fixup! add b\nfixup! add a" {
+		t.Errorf("expected a fixup per target, got:\n%s", subjects)
+	}
+	// Each fixup holds only its own hunk, and nothing is left staged.
+	if files := runGit(t, repo.Path, "show", "--name-only", "--format=", "HEAD~1"); files != "a.txt" {
+		t.Errorf("fixup for a touched %q, want only a.txt", files)
+	}
+	if staged := runGit(t, repo.Path, "diff", "--cached"); staged != "" {
+		t.Errorf("expected everything to be absorbed, still staged:\n%s", staged)
+	}
+}
+
+func TestApplyFixupsChecksBeforeChanging(t *testing.T) {
+	repo, targets, groups := fixupTestRepo(t)
+	head := runGit(t, repo.Path, "rev-parse", "HEAD")
+	staged := runGit(t, repo.Path, "diff", "--cached")
+
+	// A hunk whose context no longer matches HEAD.
+	bad := groups[targets[1]][0]
+	bad.Content = strings.Replace(bad.Content, " b1", " zz", 1)
+	groups[targets[1]] = []Hunk{bad}
+
+	err := repo.ApplyFixups(context.Background(), targets, groups)
+	if err == nil || !strings.Contains(err.Error(), "does not apply") {
+		t.Fatalf("expected the check to fail, got %v", err)
+	}
+	if got := runGit(t, repo.Path, "rev-parse", "HEAD"); got != head {
+		t.Error("expected no fixup commit after a failed check")
+	}
+	if got := runGit(t, repo.Path, "diff", "--cached"); got != staged {
+		t.Errorf("expected the staged changes to be untouched, got:\n%s", got)
+	}
+}
+
+func TestApplyFixupsRollsBack(t *testing.T) {
+	repo, targets, groups := fixupTestRepo(t)
+	head := runGit(t, repo.Path, "rev-parse", "HEAD")
+	staged := runGit(t, repo.Path, "diff", "--cached")
+
+	// Let the first fixup through and reject the second.
+	hook := "#!/bin/sh\ncase \"$(git log -1 --format=%s)\" in fixup!*) exit 1;; esac\n"
+	writeFile(t, repo.Path, ".git/hooks/pre-commit", hook)
+	if err := os.Chmod(filepath.Join(repo.Path, ".git/hooks/pre-commit"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	err := repo.ApplyFixups(context.Background(), targets, groups)
+	if err == nil || !strings.Contains(err.Error(), "no fixups were kept") {
+		t.Fatalf("expected the second fixup to fail and be rolled back, got %v", err)
+	}
+	if got := runGit(t, repo.Path, "rev-parse", "HEAD"); got != head {
+		t.Errorf("HEAD = %s, want the first fixup dropped (%s)", got, head)
+	}
+	if got := runGit(t, repo.Path, "diff", "--cached"); got != staged {
+		t.Errorf("expected the staged changes to be restored, got:\n%s", got)
+	}
+	if got := runGit(t, repo.Path, "diff"); got != "" {
+		t.Errorf("expected no unstaged changes after the rollback, got:\n%s", got)
+	}
+}
+
+func TestScopedAbsorbHunks(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "api/handler.go", "package api\n\nfunc Handle() {}\n")
+	writeFile(t, repo.Path, "web/app.js", "export const app = 1;\n")
+	runGit(t, repo.Path, "add", ".")
+	runGit(t, repo.Path, "commit", "-q", "-m", "add api and web")
+
+	writeFile(t, repo.Path, "api/handler.go", "package api\n\n// Handle serves requests.\nfunc Handle() {}\n")
+	writeFile(t, repo.Path, "web/app.js", "export const app = 2;\n")
+	runGit(t, repo.Path, "add", ".")
+
+	diff, err := repo.GetDiffForFiles(ctx, true, []string{"api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunks, err := SplitDiffIntoHunks(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hunks) != 1 || hunks[0].FilePath != "api/handler.go" {
+		t.Fatalf("expected only the api hunk, got %+v", hunks)
+	}
+
+	// Absorbing the scoped hunk leaves the other directory staged.
+	target := runGit(t, repo.Path, "rev-parse", "HEAD")
+	if err := repo.ApplyFixups(ctx, []string{target}, map[string][]Hunk{target: hunks}); err != nil {
+		t.Fatal(err)
+	}
+	if files := runGit(t, repo.Path, "show", "--name-only", "--format=", "HEAD"); files != "api/handler.go" {
+		t.Errorf("fixup touched %q, want only api/handler.go", files)
+	}
+	if staged := runGit(t, repo.Path, "diff", "--cached", "--name-only"); staged != "web/app.js" {
+		t.Errorf("expected web/app.js to stay staged, got %q", staged)
+	}
+
+	// No pathspec means the whole staged diff.
+	all, err := repo.GetDiffForFiles(ctx, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(all, "web/app.js") {
+		t.Errorf("expected the unscoped diff to include web/app.js, got:\n%s", all)
+	}
+}
+
+func TestCommitHunks(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	var lines []string
+	for i := 1; i <= 20; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	writeFile(t, repo.Path, "list.txt", strings.Join(lines, "\n")+"\n")
+	runGit(t, repo.Path, "add", "list.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "add list")
+
+	// Two hunks in one file, the first adding lines so the second moves,
+	// and a third in another file.
+	changed := append([]string{"line 1", "new a", "new b"}, lines[1:]...)
+	changed[len(changed)-1] = "line twenty"
+	writeFile(t, repo.Path, "list.txt", strings.Join(changed, "\n")+"\n")
+	writeFile(t, repo.Path, "other.txt", "other\n")
+	runGit(t, repo.Path, "add", ".")
+	staged := runGit(t, repo.Path, "diff", "--cached", "--stat")
+
+	diff, err := repo.GetDiff(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hunks, err := SplitDiffIntoHunks(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hunks) != 3 {
+		t.Fatalf("expected 3 hunks, got %d", len(hunks))
+	}
+
+	if err := repo.CommitHunks(ctx, hunks[:1], "feat: add new lines"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CommitHunks(ctx, hunks[1:2], "fix: spell out twenty"); err != nil {
+		t.Fatal(err)
+	}
+
+	if subjects := runGit(t, repo.Path, "log", "-2", "--format=%s"); subjects != "fix: spell out twenty\nfeat: add new lines" {
+		t.Errorf("expected a commit per group, got:\n%s", subjects)
+	}
+	if got := runGit(t, repo.Path, "show", "HEAD~1:list.txt"); strings.Contains(got, "twenty") || !strings.Contains(got, "new a") {
+		t.Errorf("first commit should hold only the first hunk, got:\n%s", got)
+	}
+	if got := runGit(t, repo.Path, "diff", "--cached", "--name-only"); got != "other.txt" {
+		t.Errorf("expected only other.txt to stay staged, got %q (was %s)", got, staged)
+	}
+	if got := runGit(t, repo.Path, "diff"); got != "" {
+		t.Errorf("expected the working tree to be untouched, got:\n%s", got)
+	}
+}
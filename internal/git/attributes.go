@@ -0,0 +1,104 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// emptyTree is the hash of git's empty tree, to diff the index against.
+const emptyTree = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// IsBinary reports whether git treats a file in the index as binary. The
+// binary, diff and text attributes from .gitattributes decide when they are
+// set; otherwise git's own content check does, as git diff --numstat
+// reports it. A file that isn't in the index is not binary.
+func (r *Repository) IsBinary(ctx context.Context, file string) (bool, error) {
+	classes, err := r.classifyBinary(ctx, "--cached", emptyTree, "--", file)
+	if err != nil {
+		return false, err
+	}
+	return classes[file], nil
+}
+
+// StagedBinaryFiles classifies every staged file, other than deletions, as
+// binary (true) or text (false) the way IsBinary does.
+func (r *Repository) StagedBinaryFiles(ctx context.Context) (map[string]bool, error) {
+	return r.classifyBinary(ctx, "--cached", "--diff-filter=d")
+}
+
+// classifyBinary runs git diff --numstat with args and classifies the files
+// it lists, letting their attributes override the numstat verdict.
+func (r *Repository) classifyBinary(ctx context.Context, args ...string) (map[string]bool, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"diff", "--numstat", "--no-renames", "-z"}, args...)...)
+	cmd.Dir = r.Path
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read diff stats: %w", err)
+	}
+
+	// Each entry is "<added>\t<deleted>\t<path>", NUL-terminated; binary
+	// files have "-" for both counts.
+	classes := make(map[string]bool)
+	var files []string
+	for _, entry := range strings.Split(string(output), "\x00") {
+		fields := strings.SplitN(entry, "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		classes[fields[2]] = fields[0] == "-" && fields[1] == "-"
+		files = append(files, fields[2])
+	}
+	if len(files) == 0 {
+		return classes, nil
+	}
+
+	attributes, err := r.binaryAttributes(ctx, files)
+	if err != nil {
+		return nil, err
+	}
+	for file, attrs := range attributes {
+		if binary, ok := binaryFromAttributes(attrs); ok {
+			classes[file] = binary
+		}
+	}
+	return classes, nil
+}
+
+// binaryAttributes returns the binary, diff and text attributes of files,
+// keyed by path and then attribute name.
+func (r *Repository) binaryAttributes(ctx context.Context, files []string) (map[string]map[string]string, error) {
+	cmd := exec.CommandContext(ctx, "git", "check-attr", "-z", "--stdin", "binary", "diff", "text")
+	cmd.Dir = r.Path
+	cmd.Stdin = strings.NewReader(strings.Join(files, "\x00") + "\x00")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read git attributes: %w", err)
+	}
+
+	// The output is "<path> NUL <attribute> NUL <value> NUL" per attribute.
+	attributes := make(map[string]map[string]string)
+	fields := strings.Split(string(output), "\x00")
+	for i := 0; i+2 < len(fields); i += 3 {
+		file, attr, value := fields[i], fields[i+1], fields[i+2]
+		if attributes[file] == nil {
+			attributes[file] = make(map[string]string)
+		}
+		attributes[file][attr] = value
+	}
+	return attributes, nil
+}
+
+// binaryFromAttributes decides whether a file is binary from its
+// attributes: "binary" or "-diff" make it binary, "text" or "diff" make it
+// text. ok is false when none of them is set either way.
+func binaryFromAttributes(attrs map[string]string) (binary, ok bool) {
+	switch {
+	case attrs["binary"] == "set", attrs["diff"] == "unset":
+		return true, true
+	case attrs["text"] == "set", attrs["diff"] == "set":
+		return false, true
+	}
+	return false, false
+}
@@ -0,0 +1,52 @@
+package git
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIsBinary(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, ".gitattributes", "*.dat text\n*.lock -diff\n")
+	writeFile(t, repo.Path, "firmware", "\x7fELF\x00\x01\x02\x00")
+	writeFile(t, repo.Path, "table.dat", "id,name\n1,alice\n")
+	writeFile(t, repo.Path, "yarn.lock", "left-pad@1.0.0\n")
+	writeFile(t, repo.Path, "logo.svg", "<svg></svg>\n")
+	runGit(t, repo.Path, "add", "-A")
+
+	expected := map[string]bool{
+		"firmware":       true,  // no extension, detected from the content
+		"table.dat":      false, // marked text
+		"yarn.lock":      true,  // text, but marked -diff
+		"logo.svg":       false, // text despite an extension usually binary
+		".gitattributes": false,
+	}
+	for file, want := range expected {
+		got, err := repo.IsBinary(ctx, file)
+		if err != nil || got != want {
+			t.Errorf("IsBinary(%s) = %v, %v; expected %v", file, got, err, want)
+		}
+	}
+
+	// Committed files stay in the index, so they are still classified
+	runGit(t, repo.Path, "commit", "-q", "-m", "add files")
+	if binary, err := repo.IsBinary(ctx, "firmware"); err != nil || !binary {
+		t.Errorf("IsBinary(committed firmware) = %v, %v; expected true", binary, err)
+	}
+	if binary, err := repo.IsBinary(ctx, "missing"); err != nil || binary {
+		t.Errorf("IsBinary(missing) = %v, %v; expected false", binary, err)
+	}
+
+	writeFile(t, repo.Path, "table.dat", "id,name\n1,bob\n")
+	writeFile(t, repo.Path, "firmware", "\x7fELF\x00\x01\x03\x00")
+	runGit(t, repo.Path, "add", "-A")
+	staged, err := repo.StagedBinaryFiles(ctx)
+	if err != nil {
+		t.Fatalf("StagedBinaryFiles failed: %v", err)
+	}
+	if len(staged) != 2 || !staged["firmware"] || staged["table.dat"] {
+		t.Errorf("StagedBinaryFiles() = %v, expected firmware binary and table.dat text", staged)
+	}
+}
@@ -0,0 +1,80 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// GitConfigGet returns the value of a git config key as git resolves it for
+// the repository (local, then global and system config). An unset key is
+// not an error: it returns "".
+func (r *Repository) GitConfigGet(ctx context.Context, key string) (string, error) {
+	return r.gitConfigGet(ctx, key)
+}
+
+// GitConfigGetBool returns a boolean git config key, accepting every
+// spelling git does ("true", "yes", "on", "1" and their opposites). An unset
+// key returns false.
+func (r *Repository) GitConfigGetBool(ctx context.Context, key string) (bool, error) {
+	value, err := r.gitConfigGet(ctx, key, "--type=bool")
+	if err != nil {
+		return false, err
+	}
+	return value == "true", nil
+}
+
+// gitConfigGet runs git config --get with extra options. Exit status 1
+// without a message means the key is unset; anything else is a real
+// failure, such as an invalid key or a value that isn't of the requested
+// type.
+func (r *Repository) gitConfigGet(ctx context.Context, key string, options ...string) (string, error) {
+	args := append([]string{"config"}, options...)
+	args = append(args, "--get", key)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.Path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return "", nil
+		}
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("git config %s failed: %s", key, strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("git config %s failed: %w", key, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// gitConfig returns the value of a git config key, or "" if it is unset or
+// can't be read. It is for settings whose absence and breakage are handled
+// the same way.
+func (r *Repository) gitConfig(ctx context.Context, key string) string {
+	value, _ := r.gitConfigGet(ctx, key)
+	return value
+}
+
+// AuthorEmail returns the email the next commit will be authored with, as
+// git resolves it from GIT_AUTHOR_EMAIL, user.email and the defaults.
+func (r *Repository) AuthorEmail(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "var", "GIT_AUTHOR_IDENT")
+	cmd.Dir = r.Path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the author identity: %w", err)
+	}
+
+	// The ident is "Name <email> timestamp zone".
+	ident := string(output)
+	start, end := strings.Index(ident, "<"), strings.LastIndex(ident, ">")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("unexpected author identity %q", strings.TrimSpace(ident))
+	}
+	return ident[start+1 : end], nil
+}
@@ -0,0 +1,79 @@
+package git
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+func TestGitConfigGet(t *testing.T) {
+	// Keep the user's global config out of the test.
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	runGit(t, repo.Path, "config", "cmt.example", "some value")
+	value, err := repo.GitConfigGet(ctx, "cmt.example")
+	if err != nil || value != "some value" {
+		t.Errorf("GitConfigGet(set key) = %q, %v; expected %q", value, err, "some value")
+	}
+
+	value, err = repo.GitConfigGet(ctx, "cmt.unset")
+	if err != nil || value != "" {
+		t.Errorf("GitConfigGet(unset key) = %q, %v; expected an empty value and no error", value, err)
+	}
+
+	if _, err := repo.GitConfigGet(ctx, "not-a-valid-key"); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+func TestGitConfigGetBool(t *testing.T) {
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		value    string
+		expected bool
+	}{
+		{"true", true},
+		{"yes", true},
+		{"on", true},
+		{"1", true},
+		{"false", false},
+		{"no", false},
+		{"0", false},
+	}
+	for _, tt := range tests {
+		runGit(t, repo.Path, "config", "cmt.flag", tt.value)
+		got, err := repo.GitConfigGetBool(ctx, "cmt.flag")
+		if err != nil || got != tt.expected {
+			t.Errorf("GitConfigGetBool(%q) = %v, %v; expected %v", tt.value, got, err, tt.expected)
+		}
+	}
+
+	if got, err := repo.GitConfigGetBool(ctx, "cmt.unset"); err != nil || got {
+		t.Errorf("GitConfigGetBool(unset key) = %v, %v; expected false and no error", got, err)
+	}
+
+	runGit(t, repo.Path, "config", "cmt.flag", "maybe")
+	if _, err := repo.GitConfigGetBool(ctx, "cmt.flag"); err == nil {
+		t.Error("expected an error for a value that isn't a boolean")
+	}
+}
+
+func TestAuthorEmail(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	email, err := repo.AuthorEmail(ctx)
+	if err != nil || email != "test@example.com" {
+		t.Errorf("AuthorEmail() = %q, %v; expected the configured user.email", email, err)
+	}
+
+	t.Setenv("GIT_AUTHOR_EMAIL", "pair@example.com")
+	if email, _ := repo.AuthorEmail(ctx); email != "pair@example.com" {
+		t.Errorf("AuthorEmail() = %q; expected GIT_AUTHOR_EMAIL to win", email)
+	}
+}
@@ -3,10 +3,15 @@ package git
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -14,15 +19,47 @@ import (
 // Repository represents a git repository.
 type Repository struct {
 	Path string
+	// MaxDiffBytes caps the size of diffs read by GetDiff.
+	// Zero means DefaultMaxDiffBytes.
+	MaxDiffBytes int64
+	// BaseBranch overrides the default branch GetDefaultBranch detects.
+	BaseBranch string
+	// IgnoreWhitespace leaves whitespace-only changes out of the diffs
+	// GetDiff and ReadStagedChanges read (git diff --ignore-all-space).
+	// Such diffs describe the changes; they can't be applied.
+	IgnoreWhitespace bool
 }
 
 // FileStatus represents the status of a file in git.
 type FileStatus struct {
 	Path     string
+	OldPath  string // Previous path for renames and copies.
 	Status   string // M=modified, A=added, D=deleted, R=renamed, C=copied, U=untracked
 	IsStaged bool
 }
 
+// String formats the status as "M path", or "R old -> new" for renames.
+func (f FileStatus) String() string {
+	if f.OldPath != "" {
+		return fmt.Sprintf("%s %s -> %s", f.Status, f.OldPath, f.Path)
+	}
+	return fmt.Sprintf("%s %s", f.Status, f.Path)
+}
+
+// DeletionOnly reports whether files, as GetStagedFilesWithStatus returns
+// them, are all deletions: a commit that only removes files.
+func DeletionOnly(files []FileStatus) bool {
+	if len(files) == 0 {
+		return false
+	}
+	for _, f := range files {
+		if f.Status != "D" {
+			return false
+		}
+	}
+	return true
+}
+
 // NewRepository creates a new Repository instance.
 func NewRepository(path string) (*Repository, error) {
 	if path == "" {
@@ -67,38 +104,261 @@ func (r *Repository) GetRootPath() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// GetCommonDir returns the git directory shared by all worktrees of the
+// repository. Unlike <root>/.git it is a directory in worktrees and
+// submodules too.
+func (r *Repository) GetCommonDir() (string, error) {
+	cmd := exec.Command("git", "rev-parse", "--git-common-dir")
+	cmd.Dir = r.Path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get git directory: %w", err)
+	}
+	dir := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.Path, dir)
+	}
+	return dir, nil
+}
+
+// DefaultMaxDiffBytes is the diff size cap used when Repository.MaxDiffBytes is unset.
+const DefaultMaxDiffBytes = 50 << 20
+
+// DiffTooLargeError is returned when a diff exceeds the configured size cap.
+type DiffTooLargeError struct {
+	Limit int64
+}
+
+func (e *DiffTooLargeError) Error() string {
+	// Small limits would round down to "0 MiB"
+	limit := fmt.Sprintf("%d MiB", e.Limit>>20)
+	switch {
+	case e.Limit < 1<<10:
+		limit = fmt.Sprintf("%d bytes", e.Limit)
+	case e.Limit < 1<<20:
+		limit = fmt.Sprintf("%d KiB", e.Limit>>10)
+	}
+	return fmt.Sprintf("diff exceeds %s; stage fewer files or raise max_diff_bytes", limit)
+}
+
+// diffArgs are the options every content diff is read with.
+var diffArgs = []string{
+	"--no-color",    // No color codes
+	"--no-ext-diff", // Don't use external diff tools
+	"--unified=3",   // 3 lines of context
+}
+
+// diffOptions returns the options content diffs are read with, adding
+// --ignore-all-space when IgnoreWhitespace is set.
+func (r *Repository) diffOptions() []string {
+	if !r.IgnoreWhitespace {
+		return diffArgs
+	}
+	return append(append([]string(nil), diffArgs...), "--ignore-all-space")
+}
+
 // GetDiff returns the diff of staged changes.
+// The output is read through a bounded reader; a *DiffTooLargeError is
+// returned instead of buffering diffs larger than MaxDiffBytes.
 func (r *Repository) GetDiff(ctx context.Context, staged bool) (string, error) {
 	args := []string{"diff"}
 
 	if staged {
 		args = append(args, "--cached")
 	}
+	args = append(args, r.diffOptions()...)
+
+	return r.readDiff(ctx, args)
+}
+
+// GetDiffForFiles returns the diff like GetDiff, limited to the files that
+// match paths. Paths are git pathspecs, relative to the repository path.
+// No paths means no limit.
+func (r *Repository) GetDiffForFiles(ctx context.Context, staged bool, paths []string) (string, error) {
+	args := []string{"diff"}
+
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, r.diffOptions()...)
+	args = append(append(args, "--"), paths...)
+
+	return r.readDiff(ctx, args)
+}
+
+// StagedChanges is the staged diff together with the staged files and the
+// StagedDiffHash, all read from a single git diff so that they describe the
+// same index state.
+type StagedChanges struct {
+	Diff  string       // as GetDiff(ctx, true) returns it
+	Files []FileStatus // as GetStagedFilesWithStatus returns them
+	Hash  string       // as StagedDiffHash returns it
+	// WhitespaceOnly is set when files are staged but IgnoreWhitespace left
+	// nothing of their diff: every change is to whitespace.
+	WhitespaceOnly bool
+}
+
+// ReadStagedChanges reads the staged changes with one git diff. The raw
+// file list git prints ahead of the patch gives the files and the hash.
+// The size cap of GetDiff applies.
+func (r *Repository) ReadStagedChanges(ctx context.Context) (*StagedChanges, error) {
+	args := append([]string{"diff", "--cached", "--patch-with-raw", "--no-abbrev", "-z"}, r.diffOptions()...)
+	output, err := r.readDiff(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// With -z the raw entries are NUL-terminated, and an empty entry
+	// separates them from the patch, which -z leaves as it is.
+	raw := output
+	patch := ""
+	if i := strings.Index(output, "\x00\x00"); i >= 0 {
+		raw, patch = output[:i+1], output[i+2:]
+	}
 
-	// Add options for better diff output
-	args = append(args,
-		"--no-color",    // No color codes
-		"--no-ext-diff", // Don't use external diff tools
-		"--unified=3",   // 3 lines of context
-	)
+	// The raw entries list every staged file, whitespace-only ones included,
+	// so the hash still notices a changed index
+	hash := sha256.Sum256([]byte(raw))
+	files := parseRawStatus(raw)
+	return &StagedChanges{
+		Diff:           patch,
+		Files:          files,
+		Hash:           hex.EncodeToString(hash[:]),
+		WhitespaceOnly: r.IgnoreWhitespace && len(files) > 0 && strings.TrimSpace(patch) == "",
+	}, nil
+}
+
+// readDiff runs a git diff command and returns its output, capped at
+// MaxDiffBytes.
+func (r *Repository) readDiff(ctx context.Context, args []string) (string, error) {
+	limit := r.MaxDiffBytes
+	if limit <= 0 {
+		limit = DefaultMaxDiffBytes
+	}
+
+	// Cancelled when the cap is hit, to stop git early.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Dir = r.Path
 
-	output, err := cmd.Output()
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("git diff failed: %s", exitErr.Stderr)
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+
+	// Read one byte past the cap to tell "exactly at" from "over".
+	output, readErr := io.ReadAll(io.LimitReader(stdout, limit+1))
+	if int64(len(output)) > limit {
+		cancel()
+		cmd.Wait()
+		return "", &DiffTooLargeError{Limit: limit}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("git diff failed: %s", strings.TrimSpace(stderr.String()))
 		}
 		return "", fmt.Errorf("git diff failed: %w", err)
 	}
+	if readErr != nil {
+		return "", fmt.Errorf("failed to read git diff: %w", readErr)
+	}
 
 	return string(output), nil
 }
 
+// GetDiffStat returns the `git diff --cached --stat` summary of the staged
+// changes. Unlike the diff it stays short for binary files.
+func (r *Repository) GetDiffStat(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--stat", "--no-color")
+	cmd.Dir = r.Path
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git diff --stat failed: %w", err)
+	}
+	return strings.TrimRight(string(output), "\n"), nil
+}
+
+// StagedDiffHash returns a SHA-256 hash of the staged changes: the raw
+// diff, which names every staged file with its mode and full blob IDs, so
+// binary content counts too. Equal hashes mean the index hasn't changed.
+func (r *Repository) StagedDiffHash(ctx context.Context) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--raw", "--no-abbrev", "-z", "--no-color", "--no-ext-diff")
+	cmd.Dir = r.Path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	hash := sha256.New()
+	cmd.Stdout = hash
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("git diff failed: %s", strings.TrimSpace(stderr.String()))
+		}
+		return "", fmt.Errorf("git diff failed: %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// StagedFileSizes returns the size in bytes of each staged file as it is in
+// the index, keyed by path. Deleted files are left out. Sizes come from the
+// object database, so the blobs themselves are not read.
+func (r *Repository) StagedFileSizes(ctx context.Context) (map[string]int64, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--raw", "--no-abbrev", "--no-renames", "--diff-filter=d", "-z")
+	cmd.Dir = r.Path
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %w", err)
+	}
+
+	// Each entry is ":<modes> <old sha> <new sha> <status>" and the path,
+	// NUL-terminated.
+	var paths, blobs []string
+	fields := strings.Split(string(output), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		meta := strings.Fields(fields[i])
+		if len(meta) < 4 || meta[3] == strings.Repeat("0", len(meta[3])) {
+			continue // submodules and other entries without a blob
+		}
+		paths = append(paths, fields[i+1])
+		blobs = append(blobs, meta[3])
+	}
+	sizes := make(map[string]int64, len(paths))
+	if len(blobs) == 0 {
+		return sizes, nil
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "cat-file", "--batch-check=%(objectsize)")
+	cmd.Dir = r.Path
+	cmd.Stdin = strings.NewReader(strings.Join(blobs, "\n") + "\n")
+	output, err = cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read staged file sizes: %w", err)
+	}
+	for i, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if i >= len(paths) {
+			break
+		}
+		if size, err := strconv.ParseInt(line, 10, 64); err == nil {
+			sizes[paths[i]] = size
+		}
+	}
+	return sizes, nil
+}
+
 // GetStatus returns the status of files in the repository.
 func (r *Repository) GetStatus(ctx context.Context) ([]FileStatus, error) {
-	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain", "-uall")
+	cmd := exec.CommandContext(ctx, "git", "status", "--porcelain", "-z", "-uall")
 	cmd.Dir = r.Path
 
 	output, err := cmd.Output()
@@ -107,28 +367,23 @@ func (r *Repository) GetStatus(ctx context.Context) ([]FileStatus, error) {
 	}
 
 	var files []FileStatus
-	lines := strings.Split(string(output), "\n")
+	entries := splitNUL(string(output))
 
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
 
-		// Parse status line (format: "XY filename")
-		if len(line) < 3 {
+		// Parse status entry (format: "XY filename")
+		if len(entry) < 3 {
 			continue
 		}
 
-		stagedStatus := line[0]
-		unstagedStatus := line[1]
-		filename := strings.TrimSpace(line[3:])
+		stagedStatus := entry[0]
+		unstagedStatus := entry[1]
+		filename := entry[3:]
 
-		// Handle renamed files (format: "R  old -> new")
-		if strings.Contains(filename, " -> ") {
-			parts := strings.Split(filename, " -> ")
-			if len(parts) == 2 {
-				filename = parts[1]
-			}
+		// Renamed and copied files are followed by their original path
+		if stagedStatus == 'R' || stagedStatus == 'C' || unstagedStatus == 'R' || unstagedStatus == 'C' {
+			i++
 		}
 
 		// Determine if file is staged
@@ -156,7 +411,7 @@ func (r *Repository) GetStatus(ctx context.Context) ([]FileStatus, error) {
 
 // GetStagedFiles returns a list of staged file paths.
 func (r *Repository) GetStagedFiles(ctx context.Context) ([]string, error) {
-	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--name-only")
+	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--name-only", "-z")
 	cmd.Dir = r.Path
 
 	output, err := cmd.Output()
@@ -164,19 +419,83 @@ func (r *Repository) GetStagedFiles(ctx context.Context) ([]string, error) {
 		return nil, fmt.Errorf("failed to get staged files: %w", err)
 	}
 
-	if len(output) == 0 {
+	files := splitNUL(string(output))
+	if files == nil {
 		return []string{}, nil
 	}
+	return files, nil
+}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	var files []string
-	for _, line := range lines {
-		if line != "" {
-			files = append(files, line)
+// GetStagedFilesWithStatus returns the staged files along with how each one
+// changed (added, modified, deleted, renamed...).
+func (r *Repository) GetStagedFilesWithStatus(ctx context.Context) ([]FileStatus, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--name-status", "-z")
+	cmd.Dir = r.Path
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged files: %w", err)
+	}
+
+	return parseNameStatus(string(output)), nil
+}
+
+// parseNameStatus parses `git diff --name-status -z` output.
+// Fields are NUL-terminated: "M", "path", or "R100", "old", "new" for
+// renames and copies. Paths are as they are, never quoted.
+func parseNameStatus(output string) []FileStatus {
+	var files []FileStatus
+	fields := strings.Split(output, "\x00")
+	for i := 0; i < len(fields); i++ {
+		status := fields[i]
+		if status == "" {
+			continue
+		}
+
+		// Drop the similarity score from renames and copies (e.g. R100).
+		file := FileStatus{Status: status[:1], IsStaged: true}
+		if file.Status == "R" || file.Status == "C" {
+			if i+2 >= len(fields) {
+				break
+			}
+			file.OldPath, file.Path = fields[i+1], fields[i+2]
+			i += 2
+		} else {
+			if i+1 >= len(fields) {
+				break
+			}
+			file.Path = fields[i+1]
+			i++
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// parseRawStatus parses `git diff --raw -z` output, where each
+// ":100644 100644 <old> <new> M" is followed by its paths like in
+// parseNameStatus, into staged files.
+func parseRawStatus(output string) []FileStatus {
+	fields := strings.Split(output, "\x00")
+	for i, field := range fields {
+		if strings.HasPrefix(field, ":") {
+			meta := strings.Fields(field)
+			fields[i] = meta[len(meta)-1]
 		}
 	}
+	return parseNameStatus(strings.Join(fields, "\x00"))
+}
 
-	return files, nil
+// splitNUL splits NUL-terminated git output, as printed with -z, into its
+// entries.
+func splitNUL(output string) []string {
+	var entries []string
+	for _, entry := range strings.Split(output, "\x00") {
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
 }
 
 // StageAll stages all changes in the repository.
@@ -191,7 +510,8 @@ func (r *Repository) StageAll(ctx context.Context) error {
 	return nil
 }
 
-// StageUpdated stages all updated files in the repository.
+// StageUpdated stages modifications and deletions of tracked files, leaving
+// untracked files alone (git add -u).
 func (r *Repository) StageUpdated(ctx context.Context) error {
 	cmd := exec.CommandContext(ctx, "git", "add", "-u")
 	cmd.Dir = r.Path
@@ -237,19 +557,85 @@ func (r *Repository) UnstageFiles(ctx context.Context, files []string) error {
 	return nil
 }
 
+// CommitOptions controls optional git commit behavior.
+type CommitOptions struct {
+	// Amend replaces the HEAD commit instead of creating a new one.
+	Amend bool
+	// NoEdit keeps the existing HEAD message when amending.
+	NoEdit bool
+	// MessageOnly amends only the message, leaving staged changes staged
+	// rather than folding them into the commit.
+	MessageOnly bool
+	// AllowEmpty permits a commit that records no changes.
+	AllowEmpty bool
+	// Sign signs the commit with the configured key (git commit -S).
+	Sign bool
+	// SigningKey signs the commit with this GPG key ID or SSH key, and
+	// implies Sign.
+	SigningKey string
+}
+
 // Commit creates a commit with the given message.
 func (r *Repository) Commit(ctx context.Context, message string) error {
-	if message == "" {
+	return r.CommitWithOptions(ctx, message, CommitOptions{})
+}
+
+// CommitWithOptions creates a commit with the given message and options.
+// The message may be empty or blank only when amending with NoEdit. It is passed to
+// git on stdin rather than as an argument, so messages of any length and
+// content are recorded faithfully.
+func (r *Repository) CommitWithOptions(ctx context.Context, message string, opts CommitOptions) error {
+	keepMessage := opts.Amend && opts.NoEdit
+	if strings.TrimSpace(message) == "" && !keepMessage {
 		return fmt.Errorf("commit message cannot be empty")
 	}
 
-	cmd := exec.CommandContext(ctx, "git", "commit", "-m", message)
+	// A signed commit may need a passphrase, so stdin stays on the terminal
+	// and the message goes through a temporary file instead.
+	signing := r.willSign(ctx, opts)
+	messageFile := "-"
+	switch {
+	case keepMessage:
+		messageFile = ""
+	case signing:
+		file, err := os.CreateTemp("", "cmt-commit-*.txt")
+		if err != nil {
+			return fmt.Errorf("failed to write commit message: %w", err)
+		}
+		defer os.Remove(file.Name())
+		_, err = file.WriteString(message)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write commit message: %w", err)
+		}
+		messageFile = file.Name()
+	}
+
+	cmd := exec.CommandContext(ctx, "git", commitArgs(opts, messageFile)...)
 	cmd.Dir = r.Path
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
+	switch {
+	case signing:
+		cmd.Stdin = os.Stdin
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		if os.Getenv("GPG_TTY") == "" {
+			if tty := terminalName(); tty != "" {
+				cmd.Env = append(os.Environ(), "GPG_TTY="+tty)
+			}
+		}
+	case messageFile == "-":
+		cmd.Stdin = strings.NewReader(message)
+	}
+
 	if err := cmd.Run(); err != nil {
+		if signing && isSigningFailure(stderr.String()) {
+			return fmt.Errorf("git commit failed: %s\n\n%s", strings.TrimSpace(stderr.String()), signingFailureHelp)
+		}
 		if stderr.Len() > 0 {
 			return fmt.Errorf("git commit failed: %s", stderr.String())
 		}
@@ -259,8 +645,20 @@ func (r *Repository) Commit(ctx context.Context, message string) error {
 	return nil
 }
 
+// ErrDetachedHead is returned by Push when HEAD is not on a branch.
+var ErrDetachedHead = errors.New("HEAD is detached; create a branch before pushing")
+
 // Push pushes commits to the remote repository.
 func (r *Repository) Push(ctx context.Context) error {
+	// A detached HEAD would be pushed as a branch literally named "HEAD"
+	detached, err := r.IsDetachedHead(ctx)
+	if err != nil {
+		return err
+	}
+	if detached {
+		return ErrDetachedHead
+	}
+
 	// Get current branch
 	branch, err := r.GetCurrentBranch(ctx)
 	if err != nil {
@@ -296,6 +694,53 @@ func (r *Repository) GetCurrentBranch(ctx context.Context) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// IsDetachedHead reports whether HEAD points at a commit rather than a
+// branch. GetCurrentBranch returns "HEAD" in that state.
+func (r *Repository) IsDetachedHead(ctx context.Context) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "symbolic-ref", "-q", "HEAD")
+	cmd.Dir = r.Path
+
+	err := cmd.Run()
+	if err == nil {
+		return false, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, fmt.Errorf("failed to check for a detached HEAD: %w", err)
+}
+
+// CreateBranch creates a branch at HEAD and switches to it.
+func (r *Repository) CreateBranch(ctx context.Context, name string) error {
+	cmd := exec.CommandContext(ctx, "git", "checkout", "-b", name)
+	cmd.Dir = r.Path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("failed to create branch %s: %s", name, strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("failed to create branch %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// IsCommitPushed checks if a commit is reachable from any remote-tracking branch.
+func (r *Repository) IsCommitPushed(ctx context.Context, sha string) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "branch", "-r", "--contains", sha)
+	cmd.Dir = r.Path
+
+	output, err := cmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to check if commit is pushed: %w", err)
+	}
+
+	return len(strings.TrimSpace(string(output))) > 0, nil
+}
+
 // HasStagedChanges checks if there are any staged changes.
 func (r *Repository) HasStagedChanges(ctx context.Context) (bool, error) {
 	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--quiet")
@@ -329,6 +774,37 @@ func (r *Repository) GetLastCommitMessage(ctx context.Context) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// GetRecentSubjects returns the subjects of up to n recent commits on HEAD,
+// newest first. Merge and revert commits are skipped since their subjects
+// are generated by git rather than written by hand.
+func (r *Repository) GetRecentSubjects(ctx context.Context, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	// Over-fetch so skipped reverts don't leave us short.
+	cmd := exec.CommandContext(ctx, "git", "log", "--no-merges", "--format=%s", fmt.Sprintf("-n%d", n*3))
+	cmd.Dir = r.Path
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent subjects: %w", err)
+	}
+
+	var subjects []string
+	for _, subject := range strings.Split(string(output), "\n") {
+		subject = strings.TrimSpace(subject)
+		if subject == "" || strings.HasPrefix(subject, "Revert \"") || strings.HasPrefix(subject, "Merge ") {
+			continue
+		}
+		subjects = append(subjects, subject)
+		if len(subjects) == n {
+			break
+		}
+	}
+	return subjects, nil
+}
+
 // GetFileContent returns the content of a file at a specific revision.
 func (r *Repository) GetFileContent(ctx context.Context, path string, revision string) (string, error) {
 	if revision == "" {
@@ -393,9 +869,13 @@ func (r *Repository) CheckHooksExist() (map[string]bool, error) {
 
 // CommitInfo represents information about a git commit.
 type CommitInfo struct {
-	SHA     string
-	Message string
-	Diff    string
+	SHA         string
+	Message     string
+	Diff        string
+	Files       []string  // paths the commit touched, from --name-only
+	Author      string    // author name
+	AuthorEmail string    // author email
+	Date        time.Time // author date
 }
 
 // GetCommitRange returns commits between two refs with their diffs.
@@ -423,13 +903,18 @@ func (r *Repository) GetCommitRange(ctx context.Context, from, to string) ([]Com
 			continue
 		}
 
-		// Get commit message.
-		msgCmd := exec.CommandContext(ctx, "git", "log", "-1", "--pretty=format:%B", sha)
+		// Get author, date and commit message.
+		msgCmd := exec.CommandContext(ctx, "git", "log", "-1", "--pretty=format:%an%x00%ae%x00%aI%x00%B", sha)
 		msgCmd.Dir = r.Path
 		msgOutput, err := msgCmd.Output()
 		if err != nil {
 			return nil, fmt.Errorf("failed to get commit message for %s: %w", sha, err)
 		}
+		fields := strings.SplitN(string(msgOutput), "\x00", 4)
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected git log output for %s", sha)
+		}
+		date, _ := time.Parse(time.RFC3339, fields[2])
 
 		// Get commit diff.
 		diffCmd := exec.CommandContext(ctx, "git", "diff", fmt.Sprintf("%s^", sha), sha)
@@ -445,10 +930,22 @@ func (r *Repository) GetCommitRange(ctx context.Context, from, to string) ([]Com
 			}
 		}
 
+		// Get the files it touched.
+		filesCmd := exec.CommandContext(ctx, "git", "diff-tree", "--no-commit-id", "--name-only", "-r", "-z", "--root", sha)
+		filesCmd.Dir = r.Path
+		filesOutput, err := filesCmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get changed files for %s: %w", sha, err)
+		}
+
 		commits = append(commits, CommitInfo{
-			SHA:     sha,
-			Message: strings.TrimSpace(string(msgOutput)),
-			Diff:    string(diffOutput),
+			SHA:         sha,
+			Message:     strings.TrimSpace(fields[3]),
+			Diff:        string(diffOutput),
+			Files:       splitNUL(string(filesOutput)),
+			Author:      fields[0],
+			AuthorEmail: fields[1],
+			Date:        date,
 		})
 	}
 
@@ -475,27 +972,146 @@ func (r *Repository) GetUnpushedCommits(ctx context.Context) ([]CommitInfo, erro
 	return r.GetCommitRange(ctx, fmt.Sprintf("origin/%s", branch), "HEAD")
 }
 
-// GetBranchPoint finds where current branch diverged from main/master.
-func (r *Repository) GetBranchPoint(ctx context.Context) (string, error) {
-	// Try to find main branch first, then master.
-	for _, baseBranch := range []string{"main", "master"} {
-		// Check if base branch exists.
-		checkCmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", fmt.Sprintf("origin/%s", baseBranch))
-		checkCmd.Dir = r.Path
-		if err := checkCmd.Run(); err != nil {
-			continue
+// FetchTimeout bounds FetchBeforeRange, so an unreachable remote only
+// delays absorb briefly.
+const FetchTimeout = 10 * time.Second
+
+// FetchBeforeRange fetches the current branch from origin, so that
+// GetUnpushedCommits compares against what the remote has now rather than
+// what it had at the last fetch. It gives up after FetchTimeout and never
+// prompts for credentials.
+func (r *Repository) FetchBeforeRange(ctx context.Context) error {
+	branch, err := r.GetCurrentBranch(ctx)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FetchTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "fetch", "--quiet", "--no-tags", "origin", branch)
+	cmd.Dir = r.Path
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("fetching origin/%s timed out after %s", branch, FetchTimeout)
+		}
+		if stderr.Len() > 0 {
+			return fmt.Errorf("git fetch failed: %s", strings.TrimSpace(stderr.String()))
 		}
+		return fmt.Errorf("git fetch failed: %w", err)
+	}
+	return nil
+}
 
-		// Find merge base.
-		cmd := exec.CommandContext(ctx, "git", "merge-base", fmt.Sprintf("origin/%s", baseBranch), "HEAD")
-		cmd.Dir = r.Path
-		output, err := cmd.Output()
-		if err == nil {
-			return strings.TrimSpace(string(output)), nil
+// TrackingRefUpdated returns when origin/<branch> was last brought up to
+// date: the later of the last fetch (FETCH_HEAD) and the last change to the
+// ref itself, as a push makes. ok is false when the ref doesn't exist or
+// neither time is known.
+func (r *Repository) TrackingRefUpdated(ctx context.Context, branch string) (updated time.Time, ok bool, err error) {
+	ref := "refs/remotes/origin/" + branch
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", ref)
+	cmd.Dir = r.Path
+	if err := cmd.Run(); err != nil {
+		return time.Time{}, false, nil
+	}
+
+	cmd = exec.CommandContext(ctx, "git", "rev-parse", "--git-path", "FETCH_HEAD")
+	cmd.Dir = r.Path
+	output, err := cmd.Output()
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to locate FETCH_HEAD: %w", err)
+	}
+	fetchHead := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(fetchHead) {
+		fetchHead = filepath.Join(r.Path, fetchHead)
+	}
+	if info, err := os.Stat(fetchHead); err == nil {
+		updated, ok = info.ModTime(), true
+	}
+
+	// The reflog may be missing or empty, which just leaves FETCH_HEAD
+	cmd = exec.CommandContext(ctx, "git", "reflog", "show", "-1", "--format=%ct", ref, "--")
+	cmd.Dir = r.Path
+	if output, err := cmd.Output(); err == nil {
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(string(output)), 10, 64); err == nil {
+			if changed := time.Unix(seconds, 0); !ok || changed.After(updated) {
+				updated, ok = changed, true
+			}
+		}
+	}
+	return updated, ok, nil
+}
+
+// ErrNoMergeBase is returned by GetMergeBase when the commits share no
+// history.
+var ErrNoMergeBase = errors.New("no common ancestor")
+
+// GetMergeBase returns the best common ancestor of the commits a and b, or
+// an error wrapping ErrNoMergeBase if they have none.
+func (r *Repository) GetMergeBase(ctx context.Context, a, b string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "merge-base", a, b)
+	cmd.Dir = r.Path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 && stderr.Len() == 0 {
+			return "", fmt.Errorf("%s and %s: %w", a, b, ErrNoMergeBase)
+		}
+		if stderr.Len() > 0 {
+			return "", fmt.Errorf("git merge-base failed: %s", strings.TrimSpace(stderr.String()))
 		}
+		return "", fmt.Errorf("git merge-base failed: %w", err)
 	}
 
-	// If no main/master, use the root commit.
+	return strings.TrimSpace(string(output)), nil
+}
+
+// ErrNoDefaultBranch is returned by GetDefaultBranch when neither
+// BaseBranch, origin/HEAD, origin/main nor origin/master is available.
+var ErrNoDefaultBranch = errors.New("could not determine the default branch; set base_branch")
+
+// GetDefaultBranch returns the name of the branch work is based on, such as
+// "main" or "develop": BaseBranch when set, else the branch origin/HEAD
+// points at, else main or master if origin has them.
+func (r *Repository) GetDefaultBranch(ctx context.Context) (string, error) {
+	if r.BaseBranch != "" {
+		return r.BaseBranch, nil
+	}
+	if head := r.remoteHead(ctx); head != "" {
+		return strings.TrimPrefix(head, "origin/"), nil
+	}
+	for _, branch := range []string{"main", "master"} {
+		if r.refExists(ctx, "origin/"+branch) {
+			return branch, nil
+		}
+	}
+	return "", ErrNoDefaultBranch
+}
+
+// GetBranchPoint finds where the current branch diverged from the default
+// branch (see GetDefaultBranch), using origin's copy of it if there is one.
+// Without a default branch it returns the root commit.
+func (r *Repository) GetBranchPoint(ctx context.Context) (string, error) {
+	if branch, err := r.GetDefaultBranch(ctx); err == nil {
+		for _, base := range []string{"origin/" + branch, branch} {
+			if !r.refExists(ctx, base) {
+				continue
+			}
+			if mergeBase, err := r.GetMergeBase(ctx, base, "HEAD"); err == nil {
+				return mergeBase, nil
+			}
+		}
+	}
+
+	// If no base branch, use the root commit.
 	cmd := exec.CommandContext(ctx, "git", "rev-list", "--max-parents=0", "HEAD")
 	cmd.Dir = r.Path
 	output, err := cmd.Output()
@@ -506,6 +1122,25 @@ func (r *Repository) GetBranchPoint(ctx context.Context) (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// refExists reports whether ref names a commit.
+func (r *Repository) refExists(ctx context.Context, ref string) bool {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	cmd.Dir = r.Path
+	return cmd.Run() == nil
+}
+
+// remoteHead returns the branch origin/HEAD points at, such as
+// "origin/develop", or "" if it isn't set.
+func (r *Repository) remoteHead(ctx context.Context) string {
+	cmd := exec.CommandContext(ctx, "git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+	cmd.Dir = r.Path
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
 // GetCurrentCommitSHA returns the SHA of the current HEAD.
 func (r *Repository) GetCurrentCommitSHA(ctx context.Context) (string, error) {
 	cmd := exec.CommandContext(ctx, "git", "rev-parse", "HEAD")
@@ -534,6 +1169,32 @@ func (r *Repository) HasUncommittedChanges(ctx context.Context) (bool, error) {
 	return len(strings.TrimSpace(string(output))) > 0, nil
 }
 
+// IsClean reports whether the working tree and index match HEAD, with no
+// untracked files.
+func (r *Repository) IsClean(ctx context.Context) (bool, error) {
+	dirty, err := r.HasUncommittedChanges(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !dirty, nil
+}
+
+// HasUnstagedChanges checks if tracked files have changes that are not
+// staged. Untracked files are not counted.
+func (r *Repository) HasUnstagedChanges(ctx context.Context) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff", "--quiet")
+	cmd.Dir = r.Path
+
+	err := cmd.Run()
+	if err == nil {
+		return false, nil
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+		return true, nil
+	}
+	return false, fmt.Errorf("failed to check for unstaged changes: %w", err)
+}
+
 // Stash saves the current working directory and index state.
 func (r *Repository) Stash(ctx context.Context, message string) (string, error) {
 	if message == "" {
@@ -587,6 +1248,59 @@ func (r *Repository) StashPop(ctx context.Context) error {
 	return nil
 }
 
+// StashPopSHA applies the stash entry with the given SHA and removes it from
+// the stash list. It reports false, without error, when no entry has that SHA,
+// for example because the stash was already popped.
+func (r *Repository) StashPopSHA(ctx context.Context, sha string) (bool, error) {
+	listCmd := exec.CommandContext(ctx, "git", "stash", "list", "--format=%H")
+	listCmd.Dir = r.Path
+	output, err := listCmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to list stashes: %w", err)
+	}
+
+	index := -1
+	for i, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line == sha {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return false, nil
+	}
+
+	// Restore the index too, so changes that were staged come back staged.
+	// When the staged part no longer applies, fall back to restoring
+	// everything unstaged rather than leaving it in the stash.
+	stashRef := fmt.Sprintf("stash@{%d}", index)
+	if err := r.stashPop(ctx, "--index", stashRef); err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  Could not restore the staged part of the stash (%v); restoring it unstaged\n", err)
+		if err := r.stashPop(ctx, stashRef); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// stashPop runs git stash pop with args.
+func (r *Repository) stashPop(ctx context.Context, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"stash", "pop"}, args...)...)
+	cmd.Dir = r.Path
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if stderr.Len() > 0 {
+			return fmt.Errorf("git stash pop failed: %s", strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("git stash pop failed: %w", err)
+	}
+	return nil
+}
+
 // GetCommitsFromBranchPoint returns commits from branch point to HEAD.
 func (r *Repository) GetCommitsFromBranchPoint(ctx context.Context) ([]CommitInfo, error) {
 	branchPoint, err := r.GetBranchPoint(ctx)
@@ -622,7 +1336,7 @@ func (r *Repository) CreateFixupCommit(ctx context.Context, targetSHA string, me
 
 // AutosquashRebase performs an autosquash rebase onto the specified commit.
 func (r *Repository) AutosquashRebase(ctx context.Context, onto string) error {
-	cmd := exec.CommandContext(ctx, "git", "rebase", "--autosquash", "-i", "--autostash", onto)
+	cmd := exec.CommandContext(ctx, "git", "rebase", "--autosquash", "-i", onto)
 	cmd.Dir = r.Path
 
 	// Set environment variable to automatically accept the rebase todo list.
@@ -684,14 +1398,10 @@ func (r *Repository) CheckRebaseConflicts(ctx context.Context, commits []string)
 			hasConflicts = true
 
 			// Get list of conflicted files.
-			statusCmd := exec.CommandContext(ctx, "git", "diff", "--name-only", "--diff-filter=U")
+			statusCmd := exec.CommandContext(ctx, "git", "diff", "--name-only", "-z", "--diff-filter=U")
 			statusCmd.Dir = r.Path
 			output, _ := statusCmd.Output()
-
-			if len(output) > 0 {
-				files := strings.Split(strings.TrimSpace(string(output)), "\n")
-				conflictFiles = append(conflictFiles, files...)
-			}
+			conflictFiles = append(conflictFiles, splitNUL(string(output))...)
 
 			// Abort the rebase.
 			abortCmd := exec.CommandContext(ctx, "git", "rebase", "--abort")
@@ -716,8 +1426,13 @@ func (r *Repository) CreateBackupRef(ctx context.Context, name string) (string,
 	// Use custom refs namespace to avoid polluting branch list
 	refPath := fmt.Sprintf("refs/cmt-backup/%s", name)
 
-	// Create the ref pointing to HEAD
-	cmd := exec.CommandContext(ctx, "git", "update-ref", refPath, "HEAD")
+	// Create the ref pointing to HEAD, recording the branch in its reflog
+	// so the backup can later be restored onto the right branch.
+	reason := "cmt absorb backup"
+	if branch, err := r.GetCurrentBranch(ctx); err == nil && branch != "HEAD" {
+		reason = backupReflogPrefix + branch
+	}
+	cmd := exec.CommandContext(ctx, "git", "update-ref", "--create-reflog", "-m", reason, refPath, "HEAD")
 	cmd.Dir = r.Path
 
 	if err := cmd.Run(); err != nil {
@@ -727,36 +1442,129 @@ func (r *Repository) CreateBackupRef(ctx context.Context, name string) (string,
 	return refPath, nil
 }
 
-// ListBackupRefs lists all backup refs in the custom namespace.
-func (r *Repository) ListBackupRefs(ctx context.Context) ([]string, error) {
-	cmd := exec.CommandContext(ctx, "git", "show-ref", "--heads", "refs/cmt-backup/")
+// backupReflogPrefix starts the reflog message of a backup ref; the branch
+// the backup was taken on follows it.
+const backupReflogPrefix = "cmt absorb backup on "
+
+// BackupInfo describes a backup ref in the custom namespace.
+type BackupInfo struct {
+	Name    string    // Short name, e.g. absorb-123456.
+	Ref     string    // Full ref path.
+	SHA     string    // Commit the ref points to.
+	Branch  string    // Branch the backup was taken on, if recorded.
+	Created time.Time // Creation time parsed from the name, if any.
+}
+
+// ListBackups lists all backup refs with the commit each one points to.
+func (r *Repository) ListBackups(ctx context.Context) ([]BackupInfo, error) {
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref",
+		"--format=%(objectname) %(refname)", "refs/cmt-backup/")
 	cmd.Dir = r.Path
 
 	output, err := cmd.Output()
 	if err != nil {
-		// No refs found is not an error
-		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
-			return []string{}, nil
-		}
 		return nil, fmt.Errorf("failed to list backup refs: %w", err)
 	}
 
-	var refs []string
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
+	var backups []BackupInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
 		// Format: "<sha> refs/cmt-backup/absorb-123456"
 		parts := strings.Fields(line)
-		if len(parts) >= 2 {
-			refs = append(refs, parts[1])
+		if len(parts) < 2 {
+			continue
 		}
+
+		info := BackupInfo{
+			Name: strings.TrimPrefix(parts[1], "refs/cmt-backup/"),
+			Ref:  parts[1],
+			SHA:  parts[0],
+		}
+		info.Created, _ = BackupTime(info.Ref)
+		info.Branch = r.backupBranch(ctx, info.Ref)
+		backups = append(backups, info)
 	}
 
+	return backups, nil
+}
+
+// backupBranch reads the branch recorded in a backup ref's reflog.
+func (r *Repository) backupBranch(ctx context.Context, ref string) string {
+	cmd := exec.CommandContext(ctx, "git", "reflog", "show", "--format=%gs", ref)
+	cmd.Dir = r.Path
+
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if branch, ok := strings.CutPrefix(line, backupReflogPrefix); ok {
+			return branch
+		}
+	}
+	return ""
+}
+
+// ListBackupRefs lists all backup refs in the custom namespace.
+func (r *Repository) ListBackupRefs(ctx context.Context) ([]string, error) {
+	backups, err := r.ListBackups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]string, len(backups))
+	for i, backup := range backups {
+		refs[i] = backup.Ref
+	}
 	return refs, nil
 }
 
+// RestoreBackup resets to the given backup, which may be a short name such
+// as absorb-123456 or a full ref path. The branch the backup was taken on is
+// checked out first when known. Working tree changes are preserved.
+func (r *Repository) RestoreBackup(ctx context.Context, name string) (BackupInfo, error) {
+	ref := name
+	if !strings.HasPrefix(ref, "refs/") {
+		ref = "refs/cmt-backup/" + name
+	}
+
+	backups, err := r.ListBackups(ctx)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+
+	var backup *BackupInfo
+	for i := range backups {
+		if backups[i].Ref == ref {
+			backup = &backups[i]
+			break
+		}
+	}
+	if backup == nil {
+		return BackupInfo{}, fmt.Errorf("backup not found: %s", name)
+	}
+
+	if backup.Branch != "" {
+		current, err := r.GetCurrentBranch(ctx)
+		if err == nil && current != backup.Branch {
+			checkoutCmd := exec.CommandContext(ctx, "git", "checkout", backup.Branch)
+			checkoutCmd.Dir = r.Path
+			if err := checkoutCmd.Run(); err != nil {
+				return BackupInfo{}, fmt.Errorf("failed to checkout %s: %w", backup.Branch, err)
+			}
+		}
+	}
+
+	// Reset using --mixed to preserve working directory changes.
+	resetCmd := exec.CommandContext(ctx, "git", "reset", "--mixed", backup.Ref)
+	resetCmd.Dir = r.Path
+	if err := resetCmd.Run(); err != nil {
+		return BackupInfo{}, fmt.Errorf("failed to reset to backup: %w", err)
+	}
+
+	return *backup, nil
+}
+
 // DeleteBackupRef deletes a backup ref from the custom namespace.
 func (r *Repository) DeleteBackupRef(ctx context.Context, refPath string) error {
 	cmd := exec.CommandContext(ctx, "git", "update-ref", "-d", refPath)
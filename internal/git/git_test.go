@@ -0,0 +1,953 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRepo creates a temporary git repository with an initial commit.
+func newTestRepo(t *testing.T) *Repository {
+	t.Helper()
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	runGit(t, dir, "config", "user.name", "Test User")
+	runGit(t, dir, "config", "user.email", "test@example.com")
+	runGit(t, dir, "config", "commit.gpgsign", "false")
+
+	writeFile(t, dir, "README.md", "# test\n")
+	runGit(t, dir, "add", "README.md")
+	runGit(t, dir, "commit", "-q", "-m", "initial commit")
+
+	return &Repository{Path: dir}
+}
+
+// runGit runs a git command in dir and fails the test on error.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// writeFile writes content to a file relative to dir.
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCommitWithOptionsAmendNoEdit(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "forgotten.txt", "oops\n")
+	runGit(t, repo.Path, "add", "forgotten.txt")
+
+	if err := repo.CommitWithOptions(ctx, "", CommitOptions{Amend: true, NoEdit: true}); err != nil {
+		t.Fatalf("amend failed: %v", err)
+	}
+
+	msg, err := repo.GetLastCommitMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg != "initial commit" {
+		t.Errorf("expected message to be kept, got %q", msg)
+	}
+
+	count := runGit(t, repo.Path, "rev-list", "--count", "HEAD")
+	if count != "1" {
+		t.Errorf("expected a single commit after amend, got %s", count)
+	}
+
+	files := runGit(t, repo.Path, "show", "--name-only", "--format=", "HEAD")
+	if !strings.Contains(files, "forgotten.txt") {
+		t.Errorf("expected amended commit to contain forgotten.txt, got %q", files)
+	}
+}
+
+func TestCommitWithOptionsRequiresMessage(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.CommitWithOptions(context.Background(), "", CommitOptions{}); err == nil {
+		t.Error("expected error for empty message without amend --no-edit")
+	}
+	if err := repo.CommitWithOptions(context.Background(), " \n\t\n", CommitOptions{AllowEmpty: true}); err == nil {
+		t.Error("expected error for whitespace-only message")
+	}
+}
+
+func TestCommitLongMessage(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	// Larger than the 128 KiB limit Linux puts on a single argument.
+	var b strings.Builder
+	b.WriteString("feat: add a very long message")
+	for i := 0; i < 3000; i++ {
+		fmt.Fprintf(&b, "\n\nParagraph %d with `backticks`, $VARS, \"quotes\" and -dashes.", i)
+	}
+	message := b.String()
+
+	writeFile(t, repo.Path, "long.txt", "long\n")
+	runGit(t, repo.Path, "add", "long.txt")
+
+	if err := repo.Commit(ctx, message); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+
+	if got := runGit(t, repo.Path, "log", "-1", "--format=%B"); got != message {
+		t.Errorf("message was not recorded faithfully: got %d bytes, expected %d", len(got), len(message))
+	}
+}
+
+func TestIsCommitPushed(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	head, err := repo.GetCurrentCommitSHA(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushed, err := repo.IsCommitPushed(ctx, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushed {
+		t.Error("expected local-only commit to be reported as not pushed")
+	}
+
+	remote := t.TempDir()
+	runGit(t, remote, "init", "-q", "--bare")
+	runGit(t, repo.Path, "remote", "add", "origin", remote)
+	runGit(t, repo.Path, "push", "-q", "origin", "main")
+
+	pushed, err = repo.IsCommitPushed(ctx, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !pushed {
+		t.Error("expected pushed commit to be reported as pushed")
+	}
+}
+
+func TestParseNameStatus(t *testing.T) {
+	output := "A\x00internal/foo.go\x00M\x00main.go\x00D\x00old.go\x00R087\x00pkg/a.go\x00pkg/b.go\x00C100\x00src.go\x00copy.go\x00"
+
+	got := parseNameStatus(output)
+	want := []FileStatus{
+		{Status: "A", Path: "internal/foo.go", IsStaged: true},
+		{Status: "M", Path: "main.go", IsStaged: true},
+		{Status: "D", Path: "old.go", IsStaged: true},
+		{Status: "R", Path: "pkg/b.go", OldPath: "pkg/a.go", IsStaged: true},
+		{Status: "C", Path: "copy.go", OldPath: "src.go", IsStaged: true},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("expected %d files, got %d: %+v", len(want), len(got), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("file %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if s := got[3].String(); s != "R pkg/a.go -> pkg/b.go" {
+		t.Errorf("unexpected rename formatting %q", s)
+	}
+	if s := got[0].String(); s != "A internal/foo.go" {
+		t.Errorf("unexpected formatting %q", s)
+	}
+}
+
+func TestGetStagedFilesWithStatus(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "new.go", "package main\n")
+	writeFile(t, repo.Path, "README.md", "# changed\n")
+	runGit(t, repo.Path, "add", "-A")
+
+	files, err := repo.GetStagedFilesWithStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := make(map[string]string)
+	for _, f := range files {
+		statuses[f.Path] = f.Status
+	}
+	if statuses["new.go"] != "A" || statuses["README.md"] != "M" {
+		t.Errorf("unexpected statuses: %+v", files)
+	}
+}
+
+func TestDeletionOnly(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "old.go", "package main\n")
+	writeFile(t, repo.Path, "legacy.go", "package main\n")
+	runGit(t, repo.Path, "add", "-A")
+	runGit(t, repo.Path, "commit", "-q", "-m", "add files")
+
+	runGit(t, repo.Path, "rm", "-q", "old.go", "legacy.go")
+	files, err := repo.GetStagedFilesWithStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !DeletionOnly(files) {
+		t.Errorf("expected %+v to be deletion-only", files)
+	}
+
+	writeFile(t, repo.Path, "new.go", "package main\n")
+	runGit(t, repo.Path, "add", "new.go")
+	files, err = repo.GetStagedFilesWithStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if DeletionOnly(files) {
+		t.Errorf("expected %+v with an added file not to be deletion-only", files)
+	}
+	if DeletionOnly(nil) {
+		t.Error("expected nothing staged not to be deletion-only")
+	}
+}
+
+func TestStagedFilesUnusualNames(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	names := []string{"new\nline.txt", "with space.txt", "café.txt"}
+	for _, name := range names {
+		writeFile(t, repo.Path, name, "content\n")
+	}
+	runGit(t, repo.Path, "add", "-A")
+
+	files, err := repo.GetStagedFiles(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	want := append([]string(nil), names...)
+	sort.Strings(want)
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("GetStagedFiles() = %q, want %q", files, want)
+	}
+
+	// Every listing names the files as they are, unquoted
+	listings := map[string]func() ([]FileStatus, error){
+		"GetStagedFilesWithStatus": func() ([]FileStatus, error) { return repo.GetStagedFilesWithStatus(ctx) },
+		"GetStatus":                func() ([]FileStatus, error) { return repo.GetStatus(ctx) },
+		"ReadStagedChanges": func() ([]FileStatus, error) {
+			staged, err := repo.ReadStagedChanges(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return staged.Files, nil
+		},
+	}
+	for name, list := range listings {
+		statuses, err := list()
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var paths []string
+		for _, f := range statuses {
+			paths = append(paths, f.Path)
+		}
+		sort.Strings(paths)
+		if !reflect.DeepEqual(paths, want) {
+			t.Errorf("%s paths = %q, want %q", name, paths, want)
+		}
+	}
+
+	// A rename lists the new path, with the old one alongside
+	runGit(t, repo.Path, "commit", "-q", "-m", "add files")
+	runGit(t, repo.Path, "mv", "with space.txt", "renamed\tfile.txt")
+	statuses, err := repo.GetStagedFilesWithStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Path != "renamed\tfile.txt" || statuses[0].OldPath != "with space.txt" {
+		t.Errorf("unexpected rename: %+v", statuses)
+	}
+	status, err := repo.GetStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || status[0].Path != "renamed\tfile.txt" {
+		t.Errorf("unexpected status for the rename: %+v", status)
+	}
+}
+
+func TestStageUpdated(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "old.txt", "old\n")
+	runGit(t, repo.Path, "add", "old.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "add old.txt")
+
+	writeFile(t, repo.Path, "README.md", "# changed\n")
+	if err := os.Remove(filepath.Join(repo.Path, "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, repo.Path, "scratch.txt", "notes\n")
+
+	if err := repo.StageUpdated(ctx); err != nil {
+		t.Fatalf("StageUpdated() error = %v", err)
+	}
+
+	files, err := repo.GetStagedFilesWithStatus(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := make(map[string]string)
+	for _, f := range files {
+		statuses[f.Path] = f.Status
+	}
+	if len(statuses) != 2 || statuses["README.md"] != "M" || statuses["old.txt"] != "D" {
+		t.Errorf("staged %+v, want README.md modified and old.txt deleted only", files)
+	}
+	if status := runGit(t, repo.Path, "status", "--porcelain", "scratch.txt"); !strings.HasPrefix(status, "??") {
+		t.Errorf("scratch.txt status = %q, want it left untracked", status)
+	}
+}
+
+func TestListAndRestoreBackups(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	before, err := repo.GetCurrentCommitSHA(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := repo.CreateBackupRef(ctx, "absorb-1700000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, repo.Path, "later.txt", "later\n")
+	runGit(t, repo.Path, "add", "later.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "later commit")
+
+	backups, err := repo.ListBackups(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("expected 1 backup, got %d", len(backups))
+	}
+
+	b := backups[0]
+	if b.Ref != ref || b.Name != "absorb-1700000000" {
+		t.Errorf("unexpected backup names: %+v", b)
+	}
+	if b.SHA != before {
+		t.Errorf("expected backup SHA %s, got %s", before, b.SHA)
+	}
+	if b.Branch != "main" {
+		t.Errorf("expected branch main, got %q", b.Branch)
+	}
+	if b.Created.Unix() != 1700000000 {
+		t.Errorf("unexpected creation time %v", b.Created)
+	}
+
+	restored, err := repo.RestoreBackup(ctx, "absorb-1700000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.SHA != before {
+		t.Errorf("restored wrong backup: %+v", restored)
+	}
+
+	head, _ := repo.GetCurrentCommitSHA(ctx)
+	if head != before {
+		t.Errorf("expected HEAD %s after restore, got %s", before, head)
+	}
+
+	if _, err := repo.RestoreBackup(ctx, "absorb-missing"); err == nil {
+		t.Error("expected error for unknown backup")
+	}
+}
+
+func TestDiffTooLargeErrorMessage(t *testing.T) {
+	tests := map[int64]string{
+		500:      "diff exceeds 500 bytes;",
+		64 << 10: "diff exceeds 64 KiB;",
+		8 << 20:  "diff exceeds 8 MiB;",
+	}
+	for limit, want := range tests {
+		if got := (&DiffTooLargeError{Limit: limit}).Error(); !strings.HasPrefix(got, want) {
+			t.Errorf("Error() with limit %d = %q, want it to start with %q", limit, got, want)
+		}
+	}
+}
+
+func TestGetDiffSizeCap(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "big.txt", strings.Repeat("a line of generated content\n", 8000))
+	runGit(t, repo.Path, "add", "big.txt")
+
+	repo.MaxDiffBytes = 64 << 10
+	_, err := repo.GetDiff(ctx, true)
+
+	var tooLarge *DiffTooLargeError
+	if !errors.As(err, &tooLarge) {
+		t.Fatalf("expected DiffTooLargeError, got %v", err)
+	}
+	if tooLarge.Limit != repo.MaxDiffBytes {
+		t.Errorf("expected limit %d, got %d", repo.MaxDiffBytes, tooLarge.Limit)
+	}
+
+	repo.MaxDiffBytes = 0
+	diff, err := repo.GetDiff(ctx, true)
+	if err != nil {
+		t.Fatalf("expected diff under default cap, got %v", err)
+	}
+	if !strings.Contains(diff, "+++ b/big.txt") {
+		t.Error("expected full diff output")
+	}
+}
+
+func TestGetDiffReportsStderr(t *testing.T) {
+	repo := &Repository{Path: t.TempDir()}
+
+	_, err := repo.GetDiff(context.Background(), false)
+	if err == nil || !strings.Contains(err.Error(), "git") {
+		t.Fatalf("expected git error, got %v", err)
+	}
+	if strings.HasSuffix(err.Error(), "git diff failed: ") {
+		t.Errorf("expected stderr details in error, got %q", err)
+	}
+}
+
+func TestGetCommitRangeAuthorAndDate(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "a.txt", "a\n")
+	runGit(t, repo.Path, "add", "a.txt")
+	runGit(t, repo.Path, "-c", "user.name=Other Dev", "-c", "user.email=other@example.com",
+		"commit", "-q", "--date", "2024-03-01T10:00:00Z", "-m", "add a")
+
+	commits, err := repo.GetCommitRange(ctx, "HEAD~1", "HEAD")
+	if err != nil {
+		t.Fatalf("GetCommitRange failed: %v", err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("expected 1 commit, got %d", len(commits))
+	}
+
+	c := commits[0]
+	if c.Author != "Other Dev" || c.AuthorEmail != "other@example.com" {
+		t.Errorf("author = %q <%s>, want Other Dev <other@example.com>", c.Author, c.AuthorEmail)
+	}
+	if want := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC); !c.Date.Equal(want) {
+		t.Errorf("date = %v, want %v", c.Date, want)
+	}
+	if c.Message != "add a" {
+		t.Errorf("message = %q, want %q", c.Message, "add a")
+	}
+}
+
+func TestGetCommitRangeFiles(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "a.txt", "a\n")
+	writeFile(t, repo.Path, "dir/b.txt", "b\n")
+	runGit(t, repo.Path, "add", ".")
+	runGit(t, repo.Path, "commit", "-q", "-m", "add a and b")
+	runGit(t, repo.Path, "rm", "-q", "a.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "remove a")
+
+	commits, err := repo.GetCommitRange(ctx, "HEAD~2", "HEAD")
+	if err != nil {
+		t.Fatalf("GetCommitRange failed: %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(commits))
+	}
+	if want := []string{"a.txt", "dir/b.txt"}; !reflect.DeepEqual(commits[0].Files, want) {
+		t.Errorf("files = %v, want %v", commits[0].Files, want)
+	}
+	if want := []string{"a.txt"}; !reflect.DeepEqual(commits[1].Files, want) {
+		t.Errorf("files = %v, want %v", commits[1].Files, want)
+	}
+}
+
+func TestGetRecentSubjects(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	for _, subject := range []string{"feat: add a", "fix: handle b", "docs: explain c"} {
+		writeFile(t, repo.Path, "file.txt", subject+"\n")
+		runGit(t, repo.Path, "add", "file.txt")
+		runGit(t, repo.Path, "commit", "-q", "-m", subject+"\n\nbody text")
+	}
+	runGit(t, repo.Path, "revert", "--no-edit", "HEAD")
+
+	// A merge commit from a side branch.
+	runGit(t, repo.Path, "checkout", "-q", "-b", "side")
+	writeFile(t, repo.Path, "side.txt", "side\n")
+	runGit(t, repo.Path, "add", "side.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "chore: side work")
+	runGit(t, repo.Path, "checkout", "-q", "main")
+	runGit(t, repo.Path, "merge", "-q", "--no-ff", "-m", "Merge branch 'side'", "side")
+
+	subjects, err := repo.GetRecentSubjects(ctx, 3)
+	if err != nil {
+		t.Fatalf("GetRecentSubjects failed: %v", err)
+	}
+	want := []string{"chore: side work", "docs: explain c", "fix: handle b"}
+	if strings.Join(subjects, "|") != strings.Join(want, "|") {
+		t.Errorf("subjects = %q, want %q", subjects, want)
+	}
+
+	if subjects, _ := repo.GetRecentSubjects(ctx, 0); subjects != nil {
+		t.Errorf("GetRecentSubjects(0) = %q, want nil", subjects)
+	}
+}
+
+func TestStagedDiffHash(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "a.txt", "one\n")
+	runGit(t, repo.Path, "add", "a.txt")
+
+	first, err := repo.StagedDiffHash(ctx)
+	if err != nil {
+		t.Fatalf("StagedDiffHash failed: %v", err)
+	}
+	again, _ := repo.StagedDiffHash(ctx)
+	if first != again {
+		t.Errorf("hash changed without index changes: %s vs %s", first, again)
+	}
+
+	// Unstaged edits don't count.
+	writeFile(t, repo.Path, "a.txt", "one\ntwo\n")
+	if unstaged, _ := repo.StagedDiffHash(ctx); unstaged != first {
+		t.Error("hash should ignore unstaged changes")
+	}
+
+	runGit(t, repo.Path, "add", "a.txt")
+	if staged, _ := repo.StagedDiffHash(ctx); staged == first {
+		t.Error("hash should change when more changes are staged")
+	}
+}
+
+func TestReadStagedChanges(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "README.md", "# changed\n")
+	writeFile(t, repo.Path, "new.go", "package main\n")
+	writeFile(t, repo.Path, "logo.png", "\x89PNG\x00\x01")
+	runGit(t, repo.Path, "add", "-A")
+
+	// Count the git commands run, through a wrapper ahead of git on the PATH.
+	real, err := exec.LookPath("git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls.log")
+	script := "#!/bin/sh\necho \"$*\" >> " + calls + "\nexec " + real + " \"$@\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "git"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	staged, err := repo.ReadStagedChanges(ctx)
+	if err != nil {
+		t.Fatalf("ReadStagedChanges() error = %v", err)
+	}
+	log, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(log), "\n"); n != 1 {
+		t.Errorf("ReadStagedChanges ran %d git commands, want 1:\n%s", n, log)
+	}
+
+	// Each part matches what the separate calls return.
+	diff, _ := repo.GetDiff(ctx, true)
+	if staged.Diff != diff {
+		t.Errorf("Diff = %q, want %q", staged.Diff, diff)
+	}
+	files, _ := repo.GetStagedFilesWithStatus(ctx)
+	if fmt.Sprint(staged.Files) != fmt.Sprint(files) {
+		t.Errorf("Files = %v, want %v", staged.Files, files)
+	}
+	hash, _ := repo.StagedDiffHash(ctx)
+	if staged.Hash != hash {
+		t.Errorf("Hash = %s, want %s", staged.Hash, hash)
+	}
+}
+
+func TestReadStagedChangesIgnoreWhitespace(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "main.go", "func main() {\nreturn\n}\n")
+	runGit(t, repo.Path, "add", "main.go")
+	runGit(t, repo.Path, "commit", "-q", "-m", "add main")
+
+	// Reindenting only changes whitespace
+	writeFile(t, repo.Path, "main.go", "func main() {\n\treturn\n}\n")
+	runGit(t, repo.Path, "add", "main.go")
+
+	staged, err := repo.ReadStagedChanges(ctx)
+	if err != nil {
+		t.Fatalf("ReadStagedChanges() error = %v", err)
+	}
+	if !strings.Contains(staged.Diff, "+\treturn") || staged.WhitespaceOnly {
+		t.Errorf("expected the whitespace change without IgnoreWhitespace, got %q (WhitespaceOnly %v)", staged.Diff, staged.WhitespaceOnly)
+	}
+
+	repo.IgnoreWhitespace = true
+	ignored, err := repo.ReadStagedChanges(ctx)
+	if err != nil {
+		t.Fatalf("ReadStagedChanges() error = %v", err)
+	}
+	if ignored.Diff != "" || !ignored.WhitespaceOnly {
+		t.Errorf("expected an empty, whitespace-only diff, got %q (WhitespaceOnly %v)", ignored.Diff, ignored.WhitespaceOnly)
+	}
+	if len(ignored.Files) != 1 || ignored.Hash != staged.Hash {
+		t.Errorf("expected the staged file and hash to be unaffected, got %v, %s", ignored.Files, ignored.Hash)
+	}
+	if diff, _ := repo.GetDiff(ctx, true); diff != "" {
+		t.Errorf("GetDiff() = %q, expected the whitespace change to be left out", diff)
+	}
+
+	// A real change alongside still shows, without the whitespace
+	writeFile(t, repo.Path, "main.go", "func main() {\n\treturn // done\n}\n")
+	runGit(t, repo.Path, "add", "main.go")
+	ignored, _ = repo.ReadStagedChanges(ctx)
+	if !strings.Contains(ignored.Diff, "+\treturn // done") || ignored.WhitespaceOnly {
+		t.Errorf("expected the substantive change, got %q (WhitespaceOnly %v)", ignored.Diff, ignored.WhitespaceOnly)
+	}
+}
+
+func TestStagedDiffHashBinary(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "logo.bin", "\x00\x01\x02")
+	runGit(t, repo.Path, "add", "logo.bin")
+	first, _ := repo.StagedDiffHash(ctx)
+
+	writeFile(t, repo.Path, "logo.bin", "\x00\x01\x03")
+	runGit(t, repo.Path, "add", "logo.bin")
+	if second, _ := repo.StagedDiffHash(ctx); second == first {
+		t.Error("hash should change when binary content changes")
+	}
+}
+
+func TestStagedFileSizes(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "big.bin", strings.Repeat("x", 4096))
+	writeFile(t, repo.Path, "dir/with space.txt", "hello\n")
+	writeFile(t, repo.Path, "unstaged.txt", "not added\n")
+	runGit(t, repo.Path, "add", "big.bin", "dir/with space.txt")
+	runGit(t, repo.Path, "rm", "-q", "--cached", "README.md")
+
+	sizes, err := repo.StagedFileSizes(ctx)
+	if err != nil {
+		t.Fatalf("StagedFileSizes failed: %v", err)
+	}
+	expected := map[string]int64{"big.bin": 4096, "dir/with space.txt": 6}
+	if len(sizes) != len(expected) {
+		t.Fatalf("StagedFileSizes() = %v, expected %v", sizes, expected)
+	}
+	for path, size := range expected {
+		if sizes[path] != size {
+			t.Errorf("size of %s = %d, expected %d", path, sizes[path], size)
+		}
+	}
+}
+
+func TestGetDiffStatBinaryOnly(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "logo.png", "\x89PNG\x00\x01\x02")
+	runGit(t, repo.Path, "add", "logo.png")
+
+	stat, err := repo.GetDiffStat(ctx)
+	if err != nil {
+		t.Fatalf("GetDiffStat failed: %v", err)
+	}
+	if !strings.Contains(stat, "logo.png") || !strings.Contains(stat, "Bin") {
+		t.Errorf("stat = %q, want a binary entry for logo.png", stat)
+	}
+}
+
+func TestGetCommonDir(t *testing.T) {
+	repo := newTestRepo(t)
+
+	want := filepath.Join(repo.Path, ".git")
+	if got, err := repo.GetCommonDir(); err != nil || got != want {
+		t.Errorf("GetCommonDir() = %q, %v; want %q", got, err, want)
+	}
+
+	// A linked worktree has a .git file, but shares the main git directory.
+	worktree := filepath.Join(t.TempDir(), "wt")
+	runGit(t, repo.Path, "worktree", "add", "-q", worktree)
+	got, err := (&Repository{Path: worktree}).GetCommonDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(got); err != nil || !info.IsDir() || filepath.Clean(got) != want {
+		t.Errorf("worktree GetCommonDir() = %q, want the main git directory %q", got, want)
+	}
+}
+
+func TestIsCleanAndHasUnstagedChanges(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	check := func(wantClean, wantUnstaged bool) {
+		t.Helper()
+		clean, err := repo.IsClean(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		unstaged, err := repo.HasUnstagedChanges(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if clean != wantClean || unstaged != wantUnstaged {
+			t.Errorf("IsClean = %v, HasUnstagedChanges = %v; want %v, %v", clean, unstaged, wantClean, wantUnstaged)
+		}
+	}
+
+	check(true, false)
+
+	// Untracked files make the tree dirty but are not unstaged changes.
+	writeFile(t, repo.Path, "notes.txt", "todo\n")
+	check(false, false)
+
+	// Staged changes are not unstaged changes either.
+	runGit(t, repo.Path, "add", "notes.txt")
+	check(false, false)
+
+	writeFile(t, repo.Path, "README.md", "# changed\n")
+	check(false, true)
+}
+
+func TestIsDetachedHead(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	detached, err := repo.IsDetachedHead(ctx)
+	if err != nil || detached {
+		t.Fatalf("IsDetachedHead() = %v, %v on a branch; want false, nil", detached, err)
+	}
+
+	runGit(t, repo.Path, "checkout", "-q", "--detach")
+	detached, err = repo.IsDetachedHead(ctx)
+	if err != nil || !detached {
+		t.Fatalf("IsDetachedHead() = %v, %v after detaching; want true, nil", detached, err)
+	}
+
+	// Push refuses instead of pushing a branch named "HEAD".
+	if err := repo.Push(ctx); !errors.Is(err, ErrDetachedHead) {
+		t.Errorf("Push() error = %v, want ErrDetachedHead", err)
+	}
+
+	if err := repo.CreateBranch(ctx, "rescue"); err != nil {
+		t.Fatal(err)
+	}
+	if branch, _ := repo.GetCurrentBranch(ctx); branch != "rescue" {
+		t.Errorf("current branch = %q, want rescue", branch)
+	}
+	if detached, _ := repo.IsDetachedHead(ctx); detached {
+		t.Error("expected HEAD to be on the new branch")
+	}
+}
+
+func TestGetMergeBase(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	base := strings.TrimSpace(runGit(t, repo.Path, "rev-parse", "HEAD"))
+
+	runGit(t, repo.Path, "checkout", "-q", "-b", "feature")
+	writeFile(t, repo.Path, "feature.txt", "feature\n")
+	runGit(t, repo.Path, "add", "feature.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "feature work")
+
+	runGit(t, repo.Path, "checkout", "-q", "main")
+	writeFile(t, repo.Path, "main.txt", "main\n")
+	runGit(t, repo.Path, "add", "main.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "main work")
+
+	got, err := repo.GetMergeBase(ctx, "main", "feature")
+	if err != nil || got != base {
+		t.Errorf("GetMergeBase() = %q, %v, want %q", got, err, base)
+	}
+
+	runGit(t, repo.Path, "checkout", "-q", "--orphan", "unrelated")
+	runGit(t, repo.Path, "commit", "-q", "-m", "unrelated root")
+	if _, err := repo.GetMergeBase(ctx, "unrelated", "main"); !errors.Is(err, ErrNoMergeBase) {
+		t.Errorf("GetMergeBase() of unrelated histories error = %v, want ErrNoMergeBase", err)
+	}
+}
+
+func TestGetBranchPointUsesRemoteHead(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+	initial := strings.TrimSpace(runGit(t, repo.Path, "rev-parse", "HEAD"))
+
+	// The remote's default branch is "trunk", ahead of a stale origin/main.
+	runGit(t, repo.Path, "checkout", "-q", "-b", "trunk")
+	writeFile(t, repo.Path, "trunk.txt", "trunk\n")
+	runGit(t, repo.Path, "add", "trunk.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "trunk work")
+	trunk := strings.TrimSpace(runGit(t, repo.Path, "rev-parse", "HEAD"))
+	runGit(t, repo.Path, "update-ref", "refs/remotes/origin/trunk", trunk)
+	runGit(t, repo.Path, "update-ref", "refs/remotes/origin/main", initial)
+
+	runGit(t, repo.Path, "checkout", "-q", "-b", "feature")
+	writeFile(t, repo.Path, "feature.txt", "feature\n")
+	runGit(t, repo.Path, "add", "feature.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "feature work")
+
+	// Without origin/HEAD, origin/main is the fallback.
+	if got, err := repo.GetBranchPoint(ctx); err != nil || got != initial {
+		t.Errorf("GetBranchPoint() without origin/HEAD = %q, %v, want %q", got, err, initial)
+	}
+
+	runGit(t, repo.Path, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/trunk")
+	if got, err := repo.GetBranchPoint(ctx); err != nil || got != trunk {
+		t.Errorf("GetBranchPoint() = %q, %v, want the trunk commit %q", got, err, trunk)
+	}
+}
+
+func TestGetDefaultBranch(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	if _, err := repo.GetDefaultBranch(ctx); !errors.Is(err, ErrNoDefaultBranch) {
+		t.Errorf("GetDefaultBranch() without a remote error = %v, want ErrNoDefaultBranch", err)
+	}
+
+	// origin's default is "develop"; there is no origin/main or origin/master.
+	runGit(t, repo.Path, "checkout", "-q", "-b", "develop")
+	writeFile(t, repo.Path, "develop.txt", "develop\n")
+	runGit(t, repo.Path, "add", "develop.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "develop work")
+	develop := strings.TrimSpace(runGit(t, repo.Path, "rev-parse", "HEAD"))
+	runGit(t, repo.Path, "update-ref", "refs/remotes/origin/develop", develop)
+	runGit(t, repo.Path, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/develop")
+
+	if got, err := repo.GetDefaultBranch(ctx); err != nil || got != "develop" {
+		t.Errorf("GetDefaultBranch() = %q, %v, want %q", got, err, "develop")
+	}
+
+	runGit(t, repo.Path, "checkout", "-q", "-b", "feature")
+	writeFile(t, repo.Path, "feature.txt", "feature\n")
+	runGit(t, repo.Path, "add", "feature.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "feature work")
+	if got, err := repo.GetBranchPoint(ctx); err != nil || got != develop {
+		t.Errorf("GetBranchPoint() = %q, %v, want the develop commit %q", got, err, develop)
+	}
+
+	// base_branch wins, and a local-only branch is used as is.
+	initial := strings.TrimSpace(runGit(t, repo.Path, "rev-parse", "main"))
+	repo.BaseBranch = "main"
+	if got, err := repo.GetDefaultBranch(ctx); err != nil || got != "main" {
+		t.Errorf("GetDefaultBranch() with BaseBranch = %q, %v, want %q", got, err, "main")
+	}
+	if got, err := repo.GetBranchPoint(ctx); err != nil || got != initial {
+		t.Errorf("GetBranchPoint() with BaseBranch = %q, %v, want %q", got, err, initial)
+	}
+}
+
+func TestFetchBeforeRange(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	remote := t.TempDir()
+	runGit(t, remote, "init", "-q", "--bare")
+	runGit(t, repo.Path, "remote", "add", "origin", remote)
+	runGit(t, repo.Path, "push", "-q", "origin", "main")
+	first := runGit(t, repo.Path, "rev-parse", "HEAD")
+
+	writeFile(t, repo.Path, "pushed.txt", "pushed\n")
+	runGit(t, repo.Path, "add", "pushed.txt")
+	runGit(t, repo.Path, "commit", "-q", "-m", "pushed commit")
+	runGit(t, repo.Path, "push", "-q", "origin", "main")
+
+	// Simulate a stale tracking ref: the remote has the second commit, but
+	// origin/main still points at the first.
+	runGit(t, repo.Path, "update-ref", "refs/remotes/origin/main", first)
+
+	stale, err := repo.GetUnpushedCommits(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 1 {
+		t.Fatalf("expected the stale ref to report the pushed commit as unpushed, got %d commit(s)", len(stale))
+	}
+
+	if err := repo.FetchBeforeRange(ctx); err != nil {
+		t.Fatal(err)
+	}
+	unpushed, err := repo.GetUnpushedCommits(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(unpushed) != 0 {
+		t.Errorf("expected no unpushed commits after the fetch, got %d", len(unpushed))
+	}
+
+	updated, ok, err := repo.TrackingRefUpdated(ctx, "main")
+	if err != nil || !ok {
+		t.Fatalf("TrackingRefUpdated() = %v, %v, %v", updated, ok, err)
+	}
+	if age := time.Since(updated); age < 0 || age > time.Minute {
+		t.Errorf("expected the fetch to have just updated origin/main, got %s ago", age)
+	}
+	if _, ok, _ := repo.TrackingRefUpdated(ctx, "no-such-branch"); ok {
+		t.Error("expected no update time for a branch without a tracking ref")
+	}
+}
+
+func TestFetchBeforeRangeWithoutRemote(t *testing.T) {
+	repo := newTestRepo(t)
+
+	if err := repo.FetchBeforeRange(context.Background()); err == nil {
+		t.Error("expected an error without an origin remote")
+	}
+}
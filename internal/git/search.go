@@ -0,0 +1,71 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// searchLogFormat prints the fields logCommits parses, one commit per line.
+const searchLogFormat = "--format=%H%x00%an%x00%aI%x00%s"
+
+// searchArgs builds the git log arguments for SearchCommits. The query is
+// matched as plain text, ignoring case.
+func searchArgs(query string, limit int) []string {
+	return []string{"log", "--no-merges", "--regexp-ignore-case", "--fixed-strings",
+		"--grep=" + query, searchLogFormat, fmt.Sprintf("-n%d", limit)}
+}
+
+// logForFilesArgs builds the git log arguments for LogForFiles.
+func logForFilesArgs(files []string, limit int) []string {
+	args := []string{"log", "--no-merges", searchLogFormat, fmt.Sprintf("-n%d", limit), "--"}
+	return append(args, files...)
+}
+
+// SearchCommits returns up to limit commits, newest first, whose message
+// contains query. Only the subject line is kept in Message, and Diff is not
+// filled in.
+func (r *Repository) SearchCommits(ctx context.Context, query string, limit int) ([]CommitInfo, error) {
+	if query == "" || limit <= 0 {
+		return nil, nil
+	}
+	return r.logCommits(ctx, searchArgs(query, limit))
+}
+
+// LogForFiles returns up to limit commits, newest first, that touched any of
+// files. Like SearchCommits, only subjects are kept.
+func (r *Repository) LogForFiles(ctx context.Context, files []string, limit int) ([]CommitInfo, error) {
+	if len(files) == 0 || limit <= 0 {
+		return nil, nil
+	}
+	return r.logCommits(ctx, logForFilesArgs(files, limit))
+}
+
+// logCommits runs git log with searchLogFormat and parses its output.
+func (r *Repository) logCommits(ctx context.Context, args []string) ([]CommitInfo, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.Path
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to search commits: %w", err)
+	}
+
+	var commits []CommitInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		fields := strings.SplitN(line, "\x00", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		date, _ := time.Parse(time.RFC3339, fields[2])
+		commits = append(commits, CommitInfo{
+			SHA:     fields[0],
+			Author:  fields[1],
+			Date:    date,
+			Message: fields[3],
+		})
+	}
+	return commits, nil
+}
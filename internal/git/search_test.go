@@ -0,0 +1,60 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSearchArgs(t *testing.T) {
+	got := strings.Join(searchArgs("rate limit", 20), " ")
+	expected := "log --no-merges --regexp-ignore-case --fixed-strings --grep=rate limit " + searchLogFormat + " -n20"
+	if got != expected {
+		t.Errorf("searchArgs() = %q, expected %q", got, expected)
+	}
+}
+
+func TestLogForFilesArgs(t *testing.T) {
+	got := strings.Join(logForFilesArgs([]string{"a.go", "docs/b.md"}, 5), " ")
+	expected := "log --no-merges " + searchLogFormat + " -n5 -- a.go docs/b.md"
+	if got != expected {
+		t.Errorf("logForFilesArgs() = %q, expected %q", got, expected)
+	}
+}
+
+func TestSearchCommitsAndLogForFiles(t *testing.T) {
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	writeFile(t, repo.Path, "limiter.go", "package x\n")
+	runGit(t, repo.Path, "add", "limiter.go")
+	runGit(t, repo.Path, "commit", "-q", "-m", "feat(ai): add Rate limiter", "-m", "Paces requests.")
+	writeFile(t, repo.Path, "README.md", "# docs\n")
+	runGit(t, repo.Path, "add", "README.md")
+	runGit(t, repo.Path, "commit", "-q", "-m", "docs: mention pacing")
+
+	found, err := repo.SearchCommits(ctx, "rate LIMITER", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Message != "feat(ai): add Rate limiter" || found[0].Author != "Test User" {
+		t.Errorf("SearchCommits() = %+v, want the limiter commit", found)
+	}
+
+	// The query matches the body too, but only the subject is returned.
+	found, err = repo.SearchCommits(ctx, "paces", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].Message != "feat(ai): add Rate limiter" {
+		t.Errorf("SearchCommits(body) = %+v, want the limiter commit", found)
+	}
+
+	touched, err := repo.LogForFiles(ctx, []string{"README.md"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(touched) != 2 || touched[0].Message != "docs: mention pacing" || touched[1].Message != "initial commit" {
+		t.Errorf("LogForFiles() = %+v, want the two README commits newest first", touched)
+	}
+}
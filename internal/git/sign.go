@@ -0,0 +1,161 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// IsSSHSigningKey reports whether key names an SSH signing key: a public key
+// file (*.pub) or a literal key such as "key::ssh-ed25519 AAAA..." or
+// "ssh-ed25519 AAAA...". Any other key is taken to be a GPG key ID.
+func IsSSHSigningKey(key string) bool {
+	key = strings.TrimSpace(key)
+	for _, prefix := range []string{"key::", "ssh-", "sk-ssh-", "ecdsa-sha2-", "sk-ecdsa-sha2-"} {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return strings.HasSuffix(key, ".pub")
+}
+
+// commitArgs builds the git arguments for a commit with opts. The message is
+// read from messageFile ("-" for stdin); an empty messageFile keeps the
+// existing message.
+func commitArgs(opts CommitOptions, messageFile string) []string {
+	var args []string
+	if IsSSHSigningKey(opts.SigningKey) {
+		// A per-command override, so an SSH key works even when the
+		// repository is set up for GPG.
+		args = append(args, "-c", "gpg.format=ssh")
+	}
+
+	args = append(args, "commit")
+	if opts.Amend {
+		args = append(args, "--amend")
+	}
+	if opts.MessageOnly {
+		// --only without paths commits none of the index
+		args = append(args, "--only")
+	}
+	if opts.AllowEmpty {
+		args = append(args, "--allow-empty")
+	}
+	if opts.SigningKey != "" {
+		args = append(args, "--gpg-sign="+opts.SigningKey)
+	} else if opts.Sign {
+		args = append(args, "--gpg-sign")
+	}
+	if messageFile == "" {
+		args = append(args, "--no-edit")
+	} else {
+		args = append(args, "--file", messageFile)
+	}
+	return args
+}
+
+// signingFailureHelp is shown when git could not sign a commit.
+const signingFailureHelp = `The commit could not be signed. Things to check:
+  - gpg-agent needs a terminal for the passphrase prompt: export GPG_TTY=$(tty)
+  - the key exists: gpg --list-secret-keys (or the SSH key file for gpg.format=ssh)
+  - to commit without signing this once: git -c commit.gpgsign=false commit`
+
+// isSigningFailure reports whether git commit's stderr shows that signing
+// failed, as opposed to a hook or other commit error.
+func isSigningFailure(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	for _, marker := range []string{"gpg failed to sign", "failed to sign the data", "couldn't sign", "signing failed"} {
+		if strings.Contains(stderr, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// willSign reports whether a commit with opts is signed, either on request
+// or because commit.gpgsign is set.
+func (r *Repository) willSign(ctx context.Context, opts CommitOptions) bool {
+	if opts.Sign || opts.SigningKey != "" {
+		return true
+	}
+	sign, _ := r.GitConfigGetBool(ctx, "commit.gpgsign")
+	return sign
+}
+
+// terminalName returns the terminal on stdin (for GPG_TTY), or "" if stdin
+// is not a terminal.
+func terminalName() string {
+	cmd := exec.Command("tty")
+	cmd.Stdin = os.Stdin
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// CheckSigningKey verifies that a commit signed with key (or, if key is
+// empty, with the user.signingkey git config) can be made, so a missing key
+// is reported before the commit rather than as a cryptic failure from git.
+func (r *Repository) CheckSigningKey(ctx context.Context, key string) error {
+	if key == "" {
+		key = r.gitConfig(ctx, "user.signingkey")
+	}
+
+	format := r.gitConfig(ctx, "gpg.format")
+	if IsSSHSigningKey(key) {
+		format = "ssh"
+	}
+
+	switch format {
+	case "ssh":
+		if key == "" {
+			return fmt.Errorf("signing is requested but no SSH signing key is set: pass --signing-key or set user.signingkey")
+		}
+		if strings.HasPrefix(key, "key::") || !strings.HasSuffix(key, ".pub") {
+			return nil // literal public key
+		}
+		path := key
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, rest)
+			}
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("SSH signing key %s not found", key)
+		}
+		return nil
+
+	case "x509":
+		// Left to git and gpgsm.
+		return nil
+
+	default:
+		program := r.gitConfig(ctx, "gpg.program")
+		if program == "" {
+			program = "gpg"
+		}
+		if _, err := exec.LookPath(program); err != nil {
+			return fmt.Errorf("signing is requested but %s is not installed", program)
+		}
+
+		args := []string{"--list-secret-keys"}
+		if key != "" {
+			args = append(args, key)
+		}
+		cmd := exec.CommandContext(ctx, program, args...)
+		var stdout bytes.Buffer
+		cmd.Stdout = &stdout
+		if err := cmd.Run(); err != nil || strings.TrimSpace(stdout.String()) == "" {
+			if key != "" {
+				return fmt.Errorf("no GPG secret key matches %q", key)
+			}
+			return fmt.Errorf("signing is requested but no GPG secret key is available")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,96 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        CommitOptions
+		messageFile string
+		expected    string
+	}{
+		{"plain", CommitOptions{}, "-", "commit --file -"},
+		{"amend no edit", CommitOptions{Amend: true, NoEdit: true}, "", "commit --amend --no-edit"},
+		{"amend message only", CommitOptions{Amend: true, MessageOnly: true}, "-", "commit --amend --only --file -"},
+		{"sign with default key", CommitOptions{Sign: true}, "/tmp/msg", "commit --gpg-sign --file /tmp/msg"},
+		{"gpg key", CommitOptions{SigningKey: "ABCD1234"}, "/tmp/msg", "commit --gpg-sign=ABCD1234 --file /tmp/msg"},
+		{"ssh key file", CommitOptions{SigningKey: "~/.ssh/id_ed25519.pub"}, "-",
+			"-c gpg.format=ssh commit --gpg-sign=~/.ssh/id_ed25519.pub --file -"},
+		{"literal ssh key", CommitOptions{SigningKey: "key::ssh-ed25519 AAAA", AllowEmpty: true}, "-",
+			"-c gpg.format=ssh commit --allow-empty --gpg-sign=key::ssh-ed25519 AAAA --file -"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := strings.Join(commitArgs(tt.opts, tt.messageFile), " "); got != tt.expected {
+				t.Errorf("commitArgs() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckSigningKeySSH(t *testing.T) {
+	// Keep a user.signingkey from the global config out of the test.
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	repo := newTestRepo(t)
+	ctx := context.Background()
+
+	missing := filepath.Join(t.TempDir(), "missing.pub")
+	if err := repo.CheckSigningKey(ctx, missing); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+
+	present := filepath.Join(t.TempDir(), "id_ed25519.pub")
+	writeFile(t, filepath.Dir(present), "id_ed25519.pub", "ssh-ed25519 AAAA test\n")
+	if err := repo.CheckSigningKey(ctx, present); err != nil {
+		t.Errorf("expected existing key to pass, got %v", err)
+	}
+
+	runGit(t, repo.Path, "config", "gpg.format", "ssh")
+	if err := repo.CheckSigningKey(ctx, ""); err == nil || !strings.Contains(err.Error(), "no SSH signing key") {
+		t.Errorf("expected misconfiguration error, got %v", err)
+	}
+}
+
+func TestIsSigningFailure(t *testing.T) {
+	tests := []struct {
+		stderr   string
+		expected bool
+	}{
+		{"error: gpg failed to sign the data\nfatal: failed to write commit object", true},
+		{"error: Couldn't sign message\nfatal: failed to write commit object", true},
+		{"husky - pre-commit hook exited with code 1", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := isSigningFailure(tt.stderr); got != tt.expected {
+			t.Errorf("isSigningFailure(%q) = %v, expected %v", tt.stderr, got, tt.expected)
+		}
+	}
+}
+
+func TestCommitSigningFailureGuidance(t *testing.T) {
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	repo := newTestRepo(t)
+	// A gpg program that always fails stands in for a locked key.
+	runGit(t, repo.Path, "config", "gpg.program", "false")
+	runGit(t, repo.Path, "config", "commit.gpgsign", "true")
+
+	writeFile(t, repo.Path, "a.txt", "a\n")
+	runGit(t, repo.Path, "add", "a.txt")
+
+	err := repo.Commit(context.Background(), "feat: add a")
+	if err == nil {
+		t.Fatal("expected the commit to fail")
+	}
+	if !strings.Contains(err.Error(), "GPG_TTY") {
+		t.Errorf("expected signing guidance in the error, got: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Package hook runs user-configured commands around message generation.
+package hook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout is used when no timeout is configured.
+const DefaultTimeout = 10 * time.Second
+
+// PostGenerate pipes message to command's stdin and returns its stdout as the
+// new message. The command is run with "sh -c" in dir.
+//
+// On a non-zero exit, timeout or empty output the original message is
+// returned together with an error describing why, so callers can warn and
+// carry on with the unfiltered message.
+func PostGenerate(ctx context.Context, command, dir, message string, timeout time.Duration) (string, error) {
+	return run(ctx, "post-generate command", command, dir, message, message, timeout)
+}
+
+// Format pipes input, a message in the JSON form the formatter contract
+// defines, to command's stdin and returns its stdout as the final message.
+// The command is run with "sh -c" in dir.
+//
+// On a non-zero exit, timeout or empty output fallback is returned together
+// with an error describing why, as PostGenerate does.
+func Format(ctx context.Context, command, dir, input, fallback string, timeout time.Duration) (string, error) {
+	return run(ctx, "message formatter", command, dir, input, fallback, timeout)
+}
+
+// run runs command with input on stdin and returns its trimmed stdout, or
+// fallback and an error naming what failed.
+func run(ctx context.Context, what, command, dir, input, fallback string, timeout time.Duration) (string, error) {
+	if strings.TrimSpace(command) == "" {
+		return fallback, nil
+	}
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(input)
+	// Don't wait on children of the shell that keep the output pipes open.
+	cmd.WaitDelay = 500 * time.Millisecond
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fallback, fmt.Errorf("%s timed out after %s", what, timeout)
+		}
+		if stderr.Len() > 0 {
+			return fallback, fmt.Errorf("%s failed: %s", what, strings.TrimSpace(stderr.String()))
+		}
+		return fallback, fmt.Errorf("%s failed: %w", what, err)
+	}
+
+	result := strings.TrimSpace(stdout.String())
+	if result == "" {
+		return fallback, fmt.Errorf("%s produced no output", what)
+	}
+
+	return result, nil
+}
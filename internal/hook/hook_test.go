@@ -0,0 +1,89 @@
+package hook
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func requireShell(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skipf("sh not available: %v", err)
+	}
+}
+
+func TestPostGenerateTransformsMessage(t *testing.T) {
+	requireShell(t)
+
+	got, err := PostGenerate(context.Background(), "tr a-z A-Z", t.TempDir(), "feat: add login\n\nbody text", time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "FEAT: ADD LOGIN\n\nBODY TEXT" {
+		t.Errorf("unexpected message %q", got)
+	}
+}
+
+func TestPostGenerateFallsBackOnFailure(t *testing.T) {
+	requireShell(t)
+
+	original := "fix: handle nil config"
+	tests := map[string]string{
+		"non-zero exit": "echo broken >&2; exit 3",
+		"empty output":  "cat >/dev/null",
+		"timeout":       "sleep 5",
+	}
+
+	for name, command := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := PostGenerate(context.Background(), command, t.TempDir(), original, 200*time.Millisecond)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if got != original {
+				t.Errorf("expected original message, got %q", got)
+			}
+		})
+	}
+}
+
+func TestPostGenerateEmptyCommand(t *testing.T) {
+	got, err := PostGenerate(context.Background(), "  ", "", "docs: update readme", 0)
+	if err != nil || got != "docs: update readme" {
+		t.Errorf("expected passthrough, got %q, %v", got, err)
+	}
+}
+
+func TestPostGenerateReportsStderr(t *testing.T) {
+	requireShell(t)
+
+	_, err := PostGenerate(context.Background(), "echo 'subject too long' >&2; exit 1", t.TempDir(), "msg", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "subject too long") {
+		t.Errorf("expected stderr in error, got %v", err)
+	}
+}
+
+func TestFormatReceivesInput(t *testing.T) {
+	requireShell(t)
+
+	input := `{"subject":"feat: add login"}`
+	got, err := Format(context.Background(), "cat", t.TempDir(), input, "feat: add login", time.Second)
+	if err != nil || got != input {
+		t.Errorf("expected the formatter to see its input, got %q, %v", got, err)
+	}
+}
+
+func TestFormatFallsBackOnFailure(t *testing.T) {
+	requireShell(t)
+
+	got, err := Format(context.Background(), "echo 'no ticket' >&2; exit 1", t.TempDir(), "{}", "fix: handle nil config", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "message formatter failed: no ticket") {
+		t.Errorf("expected the formatter's stderr in the error, got %v", err)
+	}
+	if got != "fix: handle nil config" {
+		t.Errorf("expected the fallback message, got %q", got)
+	}
+}
@@ -0,0 +1,147 @@
+package preprocess
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// DependencyChange is a dependency whose version changed in a manifest.
+type DependencyChange struct {
+	File string // manifest path, e.g. "go.mod"
+	Name string // module or package name
+	From string // old version; empty when the dependency was added
+	To   string // new version; empty when the dependency was removed
+}
+
+// String describes the change as a commit subject fragment, e.g.
+// "bump github.com/x/y from v1.2.0 to v1.3.0".
+func (c DependencyChange) String() string {
+	switch {
+	case c.From == "":
+		return fmt.Sprintf("add %s %s", c.Name, c.To)
+	case c.To == "":
+		return fmt.Sprintf("remove %s %s", c.Name, c.From)
+	default:
+		return fmt.Sprintf("bump %s from %s to %s", c.Name, c.From, c.To)
+	}
+}
+
+// dependencyFiles are manifests and the lockfiles that change with them.
+var dependencyFiles = map[string]bool{
+	"go.mod":            true,
+	"go.sum":            true,
+	"package.json":      true,
+	"package-lock.json": true,
+	"yarn.lock":         true,
+	"pnpm-lock.yaml":    true,
+}
+
+// IsDependencyFile reports whether path is a dependency manifest or lockfile.
+func IsDependencyFile(path string) bool {
+	return dependencyFiles[filepath.Base(path)]
+}
+
+var (
+	// goModRequirePattern matches a requirement line, inside a require block
+	// or as a single "require" line, and the go and toolchain directives.
+	goModRequirePattern   = regexp.MustCompile(`^\s*(?:require\s+)?([^\s()]+)\s+(v[^\s]+)(?:\s*//.*)?$`)
+	goModDirectivePattern = regexp.MustCompile(`^\s*(go|toolchain)\s+([^\s]+)\s*$`)
+	// packageJSONEntryPattern matches a "name": "version" entry.
+	packageJSONEntryPattern = regexp.MustCompile(`^\s*"([^"]+)"\s*:\s*"([^"]*)"\s*,?\s*$`)
+	// packageJSONSectionPattern matches the start of a dependency section.
+	packageJSONSectionPattern = regexp.MustCompile(`"(?:dependencies|devDependencies|peerDependencies|optionalDependencies)"\s*:\s*\{`)
+	// versionSpecPattern matches npm version specs such as "^1.2.3" or "~0.4".
+	versionSpecPattern = regexp.MustCompile(`^(?:[\^~]|[<>]=?|=)?\s*v?\d`)
+)
+
+// ExtractDependencyChanges lists the dependency version changes in the
+// go.mod and package.json files of a unified diff, in the order they appear.
+// A removed and an added line for the same name become one bump.
+func ExtractDependencyChanges(diff string) []DependencyChange {
+	var changes []DependencyChange
+	index := make(map[string]int) // file + name -> position in changes
+
+	record := func(file, name, version string, added bool) {
+		key := file + "\x00" + name
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(changes)
+			changes = append(changes, DependencyChange{File: file, Name: name})
+			i = len(changes) - 1
+		}
+		if added {
+			changes[i].To = version
+		} else {
+			changes[i].From = version
+		}
+	}
+
+	var file string
+	var inSection *bool // package.json: nil until a section boundary is seen
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			file = extractFilePath(line)
+			inSection = nil
+			continue
+		}
+		if strings.HasPrefix(line, "@@") {
+			inSection = nil
+			continue
+		}
+		if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") || line == "" {
+			continue
+		}
+
+		prefix, content := line[0], line[1:]
+		switch filepath.Base(file) {
+		case "go.mod":
+			if prefix != '+' && prefix != '-' {
+				continue
+			}
+			if m := goModDirectivePattern.FindStringSubmatch(content); m != nil {
+				record(file, m[1], m[2], prefix == '+')
+			} else if m := goModRequirePattern.FindStringSubmatch(content); m != nil && m[1] != "module" {
+				record(file, m[1], m[2], prefix == '+')
+			}
+
+		case "package.json":
+			if packageJSONSectionPattern.MatchString(content) {
+				in := true
+				inSection = &in
+				continue
+			}
+			if strings.TrimSpace(content) == "}" || strings.TrimSpace(content) == "}," {
+				out := false
+				inSection = &out
+				continue
+			}
+			if prefix != '+' && prefix != '-' {
+				continue
+			}
+			m := packageJSONEntryPattern.FindStringSubmatch(content)
+			if m == nil {
+				continue
+			}
+			// Without a section header in view, only trust entries that
+			// look like dependencies rather than package metadata.
+			if inSection == nil && (m[1] == "version" || !versionSpecPattern.MatchString(m[2])) {
+				continue
+			}
+			if inSection != nil && !*inSection {
+				continue
+			}
+			record(file, m[1], m[2], prefix == '+')
+		}
+	}
+
+	// Drop lines that were only moved, not changed.
+	kept := changes[:0]
+	for _, c := range changes {
+		if c.From != c.To {
+			kept = append(kept, c)
+		}
+	}
+	return kept
+}
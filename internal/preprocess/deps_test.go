@@ -0,0 +1,124 @@
+package preprocess
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractDependencyChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		diff     string
+		expected []DependencyChange
+	}{
+		{
+			name: "go.mod bump, add and go directive",
+			diff: `diff --git a/go.mod b/go.mod
+index 1111111..2222222 100644
+--- a/go.mod
++++ b/go.mod
+@@ -1,10 +1,11 @@
+ module github.com/gussy/cmt
+ 
+-go 1.24.0
++go 1.25.3
+ 
+ require (
+-	github.com/charmbracelet/bubbletea v1.3.4
++	github.com/charmbracelet/bubbletea v1.3.6
+ 	github.com/urfave/cli/v3 v3.3.8
++	golang.org/x/sync v0.16.0 // indirect
+ )
+diff --git a/go.sum b/go.sum
+index 3333333..4444444 100644
+--- a/go.sum
++++ b/go.sum
+@@ -1,2 +1,2 @@
+-github.com/charmbracelet/bubbletea v1.3.4 h1:abc=
++github.com/charmbracelet/bubbletea v1.3.6 h1:def=
+`,
+			expected: []DependencyChange{
+				{File: "go.mod", Name: "go", From: "1.24.0", To: "1.25.3"},
+				{File: "go.mod", Name: "github.com/charmbracelet/bubbletea", From: "v1.3.4", To: "v1.3.6"},
+				{File: "go.mod", Name: "golang.org/x/sync", To: "v0.16.0"},
+			},
+		},
+		{
+			name: "package.json with section header in context",
+			diff: `diff --git a/package.json b/package.json
+index 1111111..2222222 100644
+--- a/package.json
++++ b/package.json
+@@ -5,9 +5,9 @@
+   "dependencies": {
+-    "react": "^18.2.0",
++    "react": "^18.3.1",
+     "react-dom": "^18.2.0"
+   },
+   "devDependencies": {
+-    "eslint": "^8.57.0"
++    "typescript": "~5.4.0"
+   }
+`,
+			expected: []DependencyChange{
+				{File: "package.json", Name: "react", From: "^18.2.0", To: "^18.3.1"},
+				{File: "package.json", Name: "eslint", From: "^8.57.0"},
+				{File: "package.json", Name: "typescript", To: "~5.4.0"},
+			},
+		},
+		{
+			name: "package.json metadata is not a dependency",
+			diff: `diff --git a/package.json b/package.json
+index 1111111..2222222 100644
+--- a/package.json
++++ b/package.json
+@@ -1,4 +1,4 @@
+ {
+   "name": "app",
+-  "version": "1.0.0",
++  "version": "1.1.0",
+`,
+			expected: nil,
+		},
+		{
+			name: "other files ignored",
+			diff: `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1 +1 @@
+-	github.com/x/y v1.0.0
++	github.com/x/y v1.1.0
+`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractDependencyChanges(tt.diff)
+			if len(got) == 0 && len(tt.expected) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ExtractDependencyChanges() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDependencyChangeString(t *testing.T) {
+	tests := []struct {
+		change   DependencyChange
+		expected string
+	}{
+		{DependencyChange{Name: "react", From: "^18.2.0", To: "^18.3.1"}, "bump react from ^18.2.0 to ^18.3.1"},
+		{DependencyChange{Name: "golang.org/x/sync", To: "v0.16.0"}, "add golang.org/x/sync v0.16.0"},
+		{DependencyChange{Name: "eslint", From: "^8.57.0"}, "remove eslint ^8.57.0"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.change.String(); got != tt.expected {
+			t.Errorf("String() = %q, expected %q", got, tt.expected)
+		}
+	}
+}
@@ -23,6 +23,11 @@ type Options struct {
 	// FilterGenerated determines whether to filter out generated/lock files.
 	// Default is true.
 	FilterGenerated bool
+
+	// BinaryFiles is git's classification of the diff's files by path:
+	// true for binary, false for text. It takes precedence over the
+	// extension list, which still decides for files it doesn't cover.
+	BinaryFiles map[string]bool
 }
 
 // Default returns default preprocessing options.
@@ -240,21 +245,35 @@ func shouldSkipFile(path string, opts Options) bool {
 		}
 	}
 
-	// Check for binary files by extension
-	if opts.FilterBinary {
-		ext := strings.ToLower(filepath.Ext(path))
-		if binaryExtensions[ext] {
-			return true
-		}
+	// Check for binary files
+	if opts.FilterBinary && isBinary(path, opts) {
+		return true
 	}
 
 	return false
 }
 
+// isBinary reports whether path is a binary file, going by opts.BinaryFiles
+// when it covers path and by the extension otherwise.
+func isBinary(path string, opts Options) bool {
+	if binary, ok := opts.BinaryFiles[path]; ok {
+		return binary
+	}
+	return binaryExtensions[strings.ToLower(filepath.Ext(path))]
+}
+
+// SkipReason reports why content for path would be filtered under opts.
+// It returns an empty string when the file is kept.
+func SkipReason(path string, opts Options) string {
+	if !shouldSkipFile(path, opts) {
+		return ""
+	}
+	return fileFilterReason(path, opts)
+}
+
 // fileFilterReason returns a human-readable reason for why a file was filtered.
 func fileFilterReason(path string, opts Options) string {
 	filename := filepath.Base(path)
-	ext := strings.ToLower(filepath.Ext(path))
 
 	if opts.FilterGenerated && generatedFiles[filename] {
 		return "generated/lock file content filtered"
@@ -262,7 +281,7 @@ func fileFilterReason(path string, opts Options) string {
 	if opts.FilterMinified && (strings.Contains(filename, ".min.js") || strings.Contains(filename, ".min.css")) {
 		return "minified file content filtered"
 	}
-	if opts.FilterBinary && binaryExtensions[ext] {
+	if opts.FilterBinary && isBinary(path, opts) {
 		return "binary file content filtered"
 	}
 	return "file content filtered"
@@ -312,6 +331,35 @@ type FilterStats struct {
 	Truncated      bool
 }
 
+// HasTextChanges reports whether a (processed) diff has anything for the
+// model to describe: added or removed lines in a hunk, or a text file that
+// was renamed, created, deleted or had its mode changed. A diff of only
+// binary or filtered files has file headers but no text changes.
+func HasTextChanges(diff string) bool {
+	var inHunk, withheld, metadata bool
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git"):
+			if metadata && !withheld {
+				return true
+			}
+			inHunk, withheld, metadata = false, false, false
+		case strings.HasPrefix(line, "@@"):
+			inHunk = true
+		case inHunk:
+			// Inside a hunk, "---" and "+++" are content, not file headers.
+			if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+				return true
+			}
+		case strings.HasPrefix(line, "Binary file"), strings.HasSuffix(line, "content filtered)"):
+			withheld = true
+		case isFileMetadataLine(line):
+			metadata = true
+		}
+	}
+	return metadata && !withheld
+}
+
 // ProcessWithStats preprocesses a git diff and returns statistics about what was filtered.
 func ProcessWithStats(diff string, opts Options) (string, *FilterStats) {
 	// Use defaults if options are zero
@@ -325,24 +373,27 @@ func ProcessWithStats(diff string, opts Options) (string, *FilterStats) {
 	var currentFile string
 	var skipCurrentFile bool
 	tokensUsed := 0
+	shownLines := 0 // lines of currentFile included so far
+	cut := len(lines)
 
-	for _, line := range lines {
+	for i, line := range lines {
 		// Check if we've exceeded token limit
 		lineTokens := estimateTokens(line)
 		if tokensUsed+lineTokens > opts.MaxTokens {
 			stats.Truncated = true
+			cut = i
 			break
 		}
 
 		// Check for file header
 		if strings.HasPrefix(line, "diff --git") {
 			currentFile = extractFilePath(line)
+			shownLines = 0
 			stats.TotalFiles++
 
 			// Check why we might skip this file
 			skipCurrentFile = false
 			filename := filepath.Base(currentFile)
-			ext := strings.ToLower(filepath.Ext(currentFile))
 
 			if opts.FilterGenerated && generatedFiles[filename] {
 				skipCurrentFile = true
@@ -352,7 +403,7 @@ func ProcessWithStats(diff string, opts Options) (string, *FilterStats) {
 				skipCurrentFile = true
 				stats.MinifiedFiles++
 				stats.FilteredFiles++
-			} else if opts.FilterBinary && binaryExtensions[ext] {
+			} else if opts.FilterBinary && isBinary(currentFile, opts) {
 				skipCurrentFile = true
 				stats.BinaryFiles++
 				stats.FilteredFiles++
@@ -398,14 +449,62 @@ func ProcessWithStats(diff string, opts Options) (string, *FilterStats) {
 		// Add the line to result
 		result = append(result, line)
 		tokensUsed += lineTokens
+		if line != "" {
+			shownLines++
+		}
 	}
 
 	stats.TokensUsed = tokensUsed
 
-	// Add truncation indicator if needed
+	// Add truncation indicator if needed, and say which files were cut
+	// short so the model doesn't describe them as if it saw them whole.
 	if stats.Truncated {
 		result = append(result, "", fmt.Sprintf("... (diff truncated at %d tokens, limit: %d)", tokensUsed, opts.MaxTokens))
+		result = append(result, truncationMarkers(lines[cut:], currentFile, skipCurrentFile, shownLines, opts)...)
 	}
 
 	return strings.Join(result, "\n"), stats
 }
+
+// truncationMarkers returns a "(file X: N of M lines shown, rest omitted)"
+// line for each file with lines past the cut. rest is the diff from the
+// cut on; current is the file the cut fell in, with shown of its lines
+// already included. Filtered files never show their content, so they get
+// no marker.
+func truncationMarkers(rest []string, current string, skipCurrent bool, shown int, opts Options) []string {
+	type tally struct {
+		path         string
+		shown, total int
+		skip         bool
+	}
+
+	var files []*tally
+	if current != "" && !strings.HasPrefix(rest[0], "diff --git") {
+		files = append(files, &tally{path: current, shown: shown, total: shown, skip: skipCurrent})
+	}
+	for _, line := range rest {
+		if strings.HasPrefix(line, "diff --git") {
+			path := extractFilePath(line)
+			files = append(files, &tally{path: path, skip: shouldSkipFile(path, opts)})
+			continue
+		}
+		// Lines of a diff are never empty; the last one of a diff ending
+		// in a newline is.
+		if len(files) == 0 || line == "" {
+			continue
+		}
+		f := files[len(files)-1]
+		if opts.FilterBinary && strings.Contains(line, "Binary files") && strings.Contains(line, "differ") {
+			f.skip = true
+		}
+		f.total++
+	}
+
+	var markers []string
+	for _, f := range files {
+		if !f.skip && f.total > f.shown {
+			markers = append(markers, fmt.Sprintf("(file %s: %d of %d lines shown, rest omitted)", f.path, f.shown, f.total))
+		}
+	}
+	return markers
+}
@@ -438,6 +438,76 @@ diff --git a/main.go b/main.go
 	}
 }
 
+func TestProcessWithStatsTruncationMarkers(t *testing.T) {
+	diff := `diff --git a/a.go b/a.go
++// first line of a.go, long enough to count
++// second line of a.go, long enough to count
++// third line of a.go, long enough to count
++// fourth line of a.go, long enough to count
+diff --git a/logo.png b/logo.png
+Binary files differ
+diff --git a/b.go b/b.go
++// only line of b.go
+`
+
+	// The header and two lines of a.go fit in the limit.
+	result, stats := ProcessWithStats(diff, Options{FilterBinary: true, MaxTokens: 30})
+	if !stats.Truncated {
+		t.Fatal("expected the diff to be truncated")
+	}
+
+	for _, want := range []string{
+		"(file a.go: 2 of 4 lines shown, rest omitted)",
+		"(file b.go: 0 of 1 lines shown, rest omitted)",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected marker %q, got:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "file logo.png:") {
+		t.Errorf("expected no marker for a filtered file, got:\n%s", result)
+	}
+
+	if result, _ := ProcessWithStats(diff, Options{FilterBinary: true, MaxTokens: 1000}); strings.Contains(result, "rest omitted") {
+		t.Errorf("expected no markers without truncation, got:\n%s", result)
+	}
+}
+
+func TestProcessWithStatsBinaryFiles(t *testing.T) {
+	diff := `diff --git a/logo.svg b/logo.svg
++<svg viewBox="0 0 10 10"></svg>
+diff --git a/firmware b/firmware
++\x7fELF
+diff --git a/photo.png b/photo.png
+Binary files differ`
+
+	// git's classification wins over the extension list, which still
+	// decides for files it doesn't cover
+	opts := Options{
+		FilterBinary: true,
+		MaxTokens:    1000,
+		BinaryFiles:  map[string]bool{"logo.svg": false, "firmware": true},
+	}
+	result, stats := ProcessWithStats(diff, opts)
+
+	if !strings.Contains(result, "<svg viewBox") {
+		t.Error("expected the SVG marked as text to keep its content")
+	}
+	if strings.Contains(result, "ELF") {
+		t.Error("expected the extensionless binary to be filtered")
+	}
+	if stats.BinaryFiles != 2 {
+		t.Errorf("expected 2 binary files, got %d", stats.BinaryFiles)
+	}
+	if got := Process(diff, opts); got != result {
+		t.Errorf("Process and ProcessWithStats disagree:\n%s\n---\n%s", got, result)
+	}
+
+	if reason := SkipReason("logo.svg", Options{FilterBinary: true}); reason != "binary file content filtered" {
+		t.Errorf("expected the extension to decide without a classification, got %q", reason)
+	}
+}
+
 func TestDefaultOptions(t *testing.T) {
 	opts := DefaultOptions()
 
@@ -482,3 +552,45 @@ func generateLongDiff() string {
 	}
 	return sb.String()
 }
+
+func TestSkipReason(t *testing.T) {
+	opts := DefaultOptions()
+
+	tests := map[string]string{
+		"main.go":         "",
+		"go.sum":          "generated/lock file content filtered",
+		"dist/app.min.js": "minified file content filtered",
+		"assets/logo.png": "binary file content filtered",
+		"docs/readme.md":  "",
+	}
+
+	for path, want := range tests {
+		if got := SkipReason(path, opts); got != want {
+			t.Errorf("SkipReason(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestHasTextChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want bool
+	}{
+		{"text change", "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-old\n+new", true},
+		{"binary only", "diff --git a/logo.png b/logo.png\nnew file mode 100644\n(binary file)\nBinary files /dev/null and b/logo.png differ", false},
+		{"headers only", "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go", false},
+		{"filtered lock file", "diff --git a/go.sum b/go.sum\ndeleted file mode 100644\n(generated/lock file content filtered)", false},
+		{"rename only", "diff --git a/old.go b/new.go\nsimilarity index 100%\nrename from old.go\nrename to new.go", true},
+		{"mode only", "diff --git a/run.sh b/run.sh\nold mode 100644\nnew mode 100755", true},
+		{"content that looks like headers", "diff --git a/a.md b/a.md\n--- a/a.md\n+++ b/a.md\n@@ -1,2 +1 @@\n title\n----", true},
+		{"binary then text", "diff --git a/logo.png b/logo.png\nBinary files a/logo.png and b/logo.png differ\ndiff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-old\n+new", true},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := HasTextChanges(tt.diff); got != tt.want {
+			t.Errorf("%s: HasTextChanges() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
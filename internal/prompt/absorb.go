@@ -0,0 +1,434 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gussy/cmt/internal/git"
+)
+
+// AbsorbRequest is the provider-independent input for absorb analysis.
+type AbsorbRequest struct {
+	// Hunks are the staged hunks to assign.
+	Hunks []git.Hunk
+	// Commits are the candidate commits, oldest first.
+	Commits []git.CommitInfo
+	// Strategy is "interactive" (offer alternatives) or "best-match".
+	Strategy string
+	// ConfidenceThreshold is the minimum confidence for best-match.
+	ConfidenceThreshold float64
+	// GroupByFile has the model assign whole files rather than single
+	// hunks, for diffs with too many hunks to look at one by one.
+	GroupByFile bool
+	// FileHistory lists, for each staged file, the candidate commits that
+	// touched it: a cheap signal of which commits a hunk's file belongs to.
+	FileHistory bool
+}
+
+// HunkAssignment represents the AI's assignment of a hunk to a commit.
+type HunkAssignment struct {
+	// Hunk is the hunk being assigned.
+	Hunk git.Hunk
+	// CommitSHA is the target commit SHA.
+	CommitSHA string
+	// CommitMessage is the first line of the commit message.
+	CommitMessage string
+	// Confidence is the AI's confidence in this assignment (0.0 to 1.0).
+	Confidence float64
+	// Reasoning is the AI's explanation for this assignment.
+	Reasoning string
+	// Alternatives are other possible assignments with lower confidence.
+	Alternatives []AlternativeAssignment
+	// Hunks, when set, are all the hunks of Hunk.FilePath, assigned
+	// together because the analysis grouped hunks by file. Hunk is the
+	// first of them.
+	Hunks []git.Hunk
+}
+
+// AllHunks returns the hunks the assignment covers: every hunk of the file
+// for a grouped assignment, else just Hunk.
+func (a HunkAssignment) AllHunks() []git.Hunk {
+	if len(a.Hunks) > 0 {
+		return a.Hunks
+	}
+	return []git.Hunk{a.Hunk}
+}
+
+// AlternativeAssignment represents an alternative commit for a hunk.
+type AlternativeAssignment struct {
+	CommitSHA     string
+	CommitMessage string
+	Confidence    float64
+	Reasoning     string
+}
+
+// AbsorbResponseSchema documents the JSON object the model must return for
+// absorb analysis. ParseAbsorbResponse reads this shape.
+const AbsorbResponseSchema = `{
+  "assignments": [
+    {
+      "hunk_index": 0,  // 0-based index of the hunk
+      "commit_sha": "abc123...",  // Full SHA of the target commit
+      "confidence": 0.95,  // Confidence score 0.0 to 1.0
+      "reasoning": "This hunk modifies the same function...",
+      "alternatives": [  // Optional, only if strategy is 'interactive'
+        {
+          "commit_sha": "def456...",
+          "confidence": 0.7,
+          "reasoning": "Could also relate to..."
+        }
+      ]
+    }
+  ],
+  "unmatched_hunks": [0, 2]  // Indices of hunks that don't match any commit
+}
+`
+
+// absorbJSONResponse is the structure for parsing the AI's JSON response.
+type absorbJSONResponse struct {
+	Assignments []struct {
+		HunkIndex    int     `json:"hunk_index"`
+		CommitSHA    string  `json:"commit_sha"`
+		Confidence   float64 `json:"confidence"`
+		Reasoning    string  `json:"reasoning"`
+		Alternatives []struct {
+			CommitSHA  string  `json:"commit_sha"`
+			Confidence float64 `json:"confidence"`
+			Reasoning  string  `json:"reasoning"`
+		} `json:"alternatives,omitempty"`
+	} `json:"assignments"`
+	UnmatchedHunks []int `json:"unmatched_hunks"`
+}
+
+// DefaultAbsorbLeftoverPrompt tells the model that the changes it describes
+// are the hunks absorb could not fold into an earlier commit.
+const DefaultAbsorbLeftoverPrompt = `These changes were left over by cmt absorb: they did not fit any of the recent commits, so they get a commit of their own.
+Describe only what these hunks do, as a focused standalone change.
+Do not mention absorb, fixups or the earlier commits.`
+
+// BuildAbsorbPrompt builds the prompt for hunk assignment analysis.
+// Providers send it as is and pass the reply to ParseAbsorbResponse.
+func BuildAbsorbPrompt(req AbsorbRequest) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You are analyzing git diff hunks to determine which previous commits they should be absorbed into.\n")
+	prompt.WriteString("Each hunk should be matched with the most semantically related commit based on:\n")
+	prompt.WriteString("1. File paths and names\n")
+	prompt.WriteString("2. Code context and functionality\n")
+	prompt.WriteString("3. Commit message relevance\n")
+	prompt.WriteString("4. Related changes in the same area\n\n")
+
+	if req.Strategy == "best-match" {
+		prompt.WriteString(fmt.Sprintf("Confidence threshold: %.2f (assign only if confidence is above this)\n", req.ConfidenceThreshold))
+		prompt.WriteString("Strategy: Choose the single best matching commit for each hunk.\n\n")
+	} else {
+		prompt.WriteString("Strategy: Provide alternatives when multiple commits could match.\n\n")
+	}
+
+	// Add commits information.
+	prompt.WriteString("Available commits (from oldest to newest):\n")
+	prompt.WriteString("=====================================\n")
+	for i, commit := range req.Commits {
+		// Get first line of commit message.
+		lines := strings.Split(commit.Message, "\n")
+		firstLine := lines[0]
+		if len(firstLine) > 72 {
+			firstLine = firstLine[:69] + "..."
+		}
+
+		prompt.WriteString(fmt.Sprintf("\nCommit %d: %s\n", i+1, shortSHA(commit.SHA)))
+		prompt.WriteString(fmt.Sprintf("Message: %s\n", firstLine))
+
+		// Add the files the commit touched.
+		if files := commitFiles(commit); len(files) > 0 {
+			prompt.WriteString("Changed files:\n")
+			for _, file := range files {
+				prompt.WriteString(fmt.Sprintf("  - %s\n", file))
+			}
+		}
+	}
+
+	if req.FileHistory {
+		writeFileHistory(&prompt, req.Hunks, req.Commits)
+	}
+
+	if req.GroupByFile {
+		writeFileGroups(&prompt, groupHunksByFile(req.Hunks))
+	} else {
+		writeHunks(&prompt, req.Hunks)
+	}
+
+	// Request structured output.
+	prompt.WriteString("\n\nProvide your analysis as a JSON object with this structure:\n")
+	prompt.WriteString("```json\n")
+	prompt.WriteString(AbsorbResponseSchema)
+	prompt.WriteString("```\n\n")
+	if req.GroupByFile {
+		prompt.WriteString("Each file is one unit: use its index as hunk_index and assign the whole file.\n")
+	}
+	prompt.WriteString("Return ONLY the JSON object, no additional explanation.")
+
+	return prompt.String()
+}
+
+// commitFiles returns the files a commit touched: its Files, or for a
+// commit read without them the files named in its diff.
+func commitFiles(commit git.CommitInfo) []string {
+	if len(commit.Files) > 0 {
+		return commit.Files
+	}
+	var files []string
+	for _, line := range strings.Split(commit.Diff, "\n") {
+		if strings.HasPrefix(line, "diff --git") {
+			parts := strings.Split(line, " ")
+			if len(parts) >= 4 {
+				files = append(files, strings.TrimPrefix(parts[3], "b/"))
+			}
+		}
+	}
+	return files
+}
+
+// writeFileHistory lists each staged file with the candidate commits that
+// touched it, by their number in the commit list, newest first.
+func writeFileHistory(prompt *strings.Builder, hunks []git.Hunk, commits []git.CommitInfo) {
+	touched := make(map[string][]int)
+	for i, commit := range commits {
+		for _, file := range commitFiles(commit) {
+			touched[file] = append(touched[file], i+1)
+		}
+	}
+
+	prompt.WriteString("\nStaged files and the commits above that touched them (newest first):\n")
+	for _, group := range groupHunksByFile(hunks) {
+		file := group[0].FilePath
+		numbers := touched[file]
+		if len(numbers) == 0 {
+			prompt.WriteString(fmt.Sprintf("  - %s: none\n", file))
+			continue
+		}
+		refs := make([]string, len(numbers))
+		for i, n := range numbers {
+			refs[len(numbers)-1-i] = fmt.Sprintf("Commit %d", n)
+		}
+		prompt.WriteString(fmt.Sprintf("  - %s: %s\n", file, strings.Join(refs, ", ")))
+	}
+}
+
+// writeHunks lists hunks for the model to assign one by one.
+func writeHunks(prompt *strings.Builder, hunks []git.Hunk) {
+	prompt.WriteString("\n\nHunks to analyze:\n")
+	prompt.WriteString("================\n")
+	for i, hunk := range hunks {
+		prompt.WriteString(fmt.Sprintf("\nHunk %d:\n", i+1))
+		prompt.WriteString(fmt.Sprintf("File: %s\n", hunk.FilePath))
+		if hunk.IsNew {
+			prompt.WriteString("Status: NEW FILE\n")
+		} else if hunk.IsDeleted {
+			prompt.WriteString("Status: DELETED FILE\n")
+		} else if hunk.IsRenamed {
+			prompt.WriteString(fmt.Sprintf("Status: RENAMED from %s\n", hunk.OldFilePath))
+		}
+		prompt.WriteString(fmt.Sprintf("Lines: %s\n", hunk.Header))
+		prompt.WriteString("Content:\n```diff\n")
+		prompt.WriteString(hunk.Content)
+		prompt.WriteString("```\n")
+	}
+}
+
+// maxFileGroupLines caps the diff lines shown for each file when hunks are
+// grouped by file.
+const maxFileGroupLines = 40
+
+// writeFileGroups lists files, each with its hunk headers and the start of
+// its changes, for the model to assign as a whole.
+func writeFileGroups(prompt *strings.Builder, groups [][]git.Hunk) {
+	prompt.WriteString("\n\nFiles to analyze (too many hunks to list one by one, so each file is assigned as a whole):\n")
+	prompt.WriteString("================\n")
+	for i, group := range groups {
+		first := group[0]
+		prompt.WriteString(fmt.Sprintf("\nFile index %d: %s\n", i, first.FilePath))
+		if first.IsNew {
+			prompt.WriteString("Status: NEW FILE\n")
+		} else if first.IsDeleted {
+			prompt.WriteString("Status: DELETED FILE\n")
+		} else if first.IsRenamed {
+			prompt.WriteString(fmt.Sprintf("Status: RENAMED from %s\n", first.OldFilePath))
+		}
+
+		headers := make([]string, len(group))
+		var lines []string
+		for j, hunk := range group {
+			headers[j] = hunk.Header
+			lines = append(lines, strings.Split(strings.TrimRight(hunk.Content, "\n"), "\n")...)
+		}
+		prompt.WriteString(fmt.Sprintf("Hunks: %d (%s)\n", len(group), strings.Join(headers, ", ")))
+
+		omitted := 0
+		if len(lines) > maxFileGroupLines {
+			omitted = len(lines) - maxFileGroupLines
+			lines = lines[:maxFileGroupLines]
+		}
+		prompt.WriteString("Content:\n```diff\n")
+		prompt.WriteString(strings.Join(lines, "\n"))
+		prompt.WriteString("\n")
+		if omitted > 0 {
+			prompt.WriteString(fmt.Sprintf("... (%d more lines)\n", omitted))
+		}
+		prompt.WriteString("```\n")
+	}
+}
+
+// groupHunksByFile groups hunks by file, in the order files first appear.
+func groupHunksByFile(hunks []git.Hunk) [][]git.Hunk {
+	var groups [][]git.Hunk
+	index := make(map[string]int)
+	for _, hunk := range hunks {
+		i, ok := index[hunk.FilePath]
+		if !ok {
+			i = len(groups)
+			index[hunk.FilePath] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], hunk)
+	}
+	return groups
+}
+
+// ParseAbsorbResponse parses the model's JSON reply to a BuildAbsorbPrompt
+// prompt. Commit SHAs are resolved to the full SHA of the matching
+// candidate, and every hunk not assigned (or below the best-match
+// threshold) is returned as unmatched.
+func ParseAbsorbResponse(response string, req AbsorbRequest) ([]HunkAssignment, []git.Hunk, error) {
+	// Clean the response to extract JSON.
+	response = strings.TrimSpace(response)
+
+	// Remove code block markers if present.
+	if strings.Contains(response, "```json") {
+		start := strings.Index(response, "{")
+		end := strings.LastIndex(response, "}")
+		if start >= 0 && end > start {
+			response = response[start : end+1]
+		}
+	}
+
+	// Parse JSON.
+	var jsonResp absorbJSONResponse
+	if err := json.Unmarshal([]byte(response), &jsonResp); err != nil {
+		// Try to extract JSON from the response.
+		lines := strings.Split(response, "\n")
+		var jsonStr strings.Builder
+		inJSON := false
+		for _, line := range lines {
+			if strings.Contains(line, "{") {
+				inJSON = true
+			}
+			if inJSON {
+				jsonStr.WriteString(line + "\n")
+			}
+			if strings.Contains(line, "}") && inJSON {
+				break
+			}
+		}
+		if jsonStr.Len() > 0 {
+			if err := json.Unmarshal([]byte(jsonStr.String()), &jsonResp); err != nil {
+				return nil, nil, fmt.Errorf("failed to parse JSON: %w", err)
+			}
+		} else {
+			return nil, nil, fmt.Errorf("no valid JSON found in response")
+		}
+	}
+
+	assignments := []HunkAssignment{}
+	unmatched := []git.Hunk{}
+
+	// The indices refer to hunks, or to files when they were grouped.
+	units := make([][]git.Hunk, len(req.Hunks))
+	if req.GroupByFile {
+		units = groupHunksByFile(req.Hunks)
+	} else {
+		for i, hunk := range req.Hunks {
+			units[i] = []git.Hunk{hunk}
+		}
+	}
+
+	// Track which hunks were assigned.
+	assignedHunks := make(map[int]bool)
+
+	// Process assignments.
+	for _, assignment := range jsonResp.Assignments {
+		if assignment.HunkIndex < 0 || assignment.HunkIndex >= len(units) {
+			continue
+		}
+
+		unit := units[assignment.HunkIndex]
+		assignedHunks[assignment.HunkIndex] = true
+
+		commitSHA, commitMessage := resolveCommit(req.Commits, assignment.CommitSHA)
+		hunkAssignment := HunkAssignment{
+			Hunk:          unit[0],
+			CommitSHA:     commitSHA,
+			CommitMessage: commitMessage,
+			Confidence:    assignment.Confidence,
+			Reasoning:     assignment.Reasoning,
+		}
+		if req.GroupByFile {
+			hunkAssignment.Hunks = unit
+		}
+
+		// Process alternatives.
+		for _, alt := range assignment.Alternatives {
+			altSHA, altMessage := resolveCommit(req.Commits, alt.CommitSHA)
+			hunkAssignment.Alternatives = append(hunkAssignment.Alternatives, AlternativeAssignment{
+				CommitSHA:     altSHA,
+				CommitMessage: altMessage,
+				Confidence:    alt.Confidence,
+				Reasoning:     alt.Reasoning,
+			})
+		}
+
+		// Apply confidence threshold if using best-match strategy.
+		if req.Strategy == "best-match" && assignment.Confidence < req.ConfidenceThreshold {
+			unmatched = append(unmatched, unit...)
+		} else {
+			assignments = append(assignments, hunkAssignment)
+		}
+	}
+
+	// Every hunk the model didn't assign is unmatched, whether or not it
+	// was listed under unmatched_hunks.
+	for i, unit := range units {
+		if !assignedHunks[i] {
+			unmatched = append(unmatched, unit...)
+		}
+	}
+
+	return assignments, unmatched, nil
+}
+
+// resolveCommit finds the candidate commit a (possibly abbreviated) SHA
+// from the model refers to, returning its full SHA and subject. Unknown
+// SHAs are returned unchanged with an empty subject.
+func resolveCommit(commits []git.CommitInfo, sha string) (string, string) {
+	prefix := shortSHA(sha)
+	if prefix == "" {
+		return sha, ""
+	}
+	for _, commit := range commits {
+		if strings.HasPrefix(commit.SHA, prefix) {
+			subject, _, _ := strings.Cut(commit.Message, "\n")
+			return commit.SHA, subject
+		}
+	}
+	return sha, ""
+}
+
+// shortSHA abbreviates a SHA to the 8 characters used in prompts and
+// changelogs.
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
@@ -0,0 +1,200 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gussy/cmt/internal/git"
+)
+
+func absorbTestRequest() AbsorbRequest {
+	return AbsorbRequest{
+		Hunks: []git.Hunk{
+			{FilePath: "auth.go", Header: "@@ -1 +1 @@", Content: "-a\n+b\n"},
+			{FilePath: "api.go", Header: "@@ -5 +5 @@", Content: "-c\n+d\n"},
+			{FilePath: "README.md", Header: "@@ -9 +9 @@", Content: "-e\n+f\n"},
+		},
+		Commits: []git.CommitInfo{
+			{SHA: "1111111122222222333333334444444455555555", Message: "feat: add auth\n\nbody"},
+			{SHA: "aaaaaaaabbbbbbbbccccccccddddddddeeeeeeee", Message: "feat: add api"},
+		},
+		Strategy:            "interactive",
+		ConfidenceThreshold: 0.7,
+	}
+}
+
+func TestBuildAbsorbPrompt(t *testing.T) {
+	result := BuildAbsorbPrompt(absorbTestRequest())
+
+	for _, want := range []string{"Commit 1: 11111111", "Message: feat: add auth\n", "File: api.go", AbsorbResponseSchema} {
+		if !strings.Contains(result, want) {
+			t.Errorf("absorb prompt missing %q", want)
+		}
+	}
+}
+
+func TestParseAbsorbResponse(t *testing.T) {
+	req := absorbTestRequest()
+	response := "Here you go:\n```json\n" + `{
+  "assignments": [
+    {"hunk_index": 0, "commit_sha": "11111111", "confidence": 0.9, "reasoning": "same file",
+     "alternatives": [{"commit_sha": "aaaaaaaa", "confidence": 0.4, "reasoning": "maybe"}]},
+    {"hunk_index": 7, "commit_sha": "aaaaaaaa", "confidence": 0.9, "reasoning": "out of range"}
+  ],
+  "unmatched_hunks": [2]
+}` + "\n```"
+
+	assignments, unmatched, err := ParseAbsorbResponse(response, req)
+	if err != nil {
+		t.Fatalf("ParseAbsorbResponse() error = %v", err)
+	}
+
+	if len(assignments) != 1 {
+		t.Fatalf("expected 1 assignment, got %d", len(assignments))
+	}
+	a := assignments[0]
+	if a.Hunk.FilePath != "auth.go" || a.CommitSHA != req.Commits[0].SHA || a.CommitMessage != "feat: add auth" {
+		t.Errorf("assignment = %+v, want auth.go -> full SHA of feat: add auth", a)
+	}
+	if len(a.Alternatives) != 1 || a.Alternatives[0].CommitSHA != req.Commits[1].SHA {
+		t.Errorf("alternatives = %+v, want the api commit", a.Alternatives)
+	}
+
+	// Hunk 1 was never mentioned and hunk 2 was listed as unmatched.
+	var paths []string
+	for _, h := range unmatched {
+		paths = append(paths, h.FilePath)
+	}
+	if strings.Join(paths, ",") != "api.go,README.md" {
+		t.Errorf("unmatched = %v, want [api.go README.md]", paths)
+	}
+}
+
+func TestParseAbsorbResponseBestMatchThreshold(t *testing.T) {
+	req := absorbTestRequest()
+	req.Strategy = "best-match"
+	response := `{"assignments": [
+		{"hunk_index": 0, "commit_sha": "11111111", "confidence": 0.9},
+		{"hunk_index": 1, "commit_sha": "aaaaaaaa", "confidence": 0.5},
+		{"hunk_index": 2, "commit_sha": "abc", "confidence": 0.8}
+	]}`
+
+	assignments, unmatched, err := ParseAbsorbResponse(response, req)
+	if err != nil {
+		t.Fatalf("ParseAbsorbResponse() error = %v", err)
+	}
+	if len(assignments) != 2 || len(unmatched) != 1 || unmatched[0].FilePath != "api.go" {
+		t.Errorf("got %d assignments and unmatched %v, want 2 and [api.go]", len(assignments), unmatched)
+	}
+	// A short unknown SHA is kept as is rather than panicking.
+	if assignments[1].CommitSHA != "abc" || assignments[1].CommitMessage != "" {
+		t.Errorf("unknown SHA assignment = %+v", assignments[1])
+	}
+}
+
+func TestParseAbsorbResponseInvalid(t *testing.T) {
+	for _, response := range []string{"", "no json here", "{not valid"} {
+		if _, _, err := ParseAbsorbResponse(response, absorbTestRequest()); err == nil {
+			t.Errorf("ParseAbsorbResponse(%q) should fail", response)
+		}
+	}
+}
+
+// manyHunksRequest has 120 hunks spread over three files.
+func manyHunksRequest() AbsorbRequest {
+	req := absorbTestRequest()
+	req.Hunks = nil
+	for i := 0; i < 120; i++ {
+		file := []string{"auth.go", "api.go", "README.md"}[i%3]
+		req.Hunks = append(req.Hunks, git.Hunk{
+			FilePath: file,
+			Header:   fmt.Sprintf("@@ -%d +%d @@", i*10, i*10),
+			Content:  fmt.Sprintf("@@ -%d +%d @@\n-old %d\n+new %d\n", i*10, i*10, i, i),
+		})
+	}
+	req.GroupByFile = true
+	return req
+}
+
+func TestBuildAbsorbPromptFileHistory(t *testing.T) {
+	req := absorbTestRequest()
+	req.Commits[0].Files = []string{"auth.go", "auth_test.go"}
+	// A commit read without Files falls back to the files in its diff
+	req.Commits[1].Diff = "diff --git a/api.go b/api.go\n--- a/api.go\n+++ b/api.go\n" +
+		"diff --git a/auth.go b/auth.go\n--- a/auth.go\n+++ b/auth.go\n"
+	req.FileHistory = true
+
+	result := BuildAbsorbPrompt(req)
+	for _, want := range []string{
+		"Message: feat: add auth\nChanged files:\n  - auth.go\n  - auth_test.go\n",
+		"Message: feat: add api\nChanged files:\n  - api.go\n  - auth.go\n",
+		"  - auth.go: Commit 2, Commit 1\n",
+		"  - api.go: Commit 2\n",
+		"  - README.md: none\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("absorb prompt missing %q", want)
+		}
+	}
+
+	req.FileHistory = false
+	if result := BuildAbsorbPrompt(req); strings.Contains(result, "touched them") {
+		t.Error("expected no file history when FileHistory is off")
+	}
+}
+
+func TestBuildAbsorbPromptGroupByFile(t *testing.T) {
+	result := BuildAbsorbPrompt(manyHunksRequest())
+
+	for _, want := range []string{"File index 0: auth.go", "File index 1: api.go", "File index 2: README.md", "Hunks: 40 (", "more lines)", "assign the whole file"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("grouped absorb prompt missing %q", want)
+		}
+	}
+	if strings.Contains(result, "Hunk 1:") || strings.Contains(result, "+new 119") {
+		t.Error("expected hunks to be summarized per file, not listed one by one")
+	}
+}
+
+func TestParseAbsorbResponseGroupByFile(t *testing.T) {
+	req := manyHunksRequest()
+	response := `{"assignments": [
+		{"hunk_index": 0, "commit_sha": "11111111", "confidence": 0.9, "reasoning": "auth changes"},
+		{"hunk_index": 1, "commit_sha": "aaaaaaaa", "confidence": 0.8, "reasoning": "api changes"}
+	], "unmatched_hunks": [2]}`
+
+	assignments, unmatched, err := ParseAbsorbResponse(response, req)
+	if err != nil {
+		t.Fatalf("ParseAbsorbResponse() error = %v", err)
+	}
+	if len(assignments) != 2 {
+		t.Fatalf("expected one assignment per file, got %d", len(assignments))
+	}
+
+	for i, want := range []struct{ file, sha string }{{"auth.go", req.Commits[0].SHA}, {"api.go", req.Commits[1].SHA}} {
+		a := assignments[i]
+		if a.Hunk.FilePath != want.file || a.CommitSHA != want.sha {
+			t.Errorf("assignment %d = %s -> %s, want %s -> %s", i, a.Hunk.FilePath, a.CommitSHA, want.file, want.sha)
+		}
+		if len(a.Hunks) != 40 || len(a.AllHunks()) != 40 {
+			t.Errorf("assignment %d covers %d hunks, want all 40 of %s", i, len(a.Hunks), want.file)
+		}
+		for _, h := range a.AllHunks() {
+			if h.FilePath != want.file {
+				t.Errorf("assignment %d includes a hunk of %s", i, h.FilePath)
+			}
+		}
+	}
+
+	if len(unmatched) != 40 || unmatched[0].FilePath != "README.md" {
+		t.Errorf("expected all 40 README.md hunks unmatched, got %d", len(unmatched))
+	}
+}
+
+func TestHunkAssignmentAllHunksUngrouped(t *testing.T) {
+	a := HunkAssignment{Hunk: git.Hunk{FilePath: "auth.go"}}
+	if hunks := a.AllHunks(); len(hunks) != 1 || hunks[0].FilePath != "auth.go" {
+		t.Errorf("AllHunks() = %+v, want just the assignment's hunk", hunks)
+	}
+}
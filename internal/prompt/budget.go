@@ -0,0 +1,95 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultInstructionFraction is the share of the budget instructions may use
+// when no fraction is configured.
+const DefaultInstructionFraction = 0.25
+
+// Budget splits a prompt token budget between instructions and the diff.
+type Budget struct {
+	// Total is the number of tokens available for the whole prompt.
+	Total int
+	// InstructionFraction is the maximum share of Total that instructions
+	// may use. The remainder is always reserved for the diff.
+	InstructionFraction float64
+}
+
+// InstructionLimit returns the maximum number of tokens for instructions.
+func (b Budget) InstructionLimit() int {
+	fraction := b.InstructionFraction
+	if fraction <= 0 || fraction >= 1 {
+		fraction = DefaultInstructionFraction
+	}
+	return int(float64(b.Total) * fraction)
+}
+
+// DiffLimit returns the number of tokens left for the diff once the given
+// instructions are accounted for. Instructions beyond InstructionLimit
+// never eat into the diff's reserved share.
+func (b Budget) DiffLimit(instructionTokens int) int {
+	used := instructionTokens
+	if limit := b.InstructionLimit(); used > limit {
+		used = limit
+	}
+	if used < 0 {
+		used = 0
+	}
+	return b.Total - used
+}
+
+// Exceeded reports whether instructions are over their share of the budget.
+func (b Budget) Exceeded(instructionTokens int) bool {
+	return instructionTokens > b.InstructionLimit()
+}
+
+// FitExamples keeps only the first of examples when, together with the
+// other instructionTokens, they are over the instructions' share. One
+// example still shows the style, so they are never all dropped.
+func (b Budget) FitExamples(examples []string, instructionTokens int) []string {
+	if len(examples) > 1 && b.Exceeded(instructionTokens+EstimateTokens(strings.Join(examples, "\n"))) {
+		return examples[:1]
+	}
+	return examples
+}
+
+// EstimateTokens provides a rough token count using ~4 characters per token.
+func EstimateTokens(text string) int {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0
+	}
+	tokens := len(text) / 4
+	if tokens < 1 {
+		tokens = 1
+	}
+	return tokens
+}
+
+// SummarizeFiles trims a file list so it fits in maxTokens, replacing the
+// tail with a "... and N more" entry. The full list is returned if it fits.
+func SummarizeFiles(files []string, maxTokens int) []string {
+	total := 0
+	for _, file := range files {
+		total += EstimateTokens(file) + 1
+	}
+	if total <= maxTokens {
+		return files
+	}
+
+	var kept []string
+	used := 0
+	for _, file := range files {
+		cost := EstimateTokens(file) + 1
+		// Leave room for the summary line.
+		if used+cost > maxTokens-4 {
+			break
+		}
+		kept = append(kept, file)
+		used += cost
+	}
+	return append(kept, fmt.Sprintf("... and %d more file(s)", len(files)-len(kept)))
+}
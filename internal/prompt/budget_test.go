@@ -0,0 +1,97 @@
+package prompt
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBudgetLimits(t *testing.T) {
+	tests := []struct {
+		name              string
+		budget            Budget
+		instructionTokens int
+		wantInstruction   int
+		wantDiff          int
+		wantExceeded      bool
+	}{
+		{
+			name:              "small instructions leave the rest for the diff",
+			budget:            Budget{Total: 1000, InstructionFraction: 0.25},
+			instructionTokens: 100,
+			wantInstruction:   250,
+			wantDiff:          900,
+		},
+		{
+			name:              "large instructions never eat the diff reserve",
+			budget:            Budget{Total: 1000, InstructionFraction: 0.25},
+			instructionTokens: 600,
+			wantInstruction:   250,
+			wantDiff:          750,
+			wantExceeded:      true,
+		},
+		{
+			name:              "exactly at the limit",
+			budget:            Budget{Total: 1000, InstructionFraction: 0.4},
+			instructionTokens: 400,
+			wantInstruction:   400,
+			wantDiff:          600,
+		},
+		{
+			name:              "invalid fraction falls back to default",
+			budget:            Budget{Total: 1000, InstructionFraction: 1.5},
+			instructionTokens: 0,
+			wantInstruction:   250,
+			wantDiff:          1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.budget.InstructionLimit(); got != tt.wantInstruction {
+				t.Errorf("InstructionLimit() = %d, want %d", got, tt.wantInstruction)
+			}
+			if got := tt.budget.DiffLimit(tt.instructionTokens); got != tt.wantDiff {
+				t.Errorf("DiffLimit(%d) = %d, want %d", tt.instructionTokens, got, tt.wantDiff)
+			}
+			if got := tt.budget.Exceeded(tt.instructionTokens); got != tt.wantExceeded {
+				t.Errorf("Exceeded(%d) = %v, want %v", tt.instructionTokens, got, tt.wantExceeded)
+			}
+		})
+	}
+}
+
+func TestSummarizeFiles(t *testing.T) {
+	files := make([]string, 50)
+	for i := range files {
+		files[i] = fmt.Sprintf("internal/package%02d/file.go", i)
+	}
+
+	if got := SummarizeFiles(files, 10000); len(got) != len(files) {
+		t.Errorf("expected full list when it fits, got %d entries", len(got))
+	}
+
+	got := SummarizeFiles(files, 50)
+	if len(got) >= len(files) {
+		t.Fatalf("expected list to be summarized, got %d entries", len(got))
+	}
+	last := got[len(got)-1]
+	want := fmt.Sprintf("... and %d more file(s)", len(files)-(len(got)-1))
+	if last != want {
+		t.Errorf("expected summary %q, got %q", want, last)
+	}
+}
+
+func TestFitExamples(t *testing.T) {
+	examples := []string{"feat(api): add rate limiting", "fix: handle nil config", "docs: update README"}
+	budget := Budget{Total: 400, InstructionFraction: 0.25}
+
+	if got := budget.FitExamples(examples, 10); len(got) != len(examples) {
+		t.Errorf("expected all examples within the budget, got %v", got)
+	}
+	if got := budget.FitExamples(examples, 95); len(got) != 1 || got[0] != examples[0] {
+		t.Errorf("expected only the first example over the budget, got %v", got)
+	}
+	if got := budget.FitExamples(examples[:1], 500); len(got) != 1 {
+		t.Errorf("expected a single example to be kept, got %v", got)
+	}
+}
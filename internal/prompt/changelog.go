@@ -0,0 +1,221 @@
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChangelogEntry is one commit as it appears in a changelog.
+type ChangelogEntry struct {
+	SHA          string
+	Type         string // conventional commit type; "" if the subject isn't conventional
+	Scope        string
+	Description  string // the subject without its type and scope
+	Breaking     bool
+	BreakingNote string // the BREAKING CHANGE footer, if any
+}
+
+// ChangelogSection is a heading of a changelog release and its entries.
+type ChangelogSection struct {
+	Title   string
+	Entries []ChangelogEntry
+}
+
+// changelogSections maps conventional commit types to Keep a Changelog
+// sections, in the order they are rendered. Breaking changes come before
+// them and everything else after them, under "Other".
+var changelogSections = []struct {
+	title string
+	types []string
+}{
+	{"Added", []string{"feat"}},
+	{"Changed", []string{"perf", "refactor", "revert"}},
+	{"Fixed", []string{"fix"}},
+}
+
+// ParseChangelogEntry splits a commit message into its changelog parts. A
+// commit is breaking when its subject has a "!" or its message has a
+// BREAKING CHANGE note.
+func ParseChangelogEntry(sha, message string) ChangelogEntry {
+	subject, rest := splitSubject(message)
+	entry := ChangelogEntry{SHA: sha, Description: strings.TrimSpace(subject)}
+	if parsed, ok := parseConventionalSubject(subject); ok {
+		entry.Type = strings.ToLower(parsed.Type)
+		entry.Scope = parsed.Scope
+		entry.Breaking = parsed.Breaking
+		entry.Description = parsed.Description
+	}
+	if note, ok := breakingChangeNote(rest); ok {
+		entry.Breaking = true
+		entry.BreakingNote = note
+	}
+	return entry
+}
+
+// breakingChangeNote returns the first BREAKING CHANGE note in a message
+// body, up to the end of its paragraph or the next trailer. Notes aren't
+// always written as proper trailers, so the whole body is searched.
+func breakingChangeNote(body string) (string, bool) {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		loc := breakingChangePattern.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		note := []string{strings.TrimSpace(line[loc[1]:])}
+		for _, next := range lines[i+1:] {
+			if strings.TrimSpace(next) == "" || trailerLinePattern.MatchString(next) {
+				break
+			}
+			note = append(note, strings.TrimSpace(next))
+		}
+		return strings.TrimSpace(strings.Join(note, "\n")), true
+	}
+	return "", false
+}
+
+// GroupChangelog sorts entries into changelog sections, skipping empty
+// ones. Merge commits and unsquashed fixups are left out, as are
+// maintenance commits (docs, test, chore, ...) unless all is set; breaking
+// commits are always kept.
+func GroupChangelog(entries []ChangelogEntry, all bool) []ChangelogSection {
+	sectionOf := make(map[string]string)
+	for _, section := range changelogSections {
+		for _, t := range section.types {
+			sectionOf[t] = section.title
+		}
+	}
+
+	grouped := make(map[string][]ChangelogEntry)
+	for _, entry := range entries {
+		switch {
+		case skipInChangelog(entry):
+			continue
+		case entry.Breaking:
+			grouped["Breaking Changes"] = append(grouped["Breaking Changes"], entry)
+		case sectionOf[entry.Type] != "":
+			grouped[sectionOf[entry.Type]] = append(grouped[sectionOf[entry.Type]], entry)
+		case entry.Type == "" || all:
+			grouped["Other"] = append(grouped["Other"], entry)
+		}
+	}
+
+	var sections []ChangelogSection
+	titles := []string{"Breaking Changes"}
+	for _, section := range changelogSections {
+		titles = append(titles, section.title)
+	}
+	for _, title := range append(titles, "Other") {
+		if len(grouped[title]) > 0 {
+			sections = append(sections, ChangelogSection{Title: title, Entries: grouped[title]})
+		}
+	}
+	return sections
+}
+
+// skipInChangelog reports whether a commit never belongs in a changelog.
+func skipInChangelog(entry ChangelogEntry) bool {
+	if entry.Type != "" {
+		return false
+	}
+	for _, prefix := range []string{"Merge ", "fixup! ", "squash! ", "amend! "} {
+		if strings.HasPrefix(entry.Description, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangelogHeading returns the heading of a release: "[Unreleased]" without
+// a version, otherwise the version and its date.
+func ChangelogHeading(version string, date time.Time) string {
+	if version == "" {
+		return "## [Unreleased]"
+	}
+	return fmt.Sprintf("## [%s] - %s", version, date.Format("2006-01-02"))
+}
+
+// RenderChangelog renders a release in Keep a Changelog style: the heading,
+// then a "###" heading and a list item per entry for each section. Entries
+// show their scope in bold and their short SHA, and breaking ones are
+// followed by their BREAKING CHANGE note.
+func RenderChangelog(heading string, sections []ChangelogSection) string {
+	var b strings.Builder
+	b.WriteString(heading)
+	b.WriteString("\n")
+	for _, section := range sections {
+		fmt.Fprintf(&b, "\n### %s\n\n", section.Title)
+		for _, entry := range section.Entries {
+			b.WriteString("- ")
+			if entry.Scope != "" {
+				fmt.Fprintf(&b, "**%s:** ", entry.Scope)
+			}
+			b.WriteString(entry.Description)
+			if entry.SHA != "" {
+				fmt.Fprintf(&b, " (%s)", shortSHA(entry.SHA))
+			}
+			b.WriteString("\n")
+			if entry.BreakingNote != "" {
+				for _, line := range strings.Split(entry.BreakingNote, "\n") {
+					fmt.Fprintf(&b, "  %s\n", strings.TrimSpace(line))
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// BuildChangelogPrompt asks the model to reword changelog entries for the
+// people reading release notes. The entries are numbered so the response can
+// be matched back to them.
+func BuildChangelogPrompt(entries []ChangelogEntry) string {
+	var b strings.Builder
+	b.WriteString("Rewrite these changelog entries for the users of the project reading its release notes.\n")
+	b.WriteString("Each entry comes from a commit subject. Keep the meaning of each one, describe the change ")
+	b.WriteString("from the user's point of view, and keep it to a single short sentence without a type prefix, ")
+	b.WriteString("scope or trailing period.\n\n")
+	b.WriteString("Reply with exactly one line per entry, in the same order, as \"<number>. <entry>\", and nothing else.\n\n")
+	for i, entry := range entries {
+		fmt.Fprintf(&b, "%d. ", i+1)
+		if entry.Type != "" {
+			b.WriteString(entry.Type)
+			if entry.Scope != "" {
+				fmt.Fprintf(&b, "(%s)", entry.Scope)
+			}
+			b.WriteString(": ")
+		}
+		b.WriteString(entry.Description)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// changelogLinePattern matches a numbered line of a changelog response.
+var changelogLinePattern = regexp.MustCompile(`^\s*(\d+)[.)]\s+(.+)$`)
+
+// ParseChangelogResponse returns the reworded entries from a response to
+// BuildChangelogPrompt, in order. It fails unless every one of the n
+// entries came back.
+func ParseChangelogResponse(response string, n int) ([]string, error) {
+	descriptions := make([]string, n)
+	for _, line := range strings.Split(response, "\n") {
+		matches := changelogLinePattern.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+		i, err := strconv.Atoi(matches[1])
+		if err != nil || i < 1 || i > n {
+			continue
+		}
+		descriptions[i-1] = strings.TrimSpace(matches[2])
+	}
+	for i, description := range descriptions {
+		if description == "" {
+			return nil, fmt.Errorf("response is missing entry %d of %d", i+1, n)
+		}
+	}
+	return descriptions, nil
+}
@@ -0,0 +1,137 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseChangelogEntry(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected ChangelogEntry
+	}{
+		{"feat(auth): add login", ChangelogEntry{Type: "feat", Scope: "auth", Description: "add login"}},
+		{"Fix: handle empty input", ChangelogEntry{Type: "fix", Description: "handle empty input"}},
+		{"refactor(api)!: drop v1 routes", ChangelogEntry{Type: "refactor", Scope: "api", Description: "drop v1 routes", Breaking: true}},
+		{
+			"feat: move config\n\nBody text.\n\nBREAKING CHANGE: config now lives in\nconfig.yml\nCloses #4",
+			ChangelogEntry{Type: "feat", Description: "move config", Breaking: true, BreakingNote: "config now lives in\nconfig.yml"},
+		},
+		{"Update dependencies", ChangelogEntry{Description: "Update dependencies"}},
+	}
+
+	for _, tt := range tests {
+		got := ParseChangelogEntry("", tt.message)
+		if got != tt.expected {
+			t.Errorf("ParseChangelogEntry(%q) = %+v, expected %+v", tt.message, got, tt.expected)
+		}
+	}
+}
+
+func TestGroupChangelog(t *testing.T) {
+	var entries []ChangelogEntry
+	for _, message := range []string{
+		"chore: bump version",
+		"feat(auth): add login",
+		"fix: handle empty password",
+		"docs: describe login",
+		"Update dependencies",
+		"Merge branch 'main' into login",
+		"fixup! feat(auth): add login",
+		"perf: cache sessions",
+		"refactor(api)!: drop v1 routes",
+		"ci!: require the new runner",
+		"feat: add logout",
+	} {
+		entries = append(entries, ParseChangelogEntry("", message))
+	}
+
+	titles := func(sections []ChangelogSection) string {
+		var parts []string
+		for _, s := range sections {
+			var descriptions []string
+			for _, e := range s.Entries {
+				descriptions = append(descriptions, e.Description)
+			}
+			parts = append(parts, s.Title+": "+strings.Join(descriptions, ", "))
+		}
+		return strings.Join(parts, "; ")
+	}
+
+	got := titles(GroupChangelog(entries, false))
+	expected := "Breaking Changes: drop v1 routes, require the new runner; " +
+		"Added: add login, add logout; Changed: cache sessions; Fixed: handle empty password; " +
+		"Other: Update dependencies"
+	if got != expected {
+		t.Errorf("GroupChangelog() =\n  %s\nexpected\n  %s", got, expected)
+	}
+
+	got = titles(GroupChangelog(entries, true))
+	if !strings.HasSuffix(got, "Other: bump version, describe login, Update dependencies") {
+		t.Errorf("expected maintenance commits under Other with all set, got\n  %s", got)
+	}
+
+	if sections := GroupChangelog(nil, false); len(sections) != 0 {
+		t.Errorf("expected no sections without entries, got %+v", sections)
+	}
+}
+
+func TestRenderChangelog(t *testing.T) {
+	sections := GroupChangelog([]ChangelogEntry{
+		ParseChangelogEntry("0123456789abcdef", "feat(auth): add login"),
+		ParseChangelogEntry("fedcba9876543210", "feat!: move config\n\nBREAKING CHANGE: config now lives in\nconfig.yml"),
+		ParseChangelogEntry("", "fix: handle empty password"),
+	}, false)
+
+	got := RenderChangelog(ChangelogHeading("1.2.0", time.Date(2024, 3, 9, 12, 0, 0, 0, time.UTC)), sections)
+	expected := `## [1.2.0] - 2024-03-09
+
+### Breaking Changes
+
+- move config (fedcba98)
+  config now lives in
+  config.yml
+
+### Added
+
+- **auth:** add login (01234567)
+
+### Fixed
+
+- handle empty password
+`
+	if got != expected {
+		t.Errorf("RenderChangelog() =\n%s\nexpected\n%s", got, expected)
+	}
+
+	if heading := ChangelogHeading("", time.Time{}); heading != "## [Unreleased]" {
+		t.Errorf("ChangelogHeading without a version = %q", heading)
+	}
+}
+
+func TestBuildChangelogPrompt(t *testing.T) {
+	result := BuildChangelogPrompt([]ChangelogEntry{
+		{Type: "feat", Scope: "auth", Description: "add login"},
+		{Description: "Update dependencies"},
+	})
+	for _, want := range []string{"1. feat(auth): add login\n", "2. Update dependencies\n"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected prompt to contain %q, got:\n%s", want, result)
+		}
+	}
+}
+
+func TestParseChangelogResponse(t *testing.T) {
+	got, err := ParseChangelogResponse("Here you go:\n2) Dependencies are up to date\n1. Sign in with your account\n", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "Sign in with your account" || got[1] != "Dependencies are up to date" {
+		t.Errorf("ParseChangelogResponse() = %q", got)
+	}
+
+	if _, err := ParseChangelogResponse("1. Sign in with your account\n3. Extra", 2); err == nil {
+		t.Error("expected an error when an entry is missing")
+	}
+}
@@ -0,0 +1,247 @@
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gussy/cmt/internal/preprocess"
+)
+
+// Predicates describes the conditions a custom prompt template can test
+// with {{if name}}...{{end}}. Each is derived from the staged changes.
+var Predicates = map[string]string{
+	"has_tests":         "a test file is staged (_test.go, .test./.spec. files, test directories)",
+	"has_docs":          "a documentation file is staged (.md, .rst, .txt, docs directories)",
+	"has_ci":            "a CI configuration file is staged",
+	"has_deps":          "a dependency manifest or lockfile is staged",
+	"has_new_files":     "a file is added",
+	"has_deleted_files": "a file is deleted",
+	"breaking":          "an exported Go func or type is removed or its signature changed, or the hint mentions a breaking change",
+	"has_hint":          "a --hint is given",
+	"has_scope":         "a --scope is given",
+}
+
+// PromptData is what a custom prompt template is rendered with.
+type PromptData struct {
+	Files []string // staged files as "M path", for {{files}} and the file predicates
+	Diff  string   // the raw staged diff, for predicates that look at content
+	// PromptDiff is the diff as the model sees it, for {{diff}}.
+	PromptDiff string
+	Hint       string // {{hint}}
+	Scope      string // {{scope}}
+}
+
+var (
+	// templateTagPattern matches a {{...}} tag.
+	templateTagPattern = regexp.MustCompile(`\{\{([^{}]*)\}\}`)
+	// exportedGoDeclPattern matches a removed or added exported Go func,
+	// method or type declaration line in a diff.
+	exportedGoDeclPattern = regexp.MustCompile(`^[+-](?:func\s+(?:\([^)]*\)\s*)?|type\s+)[A-Z]\w*`)
+)
+
+// Facts evaluates every predicate in Predicates for data.
+func Facts(data PromptData) map[string]bool {
+	files := parseDiffFiles(data.Diff)
+	seen := make(map[string]bool, len(files))
+	for _, f := range files {
+		seen[f.path] = true
+	}
+	for _, entry := range data.Files {
+		if f := parseStagedEntry(entry); !seen[f.path] {
+			seen[f.path] = true
+			files = append(files, f)
+		}
+	}
+
+	facts := map[string]bool{
+		"has_tests":         false,
+		"has_docs":          false,
+		"has_ci":            false,
+		"has_deps":          false,
+		"has_new_files":     false,
+		"has_deleted_files": false,
+		"breaking":          isBreaking(data.Diff, data.Hint),
+		"has_hint":          strings.TrimSpace(data.Hint) != "",
+		"has_scope":         strings.TrimSpace(data.Scope) != "",
+	}
+	for _, f := range files {
+		facts["has_tests"] = facts["has_tests"] || isTestPath(f.path)
+		facts["has_docs"] = facts["has_docs"] || isDocsPath(f.path)
+		facts["has_ci"] = facts["has_ci"] || isCIPath(f.path)
+		facts["has_new_files"] = facts["has_new_files"] || f.status == "A"
+		facts["has_deleted_files"] = facts["has_deleted_files"] || f.status == "D"
+		facts["has_deps"] = facts["has_deps"] || preprocess.IsDependencyFile(f.path)
+	}
+	return facts
+}
+
+// parseStagedEntry splits a staged file entry, "A path" or
+// "R old -> new", into its status and path. Entries without a status are
+// taken as modified.
+func parseStagedEntry(entry string) fileChange {
+	status, path, ok := strings.Cut(entry, " ")
+	if !ok || status == "" || len(status) > 4 || status[0] < 'A' || status[0] > 'Z' {
+		return fileChange{path: entry, status: "M"}
+	}
+	if _, newPath, renamed := strings.Cut(path, " -> "); renamed {
+		path = newPath
+	}
+	return fileChange{path: path, status: status[:1]}
+}
+
+// isBreaking reports whether the hint mentions a breaking change or the diff
+// removes an exported Go declaration line without adding it back unchanged,
+// which is how a removed or re-signed func or type shows up.
+func isBreaking(diff, hint string) bool {
+	if strings.Contains(strings.ToLower(hint), "breaking") {
+		return true
+	}
+
+	removed := make(map[string]bool)
+	added := make(map[string]bool)
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") || !exportedGoDeclPattern.MatchString(line) {
+			continue
+		}
+		decl := strings.TrimSpace(line[1:])
+		if line[0] == '-' {
+			removed[decl] = true
+		} else {
+			added[decl] = true
+		}
+	}
+	for decl := range removed {
+		if !added[decl] {
+			return true
+		}
+	}
+	return false
+}
+
+// RenderCustomPrompt renders a custom prompt template. The template is plain
+// text with these tags:
+//
+//	{{diff}}, {{files}}, {{hint}}, {{scope}}  the staged changes and options
+//	{{if name}} ... {{else}} ... {{end}}      a section kept only when the
+//	                                          predicate holds; {{if !name}}
+//	                                          negates it, and sections nest
+//
+// The names are those in Predicates. A tag alone on its line takes the line
+// with it, so conditional sections leave no blank lines behind. Other tags
+// are kept as written. If the template has no {{diff}}, the diff is added
+// at the end so the model always sees the changes.
+func RenderCustomPrompt(template string, data PromptData) (string, error) {
+	facts := Facts(data)
+	values := map[string]string{
+		"diff":  data.PromptDiff,
+		"files": strings.Join(data.Files, "\n"),
+		"hint":  data.Hint,
+		"scope": data.Scope,
+	}
+
+	// Each open {{if}} remembers whether text was being written before it,
+	// and whether its condition held.
+	type frame struct {
+		tag      string
+		outer    bool
+		cond     bool
+		seenElse bool
+	}
+	var stack []frame
+	on := true
+	usesDiff := false
+
+	var out strings.Builder
+	pos := 0
+	for _, m := range templateTagPattern.FindAllStringSubmatchIndex(template, -1) {
+		start, end := m[0], m[1]
+		tag := strings.TrimSpace(template[m[2]:m[3]])
+		name, isIf := strings.CutPrefix(tag, "if ")
+		control := isIf || tag == "else" || tag == "end"
+
+		text := template[pos:start]
+		if control {
+			// Drop a line that holds nothing but the tag.
+			lineStart := strings.LastIndexByte(text, '\n') + 1
+			rest := template[end:]
+			atLineStart := lineStart > 0 || pos == 0 || template[pos-1] == '\n'
+			if atLineStart && strings.TrimLeft(text[lineStart:], " \t") == "" {
+				trimmed := strings.TrimLeft(rest, " \t")
+				if trimmed == "" || trimmed[0] == '\n' {
+					text = text[:lineStart]
+					end += len(rest) - len(trimmed)
+					if trimmed != "" {
+						end++
+					}
+				}
+			}
+		}
+		if on {
+			out.WriteString(text)
+		}
+		pos = end
+
+		switch {
+		case isIf:
+			name = strings.TrimSpace(name)
+			negate := strings.HasPrefix(name, "!")
+			name = strings.TrimSpace(strings.TrimPrefix(name, "!"))
+			value, ok := facts[name]
+			if !ok {
+				return "", fmt.Errorf("unknown condition %q in {{%s}} (available: %s)", name, tag, strings.Join(predicateNames(), ", "))
+			}
+			stack = append(stack, frame{tag: tag, outer: on, cond: value != negate})
+			on = on && value != negate
+
+		case tag == "else":
+			if len(stack) == 0 {
+				return "", fmt.Errorf("{{else}} without {{if}}")
+			}
+			top := &stack[len(stack)-1]
+			if top.seenElse {
+				return "", fmt.Errorf("second {{else}} for {{%s}}", top.tag)
+			}
+			top.seenElse = true
+			on = top.outer && !top.cond
+
+		case tag == "end":
+			if len(stack) == 0 {
+				return "", fmt.Errorf("{{end}} without {{if}}")
+			}
+			on = stack[len(stack)-1].outer
+			stack = stack[:len(stack)-1]
+
+		default:
+			value, ok := values[tag]
+			if !ok {
+				value = template[start:end]
+			}
+			if on {
+				usesDiff = usesDiff || tag == "diff"
+				out.WriteString(value)
+			}
+		}
+	}
+	if len(stack) > 0 {
+		return "", fmt.Errorf("{{%s}} is missing its {{end}}", stack[len(stack)-1].tag)
+	}
+	out.WriteString(template[pos:])
+
+	rendered := out.String()
+	if !usesDiff && data.PromptDiff != "" {
+		rendered = strings.TrimRight(rendered, "\n") + "\n\nChanges:\n```diff\n" + data.PromptDiff + "\n```\n"
+	}
+	return rendered, nil
+}
+
+// predicateNames returns the names in Predicates in sorted order.
+func predicateNames() []string {
+	names := make([]string, 0, len(Predicates))
+	for name := range Predicates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
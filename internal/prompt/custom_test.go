@@ -0,0 +1,125 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+const testingTemplate = `Write a commit message for these changes.
+{{if has_tests}}
+End the body with a "Testing:" section describing the tests.
+{{else}}
+Do not mention tests.
+{{end}}
+{{if breaking}}
+Add a "BREAKING CHANGE:" footer.
+{{end}}
+Changes:
+{{diff}}`
+
+func TestRenderCustomPromptConditions(t *testing.T) {
+	tests := []struct {
+		name string
+		data PromptData
+		want string
+	}{
+		{
+			name: "with test files",
+			data: PromptData{Files: []string{"M parser.go", "A parser_test.go"}, PromptDiff: "+x"},
+			want: "Write a commit message for these changes.\n" +
+				"End the body with a \"Testing:\" section describing the tests.\n" +
+				"Changes:\n+x",
+		},
+		{
+			name: "without test files",
+			data: PromptData{Files: []string{"M parser.go"}, PromptDiff: "+x"},
+			want: "Write a commit message for these changes.\n" +
+				"Do not mention tests.\n" +
+				"Changes:\n+x",
+		},
+		{
+			name: "breaking hint",
+			data: PromptData{Files: []string{"M parser.go"}, Hint: "breaking: drop v1", PromptDiff: "+x"},
+			want: "Write a commit message for these changes.\n" +
+				"Do not mention tests.\n" +
+				"Add a \"BREAKING CHANGE:\" footer.\n" +
+				"Changes:\n+x",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderCustomPrompt(testingTemplate, tt.data)
+			if err != nil {
+				t.Fatalf("RenderCustomPrompt() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderCustomPrompt() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderCustomPromptInline(t *testing.T) {
+	data := PromptData{Files: []string{"M README.md"}, Scope: "docs", PromptDiff: "+x"}
+
+	got, err := RenderCustomPrompt("Scope {{scope}}{{if !has_docs}}, code only{{end}}{{if has_docs}}, docs{{end}}.\n{{diff}}", data)
+	if err != nil {
+		t.Fatalf("RenderCustomPrompt() error = %v", err)
+	}
+	if want := "Scope docs, docs.\n+x"; got != want {
+		t.Errorf("RenderCustomPrompt() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderCustomPromptAppendsDiff(t *testing.T) {
+	got, err := RenderCustomPrompt("Describe the change. {{TODO: keep}}", PromptData{PromptDiff: "+x"})
+	if err != nil {
+		t.Fatalf("RenderCustomPrompt() error = %v", err)
+	}
+	if want := "Describe the change. {{TODO: keep}}\n\nChanges:\n```diff\n+x\n```\n"; got != want {
+		t.Errorf("RenderCustomPrompt() = %q, want %q", got, want)
+	}
+}
+
+func TestRenderCustomPromptErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		want     string
+	}{
+		{"{{if has_magic}}x{{end}}", `unknown condition "has_magic"`},
+		{"{{if has_tests}}x", "missing its {{end}}"},
+		{"x{{end}}", "{{end}} without {{if}}"},
+		{"{{else}}", "{{else}} without {{if}}"},
+		{"{{if has_tests}}a{{else}}b{{else}}c{{end}}", "second {{else}}"},
+	}
+
+	for _, tt := range tests {
+		_, err := RenderCustomPrompt(tt.template, PromptData{})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("RenderCustomPrompt(%q) error = %v, want it to contain %q", tt.template, err, tt.want)
+		}
+	}
+}
+
+func TestFactsBreaking(t *testing.T) {
+	tests := []struct {
+		name string
+		diff string
+		want bool
+	}{
+		{"removed func", "-func Parse(s string) error {\n", true},
+		{"changed signature", "-func Parse(s string) error {\n+func Parse(s string, strict bool) error {\n", true},
+		{"moved func", "-func Parse(s string) error {\n+func Parse(s string) error {\n", false},
+		{"unexported func", "-func parse(s string) error {\n", false},
+		{"removed method", "-func (p *Parser) Next() Token {\n", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Facts(PromptData{Diff: tt.diff})["breaking"]; got != tt.want {
+				t.Errorf("Facts()[breaking] = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
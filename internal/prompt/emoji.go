@@ -0,0 +1,50 @@
+package prompt
+
+import (
+	"regexp"
+	"strings"
+)
+
+// shortcodePrefixPattern matches gitmoji shortcodes such as ":sparkles:" at
+// the start of a subject.
+var shortcodePrefixPattern = regexp.MustCompile(`^(?::[a-z0-9_+-]+:\s*)+`)
+
+// StripEmoji removes emoji characters, and any leading ":shortcode:"
+// prefixes, from a single line of text. Whitespace left behind by a removed
+// emoji is collapsed, so "✨ feat(ui): add  🎉 confetti" becomes
+// "feat(ui): add confetti". Text without emoji is returned unchanged.
+func StripEmoji(s string) string {
+	stripped := shortcodePrefixPattern.ReplaceAllString(s, "")
+
+	var b strings.Builder
+	for _, r := range stripped {
+		if !isEmoji(r) {
+			b.WriteRune(r)
+		}
+	}
+
+	if b.Len() == len(s) {
+		return s
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// isEmoji reports whether r is an emoji or one of the joiners and modifiers
+// that combine emoji into a single glyph.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // pictographs, emoticons, flags, skin tones
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // miscellaneous symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // stars, squares and circles such as ⭐
+		return true
+	case r == 0x231A, r == 0x231B, r >= 0x23E9 && r <= 0x23FA: // watches and media controls
+		return true
+	case r == 0x200D, r == 0x20E3, r == 0xFE0E, r == 0xFE0F: // joiner, keycap, variation selectors
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // tag sequences
+		return true
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package prompt
+
+import "testing"
+
+func TestStripEmoji(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"feat(auth): add OAuth2 login", "feat(auth): add OAuth2 login"},
+		{"✨ feat(auth): add OAuth2 login", "feat(auth): add OAuth2 login"},
+		{"feat(ui): add 🎉 confetti", "feat(ui): add confetti"},
+		{"fix: handle crash 🐛", "fix: handle crash"},
+		{":sparkles: feat: add export", "feat: add export"},
+		{":bug: :ambulance: fix: restore login", "fix: restore login"},
+		{"🧑‍💻 chore: update dev setup", "chore: update dev setup"},
+		{"⚡️ perf: cache lookups", "perf: cache lookups"},
+		{"docs: explain a:b:c keys", "docs: explain a:b:c keys"},
+		{"fix: keep  double spaces without emoji", "fix: keep  double spaces without emoji"},
+		{"feat: support 日本語 names", "feat: support 日本語 names"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := StripEmoji(tc.input); got != tc.expected {
+				t.Errorf("StripEmoji(%q) = %q, expected %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package prompt
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gussy/cmt/internal/preprocess"
+)
+
+// fileChange summarizes one file's changes in a unified diff.
+type fileChange struct {
+	path    string
+	status  string // A, M, D or R
+	changed int    // added plus removed lines
+}
+
+// FallbackMessage builds a commit message from the diff alone, without a
+// model: the type is inferred from the changed paths, the subject names the
+// most-changed file and the body lists every file. The same diff always
+// yields the same message.
+func FallbackMessage(diff string) string {
+	files := parseDiffFiles(diff)
+	if len(files) == 0 {
+		return "chore: update files"
+	}
+
+	if subject := depsSubject(files, diff); subject != "" {
+		return subject + "\n\n" + fileList(files)
+	}
+	if subject := deletionSubject(files); subject != "" {
+		return subject + "\n\n" + fileList(files)
+	}
+
+	// Most-changed file first. Ties (such as binary files, which have no
+	// line counts) prefer added files, then go by path for stable output.
+	sorted := make([]fileChange, len(files))
+	copy(sorted, files)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].changed != sorted[j].changed {
+			return sorted[i].changed > sorted[j].changed
+		}
+		if (sorted[i].status == "A") != (sorted[j].status == "A") {
+			return sorted[i].status == "A"
+		}
+		return sorted[i].path < sorted[j].path
+	})
+	top := sorted[0]
+
+	subject := fmt.Sprintf("%s: %s %s", inferType(files), statusVerb(top.status), path.Base(top.path))
+	if len(files) > 1 {
+		subject += fmt.Sprintf(" and %d other file(s)", len(files)-1)
+	}
+
+	return subject + "\n\n" + fileList(files)
+}
+
+// FormattingMessage is the message for staged changes that only touch
+// whitespace, such as a formatter run, which leave the model nothing to
+// describe: "style: format main.go", or the number of files and a list of
+// them when there are several.
+func FormattingMessage(paths []string) string {
+	switch len(paths) {
+	case 0:
+		return "style: format code"
+	case 1:
+		return "style: format " + path.Base(paths[0])
+	}
+	var body strings.Builder
+	for _, p := range paths {
+		body.WriteString("- " + p + "\n")
+	}
+	return fmt.Sprintf("style: format %d files\n\n%s", len(paths), strings.TrimRight(body.String(), "\n"))
+}
+
+// fileList renders the message body listing every file with its status.
+func fileList(files []fileChange) string {
+	var body strings.Builder
+	for _, f := range files {
+		body.WriteString(fmt.Sprintf("- %s %s\n", f.status, f.path))
+	}
+	return strings.TrimRight(body.String(), "\n")
+}
+
+// depsSubject returns a "chore(deps): ..." subject when only dependency
+// manifests and lockfiles changed and the manifests show version changes,
+// or "" otherwise.
+func depsSubject(files []fileChange, diff string) string {
+	for _, f := range files {
+		if !preprocess.IsDependencyFile(f.path) {
+			return ""
+		}
+	}
+
+	changes := preprocess.ExtractDependencyChanges(diff)
+	switch len(changes) {
+	case 0:
+		return ""
+	case 1:
+		return "chore(deps): " + changes[0].String()
+	default:
+		return fmt.Sprintf("chore(deps): update %d dependencies", len(changes))
+	}
+}
+
+// maxNamedDeletions is how many removed files a deletion-only subject names
+// before it only counts them.
+const maxNamedDeletions = 3
+
+// deletionSubject returns a subject naming the removed files when every
+// file was deleted, e.g. "chore: remove old.go and legacy.go", or "" when
+// something else changed too.
+func deletionSubject(files []fileChange) string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		if f.status != "D" {
+			return ""
+		}
+		names[i] = path.Base(f.path)
+	}
+
+	var what string
+	switch {
+	case len(names) > maxNamedDeletions:
+		what = fmt.Sprintf("%d files", len(names))
+	case len(names) == 1:
+		what = names[0]
+	default:
+		what = strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	}
+	return fmt.Sprintf("%s: remove %s", inferType(files), what)
+}
+
+// parseDiffFiles lists the files in a unified diff in the order they appear.
+func parseDiffFiles(diff string) []fileChange {
+	var files []fileChange
+	var current *fileChange
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			files = append(files, fileChange{path: diffPath(line), status: "M"})
+			current = &files[len(files)-1]
+		case current == nil:
+			continue
+		case strings.HasPrefix(line, "new file mode"):
+			current.status = "A"
+		case strings.HasPrefix(line, "deleted file mode"):
+			current.status = "D"
+		case strings.HasPrefix(line, "rename to "):
+			current.status = "R"
+			current.path = strings.TrimPrefix(line, "rename to ")
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+			continue
+		case strings.HasPrefix(line, "+"), strings.HasPrefix(line, "-"):
+			current.changed++
+		}
+	}
+	return files
+}
+
+// diffPath extracts the new path from a "diff --git a/x b/y" header.
+func diffPath(header string) string {
+	if i := strings.LastIndex(header, " b/"); i >= 0 {
+		return header[i+3:]
+	}
+	return strings.TrimPrefix(header, "diff --git ")
+}
+
+// inferType picks a conventional commit type from the changed paths.
+func inferType(files []fileChange) string {
+	all := func(match func(string) bool) bool {
+		for _, f := range files {
+			if !match(f.path) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case all(isTestPath):
+		return "test"
+	case all(isDocsPath):
+		return "docs"
+	case all(isCIPath):
+		return "ci"
+	case all(isBuildPath):
+		return "build"
+	}
+
+	for _, f := range files {
+		if f.status != "A" {
+			return "chore"
+		}
+	}
+	return "feat"
+}
+
+func isTestPath(p string) bool {
+	base := path.Base(p)
+	return strings.HasSuffix(base, "_test.go") ||
+		strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") ||
+		hasDir(p, "test", "tests", "testdata", "__tests__")
+}
+
+func isDocsPath(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	return ext == ".md" || ext == ".rst" || ext == ".txt" || hasDir(p, "docs", "doc")
+}
+
+func isCIPath(p string) bool {
+	return strings.HasPrefix(p, ".github/workflows/") || strings.HasPrefix(p, ".circleci/") ||
+		path.Base(p) == ".gitlab-ci.yml"
+}
+
+func isBuildPath(p string) bool {
+	switch path.Base(p) {
+	case "go.mod", "go.sum", "Makefile", "Dockerfile", "package.json", "package-lock.json",
+		"yarn.lock", "pnpm-lock.yaml", "Cargo.toml", "Cargo.lock", ".goreleaser.yml", ".goreleaser.yaml":
+		return true
+	}
+	return false
+}
+
+// hasDir reports whether any directory in p is one of names.
+func hasDir(p string, names ...string) bool {
+	dirs := strings.Split(path.Dir(p), "/")
+	for _, dir := range dirs {
+		for _, name := range names {
+			if dir == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// statusVerb describes a file status as a subject verb.
+func statusVerb(status string) string {
+	switch status {
+	case "A":
+		return "add"
+	case "D":
+		return "remove"
+	case "R":
+		return "rename"
+	default:
+		return "update"
+	}
+}
@@ -0,0 +1,171 @@
+package prompt
+
+import "testing"
+
+func TestFallbackMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		diff     string
+		expected string
+	}{
+		{
+			name: "modified files pick the most changed",
+			diff: `diff --git a/internal/git/git.go b/internal/git/git.go
+--- a/internal/git/git.go
++++ b/internal/git/git.go
+@@ -1,2 +1,3 @@
+-old
++new
++more
+diff --git a/cmd/cmt/main.go b/cmd/cmt/main.go
+--- a/cmd/cmt/main.go
++++ b/cmd/cmt/main.go
+@@ -1 +1 @@
+-a
++b
+diff --git a/README.md b/README.md
+--- a/README.md
++++ b/README.md
+@@ -1 +1 @@
++c
+`,
+			expected: "chore: update git.go and 2 other file(s)\n\n- M internal/git/git.go\n- M cmd/cmt/main.go\n- M README.md",
+		},
+		{
+			name: "only new files is a feature",
+			diff: `diff --git a/internal/hook/hook.go b/internal/hook/hook.go
+new file mode 100644
+--- /dev/null
++++ b/internal/hook/hook.go
+@@ -0,0 +1 @@
++package hook
+`,
+			expected: "feat: add hook.go\n\n- A internal/hook/hook.go",
+		},
+		{
+			name: "only tests",
+			diff: `diff --git a/internal/git/git_test.go b/internal/git/git_test.go
+--- a/internal/git/git_test.go
++++ b/internal/git/git_test.go
+@@ -1 +1 @@
+-a
++b
+`,
+			expected: "test: update git_test.go\n\n- M internal/git/git_test.go",
+		},
+		{
+			name: "only docs",
+			diff: `diff --git a/docs/usage.md b/docs/usage.md
+deleted file mode 100644
+--- a/docs/usage.md
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+`,
+			expected: "docs: remove usage.md\n\n- D docs/usage.md",
+		},
+		{
+			name: "only deletions names every removed file",
+			diff: `diff --git a/internal/legacy/client.go b/internal/legacy/client.go
+deleted file mode 100644
+--- a/internal/legacy/client.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+-a
+-b
+-c
+diff --git a/internal/legacy/retry.go b/internal/legacy/retry.go
+deleted file mode 100644
+--- a/internal/legacy/retry.go
++++ /dev/null
+@@ -1 +0,0 @@
+-d
+`,
+			expected: "chore: remove client.go and retry.go\n\n- D internal/legacy/client.go\n- D internal/legacy/retry.go",
+		},
+		{
+			name: "many deletions are counted",
+			diff: "diff --git a/a.go b/a.go\ndeleted file mode 100644\n" +
+				"diff --git a/b.go b/b.go\ndeleted file mode 100644\n" +
+				"diff --git a/c.go b/c.go\ndeleted file mode 100644\n" +
+				"diff --git a/d.go b/d.go\ndeleted file mode 100644\n",
+			expected: "chore: remove 4 files\n\n- D a.go\n- D b.go\n- D c.go\n- D d.go",
+		},
+		{
+			name: "rename",
+			diff: `diff --git a/go.mod b/go.sum
+similarity index 90%
+rename from go.mod
+rename to go.sum
+`,
+			expected: "build: rename go.sum\n\n- R go.sum",
+		},
+		{
+			name: "dependency bump",
+			diff: `diff --git a/go.mod b/go.mod
+--- a/go.mod
++++ b/go.mod
+@@ -3,3 +3,3 @@ go 1.25.3
+ require (
+-	github.com/urfave/cli/v3 v3.3.8
++	github.com/urfave/cli/v3 v3.4.1
+ )
+diff --git a/go.sum b/go.sum
+--- a/go.sum
++++ b/go.sum
+@@ -1 +1 @@
+-github.com/urfave/cli/v3 v3.3.8 h1:abc=
++github.com/urfave/cli/v3 v3.4.1 h1:def=
+`,
+			expected: "chore(deps): bump github.com/urfave/cli/v3 from v3.3.8 to v3.4.1\n\n- M go.mod\n- M go.sum",
+		},
+		{
+			name: "binary files only",
+			diff: `diff --git a/assets/logo.png b/assets/logo.png
+new file mode 100644
+index 0000000..1111111
+Binary files /dev/null and b/assets/logo.png differ
+diff --git a/assets/icon-16.png b/assets/icon-16.png
+index 2222222..3333333 100644
+Binary files a/assets/icon-16.png and b/assets/icon-16.png differ
+diff --git a/assets/icon-32.png b/assets/icon-32.png
+index 4444444..5555555 100644
+Binary files a/assets/icon-32.png and b/assets/icon-32.png differ
+`,
+			expected: "chore: add logo.png and 2 other file(s)\n\n- A assets/logo.png\n- M assets/icon-16.png\n- M assets/icon-32.png",
+		},
+		{
+			name:     "empty diff",
+			diff:     "",
+			expected: "chore: update files",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FallbackMessage(tc.diff)
+			if got != tc.expected {
+				t.Errorf("FallbackMessage() = %q, expected %q", got, tc.expected)
+			}
+			if again := FallbackMessage(tc.diff); again != got {
+				t.Errorf("FallbackMessage() is not deterministic: %q then %q", got, again)
+			}
+		})
+	}
+}
+
+func TestFormattingMessage(t *testing.T) {
+	tests := []struct {
+		paths    []string
+		expected string
+	}{
+		{nil, "style: format code"},
+		{[]string{"internal/ui/review.go"}, "style: format review.go"},
+		{[]string{"main.go", "internal/git/git.go"}, "style: format 2 files\n\n- main.go\n- internal/git/git.go"},
+	}
+	for _, tt := range tests {
+		if got := FormattingMessage(tt.paths); got != tt.expected {
+			t.Errorf("FormattingMessage(%q) = %q, expected %q", tt.paths, got, tt.expected)
+		}
+	}
+}
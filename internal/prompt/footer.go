@@ -0,0 +1,243 @@
+package prompt
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultClosingKeywords are the GitHub keywords that close an issue when a
+// commit referencing it lands on the default branch.
+var DefaultClosingKeywords = []string{
+	"close", "closes", "closed",
+	"fix", "fixes", "fixed",
+	"resolve", "resolves", "resolved",
+}
+
+var (
+	// hintIssuePattern matches "#42" and "GH-42" style references.
+	hintIssuePattern = regexp.MustCompile(`(?i)(?:^|[^\w#])(?:#|gh-)(\d+)\b`)
+	// branchIssuePattern matches an explicit issue reference that starts a
+	// branch name segment, e.g. "feature/issue-42" or "gh-42-typo".
+	branchIssuePattern = regexp.MustCompile(`(?i)(?:^|/)(?:issue|gh)[-_]?(\d+)(?:[-_]|$)`)
+	// branchTypeIssuePattern matches a number right after the branch type,
+	// e.g. "fix/42-login-crash". A number followed by more digits, like the
+	// date in "hotfix/2024-01-15", is not an issue.
+	branchTypeIssuePattern = regexp.MustCompile(`^([A-Za-z]+)/(\d+)(?:[-_]\D|$)`)
+	// wordPattern splits text into words for keyword matching.
+	wordPattern = regexp.MustCompile(`[A-Za-z]+`)
+)
+
+// branchTypes are the branch types, besides the closing keywords, that an
+// issue number may follow.
+var branchTypes = append([]string{"feature", "bugfix", "hotfix"}, ConventionalTypes...)
+
+// IssueFooters returns footer trailers for the issues referenced in the hint
+// and branch name. An issue preceded by one of keywords (in the hint) or on
+// a branch whose first segment is a keyword (e.g. "fix/42-crash") becomes
+// "Closes #N"; any other reference becomes "Refs #N".
+func IssueFooters(hint, branch string, keywords []string) []string {
+	closing := make(map[string]bool, len(keywords))
+	for _, k := range keywords {
+		closing[strings.ToLower(k)] = true
+	}
+
+	var order []string
+	closes := make(map[string]bool)
+	add := func(number string, isClosing bool) {
+		if _, seen := closes[number]; !seen {
+			order = append(order, number)
+		}
+		closes[number] = closes[number] || isClosing
+	}
+
+	for _, m := range hintIssuePattern.FindAllStringSubmatchIndex(hint, -1) {
+		add(hint[m[2]:m[3]], hasKeyword(hint[:m[0]], closing))
+	}
+
+	var number string
+	if m := branchIssuePattern.FindStringSubmatch(branch); m != nil {
+		number = m[1]
+	} else if m := branchTypeIssuePattern.FindStringSubmatch(branch); m != nil {
+		if branchType := strings.ToLower(m[1]); closing[branchType] || slices.Contains(branchTypes, branchType) {
+			number = m[2]
+		}
+	}
+	if number != "" {
+		prefix, _, _ := strings.Cut(branch, "/")
+		add(number, strings.Contains(branch, "/") && closing[strings.ToLower(prefix)])
+	}
+
+	footers := make([]string, 0, len(order))
+	for _, number := range order {
+		if closes[number] {
+			footers = append(footers, fmt.Sprintf("Closes #%s", number))
+		} else {
+			footers = append(footers, fmt.Sprintf("Refs #%s", number))
+		}
+	}
+	return footers
+}
+
+// coAuthorPattern matches a "Name <email>" identity.
+var coAuthorPattern = regexp.MustCompile(`^(\S.*?)\s*<([^<>\s]+@[^<>\s]+)>$`)
+
+// CoAuthorFooters returns a "Co-authored-by: Name <email>" trailer for each
+// identity named by the environment variables vars, read with getenv. A
+// variable may list several identities, separated by commas or semicolons.
+// One that is unset is read as a pair, <VAR>_NAME and <VAR>_EMAIL, as pair
+// tools set GIT_COMMITTER_NAME and GIT_COMMITTER_EMAIL. Identities with the
+// author's email, repeats and anything that isn't "Name <email>" are
+// skipped.
+func CoAuthorFooters(vars []string, getenv func(string) string, authorEmail string) []string {
+	seen := map[string]bool{strings.ToLower(authorEmail): true}
+	var footers []string
+	for _, name := range vars {
+		value := getenv(name)
+		if value == "" {
+			if pairName, pairEmail := getenv(name+"_NAME"), getenv(name+"_EMAIL"); pairName != "" && pairEmail != "" {
+				value = fmt.Sprintf("%s <%s>", pairName, pairEmail)
+			}
+		}
+		for _, identity := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' }) {
+			m := coAuthorPattern.FindStringSubmatch(strings.TrimSpace(identity))
+			if m == nil || seen[strings.ToLower(m[2])] {
+				continue
+			}
+			seen[strings.ToLower(m[2])] = true
+			footers = append(footers, fmt.Sprintf("Co-authored-by: %s <%s>", m[1], m[2]))
+		}
+	}
+	return footers
+}
+
+// hasKeyword reports whether text contains one of the closing keywords as a
+// whole word.
+func hasKeyword(text string, closing map[string]bool) bool {
+	for _, word := range wordPattern.FindAllString(text, -1) {
+		if closing[strings.ToLower(word)] {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendFooters adds footers as trailers at the end of message, skipping any
+// the message already contains. They join an existing footer block rather
+// than starting a new paragraph.
+func AppendFooters(message string, footers []string) string {
+	lower := strings.ToLower(message)
+	var missing []string
+	for _, footer := range footers {
+		if !strings.Contains(lower, strings.ToLower(footer)) {
+			missing = append(missing, footer)
+		}
+	}
+	if len(missing) == 0 {
+		return message
+	}
+
+	subject, body, existing := ParseMessage(message)
+	existing = strings.Join(append(nonEmpty(existing), missing...), "\n")
+	return FormatMessage(subject, body, existing)
+}
+
+// nonEmpty returns s as a single-element slice, or nil if s is empty.
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
+
+// breakingChangePattern matches the start of a BREAKING CHANGE note.
+var breakingChangePattern = regexp.MustCompile(`^BREAKING[ -]CHANGE:\s*`)
+
+// NormalizeFooters cleans up the footer block of a generated message:
+// repeated trailers (such as two "Closes #1") are kept once, and several
+// BREAKING CHANGE notes, including one the model restated as a line of the
+// body, are merged into a single footer. A message without footers, or
+// without anything to clean up, is returned unchanged.
+func NormalizeFooters(message string) string {
+	subject, body, footers := ParseMessage(message)
+	if footers == "" {
+		return message
+	}
+
+	changed := false
+	seen := make(map[string]bool)
+	var entries, notes []string
+	breakingAt := -1
+	addNote := func(note string) {
+		key := "breaking\x00" + footerKey(note)
+		if seen[key] {
+			changed = true
+			return
+		}
+		seen[key] = true
+		notes = append(notes, strings.TrimSpace(note))
+	}
+
+	for _, entry := range footerEntries(footers) {
+		if loc := breakingChangePattern.FindStringIndex(entry); loc != nil {
+			if breakingAt < 0 {
+				breakingAt = len(entries)
+				entries = append(entries, "")
+			} else {
+				changed = true
+			}
+			addNote(entry[loc[1]:])
+			continue
+		}
+		key := footerKey(entry)
+		if seen[key] {
+			changed = true
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, entry)
+	}
+
+	if breakingAt >= 0 {
+		var kept []string
+		for _, line := range strings.Split(body, "\n") {
+			if loc := breakingChangePattern.FindStringIndex(line); loc != nil {
+				addNote(line[loc[1]:])
+				changed = true
+				continue
+			}
+			kept = append(kept, line)
+		}
+		body = strings.Trim(strings.Join(kept, "\n"), "\n")
+		for strings.Contains(body, "\n\n\n") {
+			body = strings.ReplaceAll(body, "\n\n\n", "\n\n")
+		}
+		entries[breakingAt] = "BREAKING CHANGE: " + strings.Join(notes, "\n  ")
+	}
+
+	if !changed {
+		return message
+	}
+	return FormatMessage(subject, body, strings.Join(entries, "\n"))
+}
+
+// footerEntries splits a footer block into trailers, each with its
+// continuation lines.
+func footerEntries(block string) []string {
+	var entries []string
+	for _, line := range strings.Split(block, "\n") {
+		isContinuation := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		if isContinuation && len(entries) > 0 {
+			entries[len(entries)-1] += "\n" + line
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries
+}
+
+// footerKey identifies a trailer regardless of case and spacing.
+func footerKey(entry string) string {
+	return strings.ToLower(strings.Join(strings.Fields(entry), " "))
+}
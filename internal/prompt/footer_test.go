@@ -0,0 +1,135 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIssueFooters(t *testing.T) {
+	tests := []struct {
+		name     string
+		hint     string
+		branch   string
+		expected []string
+	}{
+		{"fixes keyword", "fixes #1", "main", []string{"Closes #1"}},
+		{"closes GH reference", "closes GH-2", "main", []string{"Closes #2"}},
+		{"keyword earlier in prose", "fixes the login crash from #42", "", []string{"Closes #42"}},
+		{"reference without keyword", "follow-up to #7", "", []string{"Refs #7"}},
+		{"no match", "tidy up logging", "main", []string{}},
+		{"keyword must be a whole word", "prefix #3", "", []string{"Refs #3"}},
+		{"branch with closing prefix", "", "fix/42-login-crash", []string{"Closes #42"}},
+		{"branch without closing prefix", "", "feature/issue-9-export", []string{"Refs #9"}},
+		{"version branch is not an issue", "", "release/1.2", []string{}},
+		{"dated release branch is not an issue", "", "release/2024-10", []string{}},
+		{"dated hotfix branch is not an issue", "", "hotfix/2024-01-15", []string{}},
+		{"number under an unknown prefix is not an issue", "", "team/42-login", []string{}},
+		{"bare number is not an issue", "", "2024-cleanup", []string{}},
+		{"hotfix branch with issue", "", "hotfix/42-crash", []string{"Refs #42"}},
+		{"explicit issue under any prefix", "", "alice/gh-12-typo", []string{"Refs #12"}},
+		{"hint and branch deduplicated", "see #5", "fix/5-typo", []string{"Closes #5"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := IssueFooters(tc.hint, tc.branch, DefaultClosingKeywords)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("IssueFooters(%q, %q) = %q, expected %q", tc.hint, tc.branch, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestIssueFootersCustomKeywords(t *testing.T) {
+	got := IssueFooters("implements #8", "", []string{"implements"})
+	if want := []string{"Closes #8"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("IssueFooters() = %q, expected %q", got, want)
+	}
+}
+
+func TestCoAuthorFooters(t *testing.T) {
+	env := map[string]string{
+		"PAIR_WITH":           "Ada Lovelace <ada@example.com>; Grace Hopper <grace@example.com>",
+		"MOB":                 "grace@example.com, Me Again <ME@example.com>, Alan Turing <alan@example.com>",
+		"GIT_COMMITTER_NAME":  "Edsger Dijkstra",
+		"GIT_COMMITTER_EMAIL": "edsger@example.com",
+	}
+	getenv := func(name string) string { return env[name] }
+
+	got := CoAuthorFooters([]string{"PAIR_WITH", "MOB", "GIT_COMMITTER", "UNSET"}, getenv, "me@example.com")
+	want := []string{
+		"Co-authored-by: Ada Lovelace <ada@example.com>",
+		"Co-authored-by: Grace Hopper <grace@example.com>",
+		"Co-authored-by: Alan Turing <alan@example.com>",
+		"Co-authored-by: Edsger Dijkstra <edsger@example.com>",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CoAuthorFooters() = %q, expected %q", got, want)
+	}
+
+	// Pairing with yourself adds nothing
+	env["GIT_COMMITTER_EMAIL"] = "me@example.com"
+	if got := CoAuthorFooters([]string{"GIT_COMMITTER"}, getenv, "me@example.com"); len(got) != 0 {
+		t.Errorf("expected the author to be skipped, got %q", got)
+	}
+
+	message := AppendFooters("feat: pair on login\n\nCloses #3", want[:1])
+	if expected := "feat: pair on login\n\nCloses #3\nCo-authored-by: Ada Lovelace <ada@example.com>"; message != expected {
+		t.Errorf("AppendFooters() = %q, expected %q", message, expected)
+	}
+}
+
+func TestAppendFooters(t *testing.T) {
+	tests := []struct {
+		message  string
+		footers  []string
+		expected string
+	}{
+		{"fix: crash", nil, "fix: crash"},
+		{"fix: crash\n", []string{"Closes #1"}, "fix: crash\n\nCloses #1"},
+		{"fix: crash\n\nCloses #1", []string{"Closes #1", "Refs #2"}, "fix: crash\n\nCloses #1\nRefs #2"},
+		{"fix: crash\n\nSee the docs: more text", []string{"Refs #2"}, "fix: crash\n\nSee the docs: more text\n\nRefs #2"},
+		{"fix: crash\n\ncloses #1", []string{"Closes #1"}, "fix: crash\n\ncloses #1"},
+	}
+
+	for _, tc := range tests {
+		if got := AppendFooters(tc.message, tc.footers); got != tc.expected {
+			t.Errorf("AppendFooters(%q, %q) = %q, expected %q", tc.message, tc.footers, got, tc.expected)
+		}
+	}
+}
+
+func TestNormalizeFooters(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{"no footers", "fix: crash\n\nCloses the gap.", "fix: crash\n\nCloses the gap."},
+		{"nothing to clean", "fix: crash\n\nCloses #1\nRefs #2", "fix: crash\n\nCloses #1\nRefs #2"},
+		{"duplicate issue reference", "fix: crash\n\nCloses #1\nRefs #2\ncloses  #1", "fix: crash\n\nCloses #1\nRefs #2"},
+		{
+			"two breaking changes",
+			"feat!: new API\n\nBREAKING CHANGE: drop v1 endpoints\nCloses #3\nBREAKING-CHANGE: rename Client.Do",
+			"feat!: new API\n\nBREAKING CHANGE: drop v1 endpoints\n  rename Client.Do\nCloses #3",
+		},
+		{
+			"breaking change restated in body",
+			"feat!: new API\n\nRewrite the client.\nBREAKING CHANGE: drop v1 endpoints\n\nBREAKING CHANGE: drop v1 endpoints",
+			"feat!: new API\n\nRewrite the client.\n\nBREAKING CHANGE: drop v1 endpoints",
+		},
+		{
+			"repeated breaking change",
+			"feat!: new API\n\nBREAKING CHANGE: drop v1\nBREAKING CHANGE: drop  v1",
+			"feat!: new API\n\nBREAKING CHANGE: drop v1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeFooters(tt.message); got != tt.expected {
+				t.Errorf("NormalizeFooters() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
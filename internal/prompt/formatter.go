@@ -0,0 +1,99 @@
+package prompt
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// StructuredMessage is a commit message broken into its parts. It is what
+// message formatters receive; external formatter commands get it as JSON
+// on stdin.
+type StructuredMessage struct {
+	// Subject is the whole first line.
+	Subject string `json:"subject"`
+	// Type, Scope, Breaking and Description are the parts of a
+	// conventional subject. For any other subject Type and Scope are empty
+	// and Description is the whole subject.
+	Type        string `json:"type"`
+	Scope       string `json:"scope"`
+	Breaking    bool   `json:"breaking"`
+	Description string `json:"description"`
+	// Body is the text between the subject and the footers.
+	Body string `json:"body"`
+	// Footers are the trailers, one entry per trailer with its
+	// continuation lines.
+	Footers []string `json:"footers"`
+	// Branch is the current branch, empty on a detached HEAD.
+	Branch string `json:"branch"`
+}
+
+// Structure breaks message into its parts, recording branch alongside them.
+func Structure(message, branch string) StructuredMessage {
+	subject, body, footers := ParseMessage(message)
+	msg := StructuredMessage{
+		Subject:     subject,
+		Description: strings.TrimSpace(subject),
+		Body:        body,
+		Footers:     []string{},
+		Branch:      branch,
+	}
+	if parsed, ok := parseConventionalSubject(subject); ok {
+		msg.Type = parsed.Type
+		msg.Scope = parsed.Scope
+		msg.Breaking = parsed.Breaking
+		msg.Description = parsed.Description
+	}
+	if footers != "" {
+		msg.Footers = footerEntries(footers)
+	}
+	return msg
+}
+
+// String joins the message back together from Subject, Body and Footers.
+func (m StructuredMessage) String() string {
+	return FormatMessage(m.Subject, m.Body, strings.Join(m.Footers, "\n"))
+}
+
+// Formatter turns a structured message into the final commit message.
+type Formatter func(StructuredMessage) string
+
+// Formatters are the built-in message formatters, by the name used for
+// message_formatter.
+var Formatters = map[string]Formatter{
+	"plain":  plainFormatter,
+	"ticket": ticketFormatter,
+}
+
+// plainFormatter drops the conventional type and scope, leaving a
+// capitalized description as the subject: "feat(api): add rate limiting"
+// becomes "Add rate limiting".
+func plainFormatter(m StructuredMessage) string {
+	m.Subject = capitalize(m.Description)
+	return m.String()
+}
+
+// ticketPattern matches a tracker key such as "PROJ-123".
+var ticketPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9]+-\d+\b`)
+
+// ticketFormatter prefixes the subject with the ticket key in the branch
+// name, so "feature/PROJ-123-login" gives "[PROJ-123] feat: add login".
+// Subjects that already mention the key and branches without one are left
+// alone.
+func ticketFormatter(m StructuredMessage) string {
+	ticket := ticketPattern.FindString(m.Branch)
+	if ticket != "" && !strings.Contains(m.Subject, ticket) {
+		m.Subject = "[" + ticket + "] " + m.Subject
+	}
+	return m.String()
+}
+
+// capitalize upper-cases the first letter of s.
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	if r == utf8.RuneError {
+		return s
+	}
+	return string(unicode.ToUpper(r)) + s[size:]
+}
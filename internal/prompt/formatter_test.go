@@ -0,0 +1,78 @@
+package prompt
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestStructure(t *testing.T) {
+	msg := Structure("feat(api)!: add rate limiting\n\nLimits requests per client.\n\nCloses #12\nBREAKING CHANGE: clients must retry\n  on 429", "feature/PROJ-9-limits")
+
+	want := StructuredMessage{
+		Subject:     "feat(api)!: add rate limiting",
+		Type:        "feat",
+		Scope:       "api",
+		Breaking:    true,
+		Description: "add rate limiting",
+		Body:        "Limits requests per client.",
+		Footers:     []string{"Closes #12", "BREAKING CHANGE: clients must retry\n  on 429"},
+		Branch:      "feature/PROJ-9-limits",
+	}
+	if !reflect.DeepEqual(msg, want) {
+		t.Fatalf("Structure() = %+v, want %+v", msg, want)
+	}
+	if got := msg.String(); got != "feat(api)!: add rate limiting\n\nLimits requests per client.\n\nCloses #12\nBREAKING CHANGE: clients must retry\n  on 429" {
+		t.Errorf("String() = %q", got)
+	}
+
+	plain := Structure("Update readme", "")
+	if plain.Type != "" || plain.Description != "Update readme" || plain.Footers == nil {
+		t.Errorf("unexpected structure for a plain subject: %+v", plain)
+	}
+}
+
+// TestStructuredMessageJSON pins the JSON contract external formatters
+// rely on: every field is always present, under these names.
+func TestStructuredMessageJSON(t *testing.T) {
+	data, err := json.Marshal(Structure("fix: handle nil config", "main"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"subject":     "fix: handle nil config",
+		"type":        "fix",
+		"scope":       "",
+		"breaking":    false,
+		"description": "handle nil config",
+		"body":        "",
+		"footers":     []any{},
+		"branch":      "main",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("JSON = %s, want fields %v", data, want)
+	}
+}
+
+func TestFormatters(t *testing.T) {
+	tests := []struct {
+		formatter, message, branch, want string
+	}{
+		{"plain", "feat(api): add rate limiting\n\nCloses #12", "", "Add rate limiting\n\nCloses #12"},
+		{"plain", "Update readme", "", "Update readme"},
+		{"ticket", "feat: add login", "feature/PROJ-123-login", "[PROJ-123] feat: add login"},
+		{"ticket", "feat: add login (PROJ-123)", "PROJ-123", "feat: add login (PROJ-123)"},
+		{"ticket", "feat: add login", "main", "feat: add login"},
+	}
+
+	for _, tt := range tests {
+		if got := Formatters[tt.formatter](Structure(tt.message, tt.branch)); got != tt.want {
+			t.Errorf("%s(%q, %q) = %q, want %q", tt.formatter, tt.message, tt.branch, got, tt.want)
+		}
+	}
+}
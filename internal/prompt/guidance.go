@@ -0,0 +1,69 @@
+package prompt
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// GuidanceForFiles returns the guidance rules that apply to the given file
+// paths. rules maps a file extension (".tf" or "tf", ignoring case) or an
+// exact base name ("Dockerfile") to an instruction. Each instruction is
+// returned once, ordered by its rule key, so the prompt stays short and
+// stable however many matching files are staged.
+func GuidanceForFiles(files []string, rules map[string]string) []string {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	byKey := make(map[string]string, len(rules))
+	for key, instruction := range rules {
+		if strings.TrimSpace(instruction) == "" {
+			continue
+		}
+		byKey[normalizeGuidanceKey(key)] = strings.TrimSpace(instruction)
+	}
+
+	matched := make(map[string]bool)
+	for _, file := range files {
+		base := path.Base(file)
+		if _, ok := byKey[base]; ok {
+			matched[base] = true
+		}
+		if ext := strings.ToLower(path.Ext(base)); ext != "" {
+			if _, ok := byKey[ext]; ok {
+				matched[ext] = true
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(matched))
+	for key := range matched {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var guidance []string
+	seen := make(map[string]bool)
+	for _, key := range keys {
+		if instruction := byKey[key]; !seen[instruction] {
+			seen[instruction] = true
+			guidance = append(guidance, instruction)
+		}
+	}
+	return guidance
+}
+
+// normalizeGuidanceKey turns an extension key into ".ext" in lower case.
+// Keys that look like file names (containing no dot, starting with an upper
+// case letter) are kept as base names.
+func normalizeGuidanceKey(key string) string {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, ".") {
+		return strings.ToLower(key)
+	}
+	if strings.Contains(key, ".") || (key != "" && key[0] >= 'A' && key[0] <= 'Z') {
+		return key
+	}
+	return "." + strings.ToLower(key)
+}
@@ -0,0 +1,53 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGuidanceForFiles(t *testing.T) {
+	rules := map[string]string{
+		".tf":        "Mention Terraform resources added or destroyed.",
+		"sql":        "Name the tables and columns a migration changes.",
+		".GO":        "Name the exported API that changed.",
+		"Dockerfile": "Mention base image changes.",
+		".md":        "",
+	}
+
+	tests := []struct {
+		name     string
+		files    []string
+		expected []string
+	}{
+		{
+			name:  "mixed diff",
+			files: []string{"infra/main.tf", "db/001_init.sql", "cmd/main.go", "internal/x.go"},
+			expected: []string{
+				"Name the exported API that changed.",
+				"Name the tables and columns a migration changes.",
+				"Mention Terraform resources added or destroyed.",
+			},
+		},
+		{"exact file name", []string{"build/Dockerfile"}, []string{"Mention base image changes."}},
+		{"extension ignores case", []string{"schema.SQL"}, []string{"Name the tables and columns a migration changes."}},
+		{"empty instruction skipped", []string{"README.md"}, nil},
+		{"no match", []string{"main.py"}, nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := GuidanceForFiles(tc.files, rules)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("GuidanceForFiles(%v) = %q, expected %q", tc.files, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestGuidanceForFilesDeduplicates(t *testing.T) {
+	rules := map[string]string{".yml": "Describe config changes.", ".yaml": "Describe config changes."}
+	got := GuidanceForFiles([]string{"a.yml", "b.yaml"}, rules)
+	if want := []string{"Describe config changes."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GuidanceForFiles() = %q, expected %q", got, want)
+	}
+}
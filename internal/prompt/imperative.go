@@ -0,0 +1,132 @@
+package prompt
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// imperativeVerbs are the verbs whose past, third person and -ing forms are
+// rewritten to the imperative at the start of a subject. Verbs whose third
+// person form is often a noun in subjects ("tests", "changes", "logs") are
+// left out so that "fix: tests failing on CI" keeps its meaning.
+var imperativeVerbs = []string{
+	"add", "allow", "avoid", "bump", "clean", "convert", "correct", "create",
+	"delete", "deprecate", "disable", "document", "drop", "enable", "ensure",
+	"expose", "extract", "fix", "handle", "hide", "implement", "improve",
+	"increase", "initialize", "introduce", "make", "merge", "migrate", "move",
+	"optimize", "prevent", "reduce", "refactor", "remove", "rename", "replace",
+	"restore", "return", "revert", "rewrite", "show", "simplify", "skip",
+	"split", "strip", "support", "switch", "update", "upgrade", "use", "wrap",
+}
+
+// irregularVerbForms maps forms the suffix rules don't produce to their base.
+var irregularVerbForms = map[string]string{
+	"made":      "make",
+	"hid":       "hide",
+	"hidden":    "hide",
+	"rewrote":   "rewrite",
+	"rewritten": "rewrite",
+	"shown":     "show",
+}
+
+// doubledVerbs double their final consonant before -ed and -ing.
+var doubledVerbs = map[string]bool{
+	"drop": true, "skip": true, "split": true, "strip": true, "wrap": true,
+}
+
+// verbBase maps each non-imperative form of imperativeVerbs to its base.
+var verbBase = buildVerbBase()
+
+// subjectPrefixPattern matches what may precede the description in a
+// subject: a conventional "type(scope)!: " or a "[TAG] " prefix.
+var subjectPrefixPattern = regexp.MustCompile(`^(?:[a-zA-Z]+(?:\([^)]*\))?!?:\s*|\[[^\]]+\]\s*)`)
+
+// descriptionStart returns the offset of a subject's description, past any
+// leading emoji, gitmoji shortcodes and type or tag prefix.
+func descriptionStart(subject string) int {
+	start := len(subject) - len(strings.TrimLeftFunc(subject, func(r rune) bool {
+		return isEmoji(r) || unicode.IsSpace(r)
+	}))
+	start += len(shortcodePrefixPattern.FindString(subject[start:]))
+	start += len(subjectPrefixPattern.FindString(subject[start:]))
+	return start
+}
+
+func buildVerbBase() map[string]string {
+	forms := make(map[string]string, len(imperativeVerbs)*3+len(irregularVerbForms))
+	for _, base := range imperativeVerbs {
+		for _, form := range verbForms(base) {
+			if form != base {
+				forms[form] = base
+			}
+		}
+	}
+	for form, base := range irregularVerbForms {
+		forms[form] = base
+	}
+	return forms
+}
+
+// verbForms returns the third person, past and -ing forms of a regular verb.
+func verbForms(base string) []string {
+	last := base[len(base)-1]
+	consonantY := last == 'y' && !strings.ContainsRune("aeiou", rune(base[len(base)-2]))
+
+	var third, past, gerund string
+	switch {
+	case consonantY:
+		third = base[:len(base)-1] + "ies"
+	case strings.HasSuffix(base, "s"), strings.HasSuffix(base, "x"), strings.HasSuffix(base, "z"),
+		strings.HasSuffix(base, "ch"), strings.HasSuffix(base, "sh"):
+		third = base + "es"
+	default:
+		third = base + "s"
+	}
+
+	switch {
+	case doubledVerbs[base]:
+		past = base + string(last) + "ed"
+		gerund = base + string(last) + "ing"
+	case last == 'e':
+		past = base + "d"
+		gerund = base[:len(base)-1] + "ing"
+	case consonantY:
+		past = base[:len(base)-1] + "ied"
+		gerund = base + "ing"
+	default:
+		past = base + "ed"
+		gerund = base + "ing"
+	}
+	if base == "split" {
+		past = base
+	}
+	return []string{third, past, gerund}
+}
+
+// Imperative rewrites a non-imperative verb at the start of a subject's
+// description to the imperative: "feat: added export" becomes "feat: add
+// export" and "Fixing the build" becomes "Fix the build". Conventional and
+// "[TAG]" prefixes and leading emoji are kept, as is the verb's case.
+// Subjects that don't start with a known verb form are returned unchanged.
+func Imperative(subject string) string {
+	start := descriptionStart(subject)
+	rest := subject[start:]
+	end := strings.IndexFunc(rest, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(rest)
+	}
+	word := rest[:end]
+
+	base, ok := verbBase[strings.ToLower(word)]
+	if !ok {
+		return subject
+	}
+	switch {
+	case len(word) > 1 && word == strings.ToUpper(word):
+		base = strings.ToUpper(base)
+	case unicode.IsUpper([]rune(word)[0]):
+		base = strings.ToUpper(base[:1]) + base[1:]
+	}
+	return subject[:start] + base + rest[end:]
+}
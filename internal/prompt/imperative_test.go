@@ -0,0 +1,60 @@
+package prompt
+
+import "testing"
+
+func TestImperative(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Past, third person and -ing forms.
+		{"Added user export", "Add user export"},
+		{"Adds user export", "Add user export"},
+		{"Fixing the build", "Fix the build"},
+		{"fix: fixes crash on empty input", "fix: fix crash on empty input"},
+		{"feat(api): updated rate limits", "feat(api): update rate limits"},
+		{"refactor!: removing legacy flags", "refactor!: remove legacy flags"},
+		{"chore: simplified config loading", "chore: simplify config loading"},
+		{"fix: dropped stale cache entries", "fix: drop stale cache entries"},
+		{"perf: made lookups lazy", "perf: make lookups lazy"},
+		{"[FIX] Handled nil responses", "[FIX] Handle nil responses"},
+		{"✨ Introduces dark mode", "✨ Introduce dark mode"},
+		{":bug: Fixed login redirect", ":bug: Fix login redirect"},
+		{"ADDED changelog entry", "ADD changelog entry"},
+
+		// Already imperative, or not starting with a known verb.
+		{"Add user export", "Add user export"},
+		{"feat(api): update rate limits", "feat(api): update rate limits"},
+		{"fix: tests failing on CI", "fix: tests failing on CI"},
+		{"docs: changes to the README", "docs: changes to the README"},
+		{"Addendum for the spec", "Addendum for the spec"},
+		{"fix:", "fix:"},
+		{"", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := Imperative(tc.input); got != tc.expected {
+				t.Errorf("Imperative(%q) = %q, expected %q", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestVerbForms(t *testing.T) {
+	tests := map[string][3]string{
+		"add":      {"adds", "added", "adding"},
+		"fix":      {"fixes", "fixed", "fixing"},
+		"simplify": {"simplifies", "simplified", "simplifying"},
+		"remove":   {"removes", "removed", "removing"},
+		"skip":     {"skips", "skipped", "skipping"},
+		"split":    {"splits", "split", "splitting"},
+	}
+
+	for base, want := range tests {
+		got := verbForms(base)
+		if len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+			t.Errorf("verbForms(%q) = %q, want %q", base, got, want)
+		}
+	}
+}
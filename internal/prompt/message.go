@@ -0,0 +1,117 @@
+package prompt
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// trailerLinePattern matches one footer line: a "Token: value" trailer
+// (Signed-off-by: ..., Co-authored-by: ...), a "Token #value" reference
+// (Closes #12) or a "BREAKING CHANGE: ..." note.
+var trailerLinePattern = regexp.MustCompile(`^(?:BREAKING[ -]CHANGE:\s|[A-Za-z][\w-]*(?::\s|\s+#)\S)`)
+
+// ParseMessage splits a commit message into its subject (first line), body
+// and footer block. The footer block is the last paragraph when every line
+// in it is a trailer, allowing indented continuation lines; a message whose
+// only paragraph is the subject has no footers. Subject, body and footers
+// are returned without surrounding blank lines.
+//
+// Trailer insertion (AppendFooters, AppendText) and NormalizeFooters use
+// this split so they agree on where the footers begin, as do the message
+// formatters and --no-body.
+func ParseMessage(message string) (subject, body, footers string) {
+	message = strings.Trim(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	subject, rest, _ := strings.Cut(message, "\n")
+	rest = strings.Trim(rest, "\n")
+	if rest == "" {
+		return subject, "", ""
+	}
+
+	last := rest
+	if i := strings.LastIndex(rest, "\n\n"); i >= 0 {
+		body, last = strings.Trim(rest[:i], "\n"), rest[i+2:]
+	} else {
+		body = ""
+	}
+
+	if isFooterBlock(last) {
+		return subject, body, last
+	}
+	if body == "" {
+		return subject, last, ""
+	}
+	return subject, body + "\n\n" + last, ""
+}
+
+// FormatMessage joins a subject, body and footer block back into a message,
+// separating the non-empty parts with blank lines.
+func FormatMessage(subject, body, footers string) string {
+	parts := []string{subject}
+	if body != "" {
+		parts = append(parts, body)
+	}
+	if footers != "" {
+		parts = append(parts, footers)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Normalize tidies the whitespace of a generated message: it strips
+// trailing whitespace from every line, drops blank lines around the
+// message, collapses runs of blank lines to one and puts exactly one blank
+// line between the subject and the body. Indentation is kept.
+func Normalize(message string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimRightFunc(line, unicode.IsSpace)
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		// The body always starts after a blank line
+		if blank || len(lines) == 1 {
+			lines = append(lines, "")
+		}
+		blank = false
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isFooterBlock reports whether every line of paragraph is a trailer or a
+// continuation of the trailer above it.
+func isFooterBlock(paragraph string) bool {
+	lines := strings.Split(paragraph, "\n")
+	if !trailerLinePattern.MatchString(lines[0]) {
+		return false
+	}
+	for _, line := range lines[1:] {
+		isContinuation := strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+		if !isContinuation && !trailerLinePattern.MatchString(line) {
+			return false
+		}
+	}
+	return true
+}
+
+// AppendText adds text to message where it belongs: when every line of text
+// is a trailer it joins the footer block (as AppendFooters, skipping
+// trailers already present), otherwise it becomes the last paragraph of the
+// body, above any footers.
+func AppendText(message, text string) string {
+	text = strings.Trim(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return message
+	}
+	if isFooterBlock(text) {
+		return AppendFooters(message, footerEntries(text))
+	}
+
+	subject, body, footers := ParseMessage(message)
+	if body != "" {
+		body += "\n\n"
+	}
+	return FormatMessage(subject, body+text, footers)
+}
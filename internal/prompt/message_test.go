@@ -0,0 +1,140 @@
+package prompt
+
+import "testing"
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		subject string
+		body    string
+		footers string
+	}{
+		{
+			name:    "subject only",
+			message: "fix: handle empty diff\n",
+			subject: "fix: handle empty diff",
+		},
+		{
+			name:    "subject and body",
+			message: "feat: add cache\n\nCaches responses.\n\nSecond paragraph.",
+			subject: "feat: add cache",
+			body:    "Caches responses.\n\nSecond paragraph.",
+		},
+		{
+			name:    "body and footers",
+			message: "feat: add cache\n\nCaches responses.\n\nCloses #12\nSigned-off-by: Dev <dev@example.com>",
+			subject: "feat: add cache",
+			body:    "Caches responses.",
+			footers: "Closes #12\nSigned-off-by: Dev <dev@example.com>",
+		},
+		{
+			name:    "footers without body",
+			message: "fix: typo\n\nCo-authored-by: Pair <pair@example.com>",
+			subject: "fix: typo",
+			footers: "Co-authored-by: Pair <pair@example.com>",
+		},
+		{
+			name:    "breaking change with continuation line",
+			message: "feat!: drop v1 API\n\nRemoves the old endpoints.\n\nBREAKING CHANGE: clients must use /v2,\n  which needs a token\nRefs #7",
+			subject: "feat!: drop v1 API",
+			body:    "Removes the old endpoints.",
+			footers: "BREAKING CHANGE: clients must use /v2,\n  which needs a token\nRefs #7",
+		},
+		{
+			name:    "prose last paragraph is body",
+			message: "docs: update readme\n\nExplain setup.\n\nSee the docs: more text here",
+			subject: "docs: update readme",
+			body:    "Explain setup.\n\nSee the docs: more text here",
+		},
+		{
+			name:    "structured section headers are body",
+			message: "feat: add x\n\nWhat changed:\nAdded x.\n\nWhy:\nNeeded x.",
+			subject: "feat: add x",
+			body:    "What changed:\nAdded x.\n\nWhy:\nNeeded x.",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			subject, body, footers := ParseMessage(tc.message)
+			if subject != tc.subject || body != tc.body || footers != tc.footers {
+				t.Errorf("ParseMessage() = (%q, %q, %q), expected (%q, %q, %q)",
+					subject, body, footers, tc.subject, tc.body, tc.footers)
+			}
+		})
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	tests := []struct {
+		subject, body, footers string
+		expected               string
+	}{
+		{"fix: a", "", "", "fix: a"},
+		{"fix: a", "Body.", "", "fix: a\n\nBody."},
+		{"fix: a", "", "Closes #1", "fix: a\n\nCloses #1"},
+		{"fix: a", "Body.", "Closes #1", "fix: a\n\nBody.\n\nCloses #1"},
+	}
+
+	for _, tc := range tests {
+		if got := FormatMessage(tc.subject, tc.body, tc.footers); got != tc.expected {
+			t.Errorf("FormatMessage(%q, %q, %q) = %q, expected %q", tc.subject, tc.body, tc.footers, got, tc.expected)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{"already tidy", "feat: add cache\n\nCaches responses.", "feat: add cache\n\nCaches responses."},
+		{"subject only", "fix: typo  \n\n", "fix: typo"},
+		{"missing blank line after subject", "feat: add cache\nCaches responses.", "feat: add cache\n\nCaches responses."},
+		{"extra blank lines after subject", "feat: add cache\n\n\n\nCaches responses.", "feat: add cache\n\nCaches responses."},
+		{"trailing whitespace", "feat: add cache \t\n\nCaches responses.  \nSecond line.\t", "feat: add cache\n\nCaches responses.\nSecond line."},
+		{"whitespace-only lines count as blank", "feat: add cache\n  \n\t\n \nBody.", "feat: add cache\n\nBody."},
+		{"blank runs in the body", "feat: add cache\n\nFirst.\n\n\n\nSecond.\n\nCloses #1", "feat: add cache\n\nFirst.\n\nSecond.\n\nCloses #1"},
+		{"surrounding blank lines", "\n\nfix: typo\n\nBody.\n\n\n", "fix: typo\n\nBody."},
+		{"CRLF line endings", "fix: typo\r\n\r\nBody.\r\n", "fix: typo\n\nBody."},
+		{"indentation kept", "feat: add cache\n\n- item\n  continued", "feat: add cache\n\n- item\n  continued"},
+		{"empty", "  \n\n", ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Normalize(tc.message); got != tc.expected {
+				t.Errorf("Normalize(%q) = %q, expected %q", tc.message, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAppendText(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		text     string
+		expected string
+	}{
+		{"trailer after subject", "fix: a", "Closes #12", "fix: a\n\nCloses #12"},
+		{"trailer joins footers", "fix: a\n\nBody.\n\nRefs #3", "Closes #12", "fix: a\n\nBody.\n\nRefs #3\nCloses #12"},
+		{"trailer already present", "fix: a\n\nCloses #12", "Closes #12", "fix: a\n\nCloses #12"},
+		{"several trailers", "fix: a\n\nBody.", "Closes #1\nSigned-off-by: A <a@b.c>", "fix: a\n\nBody.\n\nCloses #1\nSigned-off-by: A <a@b.c>"},
+		{"prose after subject", "fix: a", "More detail.", "fix: a\n\nMore detail."},
+		{"prose after body", "fix: a\n\nBody.", "More detail.", "fix: a\n\nBody.\n\nMore detail."},
+		{"prose goes above footers", "fix: a\n\nBody.\n\nCloses #1", "More detail.", "fix: a\n\nBody.\n\nMore detail.\n\nCloses #1"},
+		{"mixed text is prose", "fix: a", "See Closes #1 below\nand more", "fix: a\n\nSee Closes #1 below\nand more"},
+		{"empty text", "fix: a", "\n", "fix: a"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := AppendText(tc.message, tc.text); got != tc.expected {
+				t.Errorf("AppendText(%q, %q) = %q, expected %q", tc.message, tc.text, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package prompt
+
+import (
+	"sort"
+	"strings"
+)
+
+// ScopeCount is a conventional commit scope and how often it was used.
+type ScopeCount struct {
+	Scope string
+	Count int
+}
+
+// CountScopes tallies the conventional commit scopes used in subjects, most
+// used first and then by name. A subject listing several scopes, such as
+// "fix(api,ui): ...", counts towards each. Scopes are compared ignoring case.
+func CountScopes(subjects []string) []ScopeCount {
+	counts := make(map[string]int)
+	for _, subject := range subjects {
+		for _, scope := range strings.Split(ExtractScope(subject), ",") {
+			if scope = strings.ToLower(strings.TrimSpace(scope)); scope != "" {
+				counts[scope]++
+			}
+		}
+	}
+
+	scopes := make([]ScopeCount, 0, len(counts))
+	for scope, count := range counts {
+		scopes = append(scopes, ScopeCount{Scope: scope, Count: count})
+	}
+	sort.Slice(scopes, func(i, j int) bool {
+		if scopes[i].Count != scopes[j].Count {
+			return scopes[i].Count > scopes[j].Count
+		}
+		return scopes[i].Scope < scopes[j].Scope
+	})
+	return scopes
+}
@@ -0,0 +1,49 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractScopeFormats(t *testing.T) {
+	tests := []struct {
+		subject  string
+		expected string
+	}{
+		{"feat(api): add endpoint", "api"},
+		{"fix(ui/button): align icon", "ui/button"},
+		{"refactor(core)!: drop v1 config", "core"},
+		{"chore(deps-dev): bump eslint", "deps-dev"},
+		{"feat(api,ui): share types", "api,ui"},
+		{"fix: no scope", ""},
+		{"Update (docs) readme", ""},
+		{"Merge branch 'main'", ""},
+	}
+
+	for _, tc := range tests {
+		if got := ExtractScope(tc.subject); got != tc.expected {
+			t.Errorf("ExtractScope(%q) = %q, expected %q", tc.subject, got, tc.expected)
+		}
+	}
+}
+
+func TestCountScopes(t *testing.T) {
+	subjects := []string{
+		"feat(api): add endpoint",
+		"fix(API): handle nil",
+		"feat(ui): new button",
+		"fix(api,ui): share types",
+		"docs: update readme",
+		"feat(auth): add login",
+		"Initial commit",
+	}
+
+	expected := []ScopeCount{
+		{Scope: "api", Count: 3},
+		{Scope: "ui", Count: 2},
+		{Scope: "auth", Count: 1},
+	}
+	if got := CountScopes(subjects); !reflect.DeepEqual(got, expected) {
+		t.Errorf("CountScopes() = %+v, expected %+v", got, expected)
+	}
+}
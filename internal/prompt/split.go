@@ -0,0 +1,133 @@
+package prompt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/gussy/cmt/internal/git"
+)
+
+// SplitRequest is the provider-independent input for proposing a split of
+// the staged changes into several commits.
+type SplitRequest struct {
+	// Hunks are the staged hunks to cluster.
+	Hunks []git.Hunk
+	// Commits is the number of commits wanted; 0 lets the model decide.
+	Commits int
+}
+
+// SplitGroup is one proposed commit: the hunks it takes and its message.
+type SplitGroup struct {
+	// Hunks are the hunks committed together, in diff order.
+	Hunks []git.Hunk
+	// Message is the suggested commit message.
+	Message string
+}
+
+// SplitResponseSchema documents the JSON object the model must return for a
+// split. ParseSplitResponse reads this shape.
+const SplitResponseSchema = `{
+  "commits": [
+    {
+      "hunks": [1, 3],  // Numbers of the hunks in this commit, as listed above
+      "message": "feat(api): add rate limiting\n\nLimits requests per client..."
+    }
+  ]
+}
+`
+
+// splitJSONResponse is the structure for parsing the AI's JSON response.
+type splitJSONResponse struct {
+	Commits []struct {
+		Hunks   []int  `json:"hunks"`
+		Message string `json:"message"`
+	} `json:"commits"`
+}
+
+// BuildSplitPrompt builds the prompt that asks the model to cluster hunks
+// into coherent commits. Providers send it as is and pass the reply to
+// ParseSplitResponse.
+func BuildSplitPrompt(req SplitRequest) string {
+	var prompt strings.Builder
+
+	prompt.WriteString("You are splitting a large set of staged changes into a series of small, coherent git commits.\n")
+	prompt.WriteString("Group the hunks below so that each commit makes one logical change that could be reviewed on its own:\n")
+	prompt.WriteString("1. Keep a change together with the code, tests and docs that belong to it\n")
+	prompt.WriteString("2. Separate unrelated fixes, refactors, features and formatting\n")
+	prompt.WriteString("3. Order the commits so each one builds on the ones before it\n\n")
+
+	if req.Commits > 0 {
+		prompt.WriteString(fmt.Sprintf("Split the changes into exactly %d commits.\n", req.Commits))
+	} else {
+		prompt.WriteString("Use as many commits as there are logical changes, and no more.\n")
+	}
+	prompt.WriteString("Every hunk belongs to exactly one commit.\n")
+	prompt.WriteString("Write each message in conventional commit format (type(scope): subject), with a short body when the change needs explaining.")
+
+	writeHunks(&prompt, req.Hunks)
+
+	prompt.WriteString("\n\nProvide the split as a JSON object with this structure:\n")
+	prompt.WriteString("```json\n")
+	prompt.WriteString(SplitResponseSchema)
+	prompt.WriteString("```\n\n")
+	prompt.WriteString("Return ONLY the JSON object, no additional explanation.")
+
+	return prompt.String()
+}
+
+// ParseSplitResponse parses the model's JSON reply to a BuildSplitPrompt
+// prompt into the proposed commits, in the model's order. A hunk listed
+// twice stays with the first commit that claims it, unknown hunk numbers
+// are ignored and commits left without hunks are dropped. Hunks no commit
+// took are returned as unassigned. A commit without a message gets the
+// template message for its hunks.
+func ParseSplitResponse(response string, hunks []git.Hunk) ([]SplitGroup, []git.Hunk, error) {
+	var jsonResp splitJSONResponse
+	if err := json.Unmarshal([]byte(jsonObject(response)), &jsonResp); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	groups := []SplitGroup{}
+	assigned := make(map[int]bool)
+	for _, commit := range jsonResp.Commits {
+		var group SplitGroup
+		for _, n := range commit.Hunks {
+			// Hunks are numbered from 1 in the prompt
+			i := n - 1
+			if i < 0 || i >= len(hunks) || assigned[i] {
+				continue
+			}
+			assigned[i] = true
+			group.Hunks = append(group.Hunks, hunks[i])
+		}
+		if len(group.Hunks) == 0 {
+			continue
+		}
+
+		group.Message = strings.TrimSpace(commit.Message)
+		if group.Message == "" {
+			group.Message = FallbackMessage(git.FormatPatch(group.Hunks))
+		}
+		groups = append(groups, group)
+	}
+
+	unassigned := []git.Hunk{}
+	for i, hunk := range hunks {
+		if !assigned[i] {
+			unassigned = append(unassigned, hunk)
+		}
+	}
+	return groups, unassigned, nil
+}
+
+// jsonObject returns the JSON object in a response, without any code block
+// or explanation around it.
+func jsonObject(response string) string {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return strings.TrimSpace(response)
+	}
+	return response[start : end+1]
+}
@@ -0,0 +1,93 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gussy/cmt/internal/git"
+)
+
+func splitTestHunks() []git.Hunk {
+	return []git.Hunk{
+		{FilePath: "auth.go", Header: "@@ -1 +1 @@", Content: "@@ -1 +1 @@\n-a\n+b\n"},
+		{FilePath: "auth_test.go", Header: "@@ -5 +5 @@", Content: "@@ -5 +5 @@\n-c\n+d\n"},
+		{FilePath: "README.md", Header: "@@ -9 +9 @@", Content: "@@ -9 +9 @@\n-e\n+f\n"},
+	}
+}
+
+func TestBuildSplitPrompt(t *testing.T) {
+	result := BuildSplitPrompt(SplitRequest{Hunks: splitTestHunks(), Commits: 2})
+
+	for _, want := range []string{"exactly 2 commits", "Hunk 1:", "File: auth_test.go", SplitResponseSchema} {
+		if !strings.Contains(result, want) {
+			t.Errorf("split prompt missing %q", want)
+		}
+	}
+
+	if result := BuildSplitPrompt(SplitRequest{Hunks: splitTestHunks()}); strings.Contains(result, "exactly 0") || !strings.Contains(result, "as many commits as") {
+		t.Error("expected the model to choose the count when Commits is 0")
+	}
+}
+
+func TestParseSplitResponse(t *testing.T) {
+	hunks := splitTestHunks()
+	response := "Here is the split:\n```json\n" + `{
+  "commits": [
+    {"hunks": [1, 2], "message": "fix(auth): reject expired tokens\n\nAdds a test."},
+    {"hunks": [3], "message": "docs: explain token expiry"}
+  ]
+}` + "\n```"
+
+	groups, unassigned, err := ParseSplitResponse(response, hunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 || len(unassigned) != 0 {
+		t.Fatalf("expected 2 commits and nothing unassigned, got %d and %d", len(groups), len(unassigned))
+	}
+	if len(groups[0].Hunks) != 2 || groups[0].Hunks[1].FilePath != "auth_test.go" {
+		t.Errorf("expected auth.go and auth_test.go together, got %+v", groups[0].Hunks)
+	}
+	if groups[0].Message != "fix(auth): reject expired tokens\n\nAdds a test." {
+		t.Errorf("unexpected first message %q", groups[0].Message)
+	}
+	if groups[1].Message != "docs: explain token expiry" || groups[1].Hunks[0].FilePath != "README.md" {
+		t.Errorf("unexpected second commit %+v", groups[1])
+	}
+}
+
+func TestParseSplitResponseCleansUp(t *testing.T) {
+	hunks := splitTestHunks()
+	// Hunk 1 is claimed twice, 7 doesn't exist, the second commit ends up
+	// empty, the third has no message and hunk 2 is never mentioned.
+	response := `{"commits": [
+    {"hunks": [1, 7], "message": "fix(auth): reject expired tokens"},
+    {"hunks": [1], "message": "refactor: duplicate"},
+    {"hunks": [3], "message": "  "}
+  ]}`
+
+	groups, unassigned, err := ParseSplitResponse(response, hunks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected the empty commit to be dropped, got %d commits", len(groups))
+	}
+	if len(groups[0].Hunks) != 1 || groups[0].Hunks[0].FilePath != "auth.go" {
+		t.Errorf("expected hunk 1 to stay with the first commit, got %+v", groups[0].Hunks)
+	}
+	if want := FallbackMessage(git.FormatPatch(groups[1].Hunks)); groups[1].Message != want {
+		t.Errorf("expected the template message %q, got %q", want, groups[1].Message)
+	}
+	if len(unassigned) != 1 || unassigned[0].FilePath != "auth_test.go" {
+		t.Errorf("expected auth_test.go to be unassigned, got %+v", unassigned)
+	}
+}
+
+func TestParseSplitResponseInvalid(t *testing.T) {
+	for _, response := range []string{"", "no JSON here", `{"commits": [`} {
+		if _, _, err := ParseSplitResponse(response, splitTestHunks()); err == nil {
+			t.Errorf("expected an error for %q", response)
+		}
+	}
+}
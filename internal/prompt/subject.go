@@ -0,0 +1,73 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LowercaseSubject lower-cases the first letter of a subject's description,
+// so "feat: Add export" becomes "feat: add export". As with Imperative the
+// type or tag prefix and leading emoji are skipped. A first word that isn't
+// simply capitalized, such as "API", "GitHub" or "README", is left alone.
+func LowercaseSubject(subject string) string {
+	start := descriptionStart(subject)
+	rest := subject[start:]
+	end := strings.IndexFunc(rest, unicode.IsSpace)
+	if end < 0 {
+		end = len(rest)
+	}
+	word := rest[:end]
+
+	first, size := utf8.DecodeRuneInString(word)
+	if !unicode.IsUpper(first) || strings.ContainsFunc(word[size:], unicode.IsUpper) {
+		return subject
+	}
+	return subject[:start] + string(unicode.ToLower(first)) + rest[size:]
+}
+
+// StripTrailingPeriod removes a period that ends a subject, so "fix: handle
+// nil config." becomes "fix: handle nil config". An ellipsis is kept.
+func StripTrailingPeriod(subject string) string {
+	trimmed := strings.TrimRightFunc(subject, unicode.IsSpace)
+	if !strings.HasSuffix(trimmed, ".") || strings.HasSuffix(trimmed, "..") {
+		return subject
+	}
+	return strings.TrimRightFunc(strings.TrimSuffix(trimmed, "."), unicode.IsSpace)
+}
+
+// PrefixSubject prepends prefix, a fixed project code such as "PROJ-123: ",
+// to the subject of message. A subject that already starts with the prefix,
+// as when amending a commit that has it, is left alone, so prefixing twice
+// changes nothing.
+func PrefixSubject(message, prefix string) string {
+	trimmed := strings.TrimRightFunc(prefix, unicode.IsSpace)
+	if trimmed == "" {
+		return message
+	}
+	subject, rest := splitSubject(message)
+	if strings.HasPrefix(subject, trimmed) {
+		return message
+	}
+	return prefix + subject + rest
+}
+
+// UnprefixSubject undoes PrefixSubject, removing prefix from the start of
+// the subject of message if it is there.
+func UnprefixSubject(message, prefix string) string {
+	trimmed := strings.TrimRightFunc(prefix, unicode.IsSpace)
+	if trimmed == "" || !strings.HasPrefix(message, trimmed) {
+		return message
+	}
+	return strings.TrimLeftFunc(strings.TrimPrefix(message, trimmed), unicode.IsSpace)
+}
+
+// PrefixInstructions tell the model that prefix will be added to the
+// subject it writes, so it leaves the prefix out and keeps the subject short
+// enough for both to fit the usual length.
+func PrefixInstructions(prefix string) string {
+	return fmt.Sprintf("The subject line will be prefixed with %q (%d characters) afterwards. "+
+		"Do not write the prefix yourself, and keep the subject %d characters shorter than usual so it still fits once prefixed.",
+		prefix, utf8.RuneCountInString(prefix), utf8.RuneCountInString(prefix))
+}
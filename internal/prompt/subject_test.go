@@ -0,0 +1,100 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLowercaseSubject(t *testing.T) {
+	tests := map[string]string{
+		"feat: Add X":                 "feat: add X",
+		"fix(auth)!: Reject tokens":   "fix(auth)!: reject tokens",
+		"✨ feat: Add confetti":        "✨ feat: add confetti",
+		"[UI] Tidy the header":        "[UI] tidy the header",
+		"Update readme":               "update readme",
+		"feat: add X":                 "feat: add X",
+		"docs: README tweaks":         "docs: README tweaks",
+		"chore: GitHub actions cache": "chore: GitHub actions cache",
+		"fix: I/O errors":             "fix: I/O errors",
+		"":                            "",
+	}
+	for subject, want := range tests {
+		if got := LowercaseSubject(subject); got != want {
+			t.Errorf("LowercaseSubject(%q) = %q, want %q", subject, got, want)
+		}
+	}
+}
+
+func TestStripTrailingPeriod(t *testing.T) {
+	tests := map[string]string{
+		"feat: add X.":              "feat: add X",
+		"fix: handle nil config . ": "fix: handle nil config",
+		"feat: add X":               "feat: add X",
+		"wip: more to come...":      "wip: more to come...",
+		"":                          "",
+	}
+	for subject, want := range tests {
+		if got := StripTrailingPeriod(subject); got != want {
+			t.Errorf("StripTrailingPeriod(%q) = %q, want %q", subject, got, want)
+		}
+	}
+
+	if got := StripTrailingPeriod(LowercaseSubject("feat: Add X.")); got != "feat: add X" {
+		t.Errorf("expected %q, got %q", "feat: add X", got)
+	}
+}
+
+func TestPrefixSubject(t *testing.T) {
+	tests := []struct {
+		message, prefix, want string
+	}{
+		{"feat(api): add x\n\nBody.", "PROJ-123: ", "PROJ-123: feat(api): add x\n\nBody."},
+		{"fix: y", "[WEB] ", "[WEB] fix: y"},
+		{"fix: y", "", "fix: y"},
+		{"fix: y", "   ", "fix: y"},
+		// Already prefixed, as when amending: never doubled
+		{"PROJ-123: feat(api): add x", "PROJ-123: ", "PROJ-123: feat(api): add x"},
+		{"PROJ-123:feat: add x", "PROJ-123: ", "PROJ-123:feat: add x"},
+	}
+	for _, tt := range tests {
+		got := PrefixSubject(tt.message, tt.prefix)
+		if got != tt.want {
+			t.Errorf("PrefixSubject(%q, %q) = %q, want %q", tt.message, tt.prefix, got, tt.want)
+		}
+		if again := PrefixSubject(got, tt.prefix); again != got {
+			t.Errorf("PrefixSubject is not idempotent: %q became %q", got, again)
+		}
+	}
+}
+
+func TestUnprefixSubject(t *testing.T) {
+	tests := []struct {
+		message, prefix, want string
+	}{
+		{"PROJ-123: feat(api): add x\n\nBody.", "PROJ-123: ", "feat(api): add x\n\nBody."},
+		{"PROJ-123:feat: add x", "PROJ-123: ", "feat: add x"},
+		{"feat: add x", "PROJ-123: ", "feat: add x"},
+		{"feat: add x", "", "feat: add x"},
+	}
+	for _, tt := range tests {
+		if got := UnprefixSubject(tt.message, tt.prefix); got != tt.want {
+			t.Errorf("UnprefixSubject(%q, %q) = %q, want %q", tt.message, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestPrefixInstructions(t *testing.T) {
+	instructions := PrefixInstructions("PROJ-123: ")
+	for _, want := range []string{`"PROJ-123: "`, "(10 characters)", "10 characters shorter"} {
+		if !strings.Contains(instructions, want) {
+			t.Errorf("prefix instructions missing %q: %s", want, instructions)
+		}
+	}
+
+	// A 50-character subject, the length the prompt asks for, stays
+	// within it once the model has made room for the prefix
+	subject := "feat: " + strings.Repeat("x", 50-len("feat: ")-len("PROJ-123: "))
+	if got := PrefixSubject(subject, "PROJ-123: "); len(got) != 50 {
+		t.Errorf("expected the prefixed subject to be 50 characters, got %d", len(got))
+	}
+}
@@ -2,6 +2,7 @@ package prompt
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -67,14 +68,51 @@ var Templates = map[string]*Template{
 
 // Builder helps construct prompts for commit message generation.
 type Builder struct {
-	format      string
-	scope       string
-	hint        string
-	template    *Template
-	stagedFiles []string
-	diff        string
-	isOneLine   bool
-	isVerbose   bool
+	format       string
+	scope        string
+	hint         string
+	template     *Template
+	stagedFiles  []string
+	diff         string
+	isOneLine    bool
+	isVerbose    bool
+	isStructured bool
+	grounding    string
+}
+
+// StructuredSections are the body section headers of a structured message,
+// in the order they appear.
+var StructuredSections = []string{"What changed", "Why", "Impact"}
+
+// IsSectionHeader reports whether line is a structured message section
+// header such as "Why:", alone on its line.
+func IsSectionHeader(line string) bool {
+	line = strings.TrimSpace(line)
+	for _, section := range StructuredSections {
+		if line == section+":" {
+			return true
+		}
+	}
+	return false
+}
+
+// MissingSections returns the StructuredSections whose header doesn't
+// appear in the body of message, in order.
+func MissingSections(message string) []string {
+	_, body, _ := ParseMessage(message)
+	found := make(map[string]bool)
+	for _, line := range strings.Split(body, "\n") {
+		if IsSectionHeader(line) {
+			found[strings.TrimSuffix(strings.TrimSpace(line), ":")] = true
+		}
+	}
+	var missing []string
+	for _, section := range StructuredSections {
+		if !found[section] {
+			missing = append(missing, section)
+		}
+	}
+	return missing
 }
 
 // NewBuilder creates a new prompt builder.
@@ -124,6 +162,7 @@ func (b *Builder) WithDiff(diff string) *Builder {
 func (b *Builder) OneLine() *Builder {
 	b.isOneLine = true
 	b.isVerbose = false
+	b.isStructured = false
 	return b
 }
 
@@ -131,6 +170,23 @@ func (b *Builder) OneLine() *Builder {
 func (b *Builder) Verbose() *Builder {
 	b.isVerbose = true
 	b.isOneLine = false
+	b.isStructured = false
+	return b
+}
+
+// Structured sets the prompt to generate a message with a subject line and
+// the StructuredSections as body sections.
+func (b *Builder) Structured() *Builder {
+	b.isStructured = true
+	b.isOneLine = false
+	b.isVerbose = false
+	return b
+}
+
+// WithGrounding sets how firmly the model is kept to the changes in the
+// diff: "strict" or "normal".
+func (b *Builder) WithGrounding(mode string) *Builder {
+	b.grounding = mode
 	return b
 }
 
@@ -150,6 +206,8 @@ func (b *Builder) Build() string {
 		prompt.WriteString("   - What changed\n")
 		prompt.WriteString("   - Why it changed\n")
 		prompt.WriteString("   - Any important implementation details\n\n")
+	} else if b.isStructured {
+		prompt.WriteString(structuredInstructions())
 	} else {
 		prompt.WriteString("Generate a clear and concise git commit message.\n")
 		prompt.WriteString("Keep the first line under 50 characters if possible.\n\n")
@@ -198,6 +256,9 @@ func (b *Builder) Build() string {
 		prompt.WriteString("\n```\n\n")
 	}
 
+	prompt.WriteString(GroundingInstruction(b.grounding))
+	prompt.WriteString("\n\n")
+
 	// Add final instruction
 	prompt.WriteString("Generate only the commit message without any additional explanation, ")
 	prompt.WriteString("markdown formatting, or code blocks. ")
@@ -209,6 +270,38 @@ func (b *Builder) Build() string {
 	return prompt.String()
 }
 
+// DeletionOnlyInstructions tell the model how to describe a commit whose
+// every staged change deletes a file. The diff of a deletion is only removed
+// lines, which models tend to summarize as a vague "remove X".
+const DeletionOnlyInstructions = `Every file in this commit is deleted (D in the file list); nothing is added or modified.
+Write a subject that says what was removed, and list every removed file, or group of related files, in the body.
+Say why it was removed only if the diff, file names or context make that clear; do not invent a reason.`
+
+// GroundingInstruction tells the model to describe only what the diff
+// shows. "strict" also rules out claims about performance or intent that
+// the code doesn't bear out; any other mode is the normal reminder.
+func GroundingInstruction(mode string) string {
+	if mode == "strict" {
+		return "Only describe changes present in the diff; do not speculate about performance or intent not evidenced by the code. " +
+			"If the reason for a change isn't clear from the code or the context given, leave it out."
+	}
+	return "Describe the changes shown in the diff rather than guessing at ones it doesn't show."
+}
+
+// structuredInstructions describes the layout of a structured message.
+func structuredInstructions() string {
+	var b strings.Builder
+	b.WriteString("Generate a structured git commit message with:\n")
+	b.WriteString("1. A short title line (max 50 characters)\n")
+	b.WriteString("2. A blank line\n")
+	b.WriteString("3. These sections, each header on its own line followed by a short paragraph or bullet list:\n")
+	for _, section := range StructuredSections {
+		b.WriteString(fmt.Sprintf("   %s:\n", section))
+	}
+	b.WriteString("Separate sections with a blank line.\n\n")
+	return b.String()
+}
+
 // BuildRegenerationPrompt creates a prompt for regenerating with feedback.
 func BuildRegenerationPrompt(originalPrompt, previousMessage, feedback string) string {
 	var prompt strings.Builder
@@ -234,24 +327,16 @@ func BuildRegenerationPrompt(originalPrompt, previousMessage, feedback string) s
 	return prompt.String()
 }
 
-// ExtractConventionalType attempts to extract the type from a conventional commit message.
+// ExtractConventionalType returns the type of a conventional commit message,
+// e.g. "feat" for "feat(api): add endpoint". Messages whose subject line is
+// not a conventional commit have no type and return "".
 func ExtractConventionalType(message string) string {
-	// Look for conventional commit pattern
-	parts := strings.SplitN(message, ":", 2)
-	if len(parts) < 2 {
+	subject, _ := splitSubject(message)
+	parsed, ok := parseConventionalSubject(subject)
+	if !ok {
 		return ""
 	}
-
-	// Extract type (and possibly scope)
-	typeAndScope := strings.TrimSpace(parts[0])
-
-	// Check if there's a scope
-	if strings.Contains(typeAndScope, "(") {
-		typePart := strings.SplitN(typeAndScope, "(", 2)[0]
-		return strings.TrimSpace(typePart)
-	}
-
-	return typeAndScope
+	return parsed.Type
 }
 
 // FormatWithScope adds or updates the scope in a commit message.
@@ -281,3 +366,105 @@ func FormatWithScope(message, scope string) string {
 	// Not a conventional commit, return as is
 	return message
 }
+
+// ConventionalTypes lists the conventional commit types in cycling order.
+var ConventionalTypes = []string{
+	"feat", "fix", "docs", "style", "refactor", "test", "chore", "perf", "ci", "build", "revert",
+}
+
+// conventionalSubjectPattern matches "type(scope)!: description" subjects.
+var conventionalSubjectPattern = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^)]*)\))?(!)?:\s*(.*)$`)
+
+// conventionalSubject holds the parts of a conventional commit subject line.
+type conventionalSubject struct {
+	Type        string
+	Scope       string
+	Breaking    bool
+	Description string
+}
+
+// parseConventionalSubject splits a subject line into its conventional commit parts.
+// Returns false if the subject does not follow the conventional format.
+func parseConventionalSubject(subject string) (conventionalSubject, bool) {
+	matches := conventionalSubjectPattern.FindStringSubmatch(strings.TrimSpace(subject))
+	if matches == nil {
+		return conventionalSubject{}, false
+	}
+
+	return conventionalSubject{
+		Type:        matches[1],
+		Scope:       matches[2],
+		Breaking:    matches[3] == "!",
+		Description: matches[4],
+	}, true
+}
+
+// String renders the subject back into "type(scope)!: description" form.
+func (s conventionalSubject) String() string {
+	var b strings.Builder
+	b.WriteString(s.Type)
+	if s.Scope != "" {
+		b.WriteString("(" + s.Scope + ")")
+	}
+	if s.Breaking {
+		b.WriteString("!")
+	}
+	b.WriteString(": ")
+	b.WriteString(s.Description)
+	return b.String()
+}
+
+// splitSubject separates the first line of a message from the rest.
+func splitSubject(message string) (string, string) {
+	subject, rest, found := strings.Cut(message, "\n")
+	if !found {
+		return subject, ""
+	}
+	return subject, "\n" + rest
+}
+
+// FormatWithType sets or replaces the conventional commit type in the subject line.
+// Scope, breaking marker, and body are preserved. Non-conventional subjects
+// get the type prepended.
+func FormatWithType(message, commitType string) string {
+	if commitType == "" {
+		return message
+	}
+
+	subject, rest := splitSubject(message)
+	parsed, ok := parseConventionalSubject(subject)
+	if !ok {
+		return fmt.Sprintf("%s: %s%s", commitType, strings.TrimSpace(subject), rest)
+	}
+
+	parsed.Type = commitType
+	return parsed.String() + rest
+}
+
+// NextConventionalType returns the type following current in ConventionalTypes.
+// It wraps around at the end and returns the first type for unknown input.
+func NextConventionalType(current string) string {
+	for i, t := range ConventionalTypes {
+		if t == current {
+			return ConventionalTypes[(i+1)%len(ConventionalTypes)]
+		}
+	}
+	return ConventionalTypes[0]
+}
+
+// CurrentConventionalType returns the type of the message's subject line,
+// or an empty string if the subject is not a conventional commit.
+func CurrentConventionalType(message string) string {
+	return ExtractConventionalType(message)
+}
+
+// ExtractScope returns the scope of a conventional commit subject line,
+// or an empty string if there is none.
+func ExtractScope(message string) string {
+	subject, _ := splitSubject(message)
+	parsed, ok := parseConventionalSubject(subject)
+	if !ok {
+		return ""
+	}
+	return parsed.Scope
+}
@@ -0,0 +1,218 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatWithType(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		commitType string
+		expected   string
+	}{
+		{
+			name:       "replace type",
+			message:    "feat: add login",
+			commitType: "fix",
+			expected:   "fix: add login",
+		},
+		{
+			name:       "preserve scope",
+			message:    "feat(api): add endpoint",
+			commitType: "refactor",
+			expected:   "refactor(api): add endpoint",
+		},
+		{
+			name:       "preserve breaking marker",
+			message:    "feat(api)!: drop v1",
+			commitType: "refactor",
+			expected:   "refactor(api)!: drop v1",
+		},
+		{
+			name:       "preserve body",
+			message:    "feat: add login\n\nAdds OAuth: Google and GitHub.",
+			commitType: "fix",
+			expected:   "fix: add login\n\nAdds OAuth: Google and GitHub.",
+		},
+		{
+			name:       "prepend to non-conventional subject",
+			message:    "Add login page",
+			commitType: "feat",
+			expected:   "feat: Add login page",
+		},
+		{
+			name:       "empty type is a no-op",
+			message:    "feat: add login",
+			commitType: "",
+			expected:   "feat: add login",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := FormatWithType(tc.message, tc.commitType)
+			if result != tc.expected {
+				t.Errorf("FormatWithType(%q, %q) = %q, expected %q", tc.message, tc.commitType, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNextConventionalType(t *testing.T) {
+	tests := []struct {
+		current  string
+		expected string
+	}{
+		{"feat", "fix"},
+		{"fix", "docs"},
+		{"revert", "feat"},
+		{"", "feat"},
+		{"unknown", "feat"},
+	}
+
+	for _, tc := range tests {
+		result := NextConventionalType(tc.current)
+		if result != tc.expected {
+			t.Errorf("NextConventionalType(%q) = %q, expected %q", tc.current, result, tc.expected)
+		}
+	}
+}
+
+func TestFormatWithScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		scope    string
+		expected string
+	}{
+		{"add scope", "feat: add login", "auth", "feat(auth): add login"},
+		{"replace scope", "feat(api): add login", "auth", "feat(auth): add login"},
+		{"empty scope is a no-op", "feat(api): add login", "", "feat(api): add login"},
+		{"non-conventional unchanged", "Add login page", "auth", "Add login page"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := FormatWithScope(tc.message, tc.scope)
+			if result != tc.expected {
+				t.Errorf("FormatWithScope(%q, %q) = %q, expected %q", tc.message, tc.scope, result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCurrentConventionalTypeAndScope(t *testing.T) {
+	tests := []struct {
+		message       string
+		expectedType  string
+		expectedScope string
+	}{
+		{"feat(api): add endpoint", "feat", "api"},
+		{"fix: resolve crash", "fix", ""},
+		{"feat(ui)!: redesign\n\nbody: text", "feat", "ui"},
+		{"Add login page", "", ""},
+		{"", "", ""},
+	}
+
+	for _, tc := range tests {
+		if got := CurrentConventionalType(tc.message); got != tc.expectedType {
+			t.Errorf("CurrentConventionalType(%q) = %q, expected %q", tc.message, got, tc.expectedType)
+		}
+		if got := ExtractScope(tc.message); got != tc.expectedScope {
+			t.Errorf("ExtractScope(%q) = %q, expected %q", tc.message, got, tc.expectedScope)
+		}
+	}
+}
+
+func TestExtractConventionalType(t *testing.T) {
+	tests := []struct {
+		message       string
+		expectedType  string
+		expectedScope string
+	}{
+		{"feat: add login", "feat", ""},
+		{"fix(auth): handle expired tokens", "fix", "auth"},
+		{"refactor(api,db)!: split the store", "refactor", "api,db"},
+		{"chore(deps): bump yaml\n\nBREAKING CHANGE: none", "chore", "deps"},
+		{"  docs: fix typo  ", "docs", ""},
+		{"Merge branch 'main' into feature", "", ""},
+		{"Update README.md", "", ""},
+		{"Release v1.2: notes follow", "", ""},
+		{"add login\n\nfeat: in the body only", "", ""},
+		{"✨ feat: gitmoji prefix", "", ""},
+		{"", "", ""},
+	}
+
+	for _, tc := range tests {
+		if got := ExtractConventionalType(tc.message); got != tc.expectedType {
+			t.Errorf("ExtractConventionalType(%q) = %q, expected %q", tc.message, got, tc.expectedType)
+		}
+		if got := ExtractScope(tc.message); got != tc.expectedScope {
+			t.Errorf("ExtractScope(%q) = %q, expected %q", tc.message, got, tc.expectedScope)
+		}
+	}
+}
+
+func TestBuildStructured(t *testing.T) {
+	result := NewBuilder().Structured().WithDiff("+x").Build()
+
+	for _, section := range StructuredSections {
+		if !strings.Contains(result, section+":") {
+			t.Errorf("structured prompt missing section %q", section)
+		}
+	}
+
+	// A later format choice replaces the structured layout.
+	if result := NewBuilder().Structured().OneLine().Build(); strings.Contains(result, "What changed:") {
+		t.Error("OneLine() should clear the structured format")
+	}
+}
+
+func TestBuildGrounding(t *testing.T) {
+	strict := "Only describe changes present in the diff; do not speculate about performance or intent not evidenced by the code."
+
+	if result := NewBuilder().WithGrounding("strict").WithDiff("+x").Build(); !strings.Contains(result, strict) {
+		t.Errorf("strict prompt missing the grounding instruction:\n%s", result)
+	}
+	result := NewBuilder().WithGrounding("normal").WithDiff("+x").Build()
+	if strings.Contains(result, strict) {
+		t.Error("normal prompt should not have the strict instruction")
+	}
+	if !strings.Contains(result, GroundingInstruction("normal")) {
+		t.Error("normal prompt missing the grounding reminder")
+	}
+}
+
+func TestIsSectionHeader(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected bool
+	}{
+		{"What changed:", true},
+		{"  Why:", true},
+		{"Impact:", true},
+		{"Why: because", false},
+		{"Impact", false},
+		{"feat: add endpoint", false},
+	}
+
+	for _, tc := range tests {
+		if got := IsSectionHeader(tc.line); got != tc.expected {
+			t.Errorf("IsSectionHeader(%q) = %v, expected %v", tc.line, got, tc.expected)
+		}
+	}
+}
+
+func TestMissingSections(t *testing.T) {
+	complete := "feat: add export\n\nWhat changed:\nAdds CSV export.\n\nWhy:\nUsers asked.\n\nImpact:\nNone."
+	if missing := MissingSections(complete); len(missing) != 0 {
+		t.Errorf("expected no missing sections, got %v", missing)
+	}
+
+	inline := "feat: add export\n\nWhat changed: adds CSV export.\n\nImpact:\nNone."
+	if missing := MissingSections(inline); strings.Join(missing, ",") != "What changed,Why" {
+		t.Errorf("expected What changed and Why to be missing, got %v", missing)
+	}
+}
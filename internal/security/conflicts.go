@@ -0,0 +1,51 @@
+package security
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ConflictMarker is a merge conflict marker left in an added line.
+type ConflictMarker struct {
+	FilePath string // File containing the marker.
+	Line     int    // Line number in the staged version of the file.
+	Marker   string // The marker line, e.g. "<<<<<<< HEAD".
+}
+
+var (
+	// conflictMarkerPattern matches the lines git writes around a conflict:
+	// "<<<<<<< ours", ">>>>>>> theirs" and the diff3 "||||||| base".
+	conflictMarkerPattern = regexp.MustCompile(`^(?:<{7}|>{7}|\|{7})(?:\s|$)`)
+	// conflictSeparatorPattern matches the "=======" line between the sides,
+	// which on its own is also a Markdown or reST heading underline.
+	conflictSeparatorPattern = regexp.MustCompile(`^={7}\s*$`)
+)
+
+// FindConflictMarkers returns the merge conflict markers in the lines the
+// diff adds, in diff order. A "=======" separator only counts in a file that
+// also gains one of the other markers, so heading underlines aren't
+// reported.
+func FindConflictMarkers(diff string) []ConflictMarker {
+	var markers []ConflictMarker
+	conflicted := make(map[string]bool)
+
+	walkAddedLines(diff, func(file string, lineNumber int, line string) {
+		text := strings.TrimRight(strings.TrimPrefix(line, "+"), "\r")
+		marker := ConflictMarker{FilePath: file, Line: lineNumber, Marker: text}
+		switch {
+		case conflictMarkerPattern.MatchString(text):
+			conflicted[file] = true
+			markers = append(markers, marker)
+		case conflictSeparatorPattern.MatchString(text):
+			markers = append(markers, marker)
+		}
+	})
+
+	kept := markers[:0]
+	for _, marker := range markers {
+		if conflicted[marker.FilePath] {
+			kept = append(kept, marker)
+		}
+	}
+	return kept
+}
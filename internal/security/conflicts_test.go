@@ -0,0 +1,60 @@
+package security
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindConflictMarkers(t *testing.T) {
+	diff := `diff --git a/auth.go b/auth.go
+index 1111111..2222222 100644
+--- a/auth.go
++++ b/auth.go
+@@ -10,3 +10,9 @@ func check() {
+ 	if token == "" {
++<<<<<<< HEAD
+ 		return errMissing
++=======
++		return errEmpty
++>>>>>>> feature/tokens
+ 	}
+diff --git a/README.md b/README.md
+index 3333333..4444444 100644
+--- a/README.md
++++ b/README.md
+@@ -1,2 +1,4 @@
+ Intro
++Usage
++=======
+ Outro
+`
+
+	want := []ConflictMarker{
+		{FilePath: "auth.go", Line: 11, Marker: "<<<<<<< HEAD"},
+		{FilePath: "auth.go", Line: 13, Marker: "======="},
+		{FilePath: "auth.go", Line: 15, Marker: ">>>>>>> feature/tokens"},
+	}
+	if got := FindConflictMarkers(diff); !reflect.DeepEqual(got, want) {
+		t.Errorf("FindConflictMarkers() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFindConflictMarkersIgnoresRemovedLines(t *testing.T) {
+	// Resolving a conflict removes the markers; that's what should be committed.
+	diff := `diff --git a/auth.go b/auth.go
+--- a/auth.go
++++ b/auth.go
+@@ -1,5 +1,1 @@
+-<<<<<<< HEAD
+ return errMissing
+-=======
+-return errEmpty
+->>>>>>> feature/tokens
+`
+	if got := FindConflictMarkers(diff); len(got) != 0 {
+		t.Errorf("expected no markers, got %+v", got)
+	}
+	if got := FindConflictMarkers("+<<<<<<<<<< not a marker\n+<<<<<<<x\n"); len(got) != 0 {
+		t.Errorf("expected lookalikes to be ignored, got %+v", got)
+	}
+}
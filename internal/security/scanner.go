@@ -3,6 +3,7 @@ package security
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/gussy/cmt/internal/ui"
@@ -49,6 +50,65 @@ func NewScanner() *Scanner {
 	}
 }
 
+// ScannerOptions selects which secret patterns a scanner uses, by name
+// (e.g. "Bearer Token"). Names are matched ignoring case.
+type ScannerOptions struct {
+	// Enabled, when non-empty, limits the scanner to these patterns.
+	Enabled []string
+	// Disabled patterns are never used, even if listed in Enabled.
+	Disabled []string
+}
+
+// NewScannerWithOptions creates a scanner using the patterns selected by
+// opts. Unknown pattern names are an error, so a typo can't silently leave
+// a noisy pattern switched on.
+func NewScannerWithOptions(opts ScannerOptions) (*Scanner, error) {
+	s := NewScanner()
+
+	byName := make(map[string]string, len(s.patterns))
+	for name := range s.patterns {
+		byName[strings.ToLower(name)] = name
+	}
+	resolve := func(names []string) (map[string]bool, error) {
+		selected := make(map[string]bool, len(names))
+		for _, name := range names {
+			canonical, ok := byName[strings.ToLower(strings.TrimSpace(name))]
+			if !ok {
+				return nil, fmt.Errorf("unknown secret pattern %q (known patterns: %s)",
+					name, strings.Join(s.PatternNames(), ", "))
+			}
+			selected[canonical] = true
+		}
+		return selected, nil
+	}
+
+	enabled, err := resolve(opts.Enabled)
+	if err != nil {
+		return nil, err
+	}
+	disabled, err := resolve(opts.Disabled)
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range s.patterns {
+		if disabled[name] || (len(enabled) > 0 && !enabled[name]) {
+			delete(s.patterns, name)
+		}
+	}
+	return s, nil
+}
+
+// PatternNames returns the names of the patterns the scanner uses, sorted.
+func (s *Scanner) PatternNames() []string {
+	names := make([]string, 0, len(s.patterns))
+	for name := range s.patterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // Scan analyzes the git diff for potential secrets.
 func (s *Scanner) Scan(diff string) ([]ui.Secret, error) {
 	if diff == "" {
@@ -56,12 +116,43 @@ func (s *Scanner) Scan(diff string) ([]ui.Secret, error) {
 	}
 
 	var secrets []ui.Secret
-	lines := strings.Split(diff, "\n")
+	walkAddedLines(diff, func(file string, lineNumber int, line string) {
+		// Skip if it's a test or example file.
+		if s.isTestOrExample(line) {
+			return
+		}
 
+		// Check each pattern.
+		for secretType, pattern := range s.patterns {
+			matches := pattern.FindAllString(line, -1)
+			for _, match := range matches {
+				// Check for false positives.
+				if s.isFalsePositive(match, secretType) {
+					continue
+				}
+
+				// Add the secret.
+				secrets = append(secrets, ui.Secret{
+					Type:     secretType,
+					FilePath: file,
+					Line:     lineNumber,
+					Match:    s.redact(match),
+				})
+			}
+		}
+	})
+
+	return secrets, nil
+}
+
+// walkAddedLines calls fn for every line the diff adds, with the file it is
+// in and its line number in the new version of that file. The line is
+// passed as it appears in the diff, including the leading "+".
+func walkAddedLines(diff string, fn func(file string, lineNumber int, line string)) {
 	var currentFile string
 	lineNumber := 0
 
-	for _, line := range lines {
+	for _, line := range strings.Split(diff, "\n") {
 		// Extract file path from diff headers.
 		if strings.HasPrefix(line, "diff --git") {
 			// Format: diff --git a/path/to/file b/path/to/file
@@ -78,37 +169,13 @@ func (s *Scanner) Scan(diff string) ([]ui.Secret, error) {
 		if strings.HasPrefix(line, "@@") {
 			// Parse hunk header to get starting line number.
 			// Format: @@ -1,2 +3,4 @@
-			lineNumber = s.parseHunkHeader(line)
+			lineNumber = parseHunkHeader(line)
 			continue
 		}
 
-		// Only scan added lines.
 		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
 			lineNumber++
-
-			// Skip if it's a test or example file.
-			if s.isTestOrExample(line) {
-				continue
-			}
-
-			// Check each pattern.
-			for secretType, pattern := range s.patterns {
-				matches := pattern.FindAllString(line, -1)
-				for _, match := range matches {
-					// Check for false positives.
-					if s.isFalsePositive(match, secretType) {
-						continue
-					}
-
-					// Add the secret.
-					secrets = append(secrets, ui.Secret{
-						Type:     secretType,
-						FilePath: currentFile,
-						Line:     lineNumber,
-						Match:    s.redact(match),
-					})
-				}
-			}
+			fn(currentFile, lineNumber, line)
 		} else if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
 			// Skip removed lines but track line number.
 			continue
@@ -117,12 +184,10 @@ func (s *Scanner) Scan(diff string) ([]ui.Secret, error) {
 			lineNumber++
 		}
 	}
-
-	return secrets, nil
 }
 
 // parseHunkHeader extracts the starting line number from a hunk header.
-func (s *Scanner) parseHunkHeader(header string) int {
+func parseHunkHeader(header string) int {
 	// Format: @@ -old_start,old_count +new_start,new_count @@
 	// We want new_start.
 	parts := strings.Split(header, " ")
@@ -0,0 +1,67 @@
+package security
+
+import (
+	"strings"
+	"testing"
+)
+
+const bearerDiff = `diff --git a/docs/api.md b/docs/api.md
+--- a/docs/api.md
++++ b/docs/api.md
+@@ -1,1 +1,2 @@
+ # API
++Send the header Authorization: Bearer your-token-here with each request.
+`
+
+// secretTypes returns the distinct secret types found in diff.
+func secretTypes(t *testing.T, s *Scanner, diff string) map[string]bool {
+	t.Helper()
+	secrets, err := s.Scan(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]bool)
+	for _, secret := range secrets {
+		types[secret.Type] = true
+	}
+	return types
+}
+
+func TestNewScannerWithOptionsDisabled(t *testing.T) {
+	if !secretTypes(t, NewScanner(), bearerDiff)["Bearer Token"] {
+		t.Fatal("expected the default scanner to report the bearer token")
+	}
+
+	s, err := NewScannerWithOptions(ScannerOptions{Disabled: []string{"bearer token", "Basic Auth"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types := secretTypes(t, s, bearerDiff); types["Bearer Token"] {
+		t.Errorf("disabled pattern still fired: %v", types)
+	}
+	for _, name := range s.PatternNames() {
+		if name == "Bearer Token" || name == "Basic Auth" {
+			t.Errorf("disabled pattern %q still loaded", name)
+		}
+	}
+}
+
+func TestNewScannerWithOptionsEnabled(t *testing.T) {
+	s, err := NewScannerWithOptions(ScannerOptions{
+		Enabled:  []string{"AWS Access Key", "Bearer Token"},
+		Disabled: []string{"Bearer Token"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(s.PatternNames(), ","); got != "AWS Access Key" {
+		t.Errorf("PatternNames() = %q, expected only AWS Access Key", got)
+	}
+}
+
+func TestNewScannerWithOptionsUnknownPattern(t *testing.T) {
+	_, err := NewScannerWithOptions(ScannerOptions{Disabled: []string{"Bearer Tokens"}})
+	if err == nil || !strings.Contains(err.Error(), "Bearer Tokens") {
+		t.Errorf("expected an error naming the unknown pattern, got %v", err)
+	}
+}
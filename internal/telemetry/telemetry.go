@@ -0,0 +1,184 @@
+// Package telemetry records how cmt is used, locally and only when opted
+// in, and summarizes the records for cmt stats. Nothing is sent anywhere.
+package telemetry
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// Outcomes of a run.
+const (
+	OutcomeCommitted = "committed"
+	OutcomeCancelled = "cancelled"
+	OutcomeError     = "error"
+	// OutcomeRestarted is a run that started over because the staged
+	// changes moved under it; the new run is recorded on its own.
+	OutcomeRestarted = "restarted"
+)
+
+// Event is one cmt run that generated a message.
+type Event struct {
+	Time          time.Time `json:"time"`
+	Model         string    `json:"model,omitempty"` // empty for the offline template
+	Format        string    `json:"format"`
+	GenerationMS  int64     `json:"generation_ms"` // first generation, including retries
+	PromptTokens  int       `json:"prompt_tokens"` // estimated
+	Regenerations int       `json:"regenerations"` // asked for in the review
+	Corrections   int       `json:"corrections"`   // asked for by the message checks
+	Edited        bool      `json:"edited"`
+	Outcome       string    `json:"outcome"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// Path returns the metrics file of the repository whose common git directory
+// is gitDir.
+func Path(gitDir string) string {
+	return filepath.Join(gitDir, "cmt", "metrics.jsonl")
+}
+
+// Append adds event to the metrics file at path as one JSON line, creating
+// the file and its directory as needed.
+func Append(path string, event Event) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create metrics directory: %w", err)
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open metrics file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return nil
+}
+
+// Load reads the events in the metrics file at path. A missing file holds no
+// events; lines that aren't valid events are skipped.
+func Load(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open metrics file: %w", err)
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err == nil {
+			events = append(events, event)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read metrics file: %w", err)
+	}
+	return events, nil
+}
+
+// ModelCount is how many runs used a model.
+type ModelCount struct {
+	Model string
+	Runs  int
+}
+
+// Summary aggregates recorded runs.
+type Summary struct {
+	Runs          int
+	Committed     int
+	Cancelled     int
+	Errors        int
+	AvgGeneration time.Duration // over runs that generated a message
+	AvgTokens     int           // estimated prompt tokens per run
+	Regenerations int
+	Corrections   int
+	Edited        int
+	Models        []ModelCount // most used first
+	First, Last   time.Time
+}
+
+// RegenerationRate returns the review regenerations per run.
+func (s Summary) RegenerationRate() float64 {
+	if s.Runs == 0 {
+		return 0
+	}
+	return float64(s.Regenerations) / float64(s.Runs)
+}
+
+// Summarize aggregates events. Restarted runs are left out, since the run
+// that replaced them is recorded too.
+func Summarize(events []Event) Summary {
+	var s Summary
+	var generation time.Duration
+	var generated, tokens int
+	models := make(map[string]int)
+
+	for _, e := range events {
+		if e.Outcome == OutcomeRestarted {
+			continue
+		}
+		s.Runs++
+		switch e.Outcome {
+		case OutcomeCommitted:
+			s.Committed++
+		case OutcomeCancelled:
+			s.Cancelled++
+		case OutcomeError:
+			s.Errors++
+		}
+		if e.GenerationMS > 0 {
+			generation += time.Duration(e.GenerationMS) * time.Millisecond
+			generated++
+		}
+		tokens += e.PromptTokens
+		s.Regenerations += e.Regenerations
+		s.Corrections += e.Corrections
+		if e.Edited {
+			s.Edited++
+		}
+		model := e.Model
+		if model == "" {
+			model = "offline"
+		}
+		models[model]++
+		if s.First.IsZero() || e.Time.Before(s.First) {
+			s.First = e.Time
+		}
+		if e.Time.After(s.Last) {
+			s.Last = e.Time
+		}
+	}
+
+	if generated > 0 {
+		s.AvgGeneration = generation / time.Duration(generated)
+	}
+	if s.Runs > 0 {
+		s.AvgTokens = tokens / s.Runs
+	}
+	for model, runs := range models {
+		s.Models = append(s.Models, ModelCount{Model: model, Runs: runs})
+	}
+	sort.Slice(s.Models, func(i, j int) bool {
+		if s.Models[i].Runs != s.Models[j].Runs {
+			return s.Models[i].Runs > s.Models[j].Runs
+		}
+		return s.Models[i].Model < s.Models[j].Model
+	})
+	return s
+}
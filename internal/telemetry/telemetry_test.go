@@ -0,0 +1,92 @@
+package telemetry
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendAndLoad(t *testing.T) {
+	path := Path(t.TempDir())
+
+	events, err := Load(path)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("Load(missing file) = %v, %v; expected no events", events, err)
+	}
+
+	first := Event{Time: time.Unix(100, 0).UTC(), Model: "haiku-4.5", Format: "standard", GenerationMS: 1200, Outcome: OutcomeCommitted}
+	second := Event{Time: time.Unix(200, 0).UTC(), Format: "oneline", Outcome: OutcomeError, Error: "boom"}
+	for _, e := range []Event{first, second} {
+		if err := Append(path, e); err != nil {
+			t.Fatalf("Append failed: %v", err)
+		}
+	}
+
+	// A damaged line is skipped rather than failing the whole file.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("{not json\n")
+	file.Close()
+
+	events, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(events) != 2 || events[0] != first || events[1] != second {
+		t.Errorf("Load() = %+v, expected %+v and %+v", events, first, second)
+	}
+	if filepath.Base(filepath.Dir(path)) != "cmt" {
+		t.Errorf("expected metrics under .git/cmt, got %s", path)
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	at := func(s int64) time.Time { return time.Unix(s, 0) }
+	events := []Event{
+		{Time: at(300), Model: "haiku-4.5", GenerationMS: 1000, PromptTokens: 400, Regenerations: 2, Outcome: OutcomeCommitted},
+		{Time: at(100), Model: "sonnet-4.5", GenerationMS: 3000, PromptTokens: 800, Edited: true, Outcome: OutcomeCommitted},
+		{Time: at(200), Model: "haiku-4.5", GenerationMS: 2000, PromptTokens: 600, Corrections: 1, Outcome: OutcomeCancelled},
+		{Time: at(400), Outcome: OutcomeError},
+		{Time: at(500), Model: "haiku-4.5", GenerationMS: 9000, Regenerations: 5, Outcome: OutcomeRestarted},
+	}
+
+	s := Summarize(events)
+	if s.Runs != 4 || s.Committed != 2 || s.Cancelled != 1 || s.Errors != 1 {
+		t.Errorf("unexpected counts: %+v", s)
+	}
+	if s.AvgGeneration != 2*time.Second {
+		t.Errorf("AvgGeneration = %v, expected 2s (runs without a generation don't count)", s.AvgGeneration)
+	}
+	if s.AvgTokens != 450 {
+		t.Errorf("AvgTokens = %d, expected 450", s.AvgTokens)
+	}
+	if s.Regenerations != 2 || s.RegenerationRate() != 0.5 {
+		t.Errorf("Regenerations = %d, rate %v; expected 2 and 0.5", s.Regenerations, s.RegenerationRate())
+	}
+	if s.Corrections != 1 || s.Edited != 1 {
+		t.Errorf("Corrections = %d, Edited = %d; expected 1 and 1", s.Corrections, s.Edited)
+	}
+	expectedModels := []ModelCount{{"haiku-4.5", 2}, {"offline", 1}, {"sonnet-4.5", 1}}
+	if len(s.Models) != len(expectedModels) {
+		t.Fatalf("Models = %+v, expected %+v", s.Models, expectedModels)
+	}
+	for i := range expectedModels {
+		if s.Models[i] != expectedModels[i] {
+			t.Errorf("Models = %+v, expected %+v", s.Models, expectedModels)
+			break
+		}
+	}
+	if !s.First.Equal(at(100)) || !s.Last.Equal(at(400)) {
+		t.Errorf("First, Last = %v, %v; expected %v, %v", s.First, s.Last, at(100), at(400))
+	}
+}
+
+func TestSummarizeEmpty(t *testing.T) {
+	s := Summarize(nil)
+	if s.Runs != 0 || s.RegenerationRate() != 0 || len(s.Models) != 0 {
+		t.Errorf("expected an empty summary, got %+v", s)
+	}
+}
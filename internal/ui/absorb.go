@@ -176,7 +176,7 @@ func (m AbsorbReviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				if m.currentIndex < len(m.assignments) {
 					// Move assignment to unmatched.
 					assignment := m.assignments[m.currentIndex]
-					m.unmatched = append(m.unmatched, assignment.Hunk)
+					m.unmatched = append(m.unmatched, assignment.AllHunks()...)
 
 					// Remove from assignments.
 					m.assignments = append(
@@ -322,7 +322,11 @@ func (m *AbsorbReviewModel) renderContent() string {
 		Bold(true).
 		Foreground(lipgloss.Color("214"))
 
-	b.WriteString(titleStyle.Render(fmt.Sprintf("Assignment for %s", assignment.Hunk.FilePath)))
+	title := fmt.Sprintf("Assignment for %s", assignment.Hunk.FilePath)
+	if len(assignment.Hunks) > 0 {
+		title += fmt.Sprintf(" (whole file, %d hunks)", len(assignment.Hunks))
+	}
+	b.WriteString(titleStyle.Render(title))
 	b.WriteString("\n\n")
 
 	// Target commit info
@@ -337,17 +341,8 @@ func (m *AbsorbReviewModel) renderContent() string {
 	b.WriteString("\n")
 
 	// Confidence
-	confidenceStyle := lipgloss.NewStyle()
-	if assignment.Confidence >= 0.8 {
-		confidenceStyle = confidenceStyle.Foreground(lipgloss.Color("82"))
-	} else if assignment.Confidence >= 0.5 {
-		confidenceStyle = confidenceStyle.Foreground(lipgloss.Color("214"))
-	} else {
-		confidenceStyle = confidenceStyle.Foreground(lipgloss.Color("196"))
-	}
-
 	b.WriteString(fmt.Sprintf("Confidence: %s\n",
-		confidenceStyle.Render(fmt.Sprintf("%.1f%%", assignment.Confidence*100))))
+		ConfidenceStyle(assignment.Confidence).Render(fmt.Sprintf("%.1f%%", assignment.Confidence*100))))
 	b.WriteString("\n")
 
 	// Reasoning
@@ -383,7 +378,11 @@ func (m *AbsorbReviewModel) renderContent() string {
 	removeStyle := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("196"))
 
-	lines := strings.Split(assignment.Hunk.Content, "\n")
+	var content strings.Builder
+	for _, hunk := range assignment.AllHunks() {
+		content.WriteString(strings.TrimRight(hunk.Content, "\n") + "\n")
+	}
+	lines := strings.Split(strings.TrimRight(content.String(), "\n"), "\n")
 	for _, line := range lines {
 		if strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "+++") {
 			b.WriteString(addStyle.Render(line))
@@ -507,3 +506,16 @@ func ShowAbsorbReview(resp *ai.AbsorbResponse, commits []git.CommitInfo) (bool,
 
 	return false, nil, nil
 }
+
+// ConfidenceStyle colors an absorb confidence: green from 80%, yellow from
+// 50% and red below.
+func ConfidenceStyle(confidence float64) lipgloss.Style {
+	switch {
+	case confidence >= 0.8:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("82"))
+	case confidence >= 0.5:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+	default:
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	}
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"os"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/muesli/termenv"
+)
+
+// colorEnabled records the outcome of ConfigureColor.
+var colorEnabled = true
+
+// ColorEnabled reports whether output should be colored.
+func ColorEnabled() bool {
+	return colorEnabled
+}
+
+// ConfigureColor decides whether output is colored and applies the choice to
+// every lipgloss style (review, secrets, progress, diff). Color is disabled by
+// the --no-color flag, a non-empty NO_COLOR variable, color_output: false, or
+// when stdout isn't a terminal.
+func ConfigureColor(noColorFlag, configEnabled bool) {
+	colorEnabled = resolveColor(
+		noColorFlag,
+		os.Getenv("NO_COLOR") != "",
+		configEnabled,
+		term.IsTerminal(os.Stdout.Fd()),
+	)
+
+	if !colorEnabled {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+}
+
+// resolveColor applies the color precedence rules.
+func resolveColor(noColorFlag, noColorEnv, configEnabled, isTTY bool) bool {
+	switch {
+	case noColorFlag:
+		return false
+	case noColorEnv:
+		return false
+	case !configEnabled:
+		return false
+	default:
+		return isTTY
+	}
+}
@@ -0,0 +1,30 @@
+package ui
+
+import "testing"
+
+func TestResolveColor(t *testing.T) {
+	tests := []struct {
+		name          string
+		noColorFlag   bool
+		noColorEnv    bool
+		configEnabled bool
+		isTTY         bool
+		want          bool
+	}{
+		{"terminal with defaults", false, false, true, true, true},
+		{"piped output", false, false, true, false, false},
+		{"flag wins over everything", true, false, true, true, false},
+		{"NO_COLOR wins over config", false, true, true, true, false},
+		{"config disables color", false, false, false, true, false},
+		{"all disabled", true, true, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolveColor(tt.noColorFlag, tt.noColorEnv, tt.configEnabled, tt.isTTY)
+			if got != tt.want {
+				t.Errorf("resolveColor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
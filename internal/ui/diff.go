@@ -0,0 +1,85 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+)
+
+// Styles for diff lines, shared by the review viewport and `cmt diff`.
+var (
+	diffAddedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	diffRemovedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("161"))
+	diffHunkStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("63"))
+)
+
+// colorizeDiffLine applies diff coloring to a single line.
+func colorizeDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+"):
+		return diffAddedStyle.Render(line)
+	case strings.HasPrefix(line, "-"):
+		return diffRemovedStyle.Render(line)
+	case strings.HasPrefix(line, "@@"):
+		return diffHunkStyle.Render(line)
+	default:
+		return line
+	}
+}
+
+// ColorizeDiff colors added, removed and hunk header lines of a diff.
+func ColorizeDiff(diff string) string {
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		lines[i] = colorizeDiffLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PrintDiff writes a diff to stdout, colored when ColorEnabled.
+// When stdout is a terminal and the diff is taller than it, the diff is
+// shown through $PAGER (less by default). Piped output is printed as is.
+func PrintDiff(diff string) error {
+	output := diff
+	if ColorEnabled() {
+		output = ColorizeDiff(diff)
+	}
+
+	fd := os.Stdout.Fd()
+	if term.IsTerminal(fd) {
+		_, height, err := term.GetSize(fd)
+		if err == nil && strings.Count(output, "\n")+1 > height {
+			if err := page(output); err == nil {
+				return nil
+			}
+		}
+	}
+
+	_, err := fmt.Fprintln(os.Stdout, output)
+	return err
+}
+
+// page shows content through the user's pager.
+func page(content string) error {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+
+	cmd := exec.Command("sh", "-c", pager)
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// Match git: keep colors, and quit if the content fits after all.
+	cmd.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+
+	return cmd.Run()
+}
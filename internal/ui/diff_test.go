@@ -0,0 +1,33 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+func TestColorizeDiff(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	defer lipgloss.SetColorProfile(profile)
+
+	diff := "@@ -1,2 +1,2 @@\n context\n-old\n+new"
+	lines := strings.Split(ColorizeDiff(diff), "\n")
+
+	for i, want := range []bool{true, false, true, true} {
+		colored := strings.Contains(lines[i], "\x1b[")
+		if colored != want {
+			t.Errorf("line %d %q: colored = %v, want %v", i, lines[i], colored, want)
+		}
+	}
+	if lines[1] != " context" {
+		t.Errorf("expected context line untouched, got %q", lines[1])
+	}
+
+	// The review viewport shares the same coloring.
+	if got := formatDiff(diff, 0); got != ColorizeDiff(diff) {
+		t.Errorf("formatDiff and ColorizeDiff disagree:\n%q\n%q", got, ColorizeDiff(diff))
+	}
+}
@@ -0,0 +1,245 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/gussy/cmt/internal/git"
+)
+
+// DiffPickerModel lists the staged files and shows the diff of the one
+// picked in a viewport, for reviewing a large staged set file by file.
+type DiffPickerModel struct {
+	files    []git.FileStatus
+	diffs    map[string]string // path -> that file's part of the diff
+	cursor   int
+	viewport viewport.Model
+	width    int
+	height   int
+	ready    bool
+	quit     bool
+	mode     string // "list" or "diff"
+}
+
+// diffPickerKeyMap defines the key bindings for the diff picker.
+type diffPickerKeyMap struct {
+	Up       key.Binding
+	Down     key.Binding
+	Open     key.Binding
+	Back     key.Binding
+	NextFile key.Binding
+	PrevFile key.Binding
+	Quit     key.Binding
+}
+
+var diffPickerKeys = diffPickerKeyMap{
+	Up: key.NewBinding(
+		key.WithKeys("up", "k"),
+		key.WithHelp("↑/k", "up"),
+	),
+	Down: key.NewBinding(
+		key.WithKeys("down", "j"),
+		key.WithHelp("↓/j", "down"),
+	),
+	Open: key.NewBinding(
+		key.WithKeys("enter", "right", "l"),
+		key.WithHelp("enter/→", "show diff"),
+	),
+	Back: key.NewBinding(
+		key.WithKeys("esc", "left", "h"),
+		key.WithHelp("esc/←", "back to files"),
+	),
+	NextFile: key.NewBinding(
+		key.WithKeys("tab", "n"),
+		key.WithHelp("tab/n", "next file"),
+	),
+	PrevFile: key.NewBinding(
+		key.WithKeys("shift+tab", "p"),
+		key.WithHelp("shift+tab/p", "prev file"),
+	),
+	Quit: key.NewBinding(
+		key.WithKeys("q", "ctrl+c"),
+		key.WithHelp("q", "quit"),
+	),
+}
+
+// NewDiffPickerModel creates a diff picker for the staged files and the
+// staged diff they come from.
+func NewDiffPickerModel(files []git.FileStatus, diff string) DiffPickerModel {
+	vp := viewport.New(80, 20)
+	vp.Style = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("62"))
+
+	return DiffPickerModel{
+		files:    files,
+		diffs:    splitDiffByFile(diff),
+		viewport: vp,
+		mode:     "list",
+	}
+}
+
+// Init initializes the model.
+func (m DiffPickerModel) Init() tea.Cmd {
+	return nil
+}
+
+// Update handles messages and updates the model.
+func (m DiffPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.height = msg.Height
+
+		// Leave room for the header and the controls line.
+		m.viewport.Width = msg.Width - 2
+		m.viewport.Height = max(msg.Height-6, 3)
+		m.ready = true
+
+	case tea.KeyMsg:
+		if key.Matches(msg, diffPickerKeys.Quit) {
+			m.quit = true
+			return m, tea.Quit
+		}
+
+		switch m.mode {
+		case "list":
+			switch {
+			case key.Matches(msg, diffPickerKeys.Up):
+				if m.cursor > 0 {
+					m.cursor--
+				}
+			case key.Matches(msg, diffPickerKeys.Down):
+				if m.cursor < len(m.files)-1 {
+					m.cursor++
+				}
+			case key.Matches(msg, diffPickerKeys.Open):
+				if len(m.files) > 0 {
+					m.mode = "diff"
+					m.showFile()
+				}
+			case msg.String() == "esc":
+				m.quit = true
+				return m, tea.Quit
+			}
+
+		case "diff":
+			switch {
+			case key.Matches(msg, diffPickerKeys.Back):
+				m.mode = "list"
+			case key.Matches(msg, diffPickerKeys.NextFile):
+				if m.cursor < len(m.files)-1 {
+					m.cursor++
+					m.showFile()
+				}
+			case key.Matches(msg, diffPickerKeys.PrevFile):
+				if m.cursor > 0 {
+					m.cursor--
+					m.showFile()
+				}
+			default:
+				m.viewport, cmd = m.viewport.Update(msg)
+			}
+		}
+
+	default:
+		if m.mode == "diff" {
+			m.viewport, cmd = m.viewport.Update(msg)
+		}
+	}
+
+	return m, cmd
+}
+
+// showFile loads the selected file's diff into the viewport, from the top.
+func (m *DiffPickerModel) showFile() {
+	diff := m.diffs[m.files[m.cursor].Path]
+	if diff == "" {
+		diff = "No textual diff for this file."
+	} else if ColorEnabled() {
+		diff = ColorizeDiff(diff)
+	}
+	m.viewport.SetContent(diff)
+	m.viewport.GotoTop()
+}
+
+// View renders the UI.
+func (m DiffPickerModel) View() string {
+	if !m.ready {
+		return "\n  Initializing..."
+	}
+
+	headerStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("39"))
+	selectedStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("82"))
+	controlsStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+
+	var b strings.Builder
+	if m.mode == "diff" {
+		file := m.files[m.cursor]
+		b.WriteString(headerStyle.Render(fmt.Sprintf("📄 %s (%d/%d)", file, m.cursor+1, len(m.files))))
+		b.WriteString("\n\n")
+		b.WriteString(m.viewport.View())
+		b.WriteString("\n")
+		b.WriteString(controlsStyle.Render("[↑/↓] Scroll  [tab/n] Next file  [shift+tab/p] Prev file  [esc] Files  [q] Quit"))
+		return b.String()
+	}
+
+	b.WriteString(headerStyle.Render(fmt.Sprintf("📂 Staged files (%d)", len(m.files))))
+	b.WriteString("\n\n")
+	if len(m.files) == 0 {
+		b.WriteString("  No staged files.\n")
+	}
+	for i, file := range m.files {
+		if i == m.cursor {
+			b.WriteString(selectedStyle.Render("> " + file.String()))
+		} else {
+			b.WriteString("  " + file.String())
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(controlsStyle.Render("[↑/↓] Select  [enter] Show diff  [q] Quit"))
+	return b.String()
+}
+
+// splitDiffByFile splits a unified diff into each file's part, keyed by the
+// file's new path as in the "diff --git a/old b/new" header.
+func splitDiffByFile(diff string) map[string]string {
+	parts := make(map[string]string)
+	var path string
+	var current strings.Builder
+	flush := func() {
+		if path != "" {
+			parts[path] = strings.TrimRight(current.String(), "\n")
+		}
+		current.Reset()
+	}
+
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "diff --git ") {
+			flush()
+			path = strings.TrimPrefix(line, "diff --git ")
+			if i := strings.LastIndex(path, " b/"); i >= 0 {
+				path = path[i+3:]
+			}
+		}
+		current.WriteString(line + "\n")
+	}
+	flush()
+	return parts
+}
+
+// ShowDiffPicker runs the diff picker until the user quits.
+func ShowDiffPicker(files []git.FileStatus, diff string) error {
+	p := tea.NewProgram(NewDiffPickerModel(files, diff), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		return fmt.Errorf("failed to run diff picker: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/gussy/cmt/internal/git"
+)
+
+const pickerDiff = `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -1 +1 @@
+-old a
++new a
+diff --git a/docs/b.md b/docs/b.md
+new file mode 100644
+--- /dev/null
++++ b/docs/b.md
+@@ -0,0 +1 @@
++new b
+`
+
+func pickerKey(k string) tea.KeyMsg {
+	switch k {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+func newTestPicker() DiffPickerModel {
+	files := []git.FileStatus{{Status: "M", Path: "a.go"}, {Status: "A", Path: "docs/b.md"}}
+	m := NewDiffPickerModel(files, pickerDiff)
+	updated, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 30})
+	return updated.(DiffPickerModel)
+}
+
+func sendPicker(m DiffPickerModel, keys ...string) DiffPickerModel {
+	for _, k := range keys {
+		updated, _ := m.Update(pickerKey(k))
+		m = updated.(DiffPickerModel)
+	}
+	return m
+}
+
+func TestSplitDiffByFile(t *testing.T) {
+	parts := splitDiffByFile(pickerDiff)
+	if len(parts) != 2 {
+		t.Fatalf("expected 2 files, got %d: %v", len(parts), parts)
+	}
+	if !strings.Contains(parts["a.go"], "+new a") || strings.Contains(parts["a.go"], "new b") {
+		t.Errorf("unexpected diff for a.go:\n%s", parts["a.go"])
+	}
+	if !strings.HasPrefix(parts["docs/b.md"], "diff --git a/docs/b.md") {
+		t.Errorf("unexpected diff for docs/b.md:\n%s", parts["docs/b.md"])
+	}
+}
+
+func TestDiffPickerCursorIsClamped(t *testing.T) {
+	m := newTestPicker()
+	if !m.ready {
+		t.Fatal("expected the model to be ready after a window size message")
+	}
+
+	m = sendPicker(m, "k")
+	if m.cursor != 0 {
+		t.Errorf("expected cursor to stay at 0, got %d", m.cursor)
+	}
+	m = sendPicker(m, "j", "j", "j")
+	if m.cursor != 1 {
+		t.Errorf("expected cursor to stop at the last file, got %d", m.cursor)
+	}
+}
+
+func TestDiffPickerOpensAndSwitchesFiles(t *testing.T) {
+	m := sendPicker(newTestPicker(), "enter")
+	if m.mode != "diff" {
+		t.Fatalf("expected diff mode after enter, got %q", m.mode)
+	}
+	if view := m.viewport.View(); !strings.Contains(view, "new a") {
+		t.Errorf("expected a.go's diff in the viewport, got:\n%s", view)
+	}
+
+	m = sendPicker(m, "n")
+	if m.cursor != 1 || !strings.Contains(m.viewport.View(), "new b") {
+		t.Errorf("expected next file to show docs/b.md's diff, cursor %d:\n%s", m.cursor, m.viewport.View())
+	}
+	m = sendPicker(m, "p")
+	if m.cursor != 0 || !strings.Contains(m.viewport.View(), "new a") {
+		t.Errorf("expected previous file to show a.go's diff, cursor %d:\n%s", m.cursor, m.viewport.View())
+	}
+
+	m = sendPicker(m, "esc")
+	if m.mode != "list" || m.quit {
+		t.Errorf("expected esc to go back to the file list, mode %q quit %v", m.mode, m.quit)
+	}
+}
+
+func TestDiffPickerQuit(t *testing.T) {
+	m := newTestPicker()
+	updated, cmd := m.Update(pickerKey("q"))
+	if !updated.(DiffPickerModel).quit || cmd == nil {
+		t.Error("expected q to quit the picker")
+	}
+}
@@ -8,25 +8,49 @@ import (
 	"strings"
 )
 
+// scissorsLine marks the start of the diff appended below the message, as in
+// git commit --verbose. It and everything after it is dropped on save.
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+// DefaultEditorHelp is the help shown as comments below the message in the
+// editor (like git does).
+const DefaultEditorHelp = `Please enter the commit message for your changes. Lines starting
+with '#' will be ignored, and an empty message aborts the commit.
+
+You can use the conventional commit format:
+  feat: add new feature
+  fix: fix a bug
+  docs: update documentation
+  style: formatting changes
+  refactor: code refactoring
+  test: add tests
+  chore: maintenance tasks
+`
+
+// EditorOptions controls what the editor shows below the message.
+type EditorOptions struct {
+	// Help is shown as comment lines below the message; empty shows none.
+	Help string
+	// Diff is shown as comment lines below a scissors line for reference.
+	Diff string
+}
+
 // EditInEditor opens the system editor for the user to edit the commit message.
 func EditInEditor(message string) (string, error) {
-	// Determine which editor to use.
-	editor := os.Getenv("EDITOR")
-	if editor == "" {
-		// Try common editors in order of preference.
-		editors := []string{"vim", "vi", "nano", "emacs", "code", "subl"}
-		for _, e := range editors {
-			if _, err := exec.LookPath(e); err == nil {
-				editor = e
-				break
-			}
-		}
-	}
+	return EditInEditorWithDiff(message, "")
+}
 
-	if editor == "" {
-		return "", fmt.Errorf("no editor found. Please set $EDITOR environment variable")
-	}
+// EditInEditorWithDiff opens the system editor like EditInEditor and, when
+// diff is non-empty, shows it as comment lines below the message for
+// reference. The diff is never part of the returned message.
+func EditInEditorWithDiff(message, diff string) (string, error) {
+	return EditInEditorWithOptions(message, EditorOptions{Help: DefaultEditorHelp, Diff: diff})
+}
 
+// EditInEditorWithOptions opens the system editor on message with the help
+// and diff from opts as comments. Comment lines are always stripped from the
+// result, whether or not any were added.
+func EditInEditorWithOptions(message string, opts EditorOptions) (string, error) {
 	// Create a temporary file for editing.
 	tmpFile, err := os.CreateTemp("", "cmt-commit-*.txt")
 	if err != nil {
@@ -34,42 +58,35 @@ func EditInEditor(message string) (string, error) {
 	}
 	defer os.Remove(tmpFile.Name())
 
-	// Write the current message to the file.
+	// Write the current message to the file, ending in a newline so that
+	// anything the editor appends starts on a line of its own.
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
 	if _, err := tmpFile.WriteString(message); err != nil {
 		tmpFile.Close()
 		return "", fmt.Errorf("failed to write to temp file: %w", err)
 	}
 
-	// Add help comments (like git does).
-	helpText := `
-
-# Please enter the commit message for your changes. Lines starting
-# with '#' will be ignored, and an empty message aborts the commit.
-#
-# You can use the conventional commit format:
-#   feat: add new feature
-#   fix: fix a bug
-#   docs: update documentation
-#   style: formatting changes
-#   refactor: code refactoring
-#   test: add tests
-#   chore: maintenance tasks
-#`
-	if _, err := tmpFile.WriteString(helpText); err != nil {
-		tmpFile.Close()
-		return "", fmt.Errorf("failed to write help text: %w", err)
+	if opts.Help != "" {
+		if _, err := tmpFile.WriteString("\n" + helpComment(opts.Help)); err != nil {
+			tmpFile.Close()
+			return "", fmt.Errorf("failed to write help text: %w", err)
+		}
+	}
+
+	if opts.Diff != "" {
+		if _, err := tmpFile.WriteString("\n" + diffComment(opts.Diff)); err != nil {
+			tmpFile.Close()
+			return "", fmt.Errorf("failed to write diff: %w", err)
+		}
 	}
 
 	tmpFile.Close()
 
 	// Open the editor.
-	cmd := exec.Command(editor, tmpFile.Name())
-	cmd.Stdin = os.Stdin
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("failed to run editor: %w", err)
+	if err := EditFile(tmpFile.Name()); err != nil {
+		return "", err
 	}
 
 	// Read the edited content.
@@ -88,8 +105,78 @@ func EditInEditor(message string) (string, error) {
 	return editedMessage, nil
 }
 
+// fallbackEditors are tried in order when $EDITOR is unset.
+var fallbackEditors = []string{"vim", "vi", "nano", "emacs", "code", "subl"}
+
+// ResolveEditor returns the editor EditFile runs: $EDITOR, or the first
+// common editor found on the PATH. It fails with a hint to set $EDITOR when
+// neither is available, so callers can report it before the editor is needed.
+func ResolveEditor() (string, error) {
+	if editor := os.Getenv("EDITOR"); editor != "" {
+		if _, err := exec.LookPath(editor); err != nil {
+			return "", fmt.Errorf("editor %q from $EDITOR was not found: set $EDITOR to an installed editor", editor)
+		}
+		return editor, nil
+	}
+
+	for _, e := range fallbackEditors {
+		if _, err := exec.LookPath(e); err == nil {
+			return e, nil
+		}
+	}
+	return "", fmt.Errorf("no editor found: set $EDITOR, e.g. export EDITOR=vim")
+}
+
+// EditFile opens path in the user's editor and waits for it to exit.
+// The editor is $EDITOR, or the first common editor found on the PATH.
+func EditFile(path string) error {
+	editor, err := ResolveEditor()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.Command(editor, path)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to run editor: %w", err)
+	}
+	return nil
+}
+
+// helpComment renders help text as comment lines, "#" alone for blank lines.
+func helpComment(help string) string {
+	lines := strings.Split(strings.TrimRight(help, " \t"), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = "#"
+		} else {
+			lines[i] = "# " + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffComment renders diff below a scissors line, each line commented out.
+func diffComment(diff string) string {
+	var b strings.Builder
+	b.WriteString(scissorsLine + "\n")
+	b.WriteString("# Do not modify or remove the line above.\n")
+	b.WriteString("# Everything below it will be ignored.\n")
+	for _, line := range strings.Split(strings.TrimRight(diff, "\n"), "\n") {
+		b.WriteString("# " + line + "\n")
+	}
+	return b.String()
+}
+
 // processEditedMessage removes comment lines and trims the message.
+// Anything from the scissors line on (the reference diff) is dropped.
 func processEditedMessage(content string) string {
+	if i := strings.Index(content, scissorsLine); i >= 0 {
+		content = content[:i]
+	}
 	lines := strings.Split(content, "\n")
 	var processedLines []string
 
@@ -110,19 +197,10 @@ func processEditedMessage(content string) string {
 
 // GetEditorName returns the name of the editor that will be used.
 func GetEditorName() string {
-	editor := os.Getenv("EDITOR")
-	if editor != "" {
-		// Extract just the program name from the path.
-		return filepath.Base(editor)
-	}
-
-	// Check for common editors.
-	editors := []string{"vim", "vi", "nano", "emacs", "code", "subl"}
-	for _, e := range editors {
-		if _, err := exec.LookPath(e); err == nil {
-			return e
-		}
+	editor, err := ResolveEditor()
+	if err != nil {
+		return "default editor"
 	}
-
-	return "default editor"
+	// Extract just the program name from the path.
+	return filepath.Base(editor)
 }
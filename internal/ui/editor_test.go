@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessEditedMessageDropsDiff(t *testing.T) {
+	diff := "diff --git a/x b/x\n--- a/x\n+++ b/x\n@@ -1 +1 @@\n-old\n+new\n"
+	content := "feat: add x\n\nBody text\n\n# help comment\n" + diffComment(diff)
+
+	if got, want := processEditedMessage(content), "feat: add x\n\nBody text"; got != want {
+		t.Errorf("processEditedMessage() = %q, want %q", got, want)
+	}
+
+	// Lines below the scissors are dropped even if the user uncomments them.
+	uncommented := strings.ReplaceAll(content, "# +new", "+new")
+	if got := processEditedMessage(uncommented); strings.Contains(got, "+new") {
+		t.Errorf("processEditedMessage() kept diff line: %q", got)
+	}
+}
+
+func TestEditInEditorWithDiff(t *testing.T) {
+	dir := t.TempDir()
+
+	// The "editor" saves a copy of what it was shown and leaves the file as is.
+	seen := filepath.Join(dir, "seen.txt")
+	editor := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\ncp \"$1\" " + seen + "\n"
+	if err := os.WriteFile(editor, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+
+	got, err := EditInEditorWithDiff("fix: typo", "+new line\n")
+	if err != nil {
+		t.Fatalf("EditInEditorWithDiff() error = %v", err)
+	}
+	if got != "fix: typo" {
+		t.Errorf("EditInEditorWithDiff() = %q, want %q", got, "fix: typo")
+	}
+
+	shown, err := os.ReadFile(seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(shown), scissorsLine+"\n") || !strings.Contains(string(shown), "# +new line\n") {
+		t.Errorf("editor was not shown the commented diff:\n%s", shown)
+	}
+}
+
+func TestEditInEditorWithoutHelp(t *testing.T) {
+	dir := t.TempDir()
+
+	// The "editor" saves a copy of what it was shown and adds a comment line.
+	seen := filepath.Join(dir, "seen.txt")
+	editor := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\ncp \"$1\" " + seen + "\necho '# a note' >> \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+
+	got, err := EditInEditorWithOptions("fix: typo", EditorOptions{})
+	if err != nil {
+		t.Fatalf("EditInEditorWithOptions() error = %v", err)
+	}
+	if got != "fix: typo" {
+		t.Errorf("EditInEditorWithOptions() = %q, want %q", got, "fix: typo")
+	}
+
+	shown, err := os.ReadFile(seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(shown), "#") {
+		t.Errorf("editor was shown help comments with Help unset:\n%s", shown)
+	}
+
+	if _, err := EditInEditorWithOptions("fix: typo", EditorOptions{Help: "Line one\n\nLine two\n"}); err != nil {
+		t.Fatalf("EditInEditorWithOptions() error = %v", err)
+	}
+	shown, err = os.ReadFile(seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(shown), "# Line one\n#\n# Line two\n") {
+		t.Errorf("editor was not shown the custom help:\n%s", shown)
+	}
+}
+
+func TestEditInEditorRejectsBlankMessage(t *testing.T) {
+	// The "editor" replaces the message with whitespace.
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	script := "#!/bin/sh\nprintf '  \\n\\t\\n' > \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+
+	if got, err := EditInEditorWithOptions("fix: typo", EditorOptions{}); err == nil {
+		t.Errorf("expected an error for a blank message, got %q", got)
+	}
+}
+
+func TestResolveEditor(t *testing.T) {
+	// No $EDITOR and none of the fallback editors on the PATH.
+	t.Setenv("EDITOR", "")
+	t.Setenv("PATH", t.TempDir())
+
+	if _, err := ResolveEditor(); err == nil || !strings.Contains(err.Error(), "set $EDITOR") {
+		t.Errorf("ResolveEditor() error = %v, want a hint to set $EDITOR", err)
+	}
+	if got := GetEditorName(); got != "default editor" {
+		t.Errorf("GetEditorName() = %q, want %q", got, "default editor")
+	}
+
+	t.Setenv("EDITOR", "no-such-editor")
+	if _, err := ResolveEditor(); err == nil || !strings.Contains(err.Error(), "no-such-editor") {
+		t.Errorf("ResolveEditor() error = %v, want it to name the missing editor", err)
+	}
+
+	editor := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(editor, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("EDITOR", editor)
+	if got, err := ResolveEditor(); err != nil || got != editor {
+		t.Errorf("ResolveEditor() = %q, %v, want %q", got, err, editor)
+	}
+}
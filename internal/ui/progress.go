@@ -125,6 +125,7 @@ var ProgressMessages = struct {
 	Regenerating        string
 	ScanningSecrets     string
 	CreatingCommit      string
+	AmendingCommit      string
 	PushingChanges      string
 }{
 	StagingFiles:        "Staging all changes...",
@@ -134,13 +135,42 @@ var ProgressMessages = struct {
 	Regenerating:        "Regenerating with feedback...",
 	ScanningSecrets:     "Scanning for secrets...",
 	CreatingCommit:      "Creating commit...",
+	AmendingCommit:      "Amending last commit...",
 	PushingChanges:      "Pushing to remote...",
 }
 
+// quiet suppresses decorative output when set.
+var quiet bool
+
+// SetQuiet enables or disables quiet mode. In quiet mode SimpleProgress,
+// Infof and Infoln print nothing; errors are unaffected.
+func SetQuiet(q bool) {
+	quiet = q
+}
+
+// Quiet reports whether quiet mode is enabled.
+func Quiet() bool {
+	return quiet
+}
+
+// Infof prints decorative status output unless quiet mode is on.
+func Infof(format string, args ...any) {
+	if !quiet {
+		fmt.Printf(format, args...)
+	}
+}
+
+// Infoln prints a decorative status line unless quiet mode is on.
+func Infoln(args ...any) {
+	if !quiet {
+		fmt.Println(args...)
+	}
+}
+
 // SimpleProgress shows a simple inline progress message without Bubble Tea.
 // This is useful for quick operations or when we don't want a full TUI.
 func SimpleProgress(message string) {
-	fmt.Printf("%s %s\n", spinnerStyle.Render("⠋"), message)
+	Infof("%s %s\n", spinnerStyle.Render("⠋"), message)
 }
 
 // ClearProgress clears the previous progress line.
@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+// captureStdout returns what fn writes to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	fn()
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestQuietSuppressesStatusOutput(t *testing.T) {
+	defer SetQuiet(false)
+
+	SetQuiet(true)
+	out := captureStdout(t, func() {
+		SimpleProgress("Creating commit...")
+		Infof("✅ %s\n", "done")
+		Infoln("✨ Done!")
+	})
+	if out != "" {
+		t.Errorf("expected no output in quiet mode, got %q", out)
+	}
+
+	SetQuiet(false)
+	out = captureStdout(t, func() {
+		Infoln("✨ Done!")
+	})
+	if out != "✨ Done!\n" {
+		t.Errorf("expected status output, got %q", out)
+	}
+}
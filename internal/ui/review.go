@@ -6,9 +6,12 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/gussy/cmt/internal/ai"
+	"github.com/gussy/cmt/internal/prompt"
 )
 
 // ReviewAction represents the user's decision from the review screen.
@@ -25,24 +28,42 @@ const (
 	ReviewEdit
 	// ReviewEditInline means the user wants to edit inline using textarea.
 	ReviewEditInline
+	// ReviewRegenerateWithModel means the user wants to regenerate the
+	// message with a different model.
+	ReviewRegenerateWithModel
+	// ReviewRegenerateWithFormat means the user wants to regenerate the
+	// message in a different format.
+	ReviewRegenerateWithFormat
 )
 
 // reviewModel is the Bubble Tea model for the commit review screen.
 type reviewModel struct {
-	message        string         // The generated commit message.
-	diff           string         // The git diff to display.
-	viewport       viewport.Model // Scrollable viewport for diff.
-	textarea       textarea.Model // Textarea for feedback input.
-	showFeedback   bool           // Whether to show feedback input.
-	editMode       bool           // Whether in inline edit mode.
-	editTextarea   textarea.Model // Textarea for editing message.
-	preferExternal bool           // Whether to prefer external editor (based on config).
-	action         ReviewAction   // User's final decision.
-	feedback       string         // User's feedback for regeneration.
-	width          int            // Terminal width.
-	height         int            // Terminal height.
-	ready          bool           // Whether the model is ready.
-	done           bool           // Whether the review is complete.
+	message        string          // The generated commit message.
+	diff           string          // The git diff to display.
+	viewport       viewport.Model  // Scrollable viewport for diff.
+	textarea       textarea.Model  // Textarea for feedback input.
+	showFeedback   bool            // Whether to show feedback input.
+	editMode       bool            // Whether in inline edit mode.
+	editError      string          // Why the inline edit wasn't saved, until the next key.
+	editTextarea   textarea.Model  // Textarea for editing message.
+	scopeMode      bool            // Whether the scope prompt is shown.
+	scopeInput     textinput.Model // Input for the scope prompt.
+	picker         *choicePicker   // Open model or format picker, if any.
+	models         []string        // Models offered by the model picker.
+	model          string          // Model that generated the message.
+	format         string          // Format the message was generated in.
+	status         MessageStatus   // How the message came about, for the header badge.
+	original       string          // The message as the review opened, to notice quick edits.
+	choice         string          // Option chosen in a picker.
+	preferExternal bool            // Whether to prefer external editor (based on config).
+	autoscroll     bool            // Whether the viewport follows new content.
+	shownDiff      string          // The diff currently loaded in the viewport.
+	action         ReviewAction    // User's final decision.
+	feedback       string          // User's feedback for regeneration.
+	width          int             // Terminal width.
+	height         int             // Terminal height.
+	ready          bool            // Whether the model is ready.
+	done           bool            // Whether the review is complete.
 
 	// Debug fields
 	debugReserved       int // Reserved height calculated
@@ -71,8 +92,87 @@ var (
 	focusedStyle = lipgloss.NewStyle().
 			Border(lipgloss.RoundedBorder()).
 			BorderForeground(lipgloss.Color("205"))
+
+	badgeStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214"))
 )
 
+// ReviewOptions configures the commit review screen.
+type ReviewOptions struct {
+	// EditorMode is "inline" or "external".
+	EditorMode string
+	// Autoscroll makes the diff viewport follow new content instead of resetting to the top.
+	Autoscroll bool
+	// Models are offered by the model picker; the picker is disabled when empty.
+	Models []string
+	// Model is the model that generated the message, highlighted in the picker.
+	Model string
+	// Format is the format the message was generated in, highlighted in the
+	// picker.
+	Format ai.MessageFormat
+	// Status says whether the message was regenerated or edited, shown as
+	// a badge in the header.
+	Status MessageStatus
+}
+
+// MessageStatus tracks how the message under review diverged from the
+// first suggestion, across the rounds of a review.
+type MessageStatus struct {
+	// Regenerations counts the messages generated after the first one.
+	Regenerations int
+	// Edited is set when the message was edited by hand since it was last
+	// generated.
+	Edited bool
+}
+
+// Regenerated records a new message from the AI. It replaces the edited
+// one, so the edit is forgotten.
+func (s *MessageStatus) Regenerated() {
+	s.Regenerations++
+	s.Edited = false
+}
+
+// Label returns the badge text: "regenerated (attempt N)", counting the
+// first suggestion as attempt 1, and "edited", or "" for the first
+// suggestion as it was generated.
+func (s MessageStatus) Label() string {
+	var parts []string
+	if s.Regenerations > 0 {
+		parts = append(parts, fmt.Sprintf("regenerated (attempt %d)", s.Regenerations+1))
+	}
+	if s.Edited {
+		parts = append(parts, "edited")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// choicePicker is a list of options to regenerate the message with.
+type choicePicker struct {
+	title   string
+	options []string
+	current string       // Option used for the current message.
+	cursor  int          // Highlighted option.
+	action  ReviewAction // Action reported when an option is chosen.
+}
+
+// newChoicePicker creates a picker that starts on the current option.
+func newChoicePicker(title string, options []string, current string, action ReviewAction) *choicePicker {
+	p := &choicePicker{title: title, options: options, current: current, action: action}
+	for i, option := range options {
+		if option == current {
+			p.cursor = i
+		}
+	}
+	return p
+}
+
+// reviewContentMsg replaces the message and diff shown on the review screen.
+// It is the hook for regenerated or streamed content.
+type reviewContentMsg struct {
+	message string
+	diff    string
+}
+
 // newReviewModel creates a new review model.
 func newReviewModel(message, diff string) reviewModel {
 	// Create viewport for diff display.
@@ -94,12 +194,55 @@ func newReviewModel(message, diff string) reviewModel {
 	editTa.SetValue(message)
 	editTa.Focus()
 
+	// Create input for quick scope editing.
+	scopeIn := textinput.New()
+	scopeIn.Placeholder = "scope (e.g., api, auth, ui)"
+	scopeIn.CharLimit = 40
+
 	return reviewModel{
 		message:      message,
+		original:     message,
 		diff:         diff,
 		viewport:     vp,
 		textarea:     ta,
 		editTextarea: editTa,
+		scopeInput:   scopeIn,
+		shownDiff:    diff,
+	}
+}
+
+// cycleType replaces the conventional type of the message with the next one.
+func (m *reviewModel) cycleType() {
+	next := prompt.NextConventionalType(prompt.CurrentConventionalType(m.message))
+	m.message = prompt.FormatWithType(m.message, next)
+}
+
+// applyScope sets the conventional scope of the message.
+// Non-conventional messages are left unchanged.
+func (m *reviewModel) applyScope(scope string) {
+	scope = strings.TrimSpace(scope)
+	if scope == "" || prompt.CurrentConventionalType(m.message) == "" {
+		return
+	}
+	m.message = prompt.FormatWithScope(m.message, scope)
+}
+
+// syncViewport loads the current diff into the viewport.
+// When the diff changed since it was last shown, the scroll position is reset
+// to the top, or moved to the bottom when autoscroll is enabled, so a stale
+// offset from the previous content is never kept.
+func (m *reviewModel) syncViewport(height int) {
+	m.viewport.SetContent(formatDiff(m.diff, height))
+
+	if m.diff == m.shownDiff {
+		return
+	}
+	m.shownDiff = m.diff
+
+	if m.autoscroll {
+		m.viewport.GotoBottom()
+	} else {
+		m.viewport.GotoTop()
 	}
 }
 
@@ -121,6 +264,14 @@ func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	)
 
 	switch msg := msg.(type) {
+	case reviewContentMsg:
+		m.message = msg.message
+		m.original = msg.message
+		m.diff = msg.diff
+		m.editTextarea.SetValue(msg.message)
+		m.syncViewport(m.viewport.Height)
+		return m, nil
+
 	case tea.KeyMsg:
 		// Handle inline edit mode.
 		if m.editMode {
@@ -140,6 +291,11 @@ func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			case tea.KeyEnter:
 				// Check if it's plain Enter (submit) vs Shift+Enter (newline).
 				if msg.String() != "shift+enter" {
+					// A blank message can't be committed, so keep editing.
+					if strings.TrimSpace(m.editTextarea.Value()) == "" {
+						m.editError = "The commit message can't be empty."
+						return m, nil
+					}
 					// Submit the edited message.
 					m.message = m.editTextarea.Value()
 					m.editMode = false
@@ -151,10 +307,65 @@ func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 
 			// Update edit textarea.
+			m.editError = ""
 			m.editTextarea, cmd = m.editTextarea.Update(msg)
 			return m, cmd
 		}
 
+		// Handle scope prompt.
+		if m.scopeMode {
+			switch msg.Type {
+			case tea.KeyEsc:
+				m.scopeMode = false
+				m.scopeInput.Reset()
+				return m, nil
+
+			case tea.KeyCtrlC:
+				m.action = ReviewReject
+				m.done = true
+				return m, tea.Quit
+
+			case tea.KeyEnter:
+				m.applyScope(m.scopeInput.Value())
+				m.scopeMode = false
+				m.scopeInput.Reset()
+				return m, nil
+			}
+
+			m.scopeInput, cmd = m.scopeInput.Update(msg)
+			return m, cmd
+		}
+
+		// Handle model and format pickers.
+		if m.picker != nil {
+			switch msg.String() {
+			case "esc":
+				m.picker = nil
+				return m, nil
+
+			case "ctrl+c":
+				m.action = ReviewReject
+				m.done = true
+				return m, tea.Quit
+
+			case "up", "k":
+				if m.picker.cursor > 0 {
+					m.picker.cursor--
+				}
+			case "down", "j":
+				if m.picker.cursor < len(m.picker.options)-1 {
+					m.picker.cursor++
+				}
+			case "enter":
+				m.choice = m.picker.options[m.picker.cursor]
+				m.action = m.picker.action
+				m.picker = nil
+				m.done = true
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+
 		// Handle feedback mode.
 		if m.showFeedback {
 			switch msg.Type {
@@ -200,6 +411,31 @@ func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.done = true
 			return m, tea.Quit
 
+		case "t", "T":
+			m.cycleType()
+			return m, nil
+
+		case "s", "S":
+			m.scopeMode = true
+			m.scopeInput.SetValue(m.currentScope())
+			m.scopeInput.CursorEnd()
+			return m, m.scopeInput.Focus()
+
+		case "m", "M":
+			if len(m.models) == 0 {
+				return m, nil
+			}
+			m.picker = newChoicePicker("Regenerate With Model", m.models, m.model, ReviewRegenerateWithModel)
+			return m, nil
+
+		case "f", "F":
+			formats := make([]string, len(ai.MessageFormats))
+			for i, format := range ai.MessageFormats {
+				formats[i] = format.String()
+			}
+			m.picker = newChoicePicker("Regenerate With Format", formats, m.format, ReviewRegenerateWithFormat)
+			return m, nil
+
 		case "r", "R":
 			m.showFeedback = true
 			m.textarea.Focus()
@@ -215,6 +451,7 @@ func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			} else {
 				// Use inline textarea editing
 				m.editMode = true
+				m.editError = ""
 				m.editTextarea.SetValue(m.message)
 				m.editTextarea.Focus()
 				return m, textarea.Blink
@@ -278,19 +515,18 @@ func (m reviewModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 			if !m.ready {
 				m.viewport = viewport.New(msg.Width-2, viewportHeight)
-				m.viewport.SetContent(formatDiff(m.diff, viewportHeight))
 				m.ready = true
 			} else {
 				m.viewport.Width = msg.Width - 2
 				m.viewport.Height = viewportHeight
-				// Update content with new height to ensure padding
-				m.viewport.SetContent(formatDiff(m.diff, viewportHeight))
 			}
+			// Update content with new height to ensure padding
+			m.syncViewport(viewportHeight)
 		} else if !m.ready {
 			// Initialize a minimal viewport for potential later use
 			// This won't be rendered but ensures m.ready is true
 			m.viewport = viewport.New(msg.Width-2, 5)
-			m.viewport.SetContent(formatDiff(m.diff, 5))
+			m.syncViewport(5)
 			m.ready = true
 		}
 	}
@@ -318,6 +554,16 @@ func (m reviewModel) View() string {
 		return m.viewFeedback()
 	}
 
+	// Show scope prompt.
+	if m.scopeMode {
+		return m.viewScope()
+	}
+
+	// Show model or format picker.
+	if m.picker != nil {
+		return m.viewPicker()
+	}
+
 	// Show review mode.
 	return m.viewReview()
 }
@@ -389,6 +635,60 @@ func (m reviewModel) viewFeedback() string {
 	return s.String()
 }
 
+// viewScope renders the scope prompt screen.
+func (m reviewModel) viewScope() string {
+	var s strings.Builder
+
+	// Title.
+	s.WriteString(titleStyle.Render("Set Commit Scope"))
+	s.WriteString("\n\n")
+
+	// Current subject for reference.
+	subject, _, _ := strings.Cut(m.message, "\n")
+	s.WriteString(subject)
+	s.WriteString("\n\n")
+
+	s.WriteString(m.scopeInput.View())
+	s.WriteString("\n\n")
+
+	// Help.
+	s.WriteString(helpStyle.Render("Enter to apply • Esc to cancel"))
+
+	return s.String()
+}
+
+// viewPicker renders the model or format picker screen.
+func (m reviewModel) viewPicker() string {
+	var s strings.Builder
+
+	// Title.
+	s.WriteString(titleStyle.Render(m.picker.title))
+	s.WriteString("\n\n")
+
+	for i, option := range m.picker.options {
+		cursor := "  "
+		if i == m.picker.cursor {
+			cursor = "> "
+		}
+		s.WriteString(cursor + option)
+		if option == m.picker.current {
+			s.WriteString(helpStyle.Render(" (current)"))
+		}
+		s.WriteString("\n")
+	}
+	s.WriteString("\n")
+
+	// Help.
+	s.WriteString(helpStyle.Render("↑/↓ to choose • Enter to regenerate • Esc to cancel"))
+
+	return s.String()
+}
+
+// currentScope returns the scope of the current message's subject, if any.
+func (m reviewModel) currentScope() string {
+	return prompt.ExtractScope(m.message)
+}
+
 // viewEditMode renders the inline edit mode screen.
 func (m reviewModel) viewEditMode() string {
 	var s strings.Builder
@@ -403,6 +703,11 @@ func (m reviewModel) viewEditMode() string {
 		Render(m.editTextarea.View()))
 	s.WriteString("\n\n")
 
+	if m.editError != "" {
+		s.WriteString(badgeStyle.Render(m.editError))
+		s.WriteString("\n")
+	}
+
 	// Help text.
 	s.WriteString(helpStyle.Render("Enter to save • Shift+Enter for newline • Esc to cancel"))
 
@@ -411,7 +716,17 @@ func (m reviewModel) viewEditMode() string {
 
 // viewHeader renders the header.
 func (m reviewModel) viewHeader() string {
-	return titleStyle.Render("Review Commit Message")
+	title := titleStyle.Render("Review Commit Message")
+
+	// Quick type and scope changes count as edits too
+	status := m.status
+	if m.message != m.original {
+		status.Edited = true
+	}
+	if label := status.Label(); label != "" {
+		title = lipgloss.JoinHorizontal(lipgloss.Top, title, "  ", badgeStyle.Render("● "+label))
+	}
+	return title
 }
 
 // viewFooter renders the footer with available actions.
@@ -426,16 +741,23 @@ func (m reviewModel) viewFooter() string {
 		editText = "[e]dit - External editor"
 	}
 
-	actions := []struct {
+	type footerAction struct {
 		text  string
 		width int
-	}{
+	}
+	actions := []footerAction{
 		{"[y]es - Accept", 0},
 		{"[n]o - Reject", 0},
 		{"[r]egenerate - Provide feedback", 0},
 		{editText, 0},
-		{"[q]uit - Cancel", 0},
+		{"[t]ype - Cycle type", 0},
+		{"[s]cope - Set scope", 0},
+	}
+	actions = append(actions, footerAction{"[f]ormat - Switch format", 0})
+	if len(m.models) > 0 {
+		actions = append(actions, footerAction{"[m]odel - Switch model", 0})
 	}
+	actions = append(actions, footerAction{"[q]uit - Cancel", 0})
 
 	// Calculate width for each action
 	for i := range actions {
@@ -510,24 +832,9 @@ func formatDiff(diff string, minHeight int) string {
 	}
 
 	// Apply basic coloring to diff lines.
-	var formatted []string
-	for _, line := range lines {
-		switch {
-		case strings.HasPrefix(line, "+"):
-			formatted = append(formatted, lipgloss.NewStyle().
-				Foreground(lipgloss.Color("42")).
-				Render(line))
-		case strings.HasPrefix(line, "-"):
-			formatted = append(formatted, lipgloss.NewStyle().
-				Foreground(lipgloss.Color("161")).
-				Render(line))
-		case strings.HasPrefix(line, "@@"):
-			formatted = append(formatted, lipgloss.NewStyle().
-				Foreground(lipgloss.Color("63")).
-				Render(line))
-		default:
-			formatted = append(formatted, line)
-		}
+	formatted := make([]string, len(lines))
+	for i, line := range lines {
+		formatted[i] = colorizeDiffLine(line)
 	}
 
 	result := strings.Join(formatted, "\n")
@@ -546,13 +853,45 @@ func formatDiff(diff string, minHeight int) string {
 	return result
 }
 
-// ShowCommitReview displays the interactive commit review screen.
-// Returns the action taken, feedback/edited message, and any error.
-func ShowCommitReview(message, diff, editorMode string) (ReviewAction, string, error) {
-	m := newReviewModel(message, diff)
+// ShowCommitReview displays the interactive commit review screen, or the
+// line-based review of ShowSimpleReview when SimpleUI reports so.
+// Returns the action taken, feedback/edited message (or the chosen model or
+// format for ReviewRegenerateWithModel and ReviewRegenerateWithFormat), and
+// any error.
+func ShowCommitReview(message, diff string, opts ReviewOptions) (ReviewAction, string, error) {
+	return new(ReviewSession).Show(message, diff, opts)
+}
+
+// ReviewSession is a commit review that spans regenerations. Each round
+// after the first hands the new message and diff to the previous review
+// screen as a content change, so its scroll position is reset, or follows
+// the new content with Autoscroll, rather than rebuilt from scratch.
+// The zero value is ready to use.
+type ReviewSession struct {
+	last *reviewModel
+}
+
+// Show runs one round of the review, with the same results as
+// ShowCommitReview.
+func (s *ReviewSession) Show(message, diff string, opts ReviewOptions) (ReviewAction, string, error) {
+	if SimpleUI() {
+		return ShowSimpleReview(stdinReader, os.Stdout, message, diff, opts)
+	}
+
+	var m reviewModel
+	if s.last == nil {
+		m = newReviewModel(message, diff)
+	} else {
+		m = s.last.nextRound(message, diff)
+	}
+	m.autoscroll = opts.Autoscroll
+	m.models = opts.Models
+	m.model = opts.Model
+	m.format = opts.Format.String()
+	m.status = opts.Status
 
 	// If editor mode is set to external, swap the key bindings
-	if editorMode == "external" {
+	if opts.EditorMode == "external" {
 		m.preferExternal = true
 	}
 
@@ -562,11 +901,38 @@ func ShowCommitReview(message, diff, editorMode string) (ReviewAction, string, e
 		return ReviewReject, "", fmt.Errorf("failed to run review UI: %w", err)
 	}
 
-	reviewModel := finalModel.(reviewModel)
+	final := finalModel.(reviewModel)
+	s.last = &final
+	return final.result()
+}
+
+// nextRound returns the model for the next round of a review: the decision
+// and any open prompt of the finished round are cleared and the new content
+// is loaded.
+func (m reviewModel) nextRound(message, diff string) reviewModel {
+	m.feedback = ""
+	m.choice = ""
+	m.done = false
+	m.showFeedback = false
+	m.editMode = false
+	m.editError = ""
+	m.scopeMode = false
+	m.picker = nil
+	m.textarea.Reset()
+
+	updated, _ := m.Update(reviewContentMsg{message: message, diff: diff})
+	return updated.(reviewModel)
+}
 
-	// For inline edit, return the edited message; otherwise return feedback
-	if reviewModel.action == ReviewEditInline {
-		return reviewModel.action, reviewModel.message, nil
+// result returns what ShowCommitReview reports for the final model: the
+// message for accept and inline edit, the chosen option for a model or
+// format switch and the feedback otherwise.
+func (m reviewModel) result() (ReviewAction, string, error) {
+	switch m.action {
+	case ReviewAccept, ReviewEditInline:
+		return m.action, m.message, nil
+	case ReviewRegenerateWithModel, ReviewRegenerateWithFormat:
+		return m.action, m.choice, nil
 	}
-	return reviewModel.action, reviewModel.feedback, nil
+	return m.action, m.feedback, nil
 }
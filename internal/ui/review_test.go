@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// makeDiff builds a diff with the given number of added lines.
+func makeDiff(prefix string, lines int) string {
+	var b strings.Builder
+	b.WriteString("diff --git a/file.go b/file.go\n")
+	for i := 0; i < lines; i++ {
+		b.WriteString(fmt.Sprintf("+%s line %d\n", prefix, i))
+	}
+	return b.String()
+}
+
+func TestReviewViewportResetsOnContentChange(t *testing.T) {
+	m := newReviewModel("feat: first", makeDiff("old", 40))
+	m.viewport = viewport.New(80, 5)
+	m.syncViewport(5)
+
+	m.viewport.LineDown(20)
+	if m.viewport.YOffset == 0 {
+		t.Fatal("expected viewport to be scrolled before content change")
+	}
+
+	updated, _ := m.Update(reviewContentMsg{message: "feat: second", diff: makeDiff("new", 40)})
+	got := updated.(reviewModel)
+
+	if got.viewport.YOffset != 0 {
+		t.Errorf("expected viewport to reset to top, got offset %d", got.viewport.YOffset)
+	}
+	if got.message != "feat: second" {
+		t.Errorf("expected message to be replaced, got %q", got.message)
+	}
+}
+
+func TestReviewViewportKeepsOffsetForSameContent(t *testing.T) {
+	diff := makeDiff("same", 40)
+	m := newReviewModel("feat: same", diff)
+	m.viewport = viewport.New(80, 5)
+	m.syncViewport(5)
+
+	m.viewport.LineDown(10)
+	m.syncViewport(5)
+
+	if m.viewport.YOffset != 10 {
+		t.Errorf("expected offset to be preserved on resize, got %d", m.viewport.YOffset)
+	}
+}
+
+func TestReviewViewportAutoscroll(t *testing.T) {
+	m := newReviewModel("feat: first", makeDiff("old", 10))
+	m.autoscroll = true
+	m.viewport = viewport.New(80, 5)
+	m.syncViewport(5)
+
+	updated, _ := m.Update(reviewContentMsg{message: "feat: second", diff: makeDiff("new", 40)})
+	got := updated.(reviewModel)
+
+	if !got.viewport.AtBottom() {
+		t.Errorf("expected viewport to follow new content to the bottom, got offset %d", got.viewport.YOffset)
+	}
+}
+
+func TestReviewQuickTypeAndScopeEdit(t *testing.T) {
+	m := newReviewModel("feat(api): add endpoint", "")
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	m = updated.(reviewModel)
+	if m.message != "fix(api): add endpoint" {
+		t.Errorf("expected type to cycle to fix, got %q", m.message)
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")})
+	m = updated.(reviewModel)
+	if !m.scopeMode {
+		t.Fatal("expected scope prompt to open")
+	}
+	if m.scopeInput.Value() != "api" {
+		t.Errorf("expected scope input prefilled with api, got %q", m.scopeInput.Value())
+	}
+
+	m.scopeInput.SetValue("auth")
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(reviewModel)
+	if m.scopeMode {
+		t.Error("expected scope prompt to close after enter")
+	}
+	if m.message != "fix(auth): add endpoint" {
+		t.Errorf("expected scope to be applied, got %q", m.message)
+	}
+}
+
+func TestReviewModelPicker(t *testing.T) {
+	m := newReviewModel("feat: add endpoint", "")
+	m.models = []string{"haiku-4.5", "sonnet-4.5", "opus-4.1"}
+	m.model = "haiku-4.5"
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	m = updated.(reviewModel)
+	if m.picker == nil || m.picker.action != ReviewRegenerateWithModel {
+		t.Fatal("expected model picker to open")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updated.(reviewModel)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(reviewModel)
+
+	action, model, err := m.result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != ReviewRegenerateWithModel {
+		t.Errorf("expected ReviewRegenerateWithModel, got %v", action)
+	}
+	if model != "sonnet-4.5" {
+		t.Errorf("expected sonnet-4.5 to be chosen, got %q", model)
+	}
+}
+
+func TestReviewModelPickerDisabledWithoutModels(t *testing.T) {
+	m := newReviewModel("feat: add endpoint", "")
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("m")})
+	if updated.(reviewModel).picker != nil {
+		t.Error("expected model picker to stay closed without models")
+	}
+}
+
+func TestReviewFormatPicker(t *testing.T) {
+	m := newReviewModel("feat: add endpoint", "")
+	m.format = "standard"
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	m = updated.(reviewModel)
+	if m.picker == nil || m.picker.action != ReviewRegenerateWithFormat {
+		t.Fatal("expected format picker to open")
+	}
+
+	// Esc closes the picker without choosing.
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = updated.(reviewModel)
+	if m.picker != nil || m.done {
+		t.Fatal("expected esc to close the format picker")
+	}
+
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("f")})
+	m = updated.(reviewModel)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	m = updated.(reviewModel)
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(reviewModel)
+
+	action, format, err := m.result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if action != ReviewRegenerateWithFormat {
+		t.Errorf("expected ReviewRegenerateWithFormat, got %v", action)
+	}
+	if format != "oneline" {
+		t.Errorf("expected oneline to be chosen, got %q", format)
+	}
+}
+
+func TestReviewInlineEditRejectsBlankMessage(t *testing.T) {
+	m := newReviewModel("feat: add endpoint", "")
+	m.editMode = true
+	m.editTextarea.SetValue("  \n\t")
+
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	m = updated.(reviewModel)
+	if m.done || !m.editMode {
+		t.Fatal("expected a blank inline edit to stay in edit mode")
+	}
+	if m.editError == "" || !strings.Contains(m.viewEditMode(), m.editError) {
+		t.Errorf("expected the edit view to explain why, got error %q", m.editError)
+	}
+	if m.message != "feat: add endpoint" {
+		t.Errorf("expected the message to be kept, got %q", m.message)
+	}
+
+	m.editTextarea.SetValue("fix: add endpoint")
+	updated, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	action, message, _ := updated.(reviewModel).result()
+	if action != ReviewEditInline || message != "fix: add endpoint" {
+		t.Errorf("got (%v, %q), want the edited message", action, message)
+	}
+}
+
+func TestMessageStatusLabel(t *testing.T) {
+	var status MessageStatus
+	if label := status.Label(); label != "" {
+		t.Errorf("expected no label for the first suggestion, got %q", label)
+	}
+
+	status.Edited = true
+	if label := status.Label(); label != "edited" {
+		t.Errorf("expected edited, got %q", label)
+	}
+
+	// A regenerated message replaces the edit.
+	status.Regenerated()
+	if label := status.Label(); label != "regenerated (attempt 2)" {
+		t.Errorf("expected regenerated (attempt 2), got %q", label)
+	}
+
+	status.Regenerated()
+	status.Edited = true
+	if label := status.Label(); label != "regenerated (attempt 3), edited" {
+		t.Errorf("expected regenerated (attempt 3), edited, got %q", label)
+	}
+}
+
+func TestReviewHeaderBadge(t *testing.T) {
+	m := newReviewModel("feat(api): add endpoint", "")
+	if header := m.viewHeader(); strings.Contains(header, "edited") {
+		t.Errorf("expected no badge before any change, got %q", header)
+	}
+
+	m.status = MessageStatus{Regenerations: 1}
+	if header := m.viewHeader(); !strings.Contains(header, "regenerated (attempt 2)") {
+		t.Errorf("expected regenerated badge, got %q", header)
+	}
+
+	// A quick type change counts as an edit.
+	updated, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("t")})
+	m = updated.(reviewModel)
+	if header := m.viewHeader(); !strings.Contains(header, "regenerated (attempt 2), edited") {
+		t.Errorf("expected edited badge after a type change, got %q", header)
+	}
+}
+
+func TestReviewNextRoundLoadsNewContent(t *testing.T) {
+	m := newReviewModel("feat: first", makeDiff("old", 40))
+	m.viewport = viewport.New(80, 5)
+	m.syncViewport(5)
+	m.viewport.LineDown(20)
+
+	// The first round ended with a regenerate request
+	m.showFeedback = true
+	m.feedback = "shorter"
+	m.action = ReviewRegenerate
+	m.done = true
+
+	next := m.nextRound("feat: second", makeDiff("new", 40))
+	if next.done || next.showFeedback || next.feedback != "" {
+		t.Errorf("expected the previous round's decision to be cleared, got done=%v showFeedback=%v feedback=%q", next.done, next.showFeedback, next.feedback)
+	}
+	if next.message != "feat: second" || next.original != "feat: second" {
+		t.Errorf("expected the regenerated message, got %q", next.message)
+	}
+	if next.viewport.YOffset != 0 {
+		t.Errorf("expected the viewport to reset for the new diff, got offset %d", next.viewport.YOffset)
+	}
+}
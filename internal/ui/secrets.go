@@ -2,10 +2,12 @@ package ui
 
 import (
 	"fmt"
+	"os"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
 )
 
 // SecretAction represents the user's choice for handling detected secrets.
@@ -174,6 +176,22 @@ func ShowSecretWarning(secrets []Secret) (SecretAction, error) {
 	return warningModel.action, nil
 }
 
+// IsInteractive reports whether stdin and stdout are terminals, so that
+// full-screen prompts such as the secret warning can be shown.
+func IsInteractive() bool {
+	return term.IsTerminal(os.Stdin.Fd()) && term.IsTerminal(os.Stdout.Fd())
+}
+
+// FormatSecretFindings lists secrets as plain text, one per line, for
+// output where the warning screen can't be shown.
+func FormatSecretFindings(secrets []Secret) string {
+	var b strings.Builder
+	for _, secret := range secrets {
+		fmt.Fprintf(&b, "  - %s in %s:%d (%s)\n", secret.Type, secret.FilePath, secret.Line, secret.Match)
+	}
+	return b.String()
+}
+
 // FormatSecretForDisplay redacts a secret for safe display.
 func FormatSecretForDisplay(secret string) string {
 	if len(secret) <= 8 {
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gussy/cmt/internal/ai"
+	"github.com/gussy/cmt/internal/prompt"
+)
+
+// SimpleUI reports whether the line-based review should be used instead of
+// the full-screen one: when CMT_SIMPLE_UI is set, when TERM is "dumb" or when
+// stdin or stdout is not a terminal.
+func SimpleUI() bool {
+	if value := os.Getenv("CMT_SIMPLE_UI"); value != "" {
+		simple, err := strconv.ParseBool(value)
+		return err != nil || simple
+	}
+	return os.Getenv("TERM") == "dumb" || !IsInteractive()
+}
+
+// stdinReader buffers os.Stdin once for every review pass, so answers that
+// one pass reads ahead from piped input are still there for the next.
+var stdinReader = bufio.NewReader(os.Stdin)
+
+// ShowSimpleReview is the line-based review: it prints the message and reads
+// one-letter answers from in, offering the same actions as ShowCommitReview.
+// The end of input rejects the message. When in is a *bufio.Reader it is
+// read directly, so callers can share one across passes.
+func ShowSimpleReview(in io.Reader, out io.Writer, message, diff string, opts ReviewOptions) (ReviewAction, string, error) {
+	r, ok := in.(*bufio.Reader)
+	if !ok {
+		r = bufio.NewReader(in)
+	}
+	ask := func(question string) (string, bool) {
+		fmt.Fprint(out, question)
+		line, err := r.ReadString('\n')
+		if err != nil && line == "" {
+			return "", false
+		}
+		return strings.TrimSpace(line), true
+	}
+
+	for {
+		heading := "Commit message:"
+		if label := opts.Status.Label(); label != "" {
+			heading = fmt.Sprintf("Commit message (%s):", label)
+		}
+		fmt.Fprintf(out, "\n%s\n\n%s\n\n", heading, indent(message))
+		choices := "[y]es, [n]o, [e]dit, [r]egenerate, [t]ype, [s]cope, [f]ormat, "
+		if len(opts.Models) > 0 {
+			choices += "[m]odel, "
+		}
+		answer, ok := ask(choices + "[d]iff? ")
+		if !ok {
+			return ReviewReject, "", nil
+		}
+
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			return ReviewAccept, message, nil
+
+		case "n", "no", "q":
+			return ReviewReject, "", nil
+
+		case "e", "edit":
+			if opts.EditorMode == "external" {
+				return ReviewEdit, "", nil
+			}
+			fmt.Fprintln(out, "Enter the new message, ending with a line containing only \".\":")
+			var lines []string
+			for {
+				line, err := r.ReadString('\n')
+				line = strings.TrimRight(line, "\r\n")
+				if line == "." || (err != nil && line == "") {
+					break
+				}
+				lines = append(lines, line)
+				if err != nil {
+					break
+				}
+			}
+			if edited := strings.TrimSpace(strings.Join(lines, "\n")); edited != "" {
+				return ReviewEditInline, edited, nil
+			}
+			fmt.Fprintln(out, "Empty message; keeping the current one.")
+
+		case "r", "regenerate":
+			feedback, ok := ask("Feedback: ")
+			if !ok {
+				return ReviewReject, "", nil
+			}
+			if feedback != "" {
+				return ReviewRegenerate, feedback, nil
+			}
+
+		case "t", "type":
+			next := prompt.NextConventionalType(prompt.CurrentConventionalType(message))
+			message = prompt.FormatWithType(message, next)
+
+		case "s", "scope":
+			scope, ok := ask(fmt.Sprintf("Scope [%s]: ", prompt.ExtractScope(message)))
+			if !ok {
+				return ReviewReject, "", nil
+			}
+			if scope != "" && prompt.CurrentConventionalType(message) != "" {
+				message = prompt.FormatWithScope(message, scope)
+			}
+
+		case "f", "format":
+			formats := make([]string, len(ai.MessageFormats))
+			for i, format := range ai.MessageFormats {
+				formats[i] = format.String()
+			}
+			if choice, ok := askChoice(ask, out, "Regenerate with format", formats, opts.Format.String()); ok {
+				return ReviewRegenerateWithFormat, choice, nil
+			}
+
+		case "m", "model":
+			if len(opts.Models) == 0 {
+				fmt.Fprintln(out, "No other models are available.")
+				continue
+			}
+			if choice, ok := askChoice(ask, out, "Regenerate with model", opts.Models, opts.Model); ok {
+				return ReviewRegenerateWithModel, choice, nil
+			}
+
+		case "d", "diff":
+			if diff == "" {
+				fmt.Fprintln(out, "No diff to show.")
+			} else {
+				fmt.Fprintln(out, strings.TrimRight(diff, "\n"))
+			}
+
+		default:
+			fmt.Fprintf(out, "Unknown answer %q.\n", answer)
+		}
+	}
+}
+
+// askChoice lists options by number, marking current, and returns the one
+// picked. An empty or invalid answer picks nothing.
+func askChoice(ask func(string) (string, bool), out io.Writer, title string, options []string, current string) (string, bool) {
+	fmt.Fprintf(out, "%s:\n", title)
+	for i, option := range options {
+		marker := " "
+		if option == current {
+			marker = "*"
+		}
+		fmt.Fprintf(out, " %s %d) %s\n", marker, i+1, option)
+	}
+	answer, ok := ask("Number (enter to go back): ")
+	if !ok || answer == "" {
+		return "", false
+	}
+	n, err := strconv.Atoi(answer)
+	if err != nil || n < 1 || n > len(options) {
+		fmt.Fprintf(out, "No option %q.\n", answer)
+		return "", false
+	}
+	return options[n-1], true
+}
+
+// indent prefixes every line of s with two spaces.
+func indent(s string) string {
+	return "  " + strings.ReplaceAll(s, "\n", "\n  ")
+}
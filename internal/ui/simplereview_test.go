@@ -0,0 +1,92 @@
+package ui
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/gussy/cmt/internal/ai"
+)
+
+func TestShowSimpleReview(t *testing.T) {
+	opts := ReviewOptions{Models: []string{"haiku-4.5", "sonnet-4.5"}, Model: "haiku-4.5"}
+
+	tests := []struct {
+		name       string
+		input      string
+		opts       ReviewOptions
+		wantAction ReviewAction
+		wantResult string
+	}{
+		{"accept", "y\n", opts, ReviewAccept, "feat: add login"},
+		{"reject", "n\n", opts, ReviewReject, ""},
+		{"end of input rejects", "", opts, ReviewReject, ""},
+		{"unknown answer asks again", "x\ny\n", opts, ReviewAccept, "feat: add login"},
+		{"regenerate with feedback", "r\nmention the tests\n", opts, ReviewRegenerate, "mention the tests"},
+		{"empty feedback goes back", "r\n\ny\n", opts, ReviewAccept, "feat: add login"},
+		{"inline edit", "e\nfix: correct login\n\nBody.\n.\n", opts, ReviewEditInline, "fix: correct login\n\nBody."},
+		{"blank inline edit keeps message", "e\n   \n.\ny\n", opts, ReviewAccept, "feat: add login"},
+		{"external edit", "e\n", ReviewOptions{EditorMode: "external"}, ReviewEdit, ""},
+		{"cycle type then accept", "t\ny\n", opts, ReviewAccept, "fix: add login"},
+		{"scope then accept", "s\nauth\ny\n", opts, ReviewAccept, "feat(auth): add login"},
+		{"pick model", "m\n2\n", opts, ReviewRegenerateWithModel, "sonnet-4.5"},
+		{"pick format", "f\n2\n", opts, ReviewRegenerateWithFormat, ai.MessageFormats[1].String()},
+		{"invalid choice goes back", "f\n9\nn\n", opts, ReviewReject, ""},
+		{"show diff then accept", "d\ny\n", opts, ReviewAccept, "feat: add login"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			action, result, err := ShowSimpleReview(strings.NewReader(tt.input), &out, "feat: add login", "+login()", tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if action != tt.wantAction || result != tt.wantResult {
+				t.Errorf("got (%v, %q), want (%v, %q)\noutput:\n%s", action, result, tt.wantAction, tt.wantResult, out.String())
+			}
+		})
+	}
+}
+
+func TestShowSimpleReviewOutput(t *testing.T) {
+	var out bytes.Buffer
+	ShowSimpleReview(strings.NewReader("d\nn\n"), &out, "feat: add login", "+login()", ReviewOptions{})
+
+	got := out.String()
+	for _, want := range []string{"  feat: add login", "+login()", "[y]es, [n]o, [e]dit, [r]egenerate"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "[m]odel") {
+		t.Error("expected no model choice without models")
+	}
+}
+
+func TestShowSimpleReviewSharesReaderAcrossPasses(t *testing.T) {
+	// Piped answers for a regenerate pass and the pass after it
+	in := bufio.NewReader(strings.NewReader("r\nfeedback\ny\n"))
+	var out bytes.Buffer
+
+	action, result, _ := ShowSimpleReview(in, &out, "feat: add login", "", ReviewOptions{})
+	if action != ReviewRegenerate || result != "feedback" {
+		t.Fatalf("first pass = %v, %q; want regenerate with feedback", action, result)
+	}
+	action, result, _ = ShowSimpleReview(in, &out, "feat: add sign-in", "", ReviewOptions{})
+	if action != ReviewAccept || result != "feat: add sign-in" {
+		t.Errorf("second pass = %v, %q; want the regenerated message accepted", action, result)
+	}
+}
+
+func TestSimpleUIEnv(t *testing.T) {
+	t.Setenv("CMT_SIMPLE_UI", "1")
+	if !SimpleUI() {
+		t.Error("expected CMT_SIMPLE_UI=1 to select the simple UI")
+	}
+	t.Setenv("CMT_SIMPLE_UI", "true")
+	if !SimpleUI() {
+		t.Error("expected CMT_SIMPLE_UI=true to select the simple UI")
+	}
+}